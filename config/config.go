@@ -1,9 +1,14 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
+	"github.com/Moranilt/config-keeper/pkg/encryption"
+	"github.com/Moranilt/config-keeper/pkg/listeners"
 	"github.com/Moranilt/http-utils/clients/database"
 	"github.com/spf13/viper"
 )
@@ -20,6 +25,40 @@ const (
 
 	ENV_TRACER_URL  = "TRACER_URL"
 	ENV_TRACER_NAME = "TRACER_NAME"
+
+	ENV_TRASH_RETENTION      = "TRASH_RETENTION"
+	ENV_TRASH_PURGE_INTERVAL = "TRASH_PURGE_INTERVAL"
+
+	ENV_GIT_SYNC_REPO_PATH = "GIT_SYNC_REPO_PATH"
+	ENV_GIT_SYNC_REF       = "GIT_SYNC_REF"
+	ENV_GIT_SYNC_DIR       = "GIT_SYNC_DIR"
+	ENV_GIT_SYNC_FOLDER_ID = "GIT_SYNC_FOLDER_ID"
+	ENV_GIT_SYNC_INTERVAL  = "GIT_SYNC_INTERVAL"
+
+	ENV_MAX_BODY_SIZE          = "MAX_BODY_SIZE"
+	ENV_CONTENT_MAX_SIZE       = "CONTENT_MAX_SIZE"
+	ENV_CONTENT_TOTAL_MAX_SIZE = "CONTENT_TOTAL_MAX_SIZE"
+	ENV_CONTENT_INLINE_SIZE    = "CONTENT_INLINE_SIZE"
+	ENV_CONTENT_BLOB_DIR       = "CONTENT_BLOB_DIR"
+	ENV_CONTENT_SWEEP_INTERVAL = "CONTENT_SWEEP_INTERVAL"
+
+	ENV_LISTENERS_ENCRYPTION_KEY      = "LISTENERS_ENCRYPTION_KEY"
+	ENV_LISTENERS_UNHEALTHY_THRESHOLD = "LISTENERS_UNHEALTHY_THRESHOLD"
+	ENV_LISTENERS_DISABLE_THRESHOLD   = "LISTENERS_DISABLE_THRESHOLD"
+
+	ENV_PUBLIC_URL = "PUBLIC_URL"
+)
+
+const (
+	DEFAULT_TRASH_RETENTION      = 30 * 24 * time.Hour
+	DEFAULT_TRASH_PURGE_INTERVAL = time.Hour
+
+	DEFAULT_GIT_SYNC_REF = "HEAD"
+
+	DEFAULT_MAX_BODY_SIZE          = 64 << 20
+	DEFAULT_CONTENT_MAX_SIZE       = 32 << 20
+	DEFAULT_CONTENT_INLINE_SIZE    = 1 << 20
+	DEFAULT_CONTENT_SWEEP_INTERVAL = time.Hour
 )
 
 var envVariables []string = []string{
@@ -38,11 +77,49 @@ type TracerConfig struct {
 	Name string `yaml:"name"`
 }
 
+// TrashConfig describes how long deleted items are kept and how often they are purged.
+type TrashConfig struct {
+	Retention     time.Duration
+	PurgeInterval time.Duration
+}
+
+// GitSyncConfig describes a local git repository which is synced into a folder.
+// Sync is disabled if RepoPath is empty and runs only on demand if Interval is zero.
+type GitSyncConfig struct {
+	RepoPath string
+	Ref      string
+	Dir      string
+	FolderID string
+	Interval time.Duration
+}
+
+// ContentConfig describes size limits of contents in bytes and a directory for large contents.
+// Contents larger than InlineSize are kept in BlobDir, all contents are kept in the database if BlobDir is empty.
+// BlobDir should be shared storage if several instances of the service use the same database.
+// Blobs which are not referenced by contents are removed every SweepInterval.
+// TotalMaxSize limits size of all contents and is disabled if it is zero.
+type ContentConfig struct {
+	MaxSize       int64
+	TotalMaxSize  int64
+	InlineSize    int64
+	BlobDir       string
+	SweepInterval time.Duration
+}
+
 type Config struct {
-	Tracer     *TracerConfig
-	DB         *database.Credentials
-	Port       string
-	Production bool
+	Tracer      *TracerConfig
+	Trash       *TrashConfig
+	GitSync     *GitSyncConfig
+	Content     *ContentConfig
+	Listeners   *listeners.HealthConfig
+	DB          *database.Credentials
+	Port        string
+	MaxBodySize int64
+	Production  bool
+	// ListenersEncryptionKey encrypts headers and credentials of listeners. They can not be set if it is empty.
+	ListenersEncryptionKey []byte
+	// PublicURL is an address of the service used in links sent to listeners. Links are relative if it is empty.
+	PublicURL string
 }
 
 func Read() (*Config, error) {
@@ -71,15 +148,188 @@ func Read() (*Config, error) {
 		dbCreds.SSLMode = &sslMode
 	}
 
+	trash, err := readTrashConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	gitSync, err := readGitSyncConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := readContentConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	listenersHealth, err := readListenersHealth()
+	if err != nil {
+		return nil, err
+	}
+
+	maxBodySize, err := readSize(ENV_MAX_BODY_SIZE, DEFAULT_MAX_BODY_SIZE, false)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptionKey, err := readEncryptionKey(ENV_LISTENERS_ENCRYPTION_KEY)
+	if err != nil {
+		return nil, err
+	}
+
 	envCfg = Config{
-		DB: dbCreds,
+		DB:        dbCreds,
+		Trash:     trash,
+		GitSync:   gitSync,
+		Content:   content,
+		Listeners: listenersHealth,
 		Tracer: &TracerConfig{
 			URL:  result[ENV_TRACER_URL],
 			Name: result[ENV_TRACER_NAME],
 		},
-		Port:       result[ENV_PORT],
-		Production: isProduction,
+		Port:                   result[ENV_PORT],
+		MaxBodySize:            maxBodySize,
+		Production:             isProduction,
+		ListenersEncryptionKey: encryptionKey,
+		PublicURL:              os.Getenv(ENV_PUBLIC_URL),
 	}
 
 	return &envCfg, nil
 }
+
+// readTrashConfig reads optional trash settings. Values should be in time.ParseDuration format.
+func readTrashConfig() (*TrashConfig, error) {
+	cfg := &TrashConfig{
+		Retention:     DEFAULT_TRASH_RETENTION,
+		PurgeInterval: DEFAULT_TRASH_PURGE_INTERVAL,
+	}
+
+	for name, value := range map[string]*time.Duration{
+		ENV_TRASH_RETENTION:      &cfg.Retention,
+		ENV_TRASH_PURGE_INTERVAL: &cfg.PurgeInterval,
+	} {
+		env := os.Getenv(name)
+		if env == "" {
+			continue
+		}
+
+		duration, err := time.ParseDuration(env)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("env %q should be a positive duration, got %q", name, env)
+		}
+		*value = duration
+	}
+
+	return cfg, nil
+}
+
+// readGitSyncConfig reads optional git sync settings. Interval should be in time.ParseDuration format.
+func readGitSyncConfig() (*GitSyncConfig, error) {
+	cfg := &GitSyncConfig{
+		RepoPath: os.Getenv(ENV_GIT_SYNC_REPO_PATH),
+		Ref:      os.Getenv(ENV_GIT_SYNC_REF),
+		Dir:      os.Getenv(ENV_GIT_SYNC_DIR),
+		FolderID: os.Getenv(ENV_GIT_SYNC_FOLDER_ID),
+	}
+	if cfg.Ref == "" {
+		cfg.Ref = DEFAULT_GIT_SYNC_REF
+	}
+
+	if env := os.Getenv(ENV_GIT_SYNC_INTERVAL); env != "" {
+		duration, err := time.ParseDuration(env)
+		if err != nil || duration < 0 {
+			return nil, fmt.Errorf("env %q should be a duration, got %q", ENV_GIT_SYNC_INTERVAL, env)
+		}
+		cfg.Interval = duration
+	}
+
+	return cfg, nil
+}
+
+// readContentConfig reads optional content settings. Sizes are in bytes.
+func readContentConfig() (*ContentConfig, error) {
+	cfg := &ContentConfig{
+		BlobDir:       os.Getenv(ENV_CONTENT_BLOB_DIR),
+		SweepInterval: DEFAULT_CONTENT_SWEEP_INTERVAL,
+	}
+
+	var err error
+	if cfg.MaxSize, err = readSize(ENV_CONTENT_MAX_SIZE, DEFAULT_CONTENT_MAX_SIZE, false); err != nil {
+		return nil, err
+	}
+	if cfg.TotalMaxSize, err = readSize(ENV_CONTENT_TOTAL_MAX_SIZE, 0, true); err != nil {
+		return nil, err
+	}
+	if cfg.InlineSize, err = readSize(ENV_CONTENT_INLINE_SIZE, DEFAULT_CONTENT_INLINE_SIZE, false); err != nil {
+		return nil, err
+	}
+
+	if env := os.Getenv(ENV_CONTENT_SWEEP_INTERVAL); env != "" {
+		duration, err := time.ParseDuration(env)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("env %q should be a positive duration, got %q", ENV_CONTENT_SWEEP_INTERVAL, env)
+		}
+		cfg.SweepInterval = duration
+	}
+
+	return cfg, nil
+}
+
+// readListenersHealth reads optional thresholds of consecutive failures of listeners.
+// Listeners become unhealthy before they are disabled, so the unhealthy threshold can not be greater.
+func readListenersHealth() (*listeners.HealthConfig, error) {
+	cfg := &listeners.HealthConfig{
+		UnhealthyThreshold: listeners.DEFAULT_UNHEALTHY_THRESHOLD,
+		DisableThreshold:   listeners.DEFAULT_DISABLE_THRESHOLD,
+	}
+
+	for name, value := range map[string]*int{
+		ENV_LISTENERS_UNHEALTHY_THRESHOLD: &cfg.UnhealthyThreshold,
+		ENV_LISTENERS_DISABLE_THRESHOLD:   &cfg.DisableThreshold,
+	} {
+		env := os.Getenv(name)
+		if env == "" {
+			continue
+		}
+
+		threshold, err := strconv.Atoi(env)
+		if err != nil || threshold <= 0 {
+			return nil, fmt.Errorf("env %q should be a positive number, got %q", name, env)
+		}
+		*value = threshold
+	}
+
+	if cfg.UnhealthyThreshold > cfg.DisableThreshold {
+		return nil, fmt.Errorf("env %q should not be greater than %q", ENV_LISTENERS_UNHEALTHY_THRESHOLD, ENV_LISTENERS_DISABLE_THRESHOLD)
+	}
+	return cfg, nil
+}
+
+// readSize reads a size in bytes. Zero is allowed only if allowZero is set.
+func readSize(name string, defaultValue int64, allowZero bool) (int64, error) {
+	env := os.Getenv(name)
+	if env == "" {
+		return defaultValue, nil
+	}
+
+	size, err := strconv.ParseInt(env, 10, 64)
+	if err != nil || size < 0 || (size == 0 && !allowZero) {
+		return 0, fmt.Errorf("env %q should be a positive number of bytes, got %q", name, env)
+	}
+	return size, nil
+}
+
+// readEncryptionKey reads an optional base64 encoded key of encryption.KEY_SIZE bytes.
+func readEncryptionKey(name string) ([]byte, error) {
+	env := os.Getenv(name)
+	if env == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(env)
+	if err != nil || len(key) != encryption.KEY_SIZE {
+		return nil, fmt.Errorf("env %q should be a base64 encoded key of %d bytes", name, encryption.KEY_SIZE)
+	}
+	return key, nil
+}
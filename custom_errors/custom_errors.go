@@ -11,6 +11,8 @@ const (
 	ERR_CODE_NotValid
 	ERR_CODE_Exists
 	ERR_CODE_REQUIRED_FIELD
+	ERR_CODE_TooLarge
+	ERR_CODE_Internal
 )
 
 var ERRORS = map[int]string{
@@ -23,4 +25,6 @@ var ERRORS = map[int]string{
 	ERR_CODE_NotValid:       "not valid",
 	ERR_CODE_Exists:         "already exists",
 	ERR_CODE_REQUIRED_FIELD: "required field is missing",
+	ERR_CODE_TooLarge:       "content too large",
+	ERR_CODE_Internal:       "internal error",
 }
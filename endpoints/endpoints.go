@@ -38,6 +38,16 @@ func MakeEndpoints(service service.Service, mw *middleware.Middleware) []Endpoin
 			HandleFunc: service.EditFolder,
 			Methods:    []string{http.MethodPatch},
 		},
+		{
+			Pattern:    "/folders/{folder_id}/export",
+			HandleFunc: service.ExportFolder,
+			Methods:    []string{http.MethodGet},
+		},
+		{
+			Pattern:    "/folders/{folder_id}/import",
+			HandleFunc: service.ImportFolder,
+			Methods:    []string{http.MethodPost},
+		},
 		{
 			Pattern:    "/files",
 			HandleFunc: service.CreateFile,
@@ -58,6 +68,11 @@ func MakeEndpoints(service service.Service, mw *middleware.Middleware) []Endpoin
 			HandleFunc: service.GetFile,
 			Methods:    []string{http.MethodGet},
 		},
+		{
+			Pattern:    "/files/{file_id}/export",
+			HandleFunc: service.ExportFile,
+			Methods:    []string{http.MethodGet},
+		},
 		{
 			Pattern:    "/files/{file_id}/contents",
 			HandleFunc: service.GetFileContents,
@@ -103,11 +118,96 @@ func MakeEndpoints(service service.Service, mw *middleware.Middleware) []Endpoin
 			HandleFunc: service.DeleteListener,
 			Methods:    []string{http.MethodDelete},
 		},
+		{
+			Pattern:    "/files/{file_id}/listeners/{listener_id}/deliveries",
+			HandleFunc: service.GetListenerDeliveries,
+			Methods:    []string{http.MethodGet},
+		},
+		{
+			Pattern:    "/files/{file_id}/listeners/{listener_id}/rotate-secret",
+			HandleFunc: service.RotateListenerSecret,
+			Methods:    []string{http.MethodPost},
+		},
+		{
+			Pattern:    "/files/{file_id}/listeners/{listener_id}/enable",
+			HandleFunc: service.EnableListener,
+			Methods:    []string{http.MethodPost},
+		},
+		{
+			Pattern:    "/files/{file_id}/listeners/{listener_id}/disable",
+			HandleFunc: service.DisableListener,
+			Methods:    []string{http.MethodPost},
+		},
+		{
+			Pattern:    "/files/{file_id}/listeners/{listener_id}/redeliver",
+			HandleFunc: service.RedeliverListener,
+			Methods:    []string{http.MethodPost},
+		},
+		{
+			Pattern:    "/files/{file_id}/listeners/{listener_id}/dead-letters",
+			HandleFunc: service.GetListenerDeadLetters,
+			Methods:    []string{http.MethodGet},
+		},
+		{
+			Pattern:    "/files/{file_id}/listeners/{listener_id}/dead-letters/replay",
+			HandleFunc: service.ReplayListenerDeadLetters,
+			Methods:    []string{http.MethodPost},
+		},
+		{
+			Pattern:    "/files/{file_id}/listeners/{listener_id}/ping",
+			HandleFunc: service.PingListener,
+			Methods:    []string{http.MethodPost},
+		},
 		{
 			Pattern:    "/formats",
 			HandleFunc: service.GetContentFormats,
 			Methods:    []string{http.MethodGet},
 		},
+		{
+			Pattern:    "/formats",
+			HandleFunc: service.CreateContentFormat,
+			Methods:    []string{http.MethodPost},
+		},
+		{
+			Pattern:    "/formats/{format_id}",
+			HandleFunc: service.GetContentFormat,
+			Methods:    []string{http.MethodGet},
+		},
+		{
+			Pattern:    "/formats/{format_id}",
+			HandleFunc: service.EditContentFormat,
+			Methods:    []string{http.MethodPatch},
+		},
+		{
+			Pattern:    "/formats/{format_id}",
+			HandleFunc: service.DeleteContentFormat,
+			Methods:    []string{http.MethodDelete},
+		},
+		{
+			Pattern:    "/trash",
+			HandleFunc: service.GetTrash,
+			Methods:    []string{http.MethodGet},
+		},
+		{
+			Pattern:    "/trash/folders/{folder_id}/restore",
+			HandleFunc: service.RestoreFolder,
+			Methods:    []string{http.MethodPost},
+		},
+		{
+			Pattern:    "/trash/files/{file_id}/restore",
+			HandleFunc: service.RestoreFile,
+			Methods:    []string{http.MethodPost},
+		},
+		{
+			Pattern:    "/batch",
+			HandleFunc: service.Batch,
+			Methods:    []string{http.MethodPost},
+		},
+		{
+			Pattern:    "/sync/git",
+			HandleFunc: service.SyncGit,
+			Methods:    []string{http.MethodPost},
+		},
 	}
 }
 
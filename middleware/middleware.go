@@ -1,10 +1,14 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/Moranilt/config-keeper/utils"
 	"github.com/Moranilt/http-utils/logger"
 	"github.com/Moranilt/http-utils/response"
 	"github.com/google/uuid"
@@ -16,6 +20,9 @@ type ContextKey string
 
 const (
 	TOKEN_HEADER = "X-App-Token"
+
+	// FIELDS_PARAM is a query parameter with comma separated fields of the response body, see utils.SelectFields.
+	FIELDS_PARAM = "fields"
 )
 
 type Middleware struct {
@@ -83,6 +90,63 @@ func (m *Middleware) AppTokenRequired(next http.Handler) http.Handler {
 	})
 }
 
+// Fields keeps only fields listed in FIELDS_PARAM in the body of JSON responses of GET requests.
+func (m *Middleware) Fields(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fields := utils.ParseFields(r.URL.Query().Get(FIELDS_PARAM))
+		if r.Method != http.MethodGet || len(fields) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		bw := &bufferedWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(bw, r)
+
+		data := bw.body.Bytes()
+		if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+			selected, err := selectBodyFields(data, fields)
+			if err != nil {
+				m.logger.Errorf("select fields: %v", err)
+			} else {
+				data = selected
+			}
+		}
+
+		w.Header().Del("Content-Length")
+		w.WriteHeader(bw.statusCode)
+		w.Write(data)
+	})
+}
+
+// bufferedWriter keeps the response until it is changed by middleware.
+type bufferedWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (bw *bufferedWriter) WriteHeader(code int) {
+	bw.statusCode = code
+}
+
+func (bw *bufferedWriter) Write(data []byte) (int, error) {
+	return bw.body.Write(data)
+}
+
+func selectBodyFields(data []byte, fields []string) ([]byte, error) {
+	var resp map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&resp); err != nil {
+		return nil, err
+	}
+
+	if body, ok := resp["body"]; ok {
+		resp["body"] = utils.SelectFields(body, fields)
+	}
+	return json.Marshal(resp)
+}
+
 func GetRequestID(ctx context.Context) string {
 	return ctx.Value(logger.CtxRequestId).(string)
 }
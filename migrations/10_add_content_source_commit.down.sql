@@ -0,0 +1 @@
+ALTER TABLE file_contents DROP COLUMN IF EXISTS source_commit;
\ No newline at end of file
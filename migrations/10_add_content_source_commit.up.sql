@@ -0,0 +1 @@
+ALTER TABLE file_contents ADD COLUMN source_commit VARCHAR(64) DEFAULT NULL;
\ No newline at end of file
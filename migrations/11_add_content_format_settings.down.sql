@@ -0,0 +1,14 @@
+ALTER TABLE file_contents DROP CONSTRAINT file_contents_format_id_fkey;
+ALTER TABLE file_contents ADD CONSTRAINT file_contents_format_id_fkey
+  FOREIGN KEY (format_id) REFERENCES content_formats(id) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS idx_content_formats_name;
+
+DELETE FROM content_formats WHERE name IN ('properties', 'ini', 'hcl', 'xml');
+
+ALTER TABLE content_formats
+  DROP COLUMN mime_type,
+  DROP COLUMN extension,
+  DROP COLUMN validator,
+  DROP COLUMN created_at,
+  DROP COLUMN updated_at;
@@ -0,0 +1,24 @@
+ALTER TABLE content_formats
+  ADD COLUMN mime_type VARCHAR(255) NOT NULL DEFAULT 'text/plain',
+  ADD COLUMN extension VARCHAR(32) DEFAULT NULL,
+  ADD COLUMN validator VARCHAR(64) DEFAULT NULL,
+  ADD COLUMN created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE content_formats SET mime_type = 'application/yaml', extension = 'yaml', validator = 'yaml' WHERE name = 'yaml';
+UPDATE content_formats SET mime_type = 'application/toml', extension = 'toml', validator = 'toml' WHERE name = 'toml';
+UPDATE content_formats SET mime_type = 'application/json', extension = 'json', validator = 'json' WHERE name = 'json';
+UPDATE content_formats SET mime_type = 'text/plain', extension = 'env', validator = 'env' WHERE name = 'env';
+
+INSERT INTO content_formats (name, mime_type, extension, validator) VALUES
+  ('properties', 'text/x-java-properties', 'properties', 'properties'),
+  ('ini', 'text/plain', 'ini', 'ini'),
+  ('hcl', 'application/hcl', 'hcl', 'hcl'),
+  ('xml', 'application/xml', 'xml', 'xml');
+
+CREATE UNIQUE INDEX idx_content_formats_name ON content_formats (name);
+
+-- formats which are used by contents can not be deleted
+ALTER TABLE file_contents DROP CONSTRAINT file_contents_format_id_fkey;
+ALTER TABLE file_contents ADD CONSTRAINT file_contents_format_id_fkey
+  FOREIGN KEY (format_id) REFERENCES content_formats(id) ON DELETE RESTRICT;
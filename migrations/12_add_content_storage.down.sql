@@ -0,0 +1,3 @@
+-- contents kept in blobs are not moved back into the database
+ALTER TABLE file_contents DROP COLUMN IF EXISTS blob_key;
+ALTER TABLE file_contents DROP COLUMN IF EXISTS size;
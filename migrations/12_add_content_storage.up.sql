@@ -0,0 +1,4 @@
+ALTER TABLE file_contents ADD COLUMN size BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE file_contents ADD COLUMN blob_key TEXT NOT NULL DEFAULT '';
+
+UPDATE file_contents SET size = octet_length(decode(content, 'base64'));
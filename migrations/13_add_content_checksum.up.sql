@@ -0,0 +1,6 @@
+ALTER TABLE file_contents ADD COLUMN checksum TEXT NOT NULL DEFAULT '';
+
+UPDATE file_contents SET checksum = CASE
+  WHEN blob_key <> '' THEN blob_key
+  ELSE encode(sha256(decode(content, 'base64')), 'hex')
+END;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_file_contents_file_id_version;
+DROP INDEX IF EXISTS idx_files_folder_id_name;
+DROP INDEX IF EXISTS idx_folders_parent_id_name;
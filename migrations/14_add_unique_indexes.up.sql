@@ -0,0 +1,27 @@
+-- duplicates created before the indexes keep the oldest item, names of others get their id as a suffix,
+-- so the indexes can be created and items can still be found and renamed
+UPDATE folders SET name = LEFT(folders.name, 218) || '-' || folders.id::text
+FROM (
+  SELECT id, ROW_NUMBER() OVER (PARTITION BY COALESCE(parent_id::text, ''), name ORDER BY created_at, id) AS position
+  FROM folders WHERE deleted_at IS NULL
+) AS duplicates
+WHERE folders.id = duplicates.id AND duplicates.position > 1;
+
+UPDATE files SET name = LEFT(files.name, 218) || '-' || files.id::text
+FROM (
+  SELECT id, ROW_NUMBER() OVER (PARTITION BY folder_id, name ORDER BY created_at, id) AS position
+  FROM files WHERE deleted_at IS NULL
+) AS duplicates
+WHERE files.id = duplicates.id AND duplicates.position > 1;
+
+UPDATE file_contents SET version = LEFT(file_contents.version, 218) || '-' || file_contents.id::text
+FROM (
+  SELECT id, ROW_NUMBER() OVER (PARTITION BY file_id, version ORDER BY created_at, id) AS position
+  FROM file_contents
+) AS duplicates
+WHERE file_contents.id = duplicates.id AND duplicates.position > 1;
+
+-- soft deleted items do not take names, they are checked again on restore
+CREATE UNIQUE INDEX idx_folders_parent_id_name ON folders (COALESCE(parent_id::text, ''), name) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_files_folder_id_name ON files (folder_id, name) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_file_contents_file_id_version ON file_contents (file_id, version);
@@ -0,0 +1,10 @@
+CREATE TABLE callback_outbox (
+  id BIGSERIAL PRIMARY KEY,
+  file_id UUID NOT NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT DEFAULT NULL,
+  available_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_callback_outbox_available_at ON callback_outbox (available_at);
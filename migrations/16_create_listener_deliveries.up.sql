@@ -0,0 +1,15 @@
+CREATE TABLE listener_deliveries (
+  id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+  listener_id UUID NOT NULL,
+  event_id BIGINT NOT NULL,
+  attempt INTEGER NOT NULL,
+  status VARCHAR(16) NOT NULL,
+  status_code INTEGER DEFAULT NULL,
+  latency_ms BIGINT NOT NULL,
+  response TEXT DEFAULT NULL,
+  error TEXT DEFAULT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (listener_id) REFERENCES listeners(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_listener_deliveries_listener_id_created_at ON listener_deliveries (listener_id, created_at);
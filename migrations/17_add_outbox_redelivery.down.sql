@@ -0,0 +1,2 @@
+ALTER TABLE callback_outbox DROP COLUMN IF EXISTS redelivered_event_id;
+ALTER TABLE callback_outbox DROP COLUMN IF EXISTS listener_id;
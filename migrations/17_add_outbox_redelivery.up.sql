@@ -0,0 +1,2 @@
+ALTER TABLE callback_outbox ADD COLUMN listener_id UUID DEFAULT NULL;
+ALTER TABLE callback_outbox ADD COLUMN redelivered_event_id BIGINT DEFAULT NULL;
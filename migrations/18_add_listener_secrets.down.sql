@@ -0,0 +1,3 @@
+ALTER TABLE listeners DROP COLUMN IF EXISTS previous_secret_expires_at;
+ALTER TABLE listeners DROP COLUMN IF EXISTS previous_secret;
+ALTER TABLE listeners DROP COLUMN IF EXISTS secret;
@@ -0,0 +1,6 @@
+ALTER TABLE listeners ADD COLUMN secret TEXT NOT NULL DEFAULT '';
+ALTER TABLE listeners ADD COLUMN previous_secret TEXT DEFAULT NULL;
+ALTER TABLE listeners ADD COLUMN previous_secret_expires_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+
+-- existing listeners get random secrets which are shown by rotation
+UPDATE listeners SET secret = replace(gen_random_uuid()::text || gen_random_uuid()::text, '-', '');
@@ -0,0 +1,2 @@
+-- headers and credentials of callback requests encrypted by the application
+ALTER TABLE listeners ADD COLUMN settings TEXT DEFAULT NULL;
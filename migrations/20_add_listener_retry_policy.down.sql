@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS callback_dead_letters;
+ALTER TABLE callback_outbox RENAME COLUMN event_id TO redelivered_event_id;
+ALTER TABLE listeners DROP COLUMN IF EXISTS retry_policy;
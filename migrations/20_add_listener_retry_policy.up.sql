@@ -0,0 +1,19 @@
+-- retry policy of the listener, missing fields use default values of the application
+ALTER TABLE listeners ADD COLUMN retry_policy JSONB NOT NULL DEFAULT '{}';
+
+-- events are split into a row per listener, so every listener is retried by its own policy
+ALTER TABLE callback_outbox RENAME COLUMN redelivered_event_id TO event_id;
+
+CREATE TABLE callback_dead_letters (
+  id BIGINT PRIMARY KEY,
+  file_id UUID NOT NULL,
+  listener_id UUID NOT NULL,
+  event_id BIGINT NOT NULL,
+  attempts INTEGER NOT NULL,
+  last_error TEXT DEFAULT NULL,
+  created_at TIMESTAMP WITH TIME ZONE,
+  failed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (listener_id) REFERENCES listeners(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_callback_dead_letters_listener_id_failed_at ON callback_dead_letters (listener_id, failed_at);
@@ -0,0 +1,2 @@
+-- callback requests are posted again to the location of redirects only if the listener allows it
+ALTER TABLE listeners ADD COLUMN follow_redirects BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,6 @@
+ALTER TABLE listeners DROP COLUMN IF EXISTS last_failure_at;
+ALTER TABLE listeners DROP COLUMN IF EXISTS last_success_at;
+ALTER TABLE listeners DROP COLUMN IF EXISTS consecutive_failures;
+ALTER TABLE listeners DROP COLUMN IF EXISTS disabled_reason;
+ALTER TABLE listeners DROP COLUMN IF EXISTS healthy;
+ALTER TABLE listeners DROP COLUMN IF EXISTS enabled;
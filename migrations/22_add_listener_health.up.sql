@@ -0,0 +1,7 @@
+-- listeners are marked unhealthy and disabled after consecutive failed callback requests
+ALTER TABLE listeners ADD COLUMN enabled BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE listeners ADD COLUMN healthy BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE listeners ADD COLUMN disabled_reason TEXT;
+ALTER TABLE listeners ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE listeners ADD COLUMN last_success_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE listeners ADD COLUMN last_failure_at TIMESTAMP WITH TIME ZONE;
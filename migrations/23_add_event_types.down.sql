@@ -0,0 +1,6 @@
+ALTER TABLE listeners DROP COLUMN IF EXISTS event_types;
+ALTER TABLE listener_deliveries DROP COLUMN IF EXISTS event_type;
+ALTER TABLE callback_dead_letters DROP COLUMN IF EXISTS details;
+ALTER TABLE callback_dead_letters DROP COLUMN IF EXISTS event_type;
+ALTER TABLE callback_outbox DROP COLUMN IF EXISTS details;
+ALTER TABLE callback_outbox DROP COLUMN IF EXISTS event_type;
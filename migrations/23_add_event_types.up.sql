@@ -0,0 +1,9 @@
+-- events have types, details describe the change of the event
+ALTER TABLE callback_outbox ADD COLUMN event_type VARCHAR(32) NOT NULL DEFAULT 'content.updated';
+ALTER TABLE callback_outbox ADD COLUMN details JSONB DEFAULT NULL;
+ALTER TABLE callback_dead_letters ADD COLUMN event_type VARCHAR(32) NOT NULL DEFAULT 'content.updated';
+ALTER TABLE callback_dead_letters ADD COLUMN details JSONB DEFAULT NULL;
+ALTER TABLE listener_deliveries ADD COLUMN event_type VARCHAR(32) NOT NULL DEFAULT 'content.updated';
+
+-- listeners without event types are subscribed to all events
+ALTER TABLE listeners ADD COLUMN event_types TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+ALTER TABLE listeners DROP COLUMN IF EXISTS payload_mode;
+ALTER TABLE listeners DROP COLUMN IF EXISTS version_filter;
@@ -0,0 +1,3 @@
+-- listeners get events of contents only if their versions match the filter, the payload mode defines what is sent
+ALTER TABLE listeners ADD COLUMN version_filter JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE listeners ADD COLUMN payload_mode VARCHAR(16) NOT NULL DEFAULT 'full';
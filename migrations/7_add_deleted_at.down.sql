@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_files_deleted_at;
+DROP INDEX IF EXISTS idx_folders_deleted_at;
+
+ALTER TABLE files DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE folders DROP COLUMN IF EXISTS deleted_at;
\ No newline at end of file
@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS idx_files_labels;
+DROP INDEX IF EXISTS idx_files_owner_team;
+DROP INDEX IF EXISTS idx_folders_labels;
+DROP INDEX IF EXISTS idx_folders_owner_team;
+
+ALTER TABLE files
+  DROP COLUMN IF EXISTS labels,
+  DROP COLUMN IF EXISTS contact,
+  DROP COLUMN IF EXISTS owner_team,
+  DROP COLUMN IF EXISTS description;
+
+ALTER TABLE folders
+  DROP COLUMN IF EXISTS labels,
+  DROP COLUMN IF EXISTS contact,
+  DROP COLUMN IF EXISTS owner_team,
+  DROP COLUMN IF EXISTS description;
\ No newline at end of file
@@ -0,0 +1,16 @@
+ALTER TABLE folders
+  ADD COLUMN description TEXT DEFAULT NULL,
+  ADD COLUMN owner_team VARCHAR(255) DEFAULT NULL,
+  ADD COLUMN contact VARCHAR(255) DEFAULT NULL,
+  ADD COLUMN labels JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE files
+  ADD COLUMN description TEXT DEFAULT NULL,
+  ADD COLUMN owner_team VARCHAR(255) DEFAULT NULL,
+  ADD COLUMN contact VARCHAR(255) DEFAULT NULL,
+  ADD COLUMN labels JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_folders_owner_team ON folders (owner_team);
+CREATE INDEX idx_folders_labels ON folders USING GIN (labels);
+CREATE INDEX idx_files_owner_team ON files (owner_team);
+CREATE INDEX idx_files_labels ON files USING GIN (labels);
\ No newline at end of file
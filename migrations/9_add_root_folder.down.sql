@@ -0,0 +1,14 @@
+-- files can not exist without a folder before the root folder, they should be moved into other folders
+-- or purged from the trash before the migration is reverted
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM files WHERE folder_id = '00000000-0000-0000-0000-000000000000') THEN
+    RAISE EXCEPTION 'root folder contains files, move them into other folders before reverting the migration';
+  END IF;
+END $$;
+
+ALTER TABLE folders DROP CONSTRAINT IF EXISTS folders_single_root;
+
+UPDATE folders SET parent_id = NULL WHERE parent_id = '00000000-0000-0000-0000-000000000000';
+
+DELETE FROM folders WHERE id = '00000000-0000-0000-0000-000000000000';
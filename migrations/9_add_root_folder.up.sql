@@ -0,0 +1,8 @@
+INSERT INTO folders (id, name, parent_id) VALUES ('00000000-0000-0000-0000-000000000000', 'root', NULL)
+ON CONFLICT (id) DO NOTHING;
+
+UPDATE folders SET parent_id = '00000000-0000-0000-0000-000000000000'
+WHERE parent_id IS NULL AND id <> '00000000-0000-0000-0000-000000000000';
+
+ALTER TABLE folders ADD CONSTRAINT folders_single_root
+CHECK (parent_id IS NOT NULL OR id = '00000000-0000-0000-0000-000000000000');
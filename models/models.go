@@ -1,13 +1,42 @@
 package models
 
 import (
+	"github.com/Moranilt/config-keeper/pkg/archive"
+	"github.com/Moranilt/config-keeper/pkg/batch"
+	"github.com/Moranilt/config-keeper/pkg/callback"
 	"github.com/Moranilt/config-keeper/pkg/content_formats"
+	"github.com/Moranilt/config-keeper/pkg/dead_letters"
+	"github.com/Moranilt/config-keeper/pkg/deliveries"
 	"github.com/Moranilt/config-keeper/pkg/file_contents"
 	"github.com/Moranilt/config-keeper/pkg/files"
 	"github.com/Moranilt/config-keeper/pkg/folders"
 	"github.com/Moranilt/config-keeper/pkg/listeners"
+	"github.com/Moranilt/config-keeper/pkg/trash"
+	"github.com/Moranilt/config-keeper/utils"
 )
 
+// INCLUDE_CONTENT includes data of contents into the file response.
+const INCLUDE_CONTENT = "content"
+
+// ListParams are common query parameters of list endpoints.
+type ListParams struct {
+	OrderColumn   *string `mapstructure:"order_column"`
+	OrderType     *string `mapstructure:"order_type"`
+	Limit         *string `mapstructure:"limit"`
+	NamePrefix    *string `mapstructure:"name_prefix"`
+	CreatedAfter  *string `mapstructure:"created_after"`
+	CreatedBefore *string `mapstructure:"created_before"`
+	UpdatedAfter  *string `mapstructure:"updated_after"`
+	UpdatedBefore *string `mapstructure:"updated_before"`
+}
+
+// MetadataParams are query parameters to filter folders and files by owner and labels.
+type MetadataParams struct {
+	OwnerTeam *string `mapstructure:"owner_team"`
+	// Labels in key=value,key2=value2 format. Items should contain all provided labels.
+	Labels *string `mapstructure:"labels"`
+}
+
 type CreateFolderRequest struct {
 	Name     string  `json:"name"`
 	ParentID *string `json:"parent_id"`
@@ -16,20 +45,25 @@ type CreateFolderRequest struct {
 type CreateFolderResponse folders.Folder
 
 type GetFolderRequest struct {
-	FolderID    string  `mapstructure:"folder_id"`
-	OrderColumn *string `mapstructure:"order_column"`
-	OrderType   *string `mapstructure:"order_type"`
+	FolderID       string  `mapstructure:"folder_id"`
+	FoldersCursor  *string `mapstructure:"folders_cursor"`
+	FilesCursor    *string `mapstructure:"files_cursor"`
+	ListParams     `mapstructure:",squash"`
+	MetadataParams `mapstructure:",squash"`
 }
 
 type GetFolderResponse struct {
-	ID        string            `json:"id"`
-	Name      string            `json:"name"`
-	ParentID  *string           `json:"parent_id"`
-	CreatedAt string            `json:"created_at"`
-	UpdatedAt string            `json:"updated_at"`
-	Path      string            `json:"path"`
-	Folders   []*folders.Folder `json:"folders"`
-	Files     []*files.File     `json:"files"`
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	ParentID *string `json:"parent_id"`
+	utils.Metadata
+	CreatedAt         string            `json:"created_at"`
+	UpdatedAt         string            `json:"updated_at"`
+	Path              string            `json:"path"`
+	Folders           []*folders.Folder `json:"folders"`
+	Files             []*files.File     `json:"files"`
+	FoldersNextCursor *string           `json:"folders_next_cursor"`
+	FilesNextCursor   *string           `json:"files_next_cursor"`
 }
 
 type DeleteFolderRequest struct {
@@ -41,8 +75,9 @@ type DeleteFolderResponse struct {
 }
 
 type EditFolderRequest struct {
-	FolderID string `mapstructure:"folder_id"`
-	Name     string `json:"name"`
+	FolderID string  `mapstructure:"folder_id"`
+	Name     *string `json:"name"`
+	utils.MetadataUpdate
 }
 
 type EditFolderResponse folders.Folder
@@ -63,14 +98,17 @@ type DeleteFileResponse struct {
 }
 
 type EditFileRequest struct {
-	FileID string `mapstructure:"file_id"`
-	Name   string `json:"name"`
+	FileID string  `mapstructure:"file_id"`
+	Name   *string `json:"name"`
+	utils.MetadataUpdate
 }
 
 type EditFileResponse files.File
 
 type GetFileRequest struct {
 	FileID string `mapstructure:"file_id"`
+	// Include is a comma separated list of optional data, only `content` is supported.
+	Include *string `mapstructure:"include"`
 }
 
 type GetFileResponse struct {
@@ -88,11 +126,16 @@ type CreateFileContentRequest struct {
 type CreateFileContentResponse file_contents.FileContent
 
 type GetFileContentsRequest struct {
-	FileID  string  `mapstructure:"file_id"`
-	Version *string `mapstructure:"version"`
+	FileID     string  `mapstructure:"file_id"`
+	Version    *string `mapstructure:"version"`
+	Cursor     *string `mapstructure:"cursor"`
+	ListParams `mapstructure:",squash"`
 }
 
-type GetFileContentsResponse []*file_contents.FileContent
+type GetFileContentsResponse struct {
+	Contents   []*file_contents.FileContent `json:"contents"`
+	NextCursor *string                      `json:"next_cursor"`
+}
 
 type EditFileContentRequest struct {
 	ContentID string  `mapstructure:"content_id"`
@@ -111,12 +154,23 @@ type DeleteFileContentResponse struct {
 }
 
 type CreateListenerRequest struct {
-	FileID           string `mapstructure:"file_id"`
-	Name             string `json:"name"`
-	CallbackEndpoint string `json:"callback_endpoint"`
+	FileID           string                   `mapstructure:"file_id"`
+	Name             string                   `json:"name"`
+	CallbackEndpoint string                   `json:"callback_endpoint"`
+	Headers          map[string]string        `json:"headers"`
+	Auth             *listeners.Auth          `json:"auth"`
+	RetryPolicy      *listeners.RetryPolicy   `json:"retry_policy"`
+	FollowRedirects  bool                     `json:"follow_redirects"`
+	EventTypes       []string                 `json:"event_types"`
+	VersionFilter    *listeners.VersionFilter `json:"version_filter"`
+	PayloadMode      string                   `json:"payload_mode"`
 }
 
-type CreateListenerResponse listeners.Listener
+// CreateListenerResponse contains the secret which signs payloads, it is not returned by other endpoints.
+type CreateListenerResponse struct {
+	*listeners.Listener
+	Secret string `json:"secret"`
+}
 
 type GetListenerRequest struct {
 	ListenerID string `mapstructure:"listener_id"`
@@ -125,15 +179,109 @@ type GetListenerRequest struct {
 type GetListenerResponse listeners.Listener
 
 type GetFileListenersRequest struct {
-	FileID string `mapstructure:"file_id"`
+	FileID     string  `mapstructure:"file_id"`
+	Cursor     *string `mapstructure:"cursor"`
+	ListParams `mapstructure:",squash"`
+}
+
+type GetFileListenersResponse struct {
+	Listeners  []*listeners.Listener `json:"listeners"`
+	NextCursor *string               `json:"next_cursor"`
+}
+
+type GetListenerDeliveriesRequest struct {
+	FileID     string  `mapstructure:"file_id"`
+	ListenerID string  `mapstructure:"listener_id"`
+	Status     *string `mapstructure:"status"`
+	Cursor     *string `mapstructure:"cursor"`
+	ListParams `mapstructure:",squash"`
+}
+
+type GetListenerDeliveriesResponse struct {
+	Deliveries []*deliveries.Delivery `json:"deliveries"`
+	NextCursor *string                `json:"next_cursor"`
+}
+
+type RedeliverListenerRequest struct {
+	FileID     string `mapstructure:"file_id"`
+	ListenerID string `mapstructure:"listener_id"`
+	// EventID of the past event in the delivery log of the listener. The current state of the file is sent if it is nil.
+	EventID *int64 `json:"event_id"`
+}
+
+type RedeliverListenerResponse struct {
+	Status bool `json:"status"`
+}
+
+type GetListenerDeadLettersRequest struct {
+	FileID     string  `mapstructure:"file_id"`
+	ListenerID string  `mapstructure:"listener_id"`
+	Cursor     *string `mapstructure:"cursor"`
+	ListParams `mapstructure:",squash"`
+}
+
+type GetListenerDeadLettersResponse struct {
+	DeadLetters []*dead_letters.DeadLetter `json:"dead_letters"`
+	NextCursor  *string                    `json:"next_cursor"`
+}
+
+type ReplayListenerDeadLettersRequest struct {
+	FileID     string `mapstructure:"file_id"`
+	ListenerID string `mapstructure:"listener_id"`
+	// IDs of dead letters to replay, all dead letters of the listener are replayed if it is empty.
+	IDs []int64 `json:"ids"`
+}
+
+type ReplayListenerDeadLettersResponse struct {
+	// Replayed is a number of dead letters sent to the outbox.
+	Replayed int `json:"replayed"`
+}
+
+type PingListenerRequest struct {
+	FileID     string `mapstructure:"file_id"`
+	ListenerID string `mapstructure:"listener_id"`
+}
+
+type PingListenerResponse callback.PingResult
+
+type RotateListenerSecretRequest struct {
+	FileID     string `mapstructure:"file_id"`
+	ListenerID string `mapstructure:"listener_id"`
+	// PreviousLifetime in seconds during which payloads are also signed with the previous secret.
+	PreviousLifetime *int64 `json:"previous_lifetime"`
+}
+
+type RotateListenerSecretResponse struct {
+	*listeners.Listener
+	Secret string `json:"secret"`
+}
+
+type EnableListenerRequest struct {
+	FileID     string `mapstructure:"file_id"`
+	ListenerID string `mapstructure:"listener_id"`
 }
 
-type GetFileListenersResponse []*listeners.Listener
+type EnableListenerResponse listeners.Listener
+
+type DisableListenerRequest struct {
+	FileID     string  `mapstructure:"file_id"`
+	ListenerID string  `mapstructure:"listener_id"`
+	Reason     *string `json:"reason"`
+}
+
+type DisableListenerResponse listeners.Listener
 
 type EditListenerRequest struct {
-	ListenerID       string  `mapstructure:"listener_id"`
-	Name             *string `json:"name"`
-	CallbackEndpoint *string `json:"callback_endpoint"`
+	ListenerID       string                   `mapstructure:"listener_id"`
+	Name             *string                  `json:"name"`
+	CallbackEndpoint *string                  `json:"callback_endpoint"`
+	Headers          map[string]string        `json:"headers"`
+	Auth             *listeners.Auth          `json:"auth"`
+	RetryPolicy      *listeners.RetryPolicy   `json:"retry_policy"`
+	FollowRedirects  *bool                    `json:"follow_redirects"`
+	EventTypes       []string                 `json:"event_types"`
+	VersionFilter    *listeners.VersionFilter `json:"version_filter"`
+	PayloadMode      *string                  `json:"payload_mode"`
 }
 
 type EditListenerResponse listeners.Listener
@@ -149,3 +297,130 @@ type DeleteListenerResponse struct {
 type GetContentFormatsRequest struct{}
 
 type GetContentFormatsResponse []*content_formats.ContentFormat
+
+type GetContentFormatRequest struct {
+	FormatID string `mapstructure:"format_id"`
+}
+
+type GetContentFormatResponse content_formats.ContentFormat
+
+type CreateContentFormatRequest struct {
+	Name      string  `json:"name"`
+	MimeType  *string `json:"mime_type"`
+	Extension *string `json:"extension"`
+	Validator *string `json:"validator"`
+}
+
+type CreateContentFormatResponse content_formats.ContentFormat
+
+type EditContentFormatRequest struct {
+	FormatID  string  `mapstructure:"format_id"`
+	Name      *string `json:"name"`
+	MimeType  *string `json:"mime_type"`
+	Extension *string `json:"extension"`
+	Validator *string `json:"validator"`
+}
+
+type EditContentFormatResponse content_formats.ContentFormat
+
+type DeleteContentFormatRequest struct {
+	FormatID string `mapstructure:"format_id"`
+}
+
+type DeleteContentFormatResponse struct {
+	Status bool `json:"status"`
+}
+
+type GetTrashRequest struct {
+	Cursor     *string `mapstructure:"cursor"`
+	Limit      *string `mapstructure:"limit"`
+	NamePrefix *string `mapstructure:"name_prefix"`
+}
+
+type GetTrashResponse struct {
+	Items      []*trash.Item `json:"items"`
+	NextCursor *string       `json:"next_cursor"`
+}
+
+type RestoreFolderRequest struct {
+	FolderID string `mapstructure:"folder_id"`
+}
+
+type RestoreFolderResponse struct {
+	Status bool `json:"status"`
+}
+
+type RestoreFileRequest struct {
+	FileID string `mapstructure:"file_id"`
+}
+
+type RestoreFileResponse struct {
+	Status bool `json:"status"`
+}
+
+type BatchRequest struct {
+	Operations []*batch.Operation `json:"operations"`
+}
+
+type BatchResponse struct {
+	Results []*batch.Result `json:"results"`
+}
+
+type ExportFolderRequest struct {
+	FolderID string  `mapstructure:"folder_id"`
+	Format   string  `mapstructure:"format"`
+	Version  *string `mapstructure:"version"`
+	// Files are names of env files to merge in order of precedence, used only by env format.
+	Files []string `mapstructure:"files"`
+	KubernetesParams
+}
+
+// KubernetesParams are used only by kubernetes export format.
+type KubernetesParams struct {
+	NamespaceTemplate string `mapstructure:"namespace_template"`
+	NameTemplate      string `mapstructure:"name_template"`
+}
+
+type ExportFolderResponse struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+type ExportFileRequest struct {
+	FileID  string  `mapstructure:"file_id"`
+	Format  string  `mapstructure:"format"`
+	Version *string `mapstructure:"version"`
+	// Mask are glob patterns of keys whose values are masked, used only when the content is converted.
+	Mask []string `mapstructure:"mask"`
+	KubernetesParams
+}
+
+type ExportFileResponse ExportFolderResponse
+
+type ImportFolderRequest struct {
+	FolderID string `mapstructure:"folder_id"`
+	Format   string `mapstructure:"format"`
+	DryRun   bool   `mapstructure:"dry_run"`
+	Archive  []byte
+}
+
+type ImportFolderResponse struct {
+	DryRun    bool              `json:"dry_run"`
+	Changes   []*archive.Change `json:"changes"`
+	Unchanged int               `json:"unchanged"`
+}
+
+type SyncGitRequest struct {
+	Ref    *string `json:"ref"`
+	DryRun bool    `json:"dry_run"`
+}
+
+type SyncGitResponse struct {
+	DryRun    bool              `json:"dry_run"`
+	Commit    string            `json:"commit"`
+	Version   string            `json:"version"`
+	Changes   []*archive.Change `json:"changes"`
+	Unchanged int               `json:"unchanged"`
+	Skipped   []string          `json:"skipped"`
+}
@@ -0,0 +1,379 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"path"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/blobs"
+	"github.com/Moranilt/config-keeper/pkg/callback"
+	"github.com/Moranilt/config-keeper/pkg/content_formats"
+	"github.com/Moranilt/config-keeper/pkg/folders"
+	"github.com/Moranilt/config-keeper/pkg/transaction"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/clients/database"
+	"github.com/Moranilt/http-utils/tiny_errors"
+)
+
+// errDryRun rolls back the transaction of a dry run import.
+var errDryRun = tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("dry run"))
+
+type client struct {
+	db      *database.Client
+	storage *blobs.Storage
+}
+
+type Client interface {
+	// Export builds manifest of the folder with all nested folders, files and their contents.
+	// Deleted items are skipped. Contents are decoded, so Data contains the original config.
+	Export(ctx context.Context, req *ExportRequest) (*Manifest, tiny_errors.ErrorHandler)
+
+	// Import recreates the manifest tree inside the folder in a single transaction. Existing folders
+	// and files with the same names are reused, existing versions are updated only if their content or format differs.
+	// If ctx has a transaction the tree is imported in it. Dry run is rolled back, so it should not be called
+	// in a transaction of the caller.
+	Import(ctx context.Context, req *ImportRequest) (*ImportResponse, tiny_errors.ErrorHandler)
+}
+
+// New creates a new instance of the Client interface using the provided database client.
+// Storage checks size limits of imported contents and keeps large contents.
+func New(db *database.Client, storage *blobs.Storage) Client {
+	return &client{
+		db:      db,
+		storage: storage,
+	}
+}
+
+func (c *client) Export(ctx context.Context, req *ExportRequest) (*Manifest, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	folderID := folders.ResolveID(req.FolderID)
+
+	var folderRows []*folderRow
+	err := c.db.SelectContext(ctx, &folderRows, QUERY_GET_FOLDERS, folderID)
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	if len(folderRows) == 0 {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("folder does not exist"), tiny_errors.HTTPStatus(http.StatusNotFound))
+	}
+
+	var fileRows []*fileRow
+	err = c.db.SelectContext(ctx, &fileRows, QUERY_GET_FILES, folderID)
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	var contentRows []*contentRow
+	err = c.db.SelectContext(ctx, &contentRows, QUERY_GET_CONTENTS, folderID)
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	treeFolders := make(map[string]*Folder, len(folderRows))
+	for _, row := range folderRows {
+		treeFolders[row.ID] = &Folder{Name: row.Name, Metadata: row.Metadata}
+	}
+
+	manifest := &Manifest{Version: MANIFEST_VERSION}
+	for _, row := range folderRows {
+		if row.ID == folderID {
+			manifest.Name = row.Name
+			continue
+		}
+		if row.ParentID == nil {
+			continue
+		}
+		parent := treeFolders[*row.ParentID]
+		if *row.ParentID == folderID {
+			manifest.Folders = append(manifest.Folders, treeFolders[row.ID])
+		} else if parent != nil {
+			parent.Folders = append(parent.Folders, treeFolders[row.ID])
+		}
+	}
+
+	treeFiles := make(map[string]*File, len(fileRows))
+	for _, row := range fileRows {
+		file := &File{Name: row.Name, Metadata: row.Metadata}
+		treeFiles[row.ID] = file
+		if row.FolderID == folderID {
+			manifest.Files = append(manifest.Files, file)
+		} else if parent := treeFolders[row.FolderID]; parent != nil {
+			parent.Files = append(parent.Files, file)
+		}
+	}
+
+	for _, row := range contentRows {
+		file := treeFiles[row.FileID]
+		if file == nil {
+			continue
+		}
+
+		data, err := c.storage.Decode(row.Content, row.BlobKey)
+		if err != nil {
+			return nil, err
+		}
+		file.Contents = append(file.Contents, &Content{
+			Version:      row.Version,
+			Format:       row.Format,
+			SourceCommit: row.SourceCommit,
+			Data:         data,
+		})
+	}
+
+	return manifest, nil
+}
+
+func (c *client) Import(ctx context.Context, req *ImportRequest) (*ImportResponse, tiny_errors.ErrorHandler) {
+	if req == nil || req.Manifest == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	folderID := folders.ResolveID(req.FolderID)
+
+	imp := &importer{
+		storage:       c.storage,
+		formats:       make(map[string]*content_formats.ContentFormat),
+		sourceCommit:  req.SourceCommit,
+		skipUnchanged: req.SkipUnchanged,
+		result:        &ImportResponse{Changes: make([]*Change, 0)},
+	}
+
+	err := transaction.New(c.db).Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+		imp.tx = transaction.From(ctx, c.db)
+
+		var alive bool
+		err := imp.tx.QueryRowxContext(ctx, QUERY_FOLDER_IS_ALIVE, folderID).Scan(&alive)
+		if err != nil {
+			return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		}
+		if !alive {
+			return tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("folder does not exist"), tiny_errors.HTTPStatus(http.StatusNotFound))
+		}
+
+		tErr := imp.importTree(ctx, folderID, "", req.Manifest.Folders, req.Manifest.Files)
+		if tErr != nil {
+			return tErr
+		}
+
+		if req.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && err != errDryRun {
+		return nil, err
+	}
+
+	imp.result.Events = callback.GroupByFile(imp.result.Events)
+	return imp.result, nil
+}
+
+// importer keeps state of a single import between folders of the tree.
+type importer struct {
+	tx      transaction.DB
+	storage *blobs.Storage
+	// formats caches content formats by name.
+	formats       map[string]*content_formats.ContentFormat
+	sourceCommit  *string
+	skipUnchanged bool
+	result        *ImportResponse
+}
+
+func (i *importer) importTree(ctx context.Context, parentID, dir string, treeFolders []*Folder, treeFiles []*File) tiny_errors.ErrorHandler {
+	for _, folder := range treeFolders {
+		if folder == nil {
+			continue
+		}
+
+		name, err := utils.ClearName(folder.Name)
+		if err != nil {
+			return withPath(err, path.Join(dir, folder.Name))
+		}
+		folderPath := path.Join(dir, name)
+
+		id, err := i.findOrCreate(ctx, QUERY_GET_FOLDER_ID_BY_NAME, QUERY_CREATE_FOLDER, ITEM_TYPE_FOLDER, folderPath, name, parentID, folder.Metadata)
+		if err != nil {
+			return err
+		}
+
+		err = i.importTree(ctx, id, folderPath, folder.Folders, folder.Files)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, file := range treeFiles {
+		if file == nil {
+			continue
+		}
+
+		name, err := utils.ClearName(file.Name)
+		if err != nil {
+			return withPath(err, path.Join(dir, file.Name))
+		}
+		filePath := path.Join(dir, name)
+
+		id, err := i.findOrCreate(ctx, QUERY_GET_FILE_ID_BY_NAME, QUERY_CREATE_FILE, ITEM_TYPE_FILE, filePath, name, parentID, file.Metadata)
+		if err != nil {
+			return err
+		}
+
+		for _, content := range file.Contents {
+			if content == nil {
+				continue
+			}
+			err = i.importContent(ctx, id, filePath, content)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// findOrCreate returns id of the item with the name in the parent folder. Missing item is created with the metadata.
+func (i *importer) findOrCreate(ctx context.Context, getQuery, createQuery, itemType, itemPath, name, parentID string, metadata utils.Metadata) (string, tiny_errors.ErrorHandler) {
+	var id string
+	err := i.tx.GetContext(ctx, &id, getQuery, name, parentID)
+	if err == nil {
+		i.result.Unchanged++
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	labels := metadata.Labels
+	if labels == nil {
+		labels = utils.Labels{}
+	}
+	err = i.tx.GetContext(ctx, &id, createQuery, name, parentID, metadata.Description, metadata.OwnerTeam, metadata.Contact, labels)
+	if err != nil {
+		return "", utils.DatabaseError(err, itemType+" "+itemPath+" already exists")
+	}
+
+	i.change(ACTION_CREATE, itemType, itemPath)
+	return id, nil
+}
+
+func (i *importer) importContent(ctx context.Context, fileID, filePath string, content *Content) tiny_errors.ErrorHandler {
+	contentPath := path.Join(filePath, content.Version)
+	if content.Version == "" {
+		return tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, tiny_errors.Detail("version", "required"), tiny_errors.Detail("path", filePath))
+	}
+
+	format, err := i.format(ctx, content.Format)
+	if err != nil {
+		return withPath(err, contentPath)
+	}
+	if validateErr := format.Validate(content.Data); validateErr != nil {
+		err := tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message(validateErr.Error()), tiny_errors.Detail("content", "not valid "+format.Name))
+		return withPath(err, contentPath)
+	}
+	formatID := format.ID
+	checksum := blobs.Key(content.Data)
+
+	var stored storedContent
+	dbErr := i.tx.GetContext(ctx, &stored, QUERY_GET_CONTENT_BY_VERSION, fileID, content.Version)
+	if dbErr != nil && dbErr != sql.ErrNoRows {
+		return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(dbErr.Error()))
+	}
+
+	if dbErr == sql.ErrNoRows && i.skipUnchanged {
+		var latest storedContent
+		latestErr := i.tx.GetContext(ctx, &latest, QUERY_GET_LATEST_CONTENT, fileID)
+		if latestErr != nil && latestErr != sql.ErrNoRows {
+			return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(latestErr.Error()))
+		}
+		if latestErr == nil && latest.Checksum == checksum && latest.FormatID == formatID {
+			i.result.Unchanged++
+			return nil
+		}
+	}
+
+	sourceCommit := content.SourceCommit
+	if i.sourceCommit != nil {
+		sourceCommit = i.sourceCommit
+	}
+
+	if dbErr == nil && stored.Checksum == checksum && stored.FormatID == formatID {
+		i.result.Unchanged++
+		return nil
+	}
+
+	prepared, err := i.storage.Prepare(ctx, i.tx, content.Data, stored.ID)
+	if err != nil {
+		return withPath(err, contentPath)
+	}
+
+	event := webhook.EVENT_CONTENT_UPDATED
+	contentID := stored.ID
+	if dbErr == sql.ErrNoRows {
+		event = webhook.EVENT_CONTENT_CREATED
+		dbErr = i.tx.GetContext(ctx, &contentID, QUERY_CREATE_CONTENT, fileID, content.Version, prepared.Content, formatID, sourceCommit, prepared.Size, prepared.BlobKey, prepared.Checksum)
+		i.change(ACTION_CREATE, ITEM_TYPE_CONTENT, contentPath)
+	} else {
+		_, dbErr = i.tx.ExecContext(ctx, QUERY_UPDATE_CONTENT, stored.ID, prepared.Content, formatID, sourceCommit, prepared.Size, prepared.BlobKey, prepared.Checksum)
+		i.change(ACTION_UPDATE, ITEM_TYPE_CONTENT, contentPath)
+	}
+	if dbErr != nil {
+		return utils.DatabaseError(dbErr, "file content already exists")
+	}
+
+	i.result.Events = append(i.result.Events, &callback.CallbackRequest{
+		FileID: fileID,
+		Event:  event,
+		Details: &callback.EventDetails{
+			ContentID: utils.MakePointer(contentID),
+			Version:   utils.MakePointer(content.Version),
+		},
+	})
+	return nil
+}
+
+func (i *importer) format(ctx context.Context, name string) (*content_formats.ContentFormat, tiny_errors.ErrorHandler) {
+	if format, ok := i.formats[name]; ok {
+		return format, nil
+	}
+
+	var format content_formats.ContentFormat
+	err := i.tx.GetContext(ctx, &format, QUERY_GET_FORMAT_BY_NAME, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("format", "unknown format "+name))
+		}
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	i.formats[name] = &format
+	return &format, nil
+}
+
+func (i *importer) change(action, itemType, itemPath string) {
+	i.result.Changes = append(i.result.Changes, &Change{
+		Action: action,
+		Type:   itemType,
+		Path:   itemPath,
+	})
+}
+
+// withPath adds path of the item from the archive to the error.
+func withPath(err tiny_errors.ErrorHandler, itemPath string) tiny_errors.ErrorHandler {
+	options := []tiny_errors.ErrorOption{
+		tiny_errors.Detail("path", itemPath),
+	}
+	if err.GetMessage() != "" {
+		options = append(options, tiny_errors.Message(err.GetMessage()))
+	}
+	for key, value := range err.GetDetails() {
+		options = append(options, tiny_errors.Detail(key, value))
+	}
+	return tiny_errors.New(err.GetCode(), options...)
+}
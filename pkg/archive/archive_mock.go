@@ -0,0 +1,36 @@
+package archive
+
+import (
+	"context"
+
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockClient struct {
+	mock.Mock
+}
+
+func NewMock() *MockClient {
+	return new(MockClient)
+}
+
+func (m *MockClient) Export(ctx context.Context, req *ExportRequest) (*Manifest, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	manifest := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return manifest.(*Manifest), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockClient) Import(ctx context.Context, req *ImportRequest) (*ImportResponse, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	response := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return response.(*ImportResponse), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
@@ -0,0 +1,353 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/blobs"
+	"github.com/Moranilt/config-keeper/pkg/callback"
+	"github.com/Moranilt/config-keeper/pkg/folders"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/clients/database"
+	database_mock "github.com/Moranilt/http-utils/clients/database/mock"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Export(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb}, blobs.New(&blobs.Config{}))
+
+	folderColumns := []string{"id", "parent_id", "name", "description", "owner_team", "contact", "labels"}
+	fileColumns := []string{"id", "folder_id", "name", "description", "owner_team", "contact", "labels"}
+
+	tests := []struct {
+		name           string
+		req            *ExportRequest
+		mockSetup      func()
+		expectedResult *Manifest
+		expectedError  tiny_errors.ErrorHandler
+	}{
+		{
+			name: "success",
+			req:  &ExportRequest{FolderID: "folder_id"},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FOLDERS)).WithArgs("folder_id").WillReturnRows(
+					sqlMock.NewRows(folderColumns).
+						AddRow("folder_id", folders.ROOT_ID, "services", nil, nil, nil, "{}").
+						AddRow("child_id", "folder_id", "billing", nil, "payments", nil, "{}"),
+				)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FILES)).WithArgs("folder_id").WillReturnRows(
+					sqlMock.NewRows(fileColumns).
+						AddRow("file_1", "child_id", "app.yaml", nil, nil, nil, `{"env":"prod"}`).
+						AddRow("file_2", "folder_id", "common.env", nil, nil, nil, "{}"),
+				)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_CONTENTS)).WithArgs("folder_id").WillReturnRows(
+					sqlMock.NewRows([]string{"file_id", "version", "content", "source_commit", "format"}).
+						AddRow("file_1", "v1", utils.StringToBase64("port: 8080"), "3f2a1b", "yaml").
+						AddRow("file_2", "v1", utils.StringToBase64("KEY=value"), nil, "env"),
+				)
+			},
+			expectedResult: &Manifest{
+				Version: MANIFEST_VERSION,
+				Name:    "services",
+				Folders: []*Folder{
+					{
+						Name:     "billing",
+						Metadata: utils.Metadata{OwnerTeam: utils.MakePointer("payments"), Labels: utils.Labels{}},
+						Files: []*File{
+							{
+								Name:     "app.yaml",
+								Metadata: utils.Metadata{Labels: utils.Labels{"env": "prod"}},
+								Contents: []*Content{{Version: "v1", Format: "yaml", SourceCommit: utils.MakePointer("3f2a1b"), Data: "port: 8080"}},
+							},
+						},
+					},
+				},
+				Files: []*File{
+					{
+						Name:     "common.env",
+						Metadata: utils.Metadata{Labels: utils.Labels{}},
+						Contents: []*Content{{Version: "v1", Format: "env", Data: "KEY=value"}},
+					},
+				},
+			},
+		},
+		{
+			name: "not found",
+			req:  &ExportRequest{FolderID: "folder_id"},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FOLDERS)).WithArgs("folder_id").WillReturnRows(sqlMock.NewRows(folderColumns))
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("folder does not exist")),
+		},
+		{
+			name: "sql error",
+			req:  &ExportRequest{FolderID: "root"},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FOLDERS)).WithArgs(folders.ROOT_ID).WillReturnError(assert.AnError)
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(assert.AnError.Error())),
+		},
+		{
+			name:          "empty request",
+			req:           nil,
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_BodyRequired),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			manifest, err := client.Export(context.Background(), tt.req)
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
+				assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+				assert.Nil(t, manifest)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResult, manifest)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestClient_Import(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb}, blobs.New(&blobs.Config{}))
+
+	manifest := func() *Manifest {
+		return &Manifest{
+			Version: MANIFEST_VERSION,
+			Folders: []*Folder{
+				{
+					Name: "billing",
+					Files: []*File{
+						{
+							Name: "app.yaml",
+							Contents: []*Content{
+								{Version: "v1", Format: "yaml", Data: "port: 8080"},
+								{Version: "v2", Format: "yaml", Data: "port: 9090"},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	existsRow := func(exists bool) *sqlmock.Rows {
+		return sqlMock.NewRows([]string{"exists"}).AddRow(exists)
+	}
+	idRow := func(id string) *sqlmock.Rows {
+		return sqlMock.NewRows([]string{"id"}).AddRow(id)
+	}
+	expectTree := func() {
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_IS_ALIVE)).WithArgs("folder_id").WillReturnRows(existsRow(true))
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FOLDER_ID_BY_NAME)).WithArgs("billing", "folder_id").WillReturnRows(idRow("billing_id"))
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FILE_ID_BY_NAME)).WithArgs("app.yaml", "billing_id").WillReturnError(sql.ErrNoRows)
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CREATE_FILE)).WithArgs("app.yaml", "billing_id", nil, nil, nil, utils.Labels{}).WillReturnRows(idRow("file_id"))
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FORMAT_BY_NAME)).WithArgs("yaml").WillReturnRows(idRow("yaml_id"))
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_CONTENT_BY_VERSION)).WithArgs("file_id", "v1").WillReturnRows(
+			sqlMock.NewRows([]string{"id", "checksum", "format_id"}).AddRow("content_1", blobs.Key("port: 8080"), "yaml_id"),
+		)
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_CONTENT_BY_VERSION)).WithArgs("file_id", "v2").WillReturnRows(
+			sqlMock.NewRows([]string{"id", "checksum", "format_id"}).AddRow("content_2", blobs.Key("port: 80"), "yaml_id"),
+		)
+		sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_UPDATE_CONTENT)).WithArgs("content_2", utils.StringToBase64("port: 9090"), "yaml_id", nil, int64(10), "", blobs.Key("port: 9090")).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	expectedResult := &ImportResponse{
+		Changes: []*Change{
+			{Action: ACTION_CREATE, Type: ITEM_TYPE_FILE, Path: "billing/app.yaml"},
+			{Action: ACTION_UPDATE, Type: ITEM_TYPE_CONTENT, Path: "billing/app.yaml/v2"},
+		},
+		Unchanged: 2,
+		Events: []*callback.CallbackRequest{
+			{
+				FileID:  "file_id",
+				Event:   webhook.EVENT_CONTENT_UPDATED,
+				Details: &callback.EventDetails{ContentID: utils.MakePointer("content_2"), Version: utils.MakePointer("v2")},
+			},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		req            *ImportRequest
+		mockSetup      func()
+		expectedResult *ImportResponse
+		expectedError  tiny_errors.ErrorHandler
+	}{
+		{
+			name: "success",
+			req:  &ImportRequest{FolderID: "folder_id", Manifest: manifest()},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				expectTree()
+				sqlMock.ExpectCommit()
+			},
+			expectedResult: expectedResult,
+		},
+		{
+			name: "dry run",
+			req:  &ImportRequest{FolderID: "folder_id", Manifest: manifest(), DryRun: true},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				expectTree()
+				sqlMock.ExpectRollback()
+			},
+			expectedResult: expectedResult,
+		},
+		{
+			name: "skip version with the same content as the latest one",
+			req: &ImportRequest{
+				FolderID:      "folder_id",
+				SourceCommit:  utils.MakePointer("3f2a1b"),
+				SkipUnchanged: true,
+				Manifest: &Manifest{
+					Version: MANIFEST_VERSION,
+					Files: []*File{{Name: "app.yaml", Contents: []*Content{
+						{Version: "3f2a1b", Format: "yaml", Data: "port: 8080"},
+					}}},
+				},
+			},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_IS_ALIVE)).WithArgs("folder_id").WillReturnRows(existsRow(true))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FILE_ID_BY_NAME)).WithArgs("app.yaml", "folder_id").WillReturnRows(idRow("file_id"))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FORMAT_BY_NAME)).WithArgs("yaml").WillReturnRows(idRow("yaml_id"))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_CONTENT_BY_VERSION)).WithArgs("file_id", "3f2a1b").WillReturnError(sql.ErrNoRows)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_LATEST_CONTENT)).WithArgs("file_id").WillReturnRows(
+					sqlMock.NewRows([]string{"id", "checksum", "format_id"}).AddRow("content_1", blobs.Key("port: 8080"), "yaml_id"),
+				)
+				sqlMock.ExpectCommit()
+			},
+			expectedResult: &ImportResponse{Changes: []*Change{}, Unchanged: 2},
+		},
+		{
+			name: "create version with source commit",
+			req: &ImportRequest{
+				FolderID:      "folder_id",
+				SourceCommit:  utils.MakePointer("3f2a1b"),
+				SkipUnchanged: true,
+				Manifest: &Manifest{
+					Version: MANIFEST_VERSION,
+					Files: []*File{{Name: "app.yaml", Contents: []*Content{
+						{Version: "3f2a1b", Format: "yaml", Data: "port: 9090"},
+					}}},
+				},
+			},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_IS_ALIVE)).WithArgs("folder_id").WillReturnRows(existsRow(true))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FILE_ID_BY_NAME)).WithArgs("app.yaml", "folder_id").WillReturnRows(idRow("file_id"))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FORMAT_BY_NAME)).WithArgs("yaml").WillReturnRows(idRow("yaml_id"))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_CONTENT_BY_VERSION)).WithArgs("file_id", "3f2a1b").WillReturnError(sql.ErrNoRows)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_LATEST_CONTENT)).WithArgs("file_id").WillReturnRows(
+					sqlMock.NewRows([]string{"id", "checksum", "format_id"}).AddRow("content_1", blobs.Key("port: 8080"), "yaml_id"),
+				)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CREATE_CONTENT)).
+					WithArgs("file_id", "3f2a1b", utils.StringToBase64("port: 9090"), "yaml_id", "3f2a1b", int64(10), "", blobs.Key("port: 9090")).
+					WillReturnRows(idRow("content_3"))
+				sqlMock.ExpectCommit()
+			},
+			expectedResult: &ImportResponse{
+				Changes:   []*Change{{Action: ACTION_CREATE, Type: ITEM_TYPE_CONTENT, Path: "app.yaml/3f2a1b"}},
+				Unchanged: 1,
+				Events: []*callback.CallbackRequest{
+					{
+						FileID:  "file_id",
+						Event:   webhook.EVENT_CONTENT_CREATED,
+						Details: &callback.EventDetails{ContentID: utils.MakePointer("content_3"), Version: utils.MakePointer("3f2a1b")},
+					},
+				},
+			},
+		},
+		{
+			name: "unknown format",
+			req: &ImportRequest{FolderID: "folder_id", Manifest: &Manifest{
+				Version: MANIFEST_VERSION,
+				Files:   []*File{{Name: "app.xml", Contents: []*Content{{Version: "v1", Format: "xml"}}}},
+			}},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_IS_ALIVE)).WithArgs("folder_id").WillReturnRows(existsRow(true))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FILE_ID_BY_NAME)).WithArgs("app.xml", "folder_id").WillReturnRows(idRow("file_id"))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FORMAT_BY_NAME)).WithArgs("xml").WillReturnError(sql.ErrNoRows)
+				sqlMock.ExpectRollback()
+			},
+			expectedError: tiny_errors.New(
+				custom_errors.ERR_CODE_NotValid,
+				tiny_errors.Detail("format", "unknown format xml"),
+				tiny_errors.Detail("path", "app.xml/v1"),
+			),
+		},
+		{
+			name: "content is not valid for the format",
+			req: &ImportRequest{FolderID: "folder_id", Manifest: &Manifest{
+				Version: MANIFEST_VERSION,
+				Files:   []*File{{Name: "app.json", Contents: []*Content{{Version: "v1", Format: "json", Data: "{"}}}},
+			}},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_IS_ALIVE)).WithArgs("folder_id").WillReturnRows(existsRow(true))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FILE_ID_BY_NAME)).WithArgs("app.json", "folder_id").WillReturnRows(idRow("file_id"))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FORMAT_BY_NAME)).WithArgs("json").WillReturnRows(
+					sqlMock.NewRows([]string{"id", "name", "validator"}).AddRow("json_id", "json", "json"),
+				)
+				sqlMock.ExpectRollback()
+			},
+			expectedError: tiny_errors.New(
+				custom_errors.ERR_CODE_NotValid,
+				tiny_errors.Message("unexpected end of JSON input"),
+				tiny_errors.Detail("content", "not valid json"),
+				tiny_errors.Detail("path", "app.json/v1"),
+			),
+		},
+		{
+			name: "folder does not exist",
+			req:  &ImportRequest{FolderID: "folder_id", Manifest: manifest()},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_IS_ALIVE)).WithArgs("folder_id").WillReturnRows(existsRow(false))
+				sqlMock.ExpectRollback()
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("folder does not exist")),
+		},
+		{
+			name:          "empty request",
+			req:           nil,
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_BodyRequired),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			result, err := client.Import(context.Background(), tt.req)
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
+				if tt.expectedError.GetMessage() != "" {
+					assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+				}
+				if len(tt.expectedError.GetDetails()) > 0 {
+					assert.Equal(t, tt.expectedError.GetDetails(), err.GetDetails())
+				}
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResult, result)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/http-utils/tiny_errors"
+)
+
+// ValidateFormat returns an error if archive format is not supported.
+func ValidateFormat(format string) tiny_errors.ErrorHandler {
+	if format != FORMAT_TAR_GZ && format != FORMAT_ZIP {
+		return tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("format", strings.Join([]string{FORMAT_TAR_GZ, FORMAT_ZIP}, ", ")))
+	}
+	return nil
+}
+
+// ContentType returns MIME type of the archive format.
+func ContentType(format string) string {
+	if format == FORMAT_ZIP {
+		return "application/zip"
+	}
+	return "application/gzip"
+}
+
+// Write writes manifest and every content as a separate entry into the archive of provided format.
+// Paths of the entries are stored in the manifest.
+func Write(w io.Writer, format string, manifest *Manifest) error {
+	if err := ValidateFormat(format); err != nil {
+		return err
+	}
+
+	entries := make(map[string]string)
+	var order []string
+	walk(manifest.Folders, manifest.Files, "", func(dir string, file *File) {
+		for _, content := range file.Contents {
+			content.Path = path.Join(dir, entryName(file.Name), entryName(content.Version))
+			entries[content.Path] = content.Data
+			order = append(order, content.Path)
+		}
+	})
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	entries[MANIFEST_NAME] = string(data)
+	order = append([]string{MANIFEST_NAME}, order...)
+
+	if format == FORMAT_ZIP {
+		return writeZip(w, order, entries)
+	}
+	return writeTarGz(w, order, entries)
+}
+
+// Read reads manifest from the archive and loads data of every content from entries referenced by the manifest.
+func Read(data []byte, format string) (*Manifest, tiny_errors.ErrorHandler) {
+	if err := ValidateFormat(format); err != nil {
+		return nil, err
+	}
+
+	var entries map[string][]byte
+	var err error
+	if format == FORMAT_ZIP {
+		entries, err = readZip(data)
+	} else {
+		entries, err = readTarGz(data)
+	}
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message(err.Error()), tiny_errors.Detail("archive", "can not be read"))
+	}
+
+	manifestData, ok := entries[MANIFEST_NAME]
+	if !ok {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("archive", MANIFEST_NAME+" is missing"))
+	}
+
+	var manifest Manifest
+	err = json.Unmarshal(manifestData, &manifest)
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message(err.Error()), tiny_errors.Detail("archive", MANIFEST_NAME+" is not valid"))
+	}
+	if manifest.Version != MANIFEST_VERSION {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("archive", fmt.Sprintf("unsupported manifest version %d", manifest.Version)))
+	}
+
+	var missing []string
+	walk(manifest.Folders, manifest.Files, "", func(_ string, file *File) {
+		for _, content := range file.Contents {
+			entry, ok := entries[path.Clean(content.Path)]
+			if !ok {
+				missing = append(missing, content.Path)
+				continue
+			}
+			content.Data = string(entry)
+		}
+	})
+	if len(missing) > 0 {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("archive", "missing entries: "+strings.Join(missing, ", ")))
+	}
+
+	return &manifest, nil
+}
+
+// walk calls fn for every file in the tree with a directory of the file in the archive.
+func walk(folders []*Folder, files []*File, dir string, fn func(dir string, file *File)) {
+	for _, file := range files {
+		fn(dir, file)
+	}
+	for _, folder := range folders {
+		walk(folder.Folders, folder.Files, path.Join(dir, entryName(folder.Name)), fn)
+	}
+}
+
+// entryName escapes name to be used as a single segment of the entry path.
+func entryName(name string) string {
+	escaped := url.PathEscape(name)
+	if escaped == "." || escaped == ".." {
+		return strings.ReplaceAll(escaped, ".", "%2E")
+	}
+	return escaped
+}
+
+func writeTarGz(w io.Writer, order []string, entries map[string]string) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+	modTime := time.Now()
+
+	for _, name := range order {
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(entries[name])),
+			ModTime: modTime,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(tarWriter, entries[name]); err != nil {
+			return err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+func writeZip(w io.Writer, order []string, entries map[string]string) error {
+	zipWriter := zip.NewWriter(w)
+	for _, name := range order {
+		entryWriter, err := zipWriter.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(entryWriter, entries[name]); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+func readTarGz(data []byte) (map[string][]byte, error) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	entries := make(map[string][]byte)
+	var total int64
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		total += header.Size
+		if total > MAX_ARCHIVE_SIZE {
+			return nil, fmt.Errorf("unpacked archive is larger than %d bytes", MAX_ARCHIVE_SIZE)
+		}
+
+		entry, err := io.ReadAll(io.LimitReader(tarReader, header.Size))
+		if err != nil {
+			return nil, err
+		}
+		entries[path.Clean(header.Name)] = entry
+	}
+}
+
+func readZip(data []byte) (map[string][]byte, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string][]byte)
+	var total int64
+	for _, file := range zipReader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		entry, err := readZipEntry(file, MAX_ARCHIVE_SIZE-total)
+		if err != nil {
+			return nil, err
+		}
+		total += int64(len(entry))
+		entries[path.Clean(file.Name)] = entry
+	}
+	return entries, nil
+}
+
+// readZipEntry reads at most limit bytes of the entry. Declared size of the entry is not trusted.
+func readZipEntry(file *zip.File, limit int64) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	entry, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(entry)) > limit {
+		return nil, fmt.Errorf("unpacked archive is larger than %d bytes", MAX_ARCHIVE_SIZE)
+	}
+	return entry, nil
+}
@@ -0,0 +1,95 @@
+package archive
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func testManifest() *Manifest {
+	return &Manifest{
+		Version: MANIFEST_VERSION,
+		Name:    "services",
+		Folders: []*Folder{
+			{
+				Name: "billing",
+				Files: []*File{
+					{
+						Name:     "app.yaml",
+						Metadata: utils.Metadata{OwnerTeam: utils.MakePointer("payments"), Labels: utils.Labels{"env": "prod"}},
+						Contents: []*Content{
+							{Version: "v1", Format: "yaml", Data: "port: 8080"},
+							{Version: "release/v2", Format: "yaml", Data: "port: 9090"},
+						},
+					},
+				},
+			},
+		},
+		Files: []*File{
+			{Name: "..", Contents: []*Content{{Version: "v1", Format: "env", Data: "KEY=value"}}},
+		},
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+
+	for _, format := range []string{FORMAT_TAR_GZ, FORMAT_ZIP} {
+		t.Run(format, func(t *testing.T) {
+			manifest := testManifest()
+
+			var buf bytes.Buffer
+			err := Write(&buf, format, manifest)
+			assert.NoError(t, err)
+
+			assert.Equal(t, "billing/app.yaml/v1", manifest.Folders[0].Files[0].Contents[0].Path)
+			assert.Equal(t, "billing/app.yaml/release%2Fv2", manifest.Folders[0].Files[0].Contents[1].Path)
+			assert.Equal(t, "%2E%2E/v1", manifest.Files[0].Contents[0].Path)
+
+			result, readErr := Read(buf.Bytes(), format)
+			assert.Nil(t, readErr)
+			assert.Equal(t, manifest, result)
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := Read(nil, "rar")
+		assert.Equal(t, custom_errors.ERR_CODE_NotValid, err.GetCode())
+		assert.Equal(t, map[string]string{"format": "tar.gz, zip"}, err.GetDetails())
+	})
+
+	t.Run("broken archive", func(t *testing.T) {
+		_, err := Read([]byte("not an archive"), FORMAT_ZIP)
+		assert.Equal(t, custom_errors.ERR_CODE_NotValid, err.GetCode())
+	})
+
+	t.Run("missing entry", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeZip(&buf, []string{MANIFEST_NAME}, map[string]string{
+			MANIFEST_NAME: `{"version":1,"files":[{"name":"app.yaml","contents":[{"version":"v1","format":"yaml","path":"app.yaml/v1"}]}]}`,
+		})
+		assert.NoError(t, err)
+
+		_, readErr := Read(buf.Bytes(), FORMAT_ZIP)
+		assert.Equal(t, custom_errors.ERR_CODE_NotValid, readErr.GetCode())
+		assert.Equal(t, map[string]string{"archive": "missing entries: app.yaml/v1"}, readErr.GetDetails())
+	})
+
+	t.Run("missing manifest", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeTarGz(&buf, []string{"app.yaml/v1"}, map[string]string{"app.yaml/v1": "port: 8080"})
+		assert.NoError(t, err)
+
+		_, readErr := Read(buf.Bytes(), FORMAT_TAR_GZ)
+		assert.Equal(t, custom_errors.ERR_CODE_NotValid, readErr.GetCode())
+		assert.Equal(t, map[string]string{"archive": MANIFEST_NAME + " is missing"}, readErr.GetDetails())
+	})
+}
@@ -0,0 +1,154 @@
+package archive
+
+import (
+	"github.com/Moranilt/config-keeper/pkg/callback"
+	"github.com/Moranilt/config-keeper/utils"
+)
+
+const (
+	FORMAT_TAR_GZ = "tar.gz"
+	FORMAT_ZIP    = "zip"
+
+	// MANIFEST_NAME is a name of the manifest entry in the root of an archive.
+	MANIFEST_NAME    = "manifest.json"
+	MANIFEST_VERSION = 1
+
+	// MAX_ARCHIVE_SIZE limits size of an imported archive and total size of its unpacked entries.
+	MAX_ARCHIVE_SIZE = 64 << 20
+
+	ACTION_CREATE = "create"
+	ACTION_UPDATE = "update"
+
+	ITEM_TYPE_FOLDER  = "folder"
+	ITEM_TYPE_FILE    = "file"
+	ITEM_TYPE_CONTENT = "content"
+)
+
+// subtreeCTE selects ids of the folder $1 and all its nested folders which are not deleted.
+const subtreeCTE = `WITH RECURSIVE subtree AS (
+	SELECT id FROM folders WHERE id = $1 AND deleted_at IS NULL
+	UNION ALL
+	SELECT f.id FROM folders f JOIN subtree s ON f.parent_id = s.id WHERE f.deleted_at IS NULL
+)
+`
+
+const (
+	QUERY_GET_FOLDERS = subtreeCTE + `SELECT f.id, f.parent_id, f.name, f.description, f.owner_team, f.contact, f.labels
+	FROM folders f JOIN subtree s ON s.id = f.id
+	ORDER BY f.name`
+	QUERY_GET_FILES = subtreeCTE + `SELECT f.id, f.folder_id, f.name, f.description, f.owner_team, f.contact, f.labels
+	FROM files f JOIN subtree s ON s.id = f.folder_id
+	WHERE f.deleted_at IS NULL
+	ORDER BY f.name`
+	QUERY_GET_CONTENTS = subtreeCTE + `SELECT fc.file_id, fc.version, fc.content, fc.blob_key, fc.source_commit, cf.name AS format
+	FROM file_contents fc
+	JOIN files f ON f.id = fc.file_id
+	JOIN subtree s ON s.id = f.folder_id
+	JOIN content_formats cf ON cf.id = fc.format_id
+	WHERE f.deleted_at IS NULL
+	ORDER BY fc.created_at, fc.version`
+
+	QUERY_FOLDER_IS_ALIVE        = "SELECT EXISTS(SELECT 1 FROM folders WHERE id = $1 AND deleted_at IS NULL)"
+	QUERY_GET_FOLDER_ID_BY_NAME  = "SELECT id FROM folders WHERE name = $1 AND parent_id = $2 AND deleted_at IS NULL"
+	QUERY_GET_FILE_ID_BY_NAME    = "SELECT id FROM files WHERE name = $1 AND folder_id = $2 AND deleted_at IS NULL"
+	QUERY_GET_FORMAT_BY_NAME     = "SELECT id, name, validator FROM content_formats WHERE name = $1"
+	QUERY_GET_CONTENT_BY_VERSION = "SELECT id, checksum, format_id FROM file_contents WHERE file_id = $1 AND version = $2"
+	QUERY_CREATE_FOLDER          = "INSERT INTO folders (name, parent_id, description, owner_team, contact, labels) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
+	QUERY_CREATE_FILE            = "INSERT INTO files (name, folder_id, description, owner_team, contact, labels) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
+	QUERY_GET_LATEST_CONTENT     = "SELECT id, checksum, format_id FROM file_contents WHERE file_id = $1 ORDER BY created_at DESC, id DESC LIMIT 1"
+	QUERY_CREATE_CONTENT         = "INSERT INTO file_contents (file_id, version, content, format_id, source_commit, size, blob_key, checksum) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id"
+	QUERY_UPDATE_CONTENT         = "UPDATE file_contents SET content = $2, format_id = $3, source_commit = $4, size = $5, blob_key = $6, checksum = $7, updated_at = now() WHERE id = $1"
+)
+
+// Manifest describes contents of an archive. Directory layout of the archive mirrors the folders tree:
+// every folder is a directory, every file is a directory named after the file with one entry per version.
+// Manifest keeps data which can not be restored from the layout, e.g. formats and metadata.
+type Manifest struct {
+	Version int `json:"version"`
+	// Name is a name of the exported folder. Its children are placed in the root of the archive.
+	Name    string    `json:"name"`
+	Folders []*Folder `json:"folders"`
+	Files   []*File   `json:"files"`
+}
+
+type Folder struct {
+	Name string `json:"name"`
+	utils.Metadata
+	Folders []*Folder `json:"folders"`
+	Files   []*File   `json:"files"`
+}
+
+type File struct {
+	Name string `json:"name"`
+	utils.Metadata
+	Contents []*Content `json:"contents"`
+}
+
+type Content struct {
+	Version string `json:"version"`
+	Format  string `json:"format"`
+	// Path is a path of the archive entry with the content.
+	Path         string  `json:"path"`
+	SourceCommit *string `json:"source_commit,omitempty"`
+	Data         string  `json:"-"`
+}
+
+type folderRow struct {
+	ID       string  `db:"id"`
+	ParentID *string `db:"parent_id"`
+	Name     string  `db:"name"`
+	utils.Metadata
+}
+
+type fileRow struct {
+	ID       string `db:"id"`
+	FolderID string `db:"folder_id"`
+	Name     string `db:"name"`
+	utils.Metadata
+}
+
+type contentRow struct {
+	FileID       string  `db:"file_id"`
+	Version      string  `db:"version"`
+	Content      string  `db:"content"`
+	BlobKey      string  `db:"blob_key"`
+	SourceCommit *string `db:"source_commit"`
+	Format       string  `db:"format"`
+}
+
+type storedContent struct {
+	ID       string `db:"id"`
+	Checksum string `db:"checksum"`
+	FormatID string `db:"format_id"`
+}
+
+type ExportRequest struct {
+	FolderID string
+}
+
+type ImportRequest struct {
+	FolderID string
+	Manifest *Manifest
+	// DryRun applies all changes in a transaction which is rolled back, so response shows what would change.
+	DryRun bool
+	// SourceCommit overrides source commit of every created or updated content.
+	SourceCommit *string
+	// SkipUnchanged skips a new version if its content and format are the same as in the latest version of the file.
+	SkipUnchanged bool
+}
+
+// Change is a created or updated item. Path is relative to the folder the archive was imported into.
+type Change struct {
+	Action string `json:"action"`
+	Type   string `json:"type"`
+	Path   string `json:"path"`
+}
+
+type ImportResponse struct {
+	Changes []*Change
+	// Unchanged is an amount of folders, files and contents which already existed and were left as is.
+	Unchanged int
+	// Events contain a single event of every file with created or updated contents, which should be sent
+	// to listeners after the import.
+	Events []*callback.CallbackRequest
+}
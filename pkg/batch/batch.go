@@ -0,0 +1,565 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/blobs"
+	"github.com/Moranilt/config-keeper/pkg/callback"
+	"github.com/Moranilt/config-keeper/pkg/content_formats"
+	"github.com/Moranilt/config-keeper/pkg/file_contents"
+	"github.com/Moranilt/config-keeper/pkg/files"
+	"github.com/Moranilt/config-keeper/pkg/folders"
+	"github.com/Moranilt/config-keeper/pkg/transaction"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/clients/database"
+	"github.com/Moranilt/http-utils/tiny_errors"
+)
+
+type client struct {
+	db      *database.Client
+	storage *blobs.Storage
+}
+
+type Client interface {
+	// Execute applies all operations in a single transaction. If any operation fails,
+	// nothing is changed and the error contains index of the failed operation.
+	// If ctx has a transaction operations are applied in it.
+	Execute(ctx context.Context, req *ExecuteRequest) (*ExecuteResponse, tiny_errors.ErrorHandler)
+}
+
+// New creates a new instance of the Client interface using the provided database client.
+// Storage checks size limits of contents and keeps large contents.
+func New(db *database.Client, storage *blobs.Storage) Client {
+	return &client{
+		db:      db,
+		storage: storage,
+	}
+}
+
+// execution keeps state of the batch between operations.
+type execution struct {
+	tx      transaction.DB
+	storage *blobs.Storage
+	refs    map[string]string
+	events  []*callback.CallbackRequest
+}
+
+func (c *client) Execute(ctx context.Context, req *ExecuteRequest) (*ExecuteResponse, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	if len(req.Operations) == 0 {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, tiny_errors.Detail("operations", "required"))
+	}
+
+	if len(req.Operations) > MAX_OPERATIONS {
+		return nil, tiny_errors.New(
+			custom_errors.ERR_CODE_NotValid,
+			tiny_errors.Detail("operations", fmt.Sprintf("should contain at most %d operations", MAX_OPERATIONS)),
+		)
+	}
+
+	exec := &execution{
+		storage: c.storage,
+		refs:    make(map[string]string),
+	}
+
+	results := make([]*Result, 0, len(req.Operations))
+	err := transaction.New(c.db).Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+		exec.tx = transaction.From(ctx, c.db)
+		for i, op := range req.Operations {
+			if op == nil {
+				return operationError(i, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired))
+			}
+
+			if op.Ref != nil {
+				if _, ok := exec.refs[*op.Ref]; ok || *op.Ref == "" {
+					return operationError(i, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("ref", "should be unique and not empty")))
+				}
+			}
+
+			result, opErr := exec.apply(ctx, op)
+			if opErr != nil {
+				return operationError(i, opErr)
+			}
+			result.Index = i
+			result.Op = op.Op
+
+			if op.Ref != nil {
+				exec.refs[*op.Ref] = result.ID
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExecuteResponse{
+		Results: results,
+		Events:  callback.GroupByFile(exec.events),
+	}, nil
+}
+
+func (e *execution) apply(ctx context.Context, op *Operation) (*Result, tiny_errors.ErrorHandler) {
+	switch op.Op {
+	case OP_CREATE_FILE:
+		return e.createFile(ctx, op)
+	case OP_CREATE_CONTENT:
+		return e.createContent(ctx, op)
+	case OP_EDIT_CONTENT:
+		return e.editContent(ctx, op)
+	case OP_DELETE:
+		return e.delete(ctx, op)
+	case OP_MOVE:
+		return e.move(ctx, op)
+	default:
+		return nil, tiny_errors.New(
+			custom_errors.ERR_CODE_NotValid,
+			tiny_errors.Detail("op", strings.Join([]string{OP_CREATE_FILE, OP_CREATE_CONTENT, OP_EDIT_CONTENT, OP_DELETE, OP_MOVE}, ", ")),
+		)
+	}
+}
+
+func (e *execution) createFile(ctx context.Context, op *Operation) (*Result, tiny_errors.ErrorHandler) {
+	errFields := utils.ValidateRequiredFields([]utils.RequiredField{
+		{Name: "name", Value: op.Name},
+	})
+	if errFields != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, errFields...)
+	}
+
+	name, err := utils.ClearName(*op.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	folderID, err := e.resolveFolder(ctx, op.FolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	taken, err := e.exists(ctx, QUERY_FILE_NAME_IS_TAKEN, name, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, utils.ExistsError("file already exists")
+	}
+
+	var file files.File
+	dbErr := e.tx.QueryRowxContext(ctx, files.QUERY_CREATE_FILE, folderID, name).StructScan(&file)
+	if dbErr != nil {
+		return nil, utils.DatabaseError(dbErr, "file already exists")
+	}
+
+	return &Result{ID: file.ID}, nil
+}
+
+func (e *execution) createContent(ctx context.Context, op *Operation) (*Result, tiny_errors.ErrorHandler) {
+	errFields := utils.ValidateRequiredFields([]utils.RequiredField{
+		{Name: "file_id", Value: op.FileID},
+		{Name: "version", Value: op.Version},
+		{Name: "content", Value: op.Content},
+		{Name: "format_id", Value: op.FormatID},
+	})
+	if errFields != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, errFields...)
+	}
+
+	fileID, err := e.resolveRef(*op.FileID)
+	if err != nil {
+		return nil, err
+	}
+
+	alive, err := e.exists(ctx, files.QUERY_FILE_EXISTS, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if !alive {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("file does not exist"))
+	}
+
+	if err := e.validateContent(ctx, content_formats.QUERY_GET_FORMAT, *op.FormatID, *op.Content); err != nil {
+		return nil, err
+	}
+
+	var id string
+	dbErr := e.tx.GetContext(ctx, &id, file_contents.QUERY_GET_FILES_CONTENT_ID_BY_VERSION, fileID, *op.Version)
+	if dbErr != nil && dbErr != sql.ErrNoRows {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(dbErr.Error()))
+	}
+	if len(id) > 0 {
+		return nil, utils.ExistsError("file content already exists")
+	}
+
+	stored, err := e.storage.Prepare(ctx, e.tx, *op.Content, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var content file_contents.FileContent
+	dbErr = e.tx.QueryRowxContext(
+		ctx,
+		file_contents.QUERY_CREATE_CONTENT,
+		fileID,
+		*op.Version,
+		stored.Content,
+		*op.FormatID,
+		stored.Size,
+		stored.BlobKey,
+		stored.Checksum,
+	).StructScan(&content)
+	if dbErr != nil {
+		return nil, utils.DatabaseError(dbErr, "file content already exists")
+	}
+
+	e.event(fileID, webhook.EVENT_CONTENT_CREATED, &callback.EventDetails{
+		ContentID: &content.ID,
+		Version:   &content.Version,
+	})
+	return &Result{ID: content.ID, FileID: &fileID}, nil
+}
+
+func (e *execution) editContent(ctx context.Context, op *Operation) (*Result, tiny_errors.ErrorHandler) {
+	errFields := utils.ValidateRequiredFields([]utils.RequiredField{
+		{Name: "id", Value: op.ID},
+	})
+	if errFields != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, errFields...)
+	}
+
+	if op.Version == nil && op.Content == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, tiny_errors.Detail("version or content", "required"))
+	}
+
+	id, err := e.resolveRef(*op.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := e.content(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if op.Content != nil {
+		if err := e.validateContent(ctx, content_formats.QUERY_GET_FORMAT_BY_CONTENT, id, *op.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	updateQuery := utils.NewUpdateQuery("file_contents").SetRaw("updated_at = now()")
+	if op.Version != nil {
+		updateQuery.Set("version", *op.Version)
+	}
+	if op.Content != nil {
+		stored, err := e.storage.Prepare(ctx, e.tx, *op.Content, id)
+		if err != nil {
+			return nil, err
+		}
+		updateQuery.Set("content", stored.Content).Set("size", stored.Size).Set("blob_key", stored.BlobKey).Set("checksum", stored.Checksum)
+	}
+	updateQuery.Where("id = ?", id)
+
+	_, dbErr := e.tx.ExecContext(ctx, updateQuery.String(), updateQuery.Args()...)
+	if dbErr != nil {
+		return nil, utils.DatabaseError(dbErr, "file content already exists")
+	}
+
+	details := &callback.EventDetails{
+		ContentID: &id,
+		Version:   &previous.Version,
+	}
+	if op.Version != nil && *op.Version != previous.Version {
+		details.Version = op.Version
+		details.PreviousVersion = &previous.Version
+	}
+	e.event(previous.FileID, webhook.EVENT_CONTENT_UPDATED, details)
+	return &Result{ID: id, FileID: &previous.FileID}, nil
+}
+
+func (e *execution) delete(ctx context.Context, op *Operation) (*Result, tiny_errors.ErrorHandler) {
+	errFields := utils.ValidateRequiredFields([]utils.RequiredField{
+		{Name: "target", Value: op.Target},
+		{Name: "id", Value: op.ID},
+	})
+	if errFields != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, errFields...)
+	}
+
+	id, err := e.resolveRef(*op.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch *op.Target {
+	case TARGET_FOLDER:
+		if folders.ResolveID(id) == folders.ROOT_ID {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("root folder can not be deleted"))
+		}
+
+		// files are selected before they are deleted with the folder
+		nested := make([]*files.File, 0)
+		if dbErr := e.tx.SelectContext(ctx, &nested, files.QUERY_GET_NESTED_FILES, id); dbErr != nil {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(dbErr.Error()))
+		}
+		if err := e.execAffected(ctx, folders.QUERY_DELETE_FOLDER, id); err != nil {
+			return nil, err
+		}
+		for _, file := range nested {
+			e.event(file.ID, webhook.EVENT_FILE_DELETED, &callback.EventDetails{Name: &file.Name})
+		}
+		return &Result{ID: id}, nil
+	case TARGET_FILE:
+		file, err := e.file(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.execAffected(ctx, files.QUERY_DELETE_FILE, id); err != nil {
+			return nil, err
+		}
+		e.event(file.ID, webhook.EVENT_FILE_DELETED, &callback.EventDetails{Name: &file.Name})
+		return &Result{ID: id}, nil
+	case TARGET_CONTENT:
+		content, err := e.content(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.execAffected(ctx, file_contents.QUERY_DELETE_FILE_CONTENT, id); err != nil {
+			return nil, err
+		}
+		e.event(content.FileID, webhook.EVENT_CONTENT_DELETED, &callback.EventDetails{
+			ContentID: &id,
+			Version:   &content.Version,
+		})
+		return &Result{ID: id, FileID: &content.FileID}, nil
+	default:
+		return nil, tiny_errors.New(
+			custom_errors.ERR_CODE_NotValid,
+			tiny_errors.Detail("target", strings.Join([]string{TARGET_FOLDER, TARGET_FILE, TARGET_CONTENT}, ", ")),
+		)
+	}
+}
+
+func (e *execution) move(ctx context.Context, op *Operation) (*Result, tiny_errors.ErrorHandler) {
+	errFields := utils.ValidateRequiredFields([]utils.RequiredField{
+		{Name: "target", Value: op.Target},
+		{Name: "id", Value: op.ID},
+		{Name: "folder_id", Value: op.FolderID},
+	})
+	if errFields != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, errFields...)
+	}
+
+	id, err := e.resolveRef(*op.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	folderID, err := e.resolveFolder(ctx, op.FolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	var nameTakenQuery, moveQuery string
+	var file *files.File
+	switch *op.Target {
+	case TARGET_FOLDER:
+		if folders.ResolveID(id) == folders.ROOT_ID {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("root folder can not be moved"))
+		}
+
+		nested, err := e.exists(ctx, QUERY_IS_NESTED_FOLDER, id, folderID)
+		if err != nil {
+			return nil, err
+		}
+		if nested {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("folder can not be moved into itself or its nested folder"))
+		}
+		nameTakenQuery, moveQuery = QUERY_FOLDER_NAME_IS_TAKEN, QUERY_MOVE_FOLDER
+	case TARGET_FILE:
+		file, err = e.file(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		nameTakenQuery, moveQuery = QUERY_FILE_NAME_IS_TAKEN_IN_FOLDER, QUERY_MOVE_FILE
+	default:
+		return nil, tiny_errors.New(
+			custom_errors.ERR_CODE_NotValid,
+			tiny_errors.Detail("target", strings.Join([]string{TARGET_FOLDER, TARGET_FILE}, ", ")),
+		)
+	}
+
+	taken, err := e.exists(ctx, nameTakenQuery, id, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, utils.ExistsError("item with such name already exists in destination folder")
+	}
+
+	if err := e.execAffected(ctx, moveQuery, id, folderID); err != nil {
+		return nil, err
+	}
+
+	if file != nil {
+		e.event(file.ID, webhook.EVENT_FILE_RENAMED, &callback.EventDetails{
+			Name:             &file.Name,
+			FolderID:         &folderID,
+			PreviousFolderID: file.FolderID,
+		})
+		return &Result{ID: id}, nil
+	}
+
+	// paths of all files of the moved folder are changed
+	nested := make([]*files.File, 0)
+	if dbErr := e.tx.SelectContext(ctx, &nested, files.QUERY_GET_NESTED_FILES, id); dbErr != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(dbErr.Error()))
+	}
+	for _, file := range nested {
+		e.event(file.ID, webhook.EVENT_FILE_RENAMED, &callback.EventDetails{
+			Name:     &file.Name,
+			FolderID: file.FolderID,
+		})
+	}
+	return &Result{ID: id}, nil
+}
+
+// resolveRef returns id of the referenced operation result or value as is.
+func (e *execution) resolveRef(value string) (string, tiny_errors.ErrorHandler) {
+	if !strings.HasPrefix(value, REF_PREFIX) {
+		return value, nil
+	}
+
+	id, ok := e.refs[strings.TrimPrefix(value, REF_PREFIX)]
+	if !ok {
+		return "", tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("ref", fmt.Sprintf("%q is not defined by previous operations", value)))
+	}
+	return id, nil
+}
+
+// resolveFolder resolves reference or root alias and checks that folder is not deleted.
+func (e *execution) resolveFolder(ctx context.Context, value *string) (string, tiny_errors.ErrorHandler) {
+	folderID := folders.ROOT_ID
+	if value != nil {
+		id, err := e.resolveRef(*value)
+		if err != nil {
+			return "", err
+		}
+		folderID = folders.ResolveID(id)
+	}
+
+	alive, err := e.exists(ctx, QUERY_FOLDER_IS_ALIVE, folderID)
+	if err != nil {
+		return "", err
+	}
+	if !alive {
+		return "", tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("folder does not exist"))
+	}
+
+	return folderID, nil
+}
+
+// validateContent checks content with the handler of its format. Format is selected by query with a single argument.
+func (e *execution) validateContent(ctx context.Context, query string, arg string, content string) tiny_errors.ErrorHandler {
+	var format content_formats.ContentFormat
+	err := e.tx.GetContext(ctx, &format, query, arg)
+	if err == sql.ErrNoRows {
+		return tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("content format does not exist"))
+	}
+	if err != nil {
+		return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	if err := format.Validate(content); err != nil {
+		return tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message(err.Error()), tiny_errors.Detail("content", "not valid "+format.Name))
+	}
+	return nil
+}
+
+func (e *execution) content(ctx context.Context, contentID string) (*storedContent, tiny_errors.ErrorHandler) {
+	var content storedContent
+	err := e.tx.GetContext(ctx, &content, QUERY_GET_CONTENT, contentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("file content does not exist"))
+		}
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	return &content, nil
+}
+
+func (e *execution) file(ctx context.Context, fileID string) (*files.File, tiny_errors.ErrorHandler) {
+	var file files.File
+	err := e.tx.GetContext(ctx, &file, QUERY_GET_FILE, fileID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("item does not exist"))
+		}
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	return &file, nil
+}
+
+func (e *execution) exists(ctx context.Context, q string, args ...any) (bool, tiny_errors.ErrorHandler) {
+	var result bool
+	err := e.tx.QueryRowxContext(ctx, q, args...).Scan(&result)
+	if err != nil {
+		return false, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	return result, nil
+}
+
+// execAffected runs query and returns NotFound error if no rows were changed.
+func (e *execution) execAffected(ctx context.Context, q string, args ...any) tiny_errors.ErrorHandler {
+	result, err := e.tx.ExecContext(ctx, q, args...)
+	if err != nil {
+		return utils.DatabaseError(err, "item with such name already exists in destination folder")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	if affected == 0 {
+		return tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("item does not exist"))
+	}
+	return nil
+}
+
+// event adds an event of the operation which is sent to listeners of the file after the batch.
+func (e *execution) event(fileID, event string, details *callback.EventDetails) {
+	e.events = append(e.events, &callback.CallbackRequest{
+		FileID:  fileID,
+		Event:   event,
+		Details: details,
+	})
+}
+
+// operationError adds index of the failed operation to the error.
+func operationError(index int, err tiny_errors.ErrorHandler) tiny_errors.ErrorHandler {
+	options := []tiny_errors.ErrorOption{
+		tiny_errors.Detail("operation", fmt.Sprintf("%d", index)),
+	}
+	if err.GetMessage() != "" {
+		options = append(options, tiny_errors.Message(err.GetMessage()))
+	}
+	for key, value := range err.GetDetails() {
+		options = append(options, tiny_errors.Detail(key, value))
+	}
+	if err.GetCode() == custom_errors.ERR_CODE_NotFound {
+		options = append(options, tiny_errors.HTTPStatus(http.StatusNotFound))
+	}
+
+	return tiny_errors.New(err.GetCode(), options...)
+}
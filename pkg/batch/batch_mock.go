@@ -0,0 +1,26 @@
+package batch
+
+import (
+	"context"
+
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockClient struct {
+	mock.Mock
+}
+
+func NewMock() *MockClient {
+	return new(MockClient)
+}
+
+func (m *MockClient) Execute(ctx context.Context, req *ExecuteRequest) (*ExecuteResponse, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	response := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return response.(*ExecuteResponse), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
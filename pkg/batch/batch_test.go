@@ -0,0 +1,331 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/blobs"
+	"github.com/Moranilt/config-keeper/pkg/callback"
+	"github.com/Moranilt/config-keeper/pkg/content_formats"
+	"github.com/Moranilt/config-keeper/pkg/file_contents"
+	"github.com/Moranilt/config-keeper/pkg/files"
+	"github.com/Moranilt/config-keeper/pkg/folders"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/clients/database"
+	database_mock "github.com/Moranilt/http-utils/clients/database/mock"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Execute(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb}, blobs.New(&blobs.Config{}))
+
+	existsRow := func(exists bool) *sqlmock.Rows {
+		return sqlMock.NewRows([]string{"exists"}).AddRow(exists)
+	}
+	fileRow := func() *sqlmock.Rows {
+		return sqlMock.NewRows([]string{"id", "folder_id", "name", "description", "owner_team", "contact", "labels", "created_at", "updated_at"}).
+			AddRow("file_id", folders.ROOT_ID, "file_name", nil, nil, nil, "{}", "2020-01-01", "2020-01-01")
+	}
+	contentRow := func() *sqlmock.Rows {
+		return sqlMock.NewRows([]string{"id", "file_id", "version", "content", "created_at", "updated_at", "format"}).
+			AddRow("content_id", "file_id", "v1", utils.StringToBase64("key: value"), "2020-01-01", "2020-01-01", "yaml")
+	}
+	nestedRows := func() *sqlmock.Rows {
+		return sqlMock.NewRows([]string{"id", "folder_id", "name"}).
+			AddRow("file_1", "folder_id", "app.yaml").
+			AddRow("file_2", "nested_id", ".env")
+	}
+	formatRow := func(validator string) *sqlmock.Rows {
+		return sqlMock.NewRows([]string{"id", "name", "mime_type", "extension", "validator", "created_at", "updated_at"}).
+			AddRow("format_id", validator, "text/plain", nil, validator, "2020-01-01", "2020-01-01")
+	}
+
+	tests := []struct {
+		name           string
+		req            *ExecuteRequest
+		mockSetup      func()
+		expectedResult *ExecuteResponse
+		expectedError  tiny_errors.ErrorHandler
+	}{
+		{
+			name: "create file with content using ref",
+			req: &ExecuteRequest{Operations: []*Operation{
+				{Op: OP_CREATE_FILE, Ref: utils.MakePointer("new_file"), Name: utils.MakePointer("file_name")},
+				{Op: OP_CREATE_CONTENT, FileID: utils.MakePointer("$new_file"), Version: utils.MakePointer("v1"), Content: utils.MakePointer("key: value"), FormatID: utils.MakePointer("format_id")},
+			}},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_IS_ALIVE)).WithArgs(folders.ROOT_ID).WillReturnRows(existsRow(true))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FILE_NAME_IS_TAKEN)).WithArgs("file_name", folders.ROOT_ID).WillReturnRows(existsRow(false))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(files.QUERY_CREATE_FILE)).WithArgs(folders.ROOT_ID, "file_name").WillReturnRows(fileRow())
+				sqlMock.ExpectQuery(regexp.QuoteMeta(files.QUERY_FILE_EXISTS)).WithArgs("file_id").WillReturnRows(existsRow(true))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(content_formats.QUERY_GET_FORMAT)).WithArgs("format_id").WillReturnRows(formatRow("yaml"))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(file_contents.QUERY_GET_FILES_CONTENT_ID_BY_VERSION)).WithArgs("file_id", "v1").WillReturnError(sql.ErrNoRows)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(file_contents.QUERY_CREATE_CONTENT)).
+					WithArgs("file_id", "v1", utils.StringToBase64("key: value"), "format_id", int64(10), "", blobs.Key("key: value")).
+					WillReturnRows(contentRow())
+				sqlMock.ExpectCommit()
+			},
+			expectedResult: &ExecuteResponse{
+				Results: []*Result{
+					{Index: 0, Op: OP_CREATE_FILE, ID: "file_id"},
+					{Index: 1, Op: OP_CREATE_CONTENT, ID: "content_id", FileID: utils.MakePointer("file_id")},
+				},
+				Events: []*callback.CallbackRequest{
+					{
+						FileID:  "file_id",
+						Event:   webhook.EVENT_CONTENT_CREATED,
+						Details: &callback.EventDetails{ContentID: utils.MakePointer("content_id"), Version: utils.MakePointer("v1")},
+					},
+				},
+			},
+		},
+		{
+			name: "edit and delete contents of the same file",
+			req: &ExecuteRequest{Operations: []*Operation{
+				{Op: OP_EDIT_CONTENT, ID: utils.MakePointer("content_id"), Version: utils.MakePointer("v2"), Content: utils.MakePointer("key: value")},
+				{Op: OP_DELETE, Target: utils.MakePointer(TARGET_CONTENT), ID: utils.MakePointer("old_content_id")},
+			}},
+			mockSetup: func() {
+				updateQuery := utils.NewUpdateQuery("file_contents").SetRaw("updated_at = now()").Set("version", "v2").
+					Set("content", utils.StringToBase64("key: value")).Set("size", int64(10)).Set("blob_key", "").Set("checksum", blobs.Key("key: value")).
+					Where("id = ?", "content_id")
+
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_CONTENT)).WithArgs("content_id").
+					WillReturnRows(sqlMock.NewRows([]string{"file_id", "version"}).AddRow("file_id", "v1"))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(content_formats.QUERY_GET_FORMAT_BY_CONTENT)).WithArgs("content_id").WillReturnRows(formatRow("yaml"))
+				sqlMock.ExpectExec(regexp.QuoteMeta(updateQuery.String())).
+					WithArgs("v2", utils.StringToBase64("key: value"), int64(10), "", blobs.Key("key: value"), "content_id").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_CONTENT)).WithArgs("old_content_id").
+					WillReturnRows(sqlMock.NewRows([]string{"file_id", "version"}).AddRow("file_id", "v0"))
+				sqlMock.ExpectExec(regexp.QuoteMeta(file_contents.QUERY_DELETE_FILE_CONTENT)).WithArgs("old_content_id").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+			},
+			expectedResult: &ExecuteResponse{
+				Results: []*Result{
+					{Index: 0, Op: OP_EDIT_CONTENT, ID: "content_id", FileID: utils.MakePointer("file_id")},
+					{Index: 1, Op: OP_DELETE, ID: "old_content_id", FileID: utils.MakePointer("file_id")},
+				},
+				// changes of the same file are sent as a single event
+				Events: []*callback.CallbackRequest{
+					{
+						FileID: "file_id",
+						Event:  webhook.EVENT_CONTENT_DELETED,
+						Details: &callback.EventDetails{
+							ContentID: utils.MakePointer("old_content_id"),
+							Version:   utils.MakePointer("v0"),
+							Events: []*callback.EventChange{
+								{
+									Event: webhook.EVENT_CONTENT_UPDATED,
+									Details: &callback.EventDetails{
+										ContentID:       utils.MakePointer("content_id"),
+										Version:         utils.MakePointer("v2"),
+										PreviousVersion: utils.MakePointer("v1"),
+									},
+								},
+								{
+									Event:   webhook.EVENT_CONTENT_DELETED,
+									Details: &callback.EventDetails{ContentID: utils.MakePointer("old_content_id"), Version: utils.MakePointer("v0")},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "delete folder with nested files",
+			req: &ExecuteRequest{Operations: []*Operation{
+				{Op: OP_DELETE, Target: utils.MakePointer(TARGET_FOLDER), ID: utils.MakePointer("folder_id")},
+			}},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(files.QUERY_GET_NESTED_FILES)).WithArgs("folder_id").WillReturnRows(nestedRows())
+				sqlMock.ExpectExec(regexp.QuoteMeta(folders.QUERY_DELETE_FOLDER)).WithArgs("folder_id").WillReturnResult(sqlmock.NewResult(0, 3))
+				sqlMock.ExpectCommit()
+			},
+			expectedResult: &ExecuteResponse{
+				Results: []*Result{{Index: 0, Op: OP_DELETE, ID: "folder_id"}},
+				Events: []*callback.CallbackRequest{
+					{FileID: "file_1", Event: webhook.EVENT_FILE_DELETED, Details: &callback.EventDetails{Name: utils.MakePointer("app.yaml")}},
+					{FileID: "file_2", Event: webhook.EVENT_FILE_DELETED, Details: &callback.EventDetails{Name: utils.MakePointer(".env")}},
+				},
+			},
+		},
+		{
+			name: "move file",
+			req: &ExecuteRequest{Operations: []*Operation{
+				{Op: OP_MOVE, Target: utils.MakePointer(TARGET_FILE), ID: utils.MakePointer("file_id"), FolderID: utils.MakePointer("parent_id")},
+			}},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_IS_ALIVE)).WithArgs("parent_id").WillReturnRows(existsRow(true))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FILE)).WithArgs("file_id").WillReturnRows(fileRow())
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FILE_NAME_IS_TAKEN_IN_FOLDER)).WithArgs("file_id", "parent_id").WillReturnRows(existsRow(false))
+				sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_MOVE_FILE)).WithArgs("file_id", "parent_id").WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+			},
+			expectedResult: &ExecuteResponse{
+				Results: []*Result{{Index: 0, Op: OP_MOVE, ID: "file_id"}},
+				Events: []*callback.CallbackRequest{
+					{
+						FileID: "file_id",
+						Event:  webhook.EVENT_FILE_RENAMED,
+						Details: &callback.EventDetails{
+							Name:             utils.MakePointer("file_name"),
+							FolderID:         utils.MakePointer("parent_id"),
+							PreviousFolderID: utils.MakePointer(folders.ROOT_ID),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "content is not valid for the format",
+			req: &ExecuteRequest{Operations: []*Operation{
+				{Op: OP_CREATE_CONTENT, FileID: utils.MakePointer("file_id"), Version: utils.MakePointer("v1"), Content: utils.MakePointer("{\"key\": }"), FormatID: utils.MakePointer("format_id")},
+			}},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(files.QUERY_FILE_EXISTS)).WithArgs("file_id").WillReturnRows(existsRow(true))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(content_formats.QUERY_GET_FORMAT)).WithArgs("format_id").WillReturnRows(formatRow("json"))
+				sqlMock.ExpectRollback()
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("invalid character '}' looking for beginning of value")),
+		},
+		{
+			name: "move folder",
+			req: &ExecuteRequest{Operations: []*Operation{
+				{Op: OP_MOVE, Target: utils.MakePointer(TARGET_FOLDER), ID: utils.MakePointer("folder_id"), FolderID: utils.MakePointer("parent_id")},
+			}},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_IS_ALIVE)).WithArgs("parent_id").WillReturnRows(existsRow(true))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_IS_NESTED_FOLDER)).WithArgs("folder_id", "parent_id").WillReturnRows(existsRow(false))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_NAME_IS_TAKEN)).WithArgs("folder_id", "parent_id").WillReturnRows(existsRow(false))
+				sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_MOVE_FOLDER)).WithArgs("folder_id", "parent_id").WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(files.QUERY_GET_NESTED_FILES)).WithArgs("folder_id").WillReturnRows(nestedRows())
+				sqlMock.ExpectCommit()
+			},
+			expectedResult: &ExecuteResponse{
+				Results: []*Result{{Index: 0, Op: OP_MOVE, ID: "folder_id"}},
+				Events: []*callback.CallbackRequest{
+					{
+						FileID:  "file_1",
+						Event:   webhook.EVENT_FILE_RENAMED,
+						Details: &callback.EventDetails{Name: utils.MakePointer("app.yaml"), FolderID: utils.MakePointer("folder_id")},
+					},
+					{
+						FileID:  "file_2",
+						Event:   webhook.EVENT_FILE_RENAMED,
+						Details: &callback.EventDetails{Name: utils.MakePointer(".env"), FolderID: utils.MakePointer("nested_id")},
+					},
+				},
+			},
+		},
+		{
+			name: "move folder into nested folder",
+			req: &ExecuteRequest{Operations: []*Operation{
+				{Op: OP_MOVE, Target: utils.MakePointer(TARGET_FOLDER), ID: utils.MakePointer("folder_id"), FolderID: utils.MakePointer("child_id")},
+			}},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_IS_ALIVE)).WithArgs("child_id").WillReturnRows(existsRow(true))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_IS_NESTED_FOLDER)).WithArgs("folder_id", "child_id").WillReturnRows(existsRow(true))
+				sqlMock.ExpectRollback()
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("folder can not be moved into itself or its nested folder")),
+		},
+		{
+			name: "rollback when operation fails",
+			req: &ExecuteRequest{Operations: []*Operation{
+				{Op: OP_DELETE, Target: utils.MakePointer(TARGET_FILE), ID: utils.MakePointer("file_id")},
+				{Op: OP_DELETE, Target: utils.MakePointer(TARGET_FILE), ID: utils.MakePointer("missing_id")},
+			}},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FILE)).WithArgs("file_id").WillReturnRows(fileRow())
+				sqlMock.ExpectExec(regexp.QuoteMeta(files.QUERY_DELETE_FILE)).WithArgs("file_id").WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FILE)).WithArgs("missing_id").WillReturnError(sql.ErrNoRows)
+				sqlMock.ExpectRollback()
+			},
+			expectedError: tiny_errors.New(
+				custom_errors.ERR_CODE_NotFound,
+				tiny_errors.Message("item does not exist"),
+				tiny_errors.Detail("operation", "1"),
+			),
+		},
+		{
+			name: "undefined ref",
+			req: &ExecuteRequest{Operations: []*Operation{
+				{Op: OP_DELETE, Target: utils.MakePointer(TARGET_FILE), ID: utils.MakePointer("$unknown")},
+			}},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectRollback()
+			},
+			expectedError: tiny_errors.New(
+				custom_errors.ERR_CODE_NotValid,
+				tiny_errors.Detail("operation", "0"),
+				tiny_errors.Detail("ref", `"$unknown" is not defined by previous operations`),
+			),
+		},
+		{
+			name: "unknown operation",
+			req:  &ExecuteRequest{Operations: []*Operation{{Op: "rename"}}},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectRollback()
+			},
+			expectedError: tiny_errors.New(
+				custom_errors.ERR_CODE_NotValid,
+				tiny_errors.Detail("operation", "0"),
+				tiny_errors.Detail("op", "create_file, create_content, edit_content, delete, move"),
+			),
+		},
+		{
+			name:          "empty operations",
+			req:           &ExecuteRequest{},
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, tiny_errors.Detail("operations", "required")),
+		},
+		{
+			name:          "empty request",
+			req:           nil,
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_BodyRequired),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			result, err := client.Execute(context.Background(), tt.req)
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
+				if tt.expectedError.GetMessage() != "" {
+					assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+				}
+				if len(tt.expectedError.GetDetails()) > 0 {
+					assert.Equal(t, tt.expectedError.GetDetails(), err.GetDetails())
+				}
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResult, result)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
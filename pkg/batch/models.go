@@ -0,0 +1,97 @@
+package batch
+
+import "github.com/Moranilt/config-keeper/pkg/callback"
+
+const (
+	OP_CREATE_FILE    = "create_file"
+	OP_CREATE_CONTENT = "create_content"
+	OP_EDIT_CONTENT   = "edit_content"
+	OP_DELETE         = "delete"
+	OP_MOVE           = "move"
+
+	TARGET_FOLDER  = "folder"
+	TARGET_FILE    = "file"
+	TARGET_CONTENT = "content"
+
+	// MAX_OPERATIONS is a max amount of operations in one batch.
+	MAX_OPERATIONS = 100
+
+	// REF_PREFIX marks a reference to the result of previous operation, e.g. "$new_file".
+	REF_PREFIX = "$"
+)
+
+const (
+	QUERY_FOLDER_IS_ALIVE      = "SELECT EXISTS(SELECT 1 FROM folders WHERE id = $1 AND deleted_at IS NULL)"
+	QUERY_FILE_NAME_IS_TAKEN   = "SELECT EXISTS(SELECT 1 FROM files WHERE name = $1 AND folder_id = $2 AND deleted_at IS NULL)"
+	QUERY_GET_CONTENT          = "SELECT fc.file_id, fc.version FROM file_contents fc JOIN files f ON f.id = fc.file_id WHERE fc.id = $1 AND f.deleted_at IS NULL"
+	QUERY_GET_FILE             = "SELECT id, folder_id, name FROM files WHERE id = $1 AND deleted_at IS NULL"
+	QUERY_MOVE_FILE            = "UPDATE files SET folder_id = $2, updated_at = now() WHERE id = $1 AND deleted_at IS NULL"
+	QUERY_MOVE_FOLDER          = "UPDATE folders SET parent_id = $2, updated_at = now() WHERE id = $1 AND deleted_at IS NULL"
+	QUERY_FOLDER_NAME_IS_TAKEN = `SELECT EXISTS(
+		SELECT 1 FROM folders
+		WHERE name = (SELECT name FROM folders WHERE id = $1)
+		AND parent_id = $2
+		AND deleted_at IS NULL
+		AND id <> $1
+	)`
+	QUERY_FILE_NAME_IS_TAKEN_IN_FOLDER = `SELECT EXISTS(
+		SELECT 1 FROM files
+		WHERE name = (SELECT name FROM files WHERE id = $1)
+		AND folder_id = $2
+		AND deleted_at IS NULL
+		AND id <> $1
+	)`
+	// QUERY_IS_NESTED_FOLDER checks whether $2 is $1 or one of its nested folders.
+	QUERY_IS_NESTED_FOLDER = `WITH RECURSIVE subtree AS (
+		SELECT id FROM folders WHERE id = $1
+		UNION ALL
+		SELECT f.id FROM folders f JOIN subtree s ON f.parent_id = s.id
+	)
+	SELECT EXISTS(SELECT 1 FROM subtree WHERE id = $2)`
+)
+
+// Operation is a single change in a batch. Set of required fields depends on Op:
+//   - create_file: name, folder_id(optional, root by default)
+//   - create_content: file_id, version, content, format_id
+//   - edit_content: id, version and/or content
+//   - delete: target(folder, file or content), id
+//   - move: target(folder or file), id, folder_id
+//
+// Any id field can reference result of previous operation using "$" and its ref, e.g. "$new_file".
+type Operation struct {
+	Op       string  `json:"op"`
+	Ref      *string `json:"ref"`
+	Target   *string `json:"target"`
+	ID       *string `json:"id"`
+	Name     *string `json:"name"`
+	FolderID *string `json:"folder_id"`
+	FileID   *string `json:"file_id"`
+	Version  *string `json:"version"`
+	Content  *string `json:"content"`
+	FormatID *string `json:"format_id"`
+}
+
+type ExecuteRequest struct {
+	Operations []*Operation
+}
+
+// Result describes an applied operation. FileID is set when contents of the file were changed.
+type Result struct {
+	Index  int     `json:"index"`
+	Op     string  `json:"op"`
+	ID     string  `json:"id"`
+	FileID *string `json:"file_id,omitempty"`
+}
+
+type ExecuteResponse struct {
+	Results []*Result
+	// Events contain a single event of every changed file with all its changes in order of operations.
+	// They should be sent to listeners after the batch.
+	Events []*callback.CallbackRequest
+}
+
+// storedContent is a content changed by the operation.
+type storedContent struct {
+	FileID  string `db:"file_id"`
+	Version string `db:"version"`
+}
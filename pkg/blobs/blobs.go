@@ -0,0 +1,217 @@
+package blobs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/tiny_errors"
+)
+
+// Querier is implemented by database client and transactions.
+type Querier interface {
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+}
+
+// Storage checks size limits of contents and keeps contents larger than inline size in a directory.
+// Blobs are compressed with gzip and addressed by sha256 of data, so equal contents share the same blob.
+// Blobs are not removed with contents, blobs which are not referenced by contents are removed by Sweep.
+// The directory should be shared by all instances of the service, every instance reads and sweeps all blobs.
+type Storage struct {
+	cfg Config
+}
+
+// New creates a new storage. Limits which are not positive are not checked.
+func New(cfg *Config) *Storage {
+	return &Storage{
+		cfg: *cfg,
+	}
+}
+
+// Prepare checks size limits and writes data into a blob if it is larger than inline size.
+// Size of the content with replacedID is not counted in total size, it should be empty for new contents.
+// The blob is written before the transaction of the content is committed, it is swept if the transaction is rolled back.
+func (s *Storage) Prepare(ctx context.Context, q Querier, data string, replacedID string) (*Stored, tiny_errors.ErrorHandler) {
+	size := int64(len(data))
+	if s.cfg.MaxSize > 0 && size > s.cfg.MaxSize {
+		return nil, tooLarge(fmt.Sprintf("content size %d exceeds limit of %d bytes", size, s.cfg.MaxSize))
+	}
+
+	if s.cfg.TotalMaxSize > 0 {
+		var total int64
+		err := q.GetContext(ctx, &total, QUERY_GET_TOTAL_SIZE, replacedID)
+		if err != nil {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		}
+		if total+size > s.cfg.TotalMaxSize {
+			return nil, tooLarge(fmt.Sprintf("total size of contents exceeds limit of %d bytes", s.cfg.TotalMaxSize))
+		}
+	}
+
+	checksum := Key(data)
+	if s.cfg.Dir == "" || s.cfg.InlineSize <= 0 || size <= s.cfg.InlineSize {
+		return &Stored{Content: utils.StringToBase64(data), Size: size, Checksum: checksum}, nil
+	}
+
+	if err := s.put(checksum, []byte(data)); err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Marshal, tiny_errors.Message(err.Error()))
+	}
+	return &Stored{BlobKey: checksum, Size: size, Checksum: checksum}, nil
+}
+
+// Load returns data of the blob.
+func (s *Storage) Load(key string) (string, tiny_errors.ErrorHandler) {
+	if s.cfg.Dir == "" {
+		return "", tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("blob storage is not configured"))
+	}
+
+	file, err := os.Open(s.path(key))
+	if os.IsNotExist(err) {
+		return "", tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("blob does not exist"), tiny_errors.Detail("blob_key", key))
+	}
+	if err != nil {
+		return "", tiny_errors.New(custom_errors.ERR_CODE_Marshal, tiny_errors.Message(err.Error()))
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return "", tiny_errors.New(custom_errors.ERR_CODE_Marshal, tiny_errors.Message(err.Error()))
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", tiny_errors.New(custom_errors.ERR_CODE_Marshal, tiny_errors.Message(err.Error()))
+	}
+	return string(data), nil
+}
+
+// Decode returns data of the content column or of the blob if key is not empty.
+func (s *Storage) Decode(content string, key string) (string, tiny_errors.ErrorHandler) {
+	if key != "" {
+		return s.Load(key)
+	}
+
+	data, err := utils.Base64ToString(content)
+	if err != nil {
+		return "", tiny_errors.New(custom_errors.ERR_CODE_Marshal, tiny_errors.Message(err.Error()))
+	}
+	return data, nil
+}
+
+// Sweep removes blobs which are not referenced by contents and were not written or reused after olderThan.
+// Returns the number of removed blobs.
+func (s *Storage) Sweep(ctx context.Context, q Querier, olderThan time.Time) (int, tiny_errors.ErrorHandler) {
+	if s.cfg.Dir == "" {
+		return 0, nil
+	}
+
+	var keys []string
+	if err := q.SelectContext(ctx, &keys, QUERY_GET_BLOB_KEYS); err != nil {
+		return 0, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	referenced := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		referenced[key] = struct{}{}
+	}
+
+	var removed int
+	err := filepath.WalkDir(s.cfg.Dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), BLOB_EXTENSION) {
+			return nil
+		}
+		if _, ok := referenced[strings.TrimSuffix(entry.Name(), BLOB_EXTENSION)]; ok {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(olderThan) {
+			return nil
+		}
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return removed, tiny_errors.New(custom_errors.ERR_CODE_Marshal, tiny_errors.Message(err.Error()))
+	}
+	return removed, nil
+}
+
+// Key returns a key of the blob with data, it is also used as a checksum of contents.
+func Key(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// put writes data into a temporary file and renames it, so readers never see partially written blobs.
+// Modification time of an existing blob is updated, so it is not swept while the new content is not committed.
+func (s *Storage) put(key string, data []byte) error {
+	target := s.path(key)
+	now := time.Now()
+	if err := os.Chtimes(target, now, now); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// path places blobs into subdirectories named after the first two characters of the key.
+func (s *Storage) path(key string) string {
+	prefix := key
+	if len(key) > 2 {
+		prefix = key[:2]
+	}
+	return filepath.Join(s.cfg.Dir, prefix, filepath.Base(key)+BLOB_EXTENSION)
+}
+
+func tooLarge(message string) tiny_errors.ErrorHandler {
+	return tiny_errors.New(custom_errors.ERR_CODE_TooLarge, tiny_errors.Message(message), tiny_errors.HTTPStatus(http.StatusRequestEntityTooLarge))
+}
@@ -0,0 +1,123 @@
+package blobs
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/clients/database"
+	database_mock "github.com/Moranilt/http-utils/clients/database/mock"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage_Prepare(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	db := &database.Client{mockDb}
+	dir := t.TempDir()
+
+	t.Run("small content is kept inline", func(t *testing.T) {
+		storage := New(&Config{Dir: dir, MaxSize: 100, InlineSize: 10})
+		stored, err := storage.Prepare(context.Background(), db, "key: value", "")
+		assert.Nil(t, err)
+		assert.Equal(t, &Stored{Content: utils.StringToBase64("key: value"), Size: 10, Checksum: Key("key: value")}, stored)
+
+		data, err := storage.Decode(stored.Content, stored.BlobKey)
+		assert.Nil(t, err)
+		assert.Equal(t, "key: value", data)
+	})
+
+	t.Run("large content is kept in a blob", func(t *testing.T) {
+		storage := New(&Config{Dir: dir, MaxSize: 1000, InlineSize: 10})
+		data := strings.Repeat("key: value\n", 50)
+		stored, err := storage.Prepare(context.Background(), db, data, "")
+		assert.Nil(t, err)
+		assert.Empty(t, stored.Content)
+		assert.Len(t, stored.BlobKey, 64)
+		assert.Equal(t, int64(len(data)), stored.Size)
+
+		again, err := storage.Prepare(context.Background(), db, data, "")
+		assert.Nil(t, err)
+		assert.Equal(t, stored, again)
+
+		info, statErr := os.Stat(storage.path(stored.BlobKey))
+		assert.NoError(t, statErr)
+		assert.Less(t, info.Size(), stored.Size)
+
+		loaded, err := storage.Decode(stored.Content, stored.BlobKey)
+		assert.Nil(t, err)
+		assert.Equal(t, data, loaded)
+	})
+
+	t.Run("content is larger than limit", func(t *testing.T) {
+		storage := New(&Config{Dir: dir, MaxSize: 5})
+		stored, err := storage.Prepare(context.Background(), db, "key: value", "")
+		assert.Nil(t, stored)
+		assert.Equal(t, custom_errors.ERR_CODE_TooLarge, err.GetCode())
+	})
+
+	t.Run("total size is larger than limit", func(t *testing.T) {
+		storage := New(&Config{TotalMaxSize: 100})
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_TOTAL_SIZE)).WithArgs("content_id").
+			WillReturnRows(sqlMock.NewRows([]string{"sum"}).AddRow(95))
+
+		stored, err := storage.Prepare(context.Background(), db, "key: value", "content_id")
+		assert.Nil(t, stored)
+		assert.Equal(t, custom_errors.ERR_CODE_TooLarge, err.GetCode())
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("blob does not exist", func(t *testing.T) {
+		storage := New(&Config{Dir: dir})
+		_, err := storage.Load("missing")
+		assert.Equal(t, custom_errors.ERR_CODE_NotFound, err.GetCode())
+	})
+}
+
+func TestStorage_Sweep(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	db := &database.Client{mockDb}
+	storage := New(&Config{Dir: t.TempDir(), InlineSize: 1})
+
+	var keys []string
+	for _, data := range []string{"referenced", "orphan", "recent"} {
+		stored, err := storage.Prepare(context.Background(), db, data, "")
+		assert.Nil(t, err)
+		keys = append(keys, stored.BlobKey)
+	}
+	old := time.Now().Add(-2 * SWEEP_GRACE_PERIOD)
+	for _, key := range keys[:2] {
+		assert.NoError(t, os.Chtimes(storage.path(key), old, old))
+	}
+
+	sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_BLOB_KEYS)).
+		WillReturnRows(sqlMock.NewRows([]string{"blob_key"}).AddRow(keys[0]))
+
+	removed, err := storage.Sweep(context.Background(), db, time.Now().Add(-SWEEP_GRACE_PERIOD))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, removed)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+
+	_, err = storage.Load(keys[0])
+	assert.Nil(t, err)
+	_, err = storage.Load(keys[1])
+	assert.Equal(t, custom_errors.ERR_CODE_NotFound, err.GetCode())
+	_, err = storage.Load(keys[2])
+	assert.Nil(t, err)
+
+	// reused blobs are not swept until the grace period passes
+	_, err = storage.Prepare(context.Background(), db, "referenced", "")
+	assert.Nil(t, err)
+	sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_BLOB_KEYS)).WillReturnRows(sqlMock.NewRows([]string{"blob_key"}))
+
+	removed, err = storage.Sweep(context.Background(), db, time.Now().Add(-SWEEP_GRACE_PERIOD))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, removed)
+}
@@ -0,0 +1,36 @@
+package blobs
+
+import "time"
+
+const (
+	// QUERY_GET_TOTAL_SIZE returns total size of contents except the content which is replaced.
+	QUERY_GET_TOTAL_SIZE = "SELECT COALESCE(SUM(size), 0) FROM file_contents WHERE id::text <> $1"
+	QUERY_GET_BLOB_KEYS  = "SELECT DISTINCT blob_key FROM file_contents WHERE blob_key <> ''"
+
+	BLOB_EXTENSION = ".gz"
+
+	// SWEEP_GRACE_PERIOD is how long blobs are kept after they are written or reused before they can be swept,
+	// so blobs of contents in transactions which are not committed yet are not removed.
+	SWEEP_GRACE_PERIOD = time.Hour
+)
+
+// Config describes size limits of contents and where large contents are kept.
+// Contents are kept in the database if Dir is empty. Zero TotalMaxSize disables the global limit.
+type Config struct {
+	Dir          string
+	MaxSize      int64
+	TotalMaxSize int64
+	InlineSize   int64
+}
+
+// Stored is a content prepared for a file_contents row.
+type Stored struct {
+	// Content is base64 encoded data, empty if data is kept in a blob.
+	Content string
+	// BlobKey is a key of the blob with data, empty if data is kept in Content.
+	BlobKey string
+	// Size is a size of the data in bytes.
+	Size int64
+	// Checksum is sha256 of the data in hex.
+	Checksum string
+}
@@ -0,0 +1,52 @@
+package blobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/Moranilt/http-utils/logger"
+)
+
+type Sweeper interface {
+	// Run periodically removes blobs which are not referenced by contents.
+	// The loop will continue until the provided context is canceled.
+	Run(ctx context.Context)
+}
+
+type sweeper struct {
+	log      logger.Logger
+	storage  *Storage
+	db       Querier
+	interval time.Duration
+}
+
+func NewSweeper(log logger.Logger, storage *Storage, db Querier, interval time.Duration) Sweeper {
+	return &sweeper{
+		log:      log,
+		storage:  storage,
+		db:       db,
+		interval: interval,
+	}
+}
+
+func (s *sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Info("Stopping blob sweeper")
+			return
+		case <-ticker.C:
+			removed, err := s.storage.Sweep(ctx, s.db, time.Now().Add(-SWEEP_GRACE_PERIOD))
+			if err != nil {
+				s.log.Errorf("Error while sweeping blobs: %s", err)
+				continue
+			}
+			if removed > 0 {
+				s.log.Infof("Removed %d unreferenced blobs", removed)
+			}
+		}
+	}
+}
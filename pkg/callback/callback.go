@@ -3,125 +3,277 @@ package callback
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/Moranilt/config-keeper/custom_errors"
 	"github.com/Moranilt/config-keeper/pkg/file_contents"
 	"github.com/Moranilt/config-keeper/pkg/files"
 	"github.com/Moranilt/config-keeper/pkg/listeners"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
 	"github.com/Moranilt/http-utils/logger"
-	"golang.org/x/sync/errgroup"
+	"github.com/Moranilt/http-utils/tiny_errors"
 )
 
 type CallbackService interface {
-	// Run is the main loop of the callbackService. It listens for callback requests on the sendCh channel
-	// and dispatches them to all registered listeners. The loop will continue until the provided context is canceled.
+	// Run is the main loop of the callbackService. It claims events of the outbox and dispatches them
+	// to registered listeners of the file. Every listener gets its own event which is retried by the retry policy
+	// of the listener and moved to dead letters when it runs out of attempts. Results of requests are counted
+	// in health of listeners, events of disabled listeners are moved to dead letters.
+	// The outbox is checked every POLL_INTERVAL and when events are sent. The loop will continue until
+	// the provided context is canceled.
 	Run(ctx context.Context)
 
-	// prepareListenersData processes the callback request and prepares listener data
-	// Returns a slice of listeners, serialized data, and any error encountered
-	prepareListenersData(ctx context.Context, req *CallbackRequest) ([]*listeners.Listener, []byte, error)
+	// prepareFileData returns serialized payload of the event which is sent to the listener in its payload mode.
+	prepareFileData(ctx context.Context, listener *listeners.Listener, event *Event) ([]byte, error)
 }
 
 type callbackService struct {
 	log       logger.Logger
-	sendCh    CallbackChannel
+	outbox    Outbox
 	file      files.Client
 	listeners listeners.Client
 	content   file_contents.Client
 	rc        RequestsController
+	// publicURL is an address of the service in links to contents, links are relative if it is empty.
+	publicURL string
 }
 
 func New(
 	log logger.Logger,
-	sendCh CallbackChannel,
+	outbox Outbox,
 	file files.Client,
 	listeners listeners.Client,
 	content file_contents.Client,
 	rc RequestsController,
+	publicURL string,
 ) CallbackService {
 	return &callbackService{
 		log:       log,
-		sendCh:    sendCh,
+		outbox:    outbox,
 		file:      file,
 		listeners: listeners,
 		content:   content,
 		rc:        rc,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
 	}
 }
 
 func (s *callbackService) Run(ctx context.Context) {
+	ticker := time.NewTicker(POLL_INTERVAL)
+	defer ticker.Stop()
+
 	for {
+		s.deliver(ctx)
+
 		select {
 		case <-ctx.Done():
 			s.log.Info("Stopping callback service")
 			return
-		case req := <-s.sendCh.Get():
-			go func(req *CallbackRequest) {
-				s.log.Infof("Received callback request with req %#v", req)
-				listeners, fileData, err := s.prepareListenersData(ctx, req)
-				if err != nil {
-					s.log.Error("Error while preparing data")
-					return
-				}
-				err = s.sendToListeners(ctx, listeners, fileData)
-				if err != nil {
-					s.log.Error("Error while sending data")
-					return
-				}
-			}(req)
+		case <-ticker.C:
+		case <-s.outbox.Notify():
+		}
+	}
+}
+
+// deliver claims and processes events until there are no events ready for delivery.
+// Claimed events are processed concurrently.
+func (s *callbackService) deliver(ctx context.Context) {
+	for ctx.Err() == nil {
+		events, err := s.outbox.Claim(ctx, CLAIM_LIMIT, CLAIM_LEASE)
+		if err != nil {
+			s.log.Errorf("Error claiming callback events: %s", err)
+			return
+		}
+		if len(events) == 0 {
+			return
 		}
+
+		var wg sync.WaitGroup
+		for _, event := range events {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.process(ctx, event)
+			}()
+		}
+		wg.Wait()
 	}
 }
 
-func (s *callbackService) prepareListenersData(ctx context.Context, req *CallbackRequest) ([]*listeners.Listener, []byte, error) {
+// process splits the event of the file into events of its listeners or sends the event to its listener.
+// The event is removed if it is delivered, the file or the listener does not exist anymore or versions changed
+// by the event do not match the version filter of the listener. Failed events are
+// retried by the retry policy of the listener or after the delay requested by the listener, and moved to dead letters
+// after the last attempt or a permanent failure. Events of disabled listeners are moved to dead letters without requests.
+func (s *callbackService) process(ctx context.Context, event *Event) {
+	s.log.Infof("Received callback event %d for file %s", event.ID, event.FileID)
+	if event.ListenerID == nil {
+		if err := s.outbox.Split(ctx, event.ID); err != nil {
+			s.log.Errorf("Error splitting callback event %d: %s", event.ID, err)
+		}
+		return
+	}
+
 	var err error
-	s.log.Debugf("Request data for preparing listeners: %#v", req)
-	file, err := s.file.Get(ctx, &files.GetRequest{ID: req.FileID})
-	if err != nil {
-		s.log.Errorf("Error getting file: %s", err)
-		return nil, nil, err
+	var listener *listeners.Listener
+	policy := listeners.RetryPolicy{}.WithDefaults()
+	listener, err = s.listeners.Get(ctx, &listeners.GetRequest{ID: *event.ListenerID})
+	if err == nil && !matchVersions(listener, event) {
+		s.log.Infof("Callback event %d does not match versions of listener %s", event.ID, listener.ID)
+	} else if err == nil && !listener.Enabled {
+		err = &DeliveryError{Err: fmt.Errorf("listener %s is disabled", listener.ID), Permanent: true}
+	} else if err == nil {
+		policy = listener.RetryPolicy.WithDefaults()
+		var fileData []byte
+		fileData, err = s.prepareFileData(ctx, listener, event)
+		if err == nil {
+			err = s.rc.Send(ctx, listener, event, fileData)
+			if !s.recordHealth(ctx, listener, err) {
+				err = &DeliveryError{Err: err, Permanent: true}
+			}
+		}
 	}
 
-	fileContents, err := s.content.GetMany(ctx, &file_contents.GetManyRequest{FileID: req.FileID})
-	if err != nil {
-		s.log.Error("Error getting file contents: %s", err)
-		return nil, nil, err
+	var deliveryErr *DeliveryError
+	errors.As(err, &deliveryErr)
+
+	switch {
+	case err == nil || isNotFound(err):
+		if doneErr := s.outbox.Done(ctx, event.ID); doneErr != nil {
+			s.log.Errorf("Error removing callback event %d: %s", event.ID, doneErr)
+		}
+	case event.Attempts >= policy.MaxAttempts || (deliveryErr != nil && deliveryErr.Permanent):
+		s.log.Errorf("Callback event %d is moved to dead letters after %d attempts: %s", event.ID, event.Attempts, err)
+		if deadErr := s.outbox.Dead(ctx, event.ID, err.Error()); deadErr != nil {
+			s.log.Errorf("Error moving callback event %d to dead letters: %s", event.ID, deadErr)
+		}
+	default:
+		delay := policy.Delay(event.Attempts)
+		if deliveryErr != nil && deliveryErr.RetryAfter > 0 {
+			delay = min(deliveryErr.RetryAfter, listeners.MAX_DELAY_LIMIT)
+		}
+		s.log.Errorf("Error while delivering callback event %d, retry in %s: %s", event.ID, delay, err)
+		if retryErr := s.outbox.Retry(ctx, event.ID, delay, err.Error()); retryErr != nil {
+			s.log.Errorf("Error postponing callback event %d: %s", event.ID, retryErr)
+		}
+	}
+}
+
+// recordHealth counts the result of the callback request in health of the listener.
+// Returns false if the listener is disabled after too many consecutive failures.
+func (s *callbackService) recordHealth(ctx context.Context, listener *listeners.Listener, err error) bool {
+	if ctx.Err() != nil {
+		return true
 	}
 
-	listenersList, err := s.listeners.GetMany(ctx, &listeners.GetManyRequest{FileID: req.FileID})
-	if err != nil {
-		s.log.Errorf("Error getting listeners: %s", err)
-		return nil, nil, err
+	if err == nil {
+		if recordErr := s.listeners.RecordSuccess(ctx, listener.ID); recordErr != nil {
+			s.log.Errorf("Error recording success of listener %s: %s", listener.ID, recordErr)
+		}
+		return true
+	}
+
+	enabled, recordErr := s.listeners.RecordFailure(ctx, listener.ID, err.Error())
+	if recordErr != nil {
+		s.log.Errorf("Error recording failure of listener %s: %s", listener.ID, recordErr)
+		return true
 	}
+	if !enabled {
+		s.log.Errorf("Listener %s is disabled after consecutive failures", listener.ID)
+	}
+	return enabled
+}
+
+// prepareFileData sends deleted files with their ID and name from details of the event, because they can not be read.
+// Changes of the content are sent only in the diff mode.
+func (s *callbackService) prepareFileData(ctx context.Context, listener *listeners.Listener, event *Event) ([]byte, error) {
+	details := event.Details.withoutChanges()
 
-	requestData := &FileData{
-		File:        *file,
-		FileContent: fileContents,
+	file := &files.File{ID: event.FileID}
+	if event.Type == webhook.EVENT_FILE_DELETED {
+		if details != nil && details.Name != nil {
+			file.Name = *details.Name
+		}
+	} else {
+		var err tiny_errors.ErrorHandler
+		file, err = s.file.Get(ctx, &files.GetRequest{ID: event.FileID})
+		if err != nil {
+			s.log.Errorf("Error getting file: %s", err)
+			return nil, err
+		}
+	}
+
+	var requestData any
+	switch listener.PayloadMode {
+	case listeners.PAYLOAD_DIFF, listeners.PAYLOAD_NOTIFICATION:
+		changeData := &ChangeData{
+			Event:   event.Type,
+			Details: details,
+			FileID:  file.ID,
+			Name:    file.Name,
+		}
+		if listener.PayloadMode == listeners.PAYLOAD_DIFF && event.Details != nil {
+			changeData.Changes = event.Details.Changes
+		}
+		if listener.PayloadMode == listeners.PAYLOAD_NOTIFICATION && event.Type != webhook.EVENT_FILE_DELETED {
+			changeData.URL = s.contentsURL(event)
+		}
+		requestData = changeData
+	default:
+		fileContents := []*file_contents.FileContent{}
+		if event.Type != webhook.EVENT_FILE_DELETED {
+			req := &file_contents.GetManyRequest{FileID: event.FileID, LoadBlobs: true}
+			if listener.PayloadMode == listeners.PAYLOAD_VERSION && details != nil {
+				req.Version = details.Version
+			}
+
+			var err tiny_errors.ErrorHandler
+			fileContents, _, err = s.content.GetMany(ctx, req)
+			if err != nil {
+				s.log.Error("Error getting file contents: %s", err)
+				return nil, err
+			}
+		}
+		requestData = &FileData{
+			Event:       event.Type,
+			Details:     details,
+			File:        *file,
+			FileContent: fileContents,
+		}
 	}
 
 	fileData, err := json.Marshal(requestData)
 	if err != nil {
 		s.log.Error("Error marshalling file: %s", err)
-		return nil, nil, err
+		return nil, err
 	}
 
-	return listenersList, fileData, nil
+	return fileData, nil
 }
 
-func (s *callbackService) sendToListeners(ctx context.Context, listeners []*listeners.Listener, fileData []byte) error {
-	g, ctx := errgroup.WithContext(ctx)
-	limiter := make(chan struct{}, 10) // Limit to 10 concurrent requests
-
-	for _, listener := range listeners {
-		listener := listener
-		limiter <- struct{}{}
-		g.Go(func() error {
-			defer func() { <-limiter }()
-			return s.rc.SendRequestWithRetry(ctx, listener.CallbackEndpoint, fileData)
-		})
+// contentsURL returns a link to contents of the file changed by the event, limited to the changed version if it is known.
+func (s *callbackService) contentsURL(event *Event) string {
+	link := s.publicURL + "/files/" + url.PathEscape(event.FileID) + "/contents"
+	if event.Details != nil && event.Details.Version != nil {
+		link += "?version=" + url.QueryEscape(*event.Details.Version)
 	}
+	return link
+}
 
-	if err := g.Wait(); err != nil {
-		return err
-	}
-	return nil
+// matchVersions reports whether the event changes a version matching the version filter of the listener.
+// Events of contents always have versions, events of files and redeliveries without details match all listeners.
+func matchVersions(listener *listeners.Listener, event *Event) bool {
+	versions := event.Versions()
+	return len(versions) == 0 || slices.ContainsFunc(versions, listener.VersionFilter.Match)
+}
+
+func isNotFound(err error) bool {
+	tErr, ok := err.(tiny_errors.ErrorHandler)
+	return ok && tErr.GetCode() == custom_errors.ERR_CODE_NotFound
 }
@@ -0,0 +1,79 @@
+package callback
+
+import (
+	"context"
+	"time"
+
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOutbox struct {
+	mock.Mock
+	notify chan struct{}
+}
+
+func NewMock() *MockOutbox {
+	return &MockOutbox{
+		notify: make(chan struct{}),
+	}
+}
+
+func (m *MockOutbox) Send(ctx context.Context, req *CallbackRequest) tiny_errors.ErrorHandler {
+	args := m.Called(ctx, req)
+	err := args.Get(0)
+	if err == nil {
+		return nil
+	}
+	return err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockOutbox) Claim(ctx context.Context, limit int, lease time.Duration) ([]*Event, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, limit, lease)
+	events := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return events.([]*Event), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockOutbox) Done(ctx context.Context, id int64) tiny_errors.ErrorHandler {
+	args := m.Called(ctx, id)
+	err := args.Get(0)
+	if err == nil {
+		return nil
+	}
+	return err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockOutbox) Retry(ctx context.Context, id int64, delay time.Duration, reason string) tiny_errors.ErrorHandler {
+	args := m.Called(ctx, id, delay, reason)
+	err := args.Get(0)
+	if err == nil {
+		return nil
+	}
+	return err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockOutbox) Split(ctx context.Context, id int64) tiny_errors.ErrorHandler {
+	args := m.Called(ctx, id)
+	err := args.Get(0)
+	if err == nil {
+		return nil
+	}
+	return err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockOutbox) Dead(ctx context.Context, id int64, reason string) tiny_errors.ErrorHandler {
+	args := m.Called(ctx, id, reason)
+	err := args.Get(0)
+	if err == nil {
+		return nil
+	}
+	return err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockOutbox) Notify() <-chan struct{} {
+	return m.notify
+}
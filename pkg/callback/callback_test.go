@@ -1,29 +1,38 @@
 package callback
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/deliveries"
 	"github.com/Moranilt/config-keeper/pkg/file_contents"
 	"github.com/Moranilt/config-keeper/pkg/files"
+	"github.com/Moranilt/config-keeper/pkg/formats"
 	"github.com/Moranilt/config-keeper/pkg/listeners"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/client"
 	"github.com/Moranilt/http-utils/logger"
 	"github.com/Moranilt/http-utils/tiny_errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
-func TestPrepareListenersData(t *testing.T) {
+func TestPrepareFileData(t *testing.T) {
 	// Create mock services
 	mockFile := files.NewMock()
 	mockContent := file_contents.NewMock()
-	mockListeners := listeners.NewMock()
 	mockLog := logger.NewMock()
-	sendChannel := NewChannel(1)
+	mockOutbox := NewMock()
 	// Create service with mocks
-	service := New(mockLog, sendChannel, mockFile, mockListeners, mockContent, nil)
+	service := New(mockLog, mockOutbox, mockFile, listeners.NewMock(), mockContent, nil, "")
 
 	setupMocks := func(
 		fileID string,
@@ -31,8 +40,6 @@ func TestPrepareListenersData(t *testing.T) {
 		fileError tiny_errors.ErrorHandler,
 		fileContents []*file_contents.FileContent,
 		fileContentsError tiny_errors.ErrorHandler,
-		listenersList []*listeners.Listener,
-		listenersError tiny_errors.ErrorHandler,
 	) {
 		if fileError != nil {
 			mockFile.On("Get", mock.Anything, &files.GetRequest{ID: fileID}).Return(nil, fileError)
@@ -40,42 +47,32 @@ func TestPrepareListenersData(t *testing.T) {
 			mockFile.On("Get", mock.Anything, &files.GetRequest{ID: fileID}).Return(file, nil)
 		}
 		if fileContentsError != nil {
-			mockContent.On("GetMany", mock.Anything, &file_contents.GetManyRequest{FileID: fileID}).Return(nil, fileContentsError)
+			mockContent.On("GetMany", mock.Anything, &file_contents.GetManyRequest{FileID: fileID, LoadBlobs: true}).Return(nil, nil, fileContentsError)
 		} else if fileError == nil {
-			mockContent.On("GetMany", mock.Anything, &file_contents.GetManyRequest{FileID: fileID}).Return(fileContents, nil)
-		}
-
-		if listenersError != nil {
-			mockListeners.On("GetMany", mock.Anything, &listeners.GetManyRequest{FileID: fileID}).Return(nil, listenersError)
-		} else if fileError == nil && fileContentsError == nil {
-			mockListeners.On("GetMany", mock.Anything, &listeners.GetManyRequest{FileID: fileID}).Return(listenersList, nil)
+			mockContent.On("GetMany", mock.Anything, &file_contents.GetManyRequest{FileID: fileID, LoadBlobs: true}).Return(fileContents, nil, nil)
 		}
 	}
 
 	tests := []struct {
 		name              string
-		req               *CallbackRequest
+		fileID            string
 		file              *files.File
 		fileError         tiny_errors.ErrorHandler
 		fileContents      []*file_contents.FileContent
 		fileContentsError tiny_errors.ErrorHandler
-		listeners         []*listeners.Listener
-		listenersError    tiny_errors.ErrorHandler
 		getExpectedData   func(*files.File, []*file_contents.FileContent) []byte
 		expectedError     bool
 	}{
 		{
-			name: "Successful preparation",
-			req:  &CallbackRequest{FileID: "file1"},
-			file: &files.File{ID: "file1", Name: "test.txt"},
+			name:   "Successful preparation",
+			fileID: "file1",
+			file:   &files.File{ID: "file1", Name: "test.txt"},
 			fileContents: []*file_contents.FileContent{
 				{FileID: "file1", Content: "test content"},
 			},
-			listeners: []*listeners.Listener{
-				{FileID: "file1", CallbackEndpoint: "http://example.com/callback1"},
-			},
 			getExpectedData: func(file *files.File, fileContents []*file_contents.FileContent) []byte {
 				fileData := &FileData{
+					Event:       webhook.EVENT_CONTENT_UPDATED,
 					File:        *file,
 					FileContent: fileContents,
 				}
@@ -86,51 +83,369 @@ func TestPrepareListenersData(t *testing.T) {
 		},
 		{
 			name:          "Error getting file",
-			req:           &CallbackRequest{FileID: "nonexistent"},
+			fileID:        "nonexistent",
 			fileError:     tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("file not found")),
 			expectedError: true,
 		},
 		{
 			name:              "Error getting file contents",
-			req:               &CallbackRequest{FileID: "file2"},
+			fileID:            "file2",
 			file:              &files.File{ID: "file2", Name: "error.txt"},
 			fileContentsError: tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("content not found")),
 			expectedError:     true,
 		},
-		{
-			name: "Error getting listeners",
-			req:  &CallbackRequest{FileID: "file3"},
-			file: &files.File{ID: "file3", Name: "listeners.txt"},
-			fileContents: []*file_contents.FileContent{
-				{FileID: "file3", Content: "content"},
-			},
-			listenersError: tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("listeners not found")),
-			expectedError:  true,
-		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Set up expectations
-			setupMocks(tt.req.FileID, tt.file, tt.fileError, tt.fileContents, tt.fileContentsError, tt.listeners, tt.listenersError)
+			setupMocks(tt.fileID, tt.file, tt.fileError, tt.fileContents, tt.fileContentsError)
 
 			// Call the function
-			listeners, data, err := service.prepareListenersData(context.Background(), tt.req)
+			data, err := service.prepareFileData(context.Background(), &listeners.Listener{}, &Event{FileID: tt.fileID, Type: webhook.EVENT_CONTENT_UPDATED})
 
 			// Assert expectations
 			if tt.expectedError {
 				assert.Error(t, err)
-				assert.Nil(t, listeners)
 				assert.Nil(t, data)
 			} else {
 				assert.NoError(t, err)
-				assert.NotNil(t, listeners)
 				assert.Equal(t, tt.getExpectedData(tt.file, tt.fileContents), data)
 			}
 
 			mockFile.AssertExpectations(t)
 			mockContent.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPrepareFileData_DeletedFile(t *testing.T) {
+	mockFile := files.NewMock()
+	mockContent := file_contents.NewMock()
+	service := New(logger.NewMock(), NewMock(), mockFile, listeners.NewMock(), mockContent, nil, "")
+
+	data, err := service.prepareFileData(context.Background(), &listeners.Listener{}, &Event{
+		FileID:  "file1",
+		Type:    webhook.EVENT_FILE_DELETED,
+		Details: &EventDetails{Name: utils.MakePointer("test.txt")},
+	})
+	assert.NoError(t, err)
+	expected, _ := json.Marshal(&FileData{
+		Event:       webhook.EVENT_FILE_DELETED,
+		Details:     &EventDetails{Name: utils.MakePointer("test.txt")},
+		File:        files.File{ID: "file1", Name: "test.txt"},
+		FileContent: []*file_contents.FileContent{},
+	})
+	assert.Equal(t, expected, data)
+	mockFile.AssertExpectations(t)
+	mockContent.AssertExpectations(t)
+}
+
+func TestPrepareFileData_PayloadModes(t *testing.T) {
+	file := &files.File{ID: "file1", Name: "test.yaml"}
+	version := &file_contents.FileContent{ID: "content1", FileID: "file1", Version: "v1.0.0", Content: "a: 2"}
+	changes := []*formats.Change{{Key: "a", Type: formats.CHANGE_CHANGED, Old: 1.0, New: 2.0}}
+	event := &Event{
+		FileID:  "file1",
+		Type:    webhook.EVENT_CONTENT_UPDATED,
+		Details: &EventDetails{ContentID: utils.MakePointer("content1"), Version: utils.MakePointer("v1.0.0"), Changes: changes},
+	}
+	// details are sent without changes
+	details := &EventDetails{ContentID: utils.MakePointer("content1"), Version: utils.MakePointer("v1.0.0")}
+
+	tests := []struct {
+		name      string
+		mode      string
+		publicURL string
+		mockSetup func(mockContent *file_contents.MockClient)
+		expected  any
+	}{
+		{
+			name: "full",
+			mode: listeners.PAYLOAD_FULL,
+			mockSetup: func(mockContent *file_contents.MockClient) {
+				mockContent.On("GetMany", mock.Anything, &file_contents.GetManyRequest{FileID: "file1", LoadBlobs: true}).Return([]*file_contents.FileContent{version}, nil, nil)
+			},
+			expected: &FileData{Event: webhook.EVENT_CONTENT_UPDATED, Details: details, File: *file, FileContent: []*file_contents.FileContent{version}},
+		},
+		{
+			name: "version",
+			mode: listeners.PAYLOAD_VERSION,
+			mockSetup: func(mockContent *file_contents.MockClient) {
+				mockContent.On("GetMany", mock.Anything, &file_contents.GetManyRequest{FileID: "file1", Version: utils.MakePointer("v1.0.0"), LoadBlobs: true}).Return([]*file_contents.FileContent{version}, nil, nil)
+			},
+			expected: &FileData{Event: webhook.EVENT_CONTENT_UPDATED, Details: details, File: *file, FileContent: []*file_contents.FileContent{version}},
+		},
+		{
+			name:     "diff",
+			mode:     listeners.PAYLOAD_DIFF,
+			expected: &ChangeData{Event: webhook.EVENT_CONTENT_UPDATED, Details: details, FileID: "file1", Name: "test.yaml", Changes: changes},
+		},
+		{
+			name:      "notification",
+			mode:      listeners.PAYLOAD_NOTIFICATION,
+			publicURL: "https://config.example.com/",
+			expected: &ChangeData{Event: webhook.EVENT_CONTENT_UPDATED, Details: details, FileID: "file1", Name: "test.yaml",
+				URL: "https://config.example.com/files/file1/contents?version=v1.0.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFile := files.NewMock()
+			mockContent := file_contents.NewMock()
+			mockFile.On("Get", mock.Anything, &files.GetRequest{ID: "file1"}).Return(file, nil)
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockContent)
+			}
+			service := New(logger.NewMock(), NewMock(), mockFile, listeners.NewMock(), mockContent, nil, tt.publicURL)
+
+			data, err := service.prepareFileData(context.Background(), &listeners.Listener{PayloadMode: tt.mode}, event)
+			assert.NoError(t, err)
+			expected, _ := json.Marshal(tt.expected)
+			assert.Equal(t, expected, data)
+			mockFile.AssertExpectations(t)
+			mockContent.AssertExpectations(t)
+		})
+	}
+}
+
+func TestProcess(t *testing.T) {
+	// retry policy without jitter, so delays are known
+	policy := listeners.RetryPolicy{MaxAttempts: 3, BaseDelayMs: 1000, Jitter: utils.MakePointer(0.0)}
+	listener := &listeners.Listener{ID: "listener1", FileID: "file1", CallbackEndpoint: "http://example.com/1", RetryPolicy: policy, Enabled: true}
+	disabledListener := &listeners.Listener{ID: "listener2", FileID: "file1", CallbackEndpoint: "http://example.com/2", RetryPolicy: policy}
+	pinnedListener := &listeners.Listener{ID: "listener3", FileID: "file1", CallbackEndpoint: "http://example.com/3", Enabled: true,
+		VersionFilter: listeners.VersionFilter{Range: ">=2.0.0"}}
+	taggedListener := &listeners.Listener{ID: "listener4", FileID: "file1", CallbackEndpoint: "http://example.com/4", Enabled: true,
+		VersionFilter: listeners.VersionFilter{Tag: "release-*"}}
+	data, _ := json.Marshal(&FileData{File: files.File{ID: "file1"}, FileContent: []*file_contents.FileContent{}})
+
+	mockFileData := func(mockFile *files.MockClient, mockContent *file_contents.MockClient) {
+		mockFile.On("Get", mock.Anything, &files.GetRequest{ID: "file1"}).Return(&files.File{ID: "file1"}, nil)
+		mockContent.On("GetMany", mock.Anything, &file_contents.GetManyRequest{FileID: "file1", LoadBlobs: true}).Return([]*file_contents.FileContent{}, nil, nil)
+	}
+
+	tests := []struct {
+		name      string
+		event     *Event
+		mockSetup func(mockFile *files.MockClient, mockContent *file_contents.MockClient, mockListeners *listeners.MockClient, mockOutbox *MockOutbox)
+		// response and responseErr are returned by the endpoint of the listener if the request is expected
+		response    *http.Response
+		responseErr error
+	}{
+		{
+			name:  "event of the file is split",
+			event: &Event{ID: 1, FileID: "file1", Attempts: 1},
+			mockSetup: func(mockFile *files.MockClient, mockContent *file_contents.MockClient, mockListeners *listeners.MockClient, mockOutbox *MockOutbox) {
+				mockOutbox.On("Split", mock.Anything, int64(1)).Return(nil)
+			},
+		},
+		{
+			name:  "delivered event is removed",
+			event: &Event{ID: 2, FileID: "file1", ListenerID: utils.MakePointer("listener1"), EventID: utils.MakePointer(int64(1)), Attempts: 1},
+			mockSetup: func(mockFile *files.MockClient, mockContent *file_contents.MockClient, mockListeners *listeners.MockClient, mockOutbox *MockOutbox) {
+				mockListeners.On("Get", mock.Anything, &listeners.GetRequest{ID: "listener1"}).Return(listener, nil)
+				mockFileData(mockFile, mockContent)
+				mockOutbox.On("Done", mock.Anything, int64(2)).Return(nil)
+				mockListeners.On("RecordSuccess", mock.Anything, "listener1").Return(nil)
+			},
+			response: &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(&bytes.Buffer{})},
+		},
+		{
+			name:  "event of removed listener is removed",
+			event: &Event{ID: 3, FileID: "file1", ListenerID: utils.MakePointer("listener1"), Attempts: 1},
+			mockSetup: func(mockFile *files.MockClient, mockContent *file_contents.MockClient, mockListeners *listeners.MockClient, mockOutbox *MockOutbox) {
+				mockListeners.On("Get", mock.Anything, &listeners.GetRequest{ID: "listener1"}).Return(nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound))
+				mockOutbox.On("Done", mock.Anything, int64(3)).Return(nil)
+			},
+		},
+		{
+			name:  "event of removed file is removed",
+			event: &Event{ID: 4, FileID: "file2", ListenerID: utils.MakePointer("listener1"), Attempts: 1},
+			mockSetup: func(mockFile *files.MockClient, mockContent *file_contents.MockClient, mockListeners *listeners.MockClient, mockOutbox *MockOutbox) {
+				mockListeners.On("Get", mock.Anything, &listeners.GetRequest{ID: "listener1"}).Return(listener, nil)
+				mockFile.On("Get", mock.Anything, &files.GetRequest{ID: "file2"}).Return(nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("file not found")))
+				mockOutbox.On("Done", mock.Anything, int64(4)).Return(nil)
+			},
+		},
+		{
+			name:  "failed event is retried by the policy of the listener",
+			event: &Event{ID: 5, FileID: "file1", ListenerID: utils.MakePointer("listener1"), Attempts: 2},
+			mockSetup: func(mockFile *files.MockClient, mockContent *file_contents.MockClient, mockListeners *listeners.MockClient, mockOutbox *MockOutbox) {
+				mockListeners.On("Get", mock.Anything, &listeners.GetRequest{ID: "listener1"}).Return(listener, nil)
+				mockFileData(mockFile, mockContent)
+				mockOutbox.On("Retry", mock.Anything, int64(5), 2*time.Second, "network error").Return(nil)
+				mockListeners.On("RecordFailure", mock.Anything, "listener1", "network error").Return(true, nil)
+			},
+			responseErr: fmt.Errorf("network error"),
+		},
+		{
+			name:  "event is moved to dead letters after the last attempt",
+			event: &Event{ID: 6, FileID: "file1", ListenerID: utils.MakePointer("listener1"), Attempts: 3},
+			mockSetup: func(mockFile *files.MockClient, mockContent *file_contents.MockClient, mockListeners *listeners.MockClient, mockOutbox *MockOutbox) {
+				mockListeners.On("Get", mock.Anything, &listeners.GetRequest{ID: "listener1"}).Return(listener, nil)
+				mockFileData(mockFile, mockContent)
+				mockOutbox.On("Dead", mock.Anything, int64(6), "server error from http://example.com/1: 502").Return(nil)
+				mockListeners.On("RecordFailure", mock.Anything, "listener1", "server error from http://example.com/1: 502").Return(true, nil)
+			},
+			response: &http.Response{StatusCode: http.StatusBadGateway, Body: io.NopCloser(&bytes.Buffer{})},
+		},
+		{
+			name:  "permanent failure is moved to dead letters",
+			event: &Event{ID: 7, FileID: "file1", ListenerID: utils.MakePointer("listener1"), Attempts: 1},
+			mockSetup: func(mockFile *files.MockClient, mockContent *file_contents.MockClient, mockListeners *listeners.MockClient, mockOutbox *MockOutbox) {
+				mockListeners.On("Get", mock.Anything, &listeners.GetRequest{ID: "listener1"}).Return(listener, nil)
+				mockFileData(mockFile, mockContent)
+				mockOutbox.On("Dead", mock.Anything, int64(7), "client error from http://example.com/1: 410").Return(nil)
+				mockListeners.On("RecordFailure", mock.Anything, "listener1", "client error from http://example.com/1: 410").Return(true, nil)
+			},
+			response: &http.Response{StatusCode: http.StatusGone, Body: io.NopCloser(&bytes.Buffer{})},
+		},
+		{
+			name:  "event is retried after the delay requested by the listener",
+			event: &Event{ID: 8, FileID: "file1", ListenerID: utils.MakePointer("listener1"), Attempts: 1},
+			mockSetup: func(mockFile *files.MockClient, mockContent *file_contents.MockClient, mockListeners *listeners.MockClient, mockOutbox *MockOutbox) {
+				mockListeners.On("Get", mock.Anything, &listeners.GetRequest{ID: "listener1"}).Return(listener, nil)
+				mockFileData(mockFile, mockContent)
+				mockOutbox.On("Retry", mock.Anything, int64(8), 90*time.Second, "listener http://example.com/1 asked to retry later: 429").Return(nil)
+				mockListeners.On("RecordFailure", mock.Anything, "listener1", "listener http://example.com/1 asked to retry later: 429").Return(true, nil)
+			},
+			response: &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"90"}}, Body: io.NopCloser(&bytes.Buffer{})},
+		},
+		{
+			name:  "event is moved to dead letters when the listener is disabled by the failure",
+			event: &Event{ID: 9, FileID: "file1", ListenerID: utils.MakePointer("listener1"), Attempts: 1},
+			mockSetup: func(mockFile *files.MockClient, mockContent *file_contents.MockClient, mockListeners *listeners.MockClient, mockOutbox *MockOutbox) {
+				mockListeners.On("Get", mock.Anything, &listeners.GetRequest{ID: "listener1"}).Return(listener, nil)
+				mockFileData(mockFile, mockContent)
+				mockListeners.On("RecordFailure", mock.Anything, "listener1", "network error").Return(false, nil)
+				mockOutbox.On("Dead", mock.Anything, int64(9), "network error").Return(nil)
+			},
+			responseErr: fmt.Errorf("network error"),
+		},
+		{
+			name:  "event of disabled listener is moved to dead letters without request",
+			event: &Event{ID: 10, FileID: "file1", ListenerID: utils.MakePointer("listener2"), Attempts: 1},
+			mockSetup: func(mockFile *files.MockClient, mockContent *file_contents.MockClient, mockListeners *listeners.MockClient, mockOutbox *MockOutbox) {
+				mockListeners.On("Get", mock.Anything, &listeners.GetRequest{ID: "listener2"}).Return(disabledListener, nil)
+				mockOutbox.On("Dead", mock.Anything, int64(10), "listener listener2 is disabled").Return(nil)
+			},
+		},
+		{
+			name: "event of version not matching the listener is removed without request",
+			event: &Event{ID: 11, FileID: "file1", ListenerID: utils.MakePointer("listener3"), Attempts: 1, Type: webhook.EVENT_CONTENT_UPDATED,
+				Details: &EventDetails{Version: utils.MakePointer("v1.5.0"), PreviousVersion: utils.MakePointer("v1.4.0")}},
+			mockSetup: func(mockFile *files.MockClient, mockContent *file_contents.MockClient, mockListeners *listeners.MockClient, mockOutbox *MockOutbox) {
+				mockListeners.On("Get", mock.Anything, &listeners.GetRequest{ID: "listener3"}).Return(pinnedListener, nil)
+				mockOutbox.On("Done", mock.Anything, int64(11)).Return(nil)
+			},
+		},
+		{
+			name: "synced version not matching the tag of the listener is removed without request",
+			event: &Event{ID: 12, FileID: "file1", ListenerID: utils.MakePointer("listener4"), Attempts: 1, Type: webhook.EVENT_CONTENT_CREATED,
+				Details: &EventDetails{ContentID: utils.MakePointer("content1"), Version: utils.MakePointer("3f2a1b4")}},
+			mockSetup: func(mockFile *files.MockClient, mockContent *file_contents.MockClient, mockListeners *listeners.MockClient, mockOutbox *MockOutbox) {
+				mockListeners.On("Get", mock.Anything, &listeners.GetRequest{ID: "listener4"}).Return(taggedListener, nil)
+				mockOutbox.On("Done", mock.Anything, int64(12)).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFile := files.NewMock()
+			mockContent := file_contents.NewMock()
+			mockListeners := listeners.NewMock()
+			mockOutbox := NewMock()
+			mockHttpClient := client.NewMock()
+			mockDeliveries := deliveries.NewMock()
+			mockDeliveries.On("Create", mock.Anything, mock.Anything).Return(&deliveries.Delivery{}, nil)
+			tt.mockSetup(mockFile, mockContent, mockListeners, mockOutbox)
+			if tt.response != nil || tt.responseErr != nil {
+				mockHttpClient.ExpectPost(listener.CallbackEndpoint, data, tt.responseErr, tt.response, nil)
+			}
+
+			rc := NewRequestsController(logger.NewMock(), mockHttpClient, mockDeliveries)
+			service := New(logger.NewMock(), mockOutbox, mockFile, mockListeners, mockContent, rc, "").(*callbackService)
+			service.process(context.Background(), tt.event)
+
+			assert.NoError(t, mockHttpClient.AllExpectationsDone())
+			mockFile.AssertExpectations(t)
+			mockContent.AssertExpectations(t)
 			mockListeners.AssertExpectations(t)
+			mockOutbox.AssertExpectations(t)
 		})
 	}
 }
+
+func TestProcess_Redelivery(t *testing.T) {
+	mockFile := files.NewMock()
+	mockContent := file_contents.NewMock()
+	mockListeners := listeners.NewMock()
+	mockOutbox := NewMock()
+	mockHttpClient := client.NewMock()
+	mockDeliveries := deliveries.NewMock()
+
+	mockFile.On("Get", mock.Anything, &files.GetRequest{ID: "file1"}).Return(&files.File{ID: "file1"}, nil)
+	mockContent.On("GetMany", mock.Anything, &file_contents.GetManyRequest{FileID: "file1", LoadBlobs: true}).Return([]*file_contents.FileContent{}, nil, nil)
+	mockListeners.On("Get", mock.Anything, &listeners.GetRequest{ID: "listener2"}).Return(
+		&listeners.Listener{ID: "listener2", FileID: "file1", CallbackEndpoint: "http://example.com/2", Enabled: true}, nil,
+	)
+	mockListeners.On("RecordSuccess", mock.Anything, "listener2").Return(nil)
+	data, _ := json.Marshal(&FileData{File: files.File{ID: "file1"}, FileContent: []*file_contents.FileContent{}})
+	mockHttpClient.ExpectPost("http://example.com/2", data, nil, &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(&bytes.Buffer{}),
+	}, nil)
+	mockDeliveries.On("Create", mock.Anything, mock.MatchedBy(func(req *deliveries.CreateRequest) bool {
+		return req.ListenerID == "listener2" && req.EventID == 5
+	})).Return(&deliveries.Delivery{}, nil)
+	mockOutbox.On("Done", mock.Anything, int64(7)).Return(nil)
+
+	rc := NewRequestsController(logger.NewMock(), mockHttpClient, mockDeliveries)
+	service := New(logger.NewMock(), mockOutbox, mockFile, mockListeners, mockContent, rc, "").(*callbackService)
+	service.process(context.Background(), &Event{
+		ID:         7,
+		FileID:     "file1",
+		ListenerID: utils.MakePointer("listener2"),
+		EventID:    utils.MakePointer(int64(5)),
+		Attempts:   1,
+	})
+
+	assert.NoError(t, mockHttpClient.AllExpectationsDone())
+	mockDeliveries.AssertExpectations(t)
+	mockOutbox.AssertExpectations(t)
+}
+
+func TestGroupByFile(t *testing.T) {
+	created := &EventDetails{ContentID: utils.MakePointer("content1"), Version: utils.MakePointer("v1.0.0")}
+	deleted := &EventDetails{ContentID: utils.MakePointer("content2"), Version: utils.MakePointer("v0.9.0")}
+	renamed := &EventDetails{Name: utils.MakePointer("app.yaml"), PreviousName: utils.MakePointer("app.yml")}
+
+	grouped := GroupByFile([]*CallbackRequest{
+		{FileID: "file1", Event: webhook.EVENT_CONTENT_CREATED, Details: created},
+		{FileID: "file2", Event: webhook.EVENT_FILE_RENAMED, Details: renamed},
+		{FileID: "file1", Event: webhook.EVENT_CONTENT_DELETED, Details: deleted},
+	})
+
+	assert.Equal(t, []*CallbackRequest{
+		{
+			FileID: "file1",
+			Event:  webhook.EVENT_CONTENT_DELETED,
+			Details: &EventDetails{
+				ContentID: deleted.ContentID,
+				Version:   deleted.Version,
+				Events: []*EventChange{
+					{Event: webhook.EVENT_CONTENT_CREATED, Details: created},
+					{Event: webhook.EVENT_CONTENT_DELETED, Details: deleted},
+				},
+			},
+		},
+		{FileID: "file2", Event: webhook.EVENT_FILE_RENAMED, Details: renamed},
+	}, grouped)
+
+	event := &Event{FileID: "file1", Type: grouped[0].Event, Details: grouped[0].Details}
+	assert.Equal(t, []string{"v0.9.0", "v1.0.0"}, event.Versions())
+	assert.Nil(t, GroupByFile(nil))
+}
@@ -0,0 +1,43 @@
+package callback
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Moranilt/http-utils/client"
+)
+
+// HTTPClient posts callback requests to listeners.
+type HTTPClient interface {
+	Post(ctx context.Context, url string, body []byte, headers client.Headers) (*http.Response, error)
+}
+
+type httpClient struct {
+	client *http.Client
+}
+
+// NewHTTPClient creates an HTTPClient which returns redirects as they are, so they are followed only by
+// the RequestsController with the settings of the listener.
+func NewHTTPClient(timeout time.Duration) HTTPClient {
+	return &httpClient{
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (c *httpClient) Post(ctx context.Context, url string, body []byte, headers client.Headers) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return c.client.Do(req)
+}
@@ -0,0 +1,119 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Moranilt/config-keeper/pkg/deliveries"
+	"github.com/Moranilt/config-keeper/pkg/listeners"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
+	"github.com/Moranilt/http-utils/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHTTPClient_Redirects(t *testing.T) {
+	var targetMethod, targetBody string
+	var targetCalls int
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetCalls++
+		targetMethod = r.Method
+		body, _ := io.ReadAll(r.Body)
+		targetBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL+"/hook", http.StatusFound)
+	}))
+	defer origin.Close()
+
+	tests := []struct {
+		name            string
+		followRedirects bool
+		expectedErrMsg  string
+		expectedCalls   int
+	}{
+		{
+			name:            "redirect is not followed by the client",
+			followRedirects: false,
+			expectedErrMsg:  "redirect from " + origin.URL + " is not followed: 302",
+		},
+		{
+			name:            "redirect is followed with the same method and body",
+			followRedirects: true,
+			expectedCalls:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetCalls, targetMethod, targetBody = 0, "", ""
+			mockDeliveries := deliveries.NewMock()
+			mockDeliveries.On("Create", mock.Anything, mock.Anything).Return(&deliveries.Delivery{}, nil)
+			service := NewRequestsController(logger.NewMock(), NewHTTPClient(time.Second), mockDeliveries)
+
+			listener := &listeners.Listener{ID: "listener_id", CallbackEndpoint: origin.URL, FollowRedirects: tt.followRedirects}
+			err := service.Send(context.Background(), listener, &Event{ID: 1, Type: webhook.EVENT_CONTENT_UPDATED}, []byte(`{"key":"value"}`))
+
+			assert.Equal(t, tt.expectedCalls, targetCalls)
+			if tt.expectedErrMsg != "" {
+				assert.EqualError(t, err, tt.expectedErrMsg)
+				var deliveryErr *DeliveryError
+				assert.True(t, errors.As(err, &deliveryErr))
+				assert.True(t, deliveryErr.Permanent)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, http.MethodPost, targetMethod)
+			assert.Equal(t, `{"key":"value"}`, targetBody)
+		})
+	}
+}
+
+func TestHTTPClient_CrossHostRedirect(t *testing.T) {
+	var targetHeader http.Header
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetHeader = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	var originHeader http.Header
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		originHeader = r.Header.Clone()
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer origin.Close()
+
+	mockDeliveries := deliveries.NewMock()
+	mockDeliveries.On("Create", mock.Anything, mock.Anything).Return(&deliveries.Delivery{}, nil)
+	service := NewRequestsController(logger.NewMock(), NewHTTPClient(time.Second), mockDeliveries)
+
+	listener := &listeners.Listener{
+		ID:               "listener_id",
+		CallbackEndpoint: origin.URL,
+		FollowRedirects:  true,
+		Secret:           "secret",
+		Settings: &listeners.RequestSettings{
+			Headers: map[string]string{"x-api-key": "key", "authorization": "Token custom"},
+		},
+	}
+	err := service.Send(context.Background(), listener, &Event{ID: 1, Type: webhook.EVENT_CONTENT_UPDATED}, []byte(`{"key":"value"}`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "key", originHeader.Get("X-Api-Key"))
+	assert.Equal(t, "Token custom", originHeader.Get("Authorization"))
+	assert.NotEmpty(t, originHeader.Get(webhook.HEADER_SIGNATURE))
+
+	for _, name := range []string{"X-Api-Key", "Authorization", webhook.HEADER_SIGNATURE, webhook.HEADER_TIMESTAMP} {
+		assert.Empty(t, targetHeader.Values(name), name)
+	}
+	assert.Equal(t, "application/json", targetHeader.Get("Content-Type"))
+}
@@ -1,15 +1,260 @@
 package callback
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
 	"github.com/Moranilt/config-keeper/pkg/file_contents"
 	"github.com/Moranilt/config-keeper/pkg/files"
+	"github.com/Moranilt/config-keeper/pkg/formats"
+)
+
+const (
+	QUERY_SEND_EVENT = "INSERT INTO callback_outbox (file_id, listener_id, event_id, event_type, details) VALUES ($1, $2, $3, $4, $5)"
+	// QUERY_CLAIM_EVENTS postpones claimed events by the lease, so events of a crashed instance are delivered again.
+	QUERY_CLAIM_EVENTS = `UPDATE callback_outbox SET attempts = attempts + 1, available_at = now() + make_interval(secs => $2)
+	WHERE id IN (
+		SELECT id FROM callback_outbox WHERE available_at <= now() ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, file_id, listener_id, event_id, event_type, details, attempts`
+	QUERY_DONE_EVENT  = "DELETE FROM callback_outbox WHERE id = $1"
+	QUERY_RETRY_EVENT = "UPDATE callback_outbox SET available_at = now() + make_interval(secs => $2), last_error = $3 WHERE id = $1"
+	// QUERY_SPLIT_EVENT replaces the event of the file with events of its listeners which keep ID of the original event.
+	// Events of disabled listeners are split too, so they are moved to dead letters and can be replayed.
+	// Listeners without event types are subscribed to all events, merged events match types of all their changes.
+	QUERY_SPLIT_EVENT = `WITH event AS (DELETE FROM callback_outbox WHERE id = $1 RETURNING id, file_id, event_type, details)
+	INSERT INTO callback_outbox (file_id, listener_id, event_id, event_type, details)
+	SELECT listeners.file_id, listeners.id, event.id, event.event_type, event.details FROM event
+	JOIN listeners ON listeners.file_id = event.file_id
+	AND (
+		cardinality(listeners.event_types) = 0
+		OR event.event_type = ANY(listeners.event_types)
+		OR EXISTS(
+			SELECT 1 FROM jsonb_array_elements(COALESCE(event.details->'events', '[]'::jsonb)) AS change
+			WHERE change->>'event' = ANY(listeners.event_types)
+		)
+	)`
+	QUERY_DEAD_EVENT = `WITH event AS (DELETE FROM callback_outbox WHERE id = $1
+	RETURNING id, file_id, listener_id, COALESCE(event_id, id) AS event_id, event_type, details, attempts, created_at)
+	INSERT INTO callback_dead_letters (id, file_id, listener_id, event_id, event_type, details, attempts, last_error, created_at)
+	SELECT id, file_id, listener_id, event_id, event_type, details, attempts, $2, created_at FROM event`
+)
+
+const (
+	// CLAIM_LIMIT is a maximum number of events claimed at once.
+	CLAIM_LIMIT = 10
+	// CLAIM_LEASE is a time after which claimed but not delivered events are claimed again.
+	CLAIM_LEASE = 5 * time.Minute
+	// POLL_INTERVAL is a time between checks of the outbox when no events are sent by this instance.
+	POLL_INTERVAL = time.Second
+
+	// EVENT_PING is a type of the synthetic event sent to test listeners.
+	EVENT_PING = "ping"
+
+	// MAX_REDIRECTS is a maximum number of redirects followed by a single callback request.
+	MAX_REDIRECTS = 5
 )
 
 type CallbackRequest struct {
 	FileID string
+	// Event is one of webhook.EVENTS, Details describe the change.
+	Event   string
+	Details *EventDetails
+	// ListenerID limits delivery to a single listener of the file. All listeners are called if it is nil.
+	ListenerID *string
+	// EventID is set when a past event is delivered again, so attempts are recorded in its delivery log.
+	EventID *int64
+}
+
+// Event is a callback request stored in the outbox. Events without ListenerID are split into events of
+// every listener of the file, which are retried independently.
+type Event struct {
+	ID         int64   `db:"id"`
+	FileID     string  `db:"file_id"`
+	ListenerID *string `db:"listener_id"`
+	// EventID is an ID of the original event if the event was split or is delivered again.
+	EventID  *int64        `db:"event_id"`
+	Type     string        `db:"event_type"`
+	Details  *EventDetails `db:"details"`
+	Attempts int           `db:"attempts"`
+}
+
+// EventDetails describe the change of the event. Only fields related to the type of the event are set.
+// They are stored as JSONB.
+type EventDetails struct {
+	ContentID *string `json:"content_id,omitempty"`
+	// Version is a version of the content after the change, PreviousVersion is set if the version was renamed.
+	Version         *string `json:"version,omitempty"`
+	PreviousVersion *string `json:"previous_version,omitempty"`
+	// Name is a name of the file after the change, PreviousName is set if the file was renamed.
+	Name         *string `json:"name,omitempty"`
+	PreviousName *string `json:"previous_name,omitempty"`
+	// FolderID is a folder of the file after it or its parent folder was moved, PreviousFolderID is set
+	// if the file itself was moved.
+	FolderID         *string `json:"folder_id,omitempty"`
+	PreviousFolderID *string `json:"previous_folder_id,omitempty"`
+	// Changes are changed values of the content if its format can be decoded. They are sent only to listeners
+	// with listeners.PAYLOAD_DIFF mode.
+	Changes []*formats.Change `json:"changes,omitempty"`
+	// Events are all changes of the file in order when several changes are sent as one event, e.g. by a batch.
+	// Other fields describe the last change then.
+	Events []*EventChange `json:"events,omitempty"`
+}
+
+// withoutChanges returns a copy of details without changed values of contents, including merged changes.
+func (d *EventDetails) withoutChanges() *EventDetails {
+	if d == nil {
+		return nil
+	}
+	details := *d
+	details.Changes = nil
+	if len(d.Events) > 0 {
+		details.Events = make([]*EventChange, 0, len(d.Events))
+		for _, change := range d.Events {
+			details.Events = append(details.Events, &EventChange{Event: change.Event, Details: change.Details.withoutChanges()})
+		}
+	}
+	return &details
+}
+
+// EventChange is a single change of the file sent in the event with other changes.
+type EventChange struct {
+	Event   string        `json:"event"`
+	Details *EventDetails `json:"details,omitempty"`
 }
 
+func (d EventDetails) Value() (driver.Value, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (d *EventDetails) Scan(src any) error {
+	switch value := src.(type) {
+	case []byte:
+		return json.Unmarshal(value, d)
+	case string:
+		return json.Unmarshal([]byte(value), d)
+	default:
+		return fmt.Errorf("unsupported event details type %T", src)
+	}
+}
+
+// Versions returns versions of contents changed by the event, it is empty for events of files.
+func (e *Event) Versions() []string {
+	var versions []string
+	if e.Details == nil {
+		return versions
+	}
+	details := []*EventDetails{e.Details}
+	for _, change := range e.Details.Events {
+		if change.Details != nil {
+			details = append(details, change.Details)
+		}
+	}
+	for _, d := range details {
+		if d.Version != nil && !slices.Contains(versions, *d.Version) {
+			versions = append(versions, *d.Version)
+		}
+		if d.PreviousVersion != nil && !slices.Contains(versions, *d.PreviousVersion) {
+			versions = append(versions, *d.PreviousVersion)
+		}
+	}
+	return versions
+}
+
+// GroupByFile merges requests of the same file into a single request in order of the first request of the file.
+// The merged request has the type and details of the last change and all changes in Events of its details.
+func GroupByFile(requests []*CallbackRequest) []*CallbackRequest {
+	changes := make(map[string][]*EventChange)
+	var grouped []*CallbackRequest
+	for _, req := range requests {
+		if _, ok := changes[req.FileID]; !ok {
+			grouped = append(grouped, &CallbackRequest{FileID: req.FileID})
+		}
+		changes[req.FileID] = append(changes[req.FileID], &EventChange{Event: req.Event, Details: req.Details})
+	}
+
+	for _, req := range grouped {
+		fileChanges := changes[req.FileID]
+		last := fileChanges[len(fileChanges)-1]
+		req.Event = last.Event
+		req.Details = last.Details
+		if len(fileChanges) == 1 {
+			continue
+		}
+
+		req.Details = &EventDetails{}
+		if last.Details != nil {
+			*req.Details = *last.Details
+		}
+		req.Details.Events = fileChanges
+	}
+	return grouped
+}
+
+// DeliveryID returns an ID of the event used in the delivery log.
+func (e *Event) DeliveryID() int64 {
+	if e.EventID != nil {
+		return *e.EventID
+	}
+	return e.ID
+}
+
+// DeliveryError is returned when the listener did not accept the event.
+type DeliveryError struct {
+	Err error
+	// Permanent errors are not retried, the event is moved to dead letters.
+	Permanent bool
+	// RetryAfter is a delay before the next attempt requested by the listener, zero if it is not requested.
+	RetryAfter time.Duration
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// PingEvent is a synthetic event sent to test the listener. It is not stored in the outbox.
+type PingEvent struct {
+	Event      string `json:"event"`
+	FileID     string `json:"file_id"`
+	ListenerID string `json:"listener_id"`
+}
+
+// PingResult is a response of the listener to the ping event.
+type PingResult struct {
+	Status     string  `json:"status"`
+	StatusCode *int    `json:"status_code"`
+	LatencyMs  int64   `json:"latency_ms"`
+	Response   *string `json:"response"`
+	Error      *string `json:"error"`
+}
+
+// FileData is a payload of events in listeners.PAYLOAD_FULL and listeners.PAYLOAD_VERSION modes.
+// Contents of deleted files are not sent.
 type FileData struct {
+	Event   string        `json:"event"`
+	Details *EventDetails `json:"details,omitempty"`
 	files.File
 	FileContent []*file_contents.FileContent `json:"file_contents"`
 }
+
+// ChangeData is a payload of events in listeners.PAYLOAD_DIFF and listeners.PAYLOAD_NOTIFICATION modes.
+// It describes the change without contents, Changes are set in the diff mode and URL of contents in the notification mode.
+type ChangeData struct {
+	Event   string            `json:"event"`
+	Details *EventDetails     `json:"details,omitempty"`
+	FileID  string            `json:"file_id"`
+	Name    string            `json:"name"`
+	Changes []*formats.Change `json:"changes,omitempty"`
+	URL     string            `json:"url,omitempty"`
+}
@@ -0,0 +1,131 @@
+package callback
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/transaction"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
+	"github.com/Moranilt/http-utils/clients/database"
+	"github.com/Moranilt/http-utils/tiny_errors"
+)
+
+type Outbox interface {
+	// Send stores the callback request in the outbox. If ctx has a transaction the request is stored in it,
+	// so listeners are called only if the change is committed and are never missed if it is.
+	// Requests with ListenerID are delivered only to this listener, other requests are delivered to listeners
+	// subscribed to the event.
+	Send(ctx context.Context, req *CallbackRequest) tiny_errors.ErrorHandler
+
+	// Claim returns up to limit events which are ready for delivery ordered by creation. Claimed events are
+	// skipped by other instances and postponed by lease, so they are claimed again if they are not delivered.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*Event, tiny_errors.ErrorHandler)
+
+	// Done removes the delivered event from the outbox.
+	Done(ctx context.Context, id int64) tiny_errors.ErrorHandler
+
+	// Retry postpones the event by delay and keeps the reason of the failure.
+	Retry(ctx context.Context, id int64, delay time.Duration, reason string) tiny_errors.ErrorHandler
+
+	// Split replaces the event of the file with events of every listener of the file subscribed to the event.
+	// Events of disabled listeners are moved to dead letters when they are processed.
+	Split(ctx context.Context, id int64) tiny_errors.ErrorHandler
+
+	// Dead moves the event which ran out of attempts to dead letters of its listener with the reason of the last failure.
+	Dead(ctx context.Context, id int64, reason string) tiny_errors.ErrorHandler
+
+	// Notify returns a channel which receives a value when events sent by this instance are committed.
+	Notify() <-chan struct{}
+}
+
+type outbox struct {
+	db     *database.Client
+	notify chan struct{}
+}
+
+// NewOutbox creates a new instance of the Outbox interface, which keeps callback requests in the database.
+func NewOutbox(db *database.Client) Outbox {
+	return &outbox{
+		db:     db,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (o *outbox) Send(ctx context.Context, req *CallbackRequest) tiny_errors.ErrorHandler {
+	if req == nil {
+		return tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	if !slices.Contains(webhook.EVENTS, req.Event) {
+		return tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("event", "should be one of "+strings.Join(webhook.EVENTS, ", ")))
+	}
+
+	_, err := transaction.From(ctx, o.db).ExecContext(ctx, QUERY_SEND_EVENT, req.FileID, req.ListenerID, req.EventID, req.Event, req.Details)
+	if err != nil {
+		return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	transaction.AfterCommit(ctx, o.wake)
+	return nil
+}
+
+func (o *outbox) Claim(ctx context.Context, limit int, lease time.Duration) ([]*Event, tiny_errors.ErrorHandler) {
+	var events []*Event
+	err := o.db.SelectContext(ctx, &events, QUERY_CLAIM_EVENTS, limit, lease.Seconds())
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+	return events, nil
+}
+
+func (o *outbox) Done(ctx context.Context, id int64) tiny_errors.ErrorHandler {
+	_, err := o.db.ExecContext(ctx, QUERY_DONE_EVENT, id)
+	if err != nil {
+		return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	return nil
+}
+
+func (o *outbox) Retry(ctx context.Context, id int64, delay time.Duration, reason string) tiny_errors.ErrorHandler {
+	_, err := o.db.ExecContext(ctx, QUERY_RETRY_EVENT, id, delay.Seconds(), reason)
+	if err != nil {
+		return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	return nil
+}
+
+func (o *outbox) Split(ctx context.Context, id int64) tiny_errors.ErrorHandler {
+	_, err := o.db.ExecContext(ctx, QUERY_SPLIT_EVENT, id)
+	if err != nil {
+		return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	return nil
+}
+
+func (o *outbox) Dead(ctx context.Context, id int64, reason string) tiny_errors.ErrorHandler {
+	_, err := o.db.ExecContext(ctx, QUERY_DEAD_EVENT, id, reason)
+	if err != nil {
+		return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	return nil
+}
+
+func (o *outbox) Notify() <-chan struct{} {
+	return o.notify
+}
+
+// wake does not block, a single pending notification is enough to check the outbox.
+func (o *outbox) wake() {
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,152 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/transaction"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/clients/database"
+	database_mock "github.com/Moranilt/http-utils/clients/database/mock"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutbox_Send(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	db := &database.Client{mockDb}
+	outbox := NewOutbox(db)
+
+	t.Run("notifies after commit", func(t *testing.T) {
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_SEND_EVENT)).WithArgs("file_id", nil, nil, webhook.EVENT_CONTENT_UPDATED, nil).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectCommit()
+
+		err := transaction.New(db).Run(context.Background(), func(ctx context.Context) tiny_errors.ErrorHandler {
+			err := outbox.Send(ctx, &CallbackRequest{FileID: "file_id", Event: webhook.EVENT_CONTENT_UPDATED})
+			assert.Empty(t, outbox.Notify())
+			return err
+		})
+		assert.Nil(t, err)
+		assert.Len(t, outbox.Notify(), 1)
+		<-outbox.Notify()
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("rolled back request is not notified", func(t *testing.T) {
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_SEND_EVENT)).WithArgs("file_id", nil, nil, webhook.EVENT_CONTENT_UPDATED, nil).WillReturnResult(sqlmock.NewResult(1, 1))
+		sqlMock.ExpectRollback()
+
+		err := transaction.New(db).Run(context.Background(), func(ctx context.Context) tiny_errors.ErrorHandler {
+			if err := outbox.Send(ctx, &CallbackRequest{FileID: "file_id", Event: webhook.EVENT_CONTENT_UPDATED}); err != nil {
+				return err
+			}
+			return tiny_errors.New(custom_errors.ERR_CODE_NotValid)
+		})
+		assert.Equal(t, custom_errors.ERR_CODE_NotValid, err.GetCode())
+		assert.Empty(t, outbox.Notify())
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("redelivery to the listener", func(t *testing.T) {
+		sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_SEND_EVENT)).WithArgs("file_id", "listener_id", int64(5), webhook.EVENT_CONTENT_UPDATED, nil).WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := outbox.Send(context.Background(), &CallbackRequest{FileID: "file_id", ListenerID: utils.MakePointer("listener_id"), EventID: utils.MakePointer(int64(5)), Event: webhook.EVENT_CONTENT_UPDATED})
+		assert.Nil(t, err)
+		<-outbox.Notify()
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_SEND_EVENT)).WithArgs("file_id", nil, nil, webhook.EVENT_CONTENT_UPDATED, nil).WillReturnError(errors.New("db error"))
+
+		err := outbox.Send(context.Background(), &CallbackRequest{FileID: "file_id", Event: webhook.EVENT_CONTENT_UPDATED})
+		assert.Equal(t, custom_errors.ERR_CODE_Database, err.GetCode())
+		assert.Equal(t, "db error", err.GetMessage())
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("event with details", func(t *testing.T) {
+		sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_SEND_EVENT)).
+			WithArgs("file_id", nil, nil, webhook.EVENT_FILE_RENAMED, `{"name":"new","previous_name":"old"}`).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := outbox.Send(context.Background(), &CallbackRequest{
+			FileID:  "file_id",
+			Event:   webhook.EVENT_FILE_RENAMED,
+			Details: &EventDetails{Name: utils.MakePointer("new"), PreviousName: utils.MakePointer("old")},
+		})
+		assert.Nil(t, err)
+		<-outbox.Notify()
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown event", func(t *testing.T) {
+		err := outbox.Send(context.Background(), &CallbackRequest{FileID: "file_id", Event: "file.created"})
+		assert.Equal(t, custom_errors.ERR_CODE_NotValid, err.GetCode())
+	})
+
+	t.Run("nil request", func(t *testing.T) {
+		err := outbox.Send(context.Background(), nil)
+		assert.Equal(t, custom_errors.ERR_CODE_BodyRequired, err.GetCode())
+	})
+}
+
+func TestOutbox_Claim(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	outbox := NewOutbox(&database.Client{mockDb})
+
+	rows := sqlmock.NewRows([]string{"id", "file_id", "listener_id", "event_id", "event_type", "details", "attempts"}).
+		AddRow(2, "second", "listener", 1, webhook.EVENT_CONTENT_DELETED, []byte(`{"content_id":"content","version":"v1"}`), 1).
+		AddRow(1, "first", nil, nil, webhook.EVENT_CONTENT_UPDATED, nil, 3)
+	sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CLAIM_EVENTS)).WithArgs(CLAIM_LIMIT, CLAIM_LEASE.Seconds()).WillReturnRows(rows)
+
+	events, err := outbox.Claim(context.Background(), CLAIM_LIMIT, CLAIM_LEASE)
+	assert.Nil(t, err)
+	assert.Equal(t, []*Event{
+		{ID: 1, FileID: "first", Type: webhook.EVENT_CONTENT_UPDATED, Attempts: 3},
+		{
+			ID: 2, FileID: "second", ListenerID: utils.MakePointer("listener"), EventID: utils.MakePointer(int64(1)),
+			Type: webhook.EVENT_CONTENT_DELETED, Details: &EventDetails{ContentID: utils.MakePointer("content"), Version: utils.MakePointer("v1")}, Attempts: 1,
+		},
+	}, events)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestOutbox_DoneAndRetry(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	outbox := NewOutbox(&database.Client{mockDb})
+
+	sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_RETRY_EVENT)).WithArgs(int64(1), float64(4), "timeout").WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_DONE_EVENT)).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.Nil(t, outbox.Retry(context.Background(), 1, 4*time.Second, "timeout"))
+	assert.Nil(t, outbox.Done(context.Background(), 1))
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestOutbox_SplitAndDead(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	outbox := NewOutbox(&database.Client{mockDb})
+
+	sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_SPLIT_EVENT)).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 2))
+	sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_DEAD_EVENT)).WithArgs(int64(2), "timeout").WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_DEAD_EVENT)).WithArgs(int64(3), "timeout").WillReturnError(errors.New("db error"))
+
+	assert.Nil(t, outbox.Split(context.Background(), 1))
+	assert.Nil(t, outbox.Dead(context.Background(), 2, "timeout"))
+	err := outbox.Dead(context.Background(), 3, "timeout")
+	assert.Equal(t, custom_errors.ERR_CODE_Database, err.GetCode())
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -2,91 +2,248 @@ package callback
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"math"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Moranilt/config-keeper/pkg/deliveries"
+	"github.com/Moranilt/config-keeper/pkg/listeners"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
 	"github.com/Moranilt/http-utils/client"
 	"github.com/Moranilt/http-utils/logger"
 )
 
-const (
-	MAX_RETRIES     = 3
-	BASE_DELAY      = 100 * time.Millisecond
-	MAX_DELAY       = 5 * time.Second
-	REQUEST_TIMEOUT = 10 * time.Second
-)
-
 type RequestsController interface {
-	// SendRequestWithRetry sends the given data to the specified endpoint, retrying up to MAX_RETRIES times if the request fails.
-	//
-	// It will use an exponential backoff strategy to delay between retries, with a minimum delay of BASE_DELAY and a maximum delay of MAX_DELAY.
-	//
-	// If the context is canceled during a retry, the function will return the context's error.
+	// Send makes a single attempt to send the given data of the event to the endpoint of the listener.
+	// The request is limited by the timeout of the retry policy of the listener, retries are scheduled by the outbox.
+	// Only 2xx responses are successful. Responses of the listener are returned as DeliveryError, which tells
+	// if the failure is permanent and when the listener asked to retry.
 	//
-	// If the maximum number of retries is reached, the function will return an error indicating that the maximum retries were exceeded.
-	SendRequestWithRetry(ctx context.Context, endpoint string, data []byte) error
+	// Every attempt is recorded in the delivery log of the listener. Payloads are signed with active secrets of the listener,
+	// so receivers can verify them with the webhook package.
+	Send(ctx context.Context, listener *listeners.Listener, event *Event, data []byte) error
+
+	// Ping sends the ping event to the listener once and returns its response. Failed requests are not retried
+	// and are returned in the result. The attempt is not recorded in the delivery log.
+	Ping(ctx context.Context, listener *listeners.Listener) (*PingResult, error)
 }
 
 type requestsController struct {
 	log        logger.Logger
-	httpClient client.Client
+	httpClient HTTPClient
+	deliveries deliveries.Client
 }
 
 func NewRequestsController(
 	log logger.Logger,
-	httpClient client.Client,
+	httpClient HTTPClient,
+	deliveries deliveries.Client,
 ) RequestsController {
 	return &requestsController{
 		log:        log,
 		httpClient: httpClient,
+		deliveries: deliveries,
 	}
 }
 
-func (s *requestsController) SendRequestWithRetry(ctx context.Context, endpoint string, data []byte) error {
-	for attempt := 0; attempt < MAX_RETRIES; attempt++ {
-		err := s.sendRequest(ctx, endpoint, data)
-		if err == nil {
-			return nil
-		}
+func (s *requestsController) Send(ctx context.Context, listener *listeners.Listener, event *Event, data []byte) error {
+	delivery := &deliveries.CreateRequest{
+		ListenerID: listener.ID,
+		EventID:    event.DeliveryID(),
+		EventType:  event.Type,
+	}
+	// timed out attempts are recorded too
+	defer s.record(context.WithoutCancel(ctx), delivery)
 
-		delay := s.calculateBackoff(attempt, BASE_DELAY, MAX_DELAY)
+	return s.post(ctx, listener, data, delivery)
+}
 
-		select {
-		case <-time.After(delay):
-			s.log.Debugf("retrying request to %s in %s", endpoint, delay)
-			continue
-		case <-ctx.Done():
-			return ctx.Err()
-		}
+func (s *requestsController) Ping(ctx context.Context, listener *listeners.Listener) (*PingResult, error) {
+	data, err := json.Marshal(&PingEvent{
+		Event:      EVENT_PING,
+		FileID:     listener.FileID,
+		ListenerID: listener.ID,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return fmt.Errorf("max retries reached for endpoint %s", endpoint)
+	delivery := &deliveries.CreateRequest{
+		ListenerID: listener.ID,
+	}
+	s.post(ctx, listener, data, delivery)
+
+	return &PingResult{
+		Status:     delivery.Status,
+		StatusCode: delivery.StatusCode,
+		LatencyMs:  delivery.Latency.Milliseconds(),
+		Response:   delivery.Response,
+		Error:      delivery.Error,
+	}, nil
 }
 
-func (s *requestsController) sendRequest(ctx context.Context, endpoint string, data []byte) error {
-	ctx, cancel := context.WithTimeout(ctx, REQUEST_TIMEOUT)
+// post sends signed data with custom headers of the listener to its endpoint and fills the delivery with the result of the attempt.
+// Returned errors are DeliveryError, except errors of the request which are always retried.
+func (s *requestsController) post(ctx context.Context, listener *listeners.Listener, data []byte, delivery *deliveries.CreateRequest) error {
+	endpoint := listener.CallbackEndpoint
+	delivery.Status = deliveries.STATUS_SUCCESS
+
+	ctx, cancel := context.WithTimeout(ctx, listener.RetryPolicy.Timeout())
 	defer cancel()
 
-	resp, err := s.httpClient.Post(ctx, endpoint, data, client.NewHeaders(map[string]string{
-		"Content-Type": "application/json",
-	}))
+	// names are canonical, so custom headers do not duplicate headers set by the service
+	headers := make(map[string]string)
+	for key, value := range listener.RequestHeaders() {
+		headers[http.CanonicalHeaderKey(key)] = value
+	}
+	for key, value := range webhook.Headers(time.Now(), data, listener.Secrets()...) {
+		headers[http.CanonicalHeaderKey(key)] = value
+	}
+	headers["Content-Type"] = "application/json"
+
+	start := time.Now()
+	resp, err := s.send(ctx, listener, data, headers)
+	delivery.Latency = time.Since(start)
 	if err != nil {
 		s.log.Errorf("error sending callback to %s: %s", endpoint, err)
+		delivery.Fail(err)
 		return err
 	}
-	if resp.StatusCode >= 500 {
-		s.log.Errorf("server error from %s: %d", endpoint, resp.StatusCode)
-		return fmt.Errorf("server error from %s: %d", endpoint, resp.StatusCode)
+	delivery.StatusCode = &resp.StatusCode
+	delivery.Response = readSnippet(resp.Body)
+
+	if err := classifyResponse(endpoint, resp, time.Now()); err != nil {
+		s.log.Errorf("callback to %s failed: %s", endpoint, err)
+		delivery.Fail(err)
+		return err
 	}
 	s.log.Infof("Callback sent to %s with status code %d", endpoint, resp.StatusCode)
 	return nil
 }
 
-func (s *requestsController) calculateBackoff(attempt int, baseDelay, maxDelay time.Duration) time.Duration {
-	delay := time.Duration(math.Pow(2, float64(attempt))) * baseDelay
-	if delay > maxDelay {
-		delay = maxDelay
+// send posts data to the endpoint of the listener. If the listener allows redirects, data is posted again to
+// their location up to MAX_REDIRECTS times. Custom headers, authorization and signatures of the listener are not
+// sent to other hosts.
+func (s *requestsController) send(ctx context.Context, listener *listeners.Listener, data []byte, headers map[string]string) (*http.Response, error) {
+	endpoint := listener.CallbackEndpoint
+	for redirects := 0; ; redirects++ {
+		resp, err := s.httpClient.Post(ctx, endpoint, data, client.NewHeaders(headers))
+		if err != nil || !listener.FollowRedirects || !isRedirect(resp.StatusCode) || redirects == MAX_REDIRECTS {
+			return resp, err
+		}
+
+		location, err := redirectLocation(endpoint, resp)
+		if err != nil {
+			return resp, nil
+		}
+		if resp.Body != nil {
+			resp.Body.Close()
+		}
+		if !sameHost(endpoint, location) {
+			headers = map[string]string{"Content-Type": "application/json"}
+		}
+		s.log.Debugf("following redirect from %s to %s", endpoint, location)
+		endpoint = location
+	}
+}
+
+// classifyResponse returns nil for 2xx responses. Listeners may ask to retry later with 408 and 429 responses
+// and Retry-After header. Other 4xx responses and redirects which are not followed are permanent failures.
+func classifyResponse(endpoint string, resp *http.Response, now time.Time) error {
+	code := resp.StatusCode
+	switch {
+	case code >= 200 && code < 300:
+		return nil
+	case code == http.StatusRequestTimeout || code == http.StatusTooManyRequests:
+		return &DeliveryError{
+			Err:        fmt.Errorf("listener %s asked to retry later: %d", endpoint, code),
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After"), now),
+		}
+	case code >= 300 && code < 400:
+		return &DeliveryError{Err: fmt.Errorf("redirect from %s is not followed: %d", endpoint, code), Permanent: true}
+	case code >= 400 && code < 500:
+		return &DeliveryError{Err: fmt.Errorf("client error from %s: %d", endpoint, code), Permanent: true}
+	case code >= 500:
+		return &DeliveryError{Err: fmt.Errorf("server error from %s: %d", endpoint, code)}
+	default:
+		return &DeliveryError{Err: fmt.Errorf("unexpected status from %s: %d", endpoint, code)}
+	}
+}
+
+// retryAfter parses Retry-After header in seconds or HTTP date form. Zero is returned for empty or invalid values.
+func retryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
+
+func isRedirect(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// redirectLocation resolves Location header of the response relative to the endpoint.
+func redirectLocation(endpoint string, resp *http.Response) (string, error) {
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", fmt.Errorf("redirect from %s without location", endpoint)
+	}
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	target, err := base.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	return target.String(), nil
+}
+
+func sameHost(a, b string) bool {
+	first, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	second, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return first.Host == second.Host
+}
+
+// record keeps the attempt in the delivery log. Errors are only logged, so they do not cause retries.
+func (s *requestsController) record(ctx context.Context, delivery *deliveries.CreateRequest) {
+	if _, err := s.deliveries.Create(ctx, delivery); err != nil {
+		s.log.Errorf("error recording delivery to listener %s: %s", delivery.ListenerID, err)
+	}
+}
+
+// readSnippet reads the beginning of the body and closes it. Nil is returned for empty bodies.
+func readSnippet(body io.ReadCloser) *string {
+	if body == nil {
+		return nil
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, deliveries.RESPONSE_SNIPPET_SIZE))
+	if err != nil || len(data) == 0 {
+		return nil
 	}
-	return delay
+	snippet := strings.ToValidUTF8(string(data), "")
+	return &snippet
 }
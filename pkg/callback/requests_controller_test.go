@@ -3,33 +3,43 @@ package callback
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"testing"
 	"time"
 
+	"github.com/Moranilt/config-keeper/pkg/deliveries"
+	"github.com/Moranilt/config-keeper/pkg/listeners"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
 	"github.com/Moranilt/http-utils/client"
 	"github.com/Moranilt/http-utils/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
-func TestSendRequestWithRetry(t *testing.T) {
+func TestSend(t *testing.T) {
 	mockLog := logger.NewMock()
 	mockHttpClient := client.NewMock()
-	service := NewRequestsController(mockLog, mockHttpClient)
+	mockDeliveries := deliveries.NewMock()
+	service := NewRequestsController(mockLog, mockHttpClient, mockDeliveries)
 
 	tests := []struct {
-		name           string
-		endpoint       string
-		data           []byte
-		setupMocks     func()
-		expectedError  bool
-		expectedErrMsg string
-		cancelContext  bool
+		name            string
+		endpoint        string
+		data            []byte
+		setupMocks      func()
+		expectedError   bool
+		expectedErrMsg  string
+		followRedirects bool
+		// expectedStatus is a status of the recorded attempt
+		expectedStatus     string
+		expectedPermanent  bool
+		expectedRetryAfter time.Duration
 	}{
 		{
-			name:     "successful request on first attempt",
+			name:     "successful request",
 			endpoint: "http://example.com",
 			data:     []byte(`{"key":"value"}`),
 			setupMocks: func() {
@@ -38,10 +48,11 @@ func TestSendRequestWithRetry(t *testing.T) {
 					Body:       io.NopCloser(&bytes.Buffer{}),
 				}, nil)
 			},
-			expectedError: false,
+			expectedError:  false,
+			expectedStatus: deliveries.STATUS_SUCCESS,
 		},
 		{
-			name:     "successful request after one retry",
+			name:     "server error is not retried",
 			endpoint: "http://example.com",
 			data:     []byte(`{"key":"value"}`),
 			setupMocks: func() {
@@ -49,38 +60,76 @@ func TestSendRequestWithRetry(t *testing.T) {
 					StatusCode: http.StatusInternalServerError,
 					Body:       io.NopCloser(&bytes.Buffer{}),
 				}, nil)
+			},
+			expectedError:  true,
+			expectedErrMsg: "server error from http://example.com: 500",
+			expectedStatus: deliveries.STATUS_FAILURE,
+		},
+		{
+			name:     "client error is permanent",
+			endpoint: "http://example.com",
+			data:     []byte(`{"key":"value"}`),
+			setupMocks: func() {
 				mockHttpClient.ExpectPost("http://example.com", []byte(`{"key":"value"}`), nil, &http.Response{
-					StatusCode: http.StatusOK,
+					StatusCode: http.StatusNotFound,
 					Body:       io.NopCloser(&bytes.Buffer{}),
 				}, nil)
 			},
-			expectedError: false,
+			expectedError:     true,
+			expectedErrMsg:    "client error from http://example.com: 404",
+			expectedStatus:    deliveries.STATUS_FAILURE,
+			expectedPermanent: true,
 		},
 		{
-			name:     "max retries reached",
+			name:     "too many requests with retry after",
 			endpoint: "http://example.com",
 			data:     []byte(`{"key":"value"}`),
 			setupMocks: func() {
-				for i := 0; i < 3; i++ {
-					mockHttpClient.ExpectPost("http://example.com", []byte(`{"key":"value"}`), nil, &http.Response{
-						StatusCode: http.StatusInternalServerError,
-						Body:       io.NopCloser(&bytes.Buffer{}),
-					}, nil)
-				}
+				mockHttpClient.ExpectPost("http://example.com", []byte(`{"key":"value"}`), nil, &http.Response{
+					StatusCode: http.StatusTooManyRequests,
+					Header:     http.Header{"Retry-After": []string{"120"}},
+					Body:       io.NopCloser(&bytes.Buffer{}),
+				}, nil)
 			},
-			expectedError:  true,
-			expectedErrMsg: "max retries reached for endpoint http://example.com",
+			expectedError:      true,
+			expectedErrMsg:     "listener http://example.com asked to retry later: 429",
+			expectedStatus:     deliveries.STATUS_FAILURE,
+			expectedRetryAfter: 2 * time.Minute,
 		},
 		{
-			name:     "context cancelled",
+			name:     "redirect is not followed by default",
 			endpoint: "http://example.com",
 			data:     []byte(`{"key":"value"}`),
 			setupMocks: func() {
-				mockHttpClient.ExpectPost("http://example.com", []byte(`{"key":"value"}`), fmt.Errorf("context canceled"), nil, nil)
+				mockHttpClient.ExpectPost("http://example.com", []byte(`{"key":"value"}`), nil, &http.Response{
+					StatusCode: http.StatusTemporaryRedirect,
+					Header:     http.Header{"Location": []string{"/new"}},
+					Body:       io.NopCloser(&bytes.Buffer{}),
+				}, nil)
 			},
-			expectedError:  true,
-			expectedErrMsg: "context canceled",
-			cancelContext:  true,
+			expectedError:     true,
+			expectedErrMsg:    "redirect from http://example.com is not followed: 307",
+			expectedStatus:    deliveries.STATUS_FAILURE,
+			expectedPermanent: true,
+		},
+		{
+			name:            "redirect is followed",
+			endpoint:        "http://example.com/old",
+			data:            []byte(`{"key":"value"}`),
+			followRedirects: true,
+			setupMocks: func() {
+				mockHttpClient.ExpectPost("http://example.com/old", []byte(`{"key":"value"}`), nil, &http.Response{
+					StatusCode: http.StatusPermanentRedirect,
+					Header:     http.Header{"Location": []string{"/new"}},
+					Body:       io.NopCloser(&bytes.Buffer{}),
+				}, nil)
+				mockHttpClient.ExpectPost("http://example.com/new", []byte(`{"key":"value"}`), nil, &http.Response{
+					StatusCode: http.StatusNoContent,
+					Body:       io.NopCloser(&bytes.Buffer{}),
+				}, nil)
+			},
+			expectedError:  false,
+			expectedStatus: deliveries.STATUS_SUCCESS,
 		},
 		{
 			name:     "network error",
@@ -88,41 +137,90 @@ func TestSendRequestWithRetry(t *testing.T) {
 			data:     []byte(`{"key":"value"}`),
 			setupMocks: func() {
 				mockHttpClient.ExpectPost("http://example.com", []byte(`{"key":"value"}`), fmt.Errorf("network error"), nil, nil)
-				mockHttpClient.ExpectPost("http://example.com", []byte(`{"key":"value"}`), fmt.Errorf("network error"), nil, nil)
-				mockHttpClient.ExpectPost("http://example.com", []byte(`{"key":"value"}`), fmt.Errorf("network error"), nil, nil)
 			},
 			expectedError:  true,
-			expectedErrMsg: "max retries reached for endpoint http://example.com",
+			expectedErrMsg: "network error",
+			expectedStatus: deliveries.STATUS_FAILURE,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setupMocks()
-			ctx := context.Background()
-			ctx, cancel := context.WithCancel(ctx)
-			defer cancel()
-			if tt.cancelContext {
-				go func() {
-					time.Sleep(10 * time.Millisecond)
-					cancel()
-				}()
-			}
-			err := service.SendRequestWithRetry(ctx, tt.endpoint, tt.data)
+			var statuses []string
+			mockDeliveries.On("Create", mock.Anything, mock.MatchedBy(func(req *deliveries.CreateRequest) bool {
+				return req.ListenerID == "listener_id" && req.EventID == 1 && req.EventType == webhook.EVENT_CONTENT_UPDATED
+			})).Run(func(args mock.Arguments) {
+				statuses = append(statuses, args.Get(1).(*deliveries.CreateRequest).Status)
+			}).Return(&deliveries.Delivery{}, nil)
+			defer func() { mockDeliveries.ExpectedCalls = nil }()
+
+			listener := &listeners.Listener{ID: "listener_id", CallbackEndpoint: tt.endpoint, FollowRedirects: tt.followRedirects}
+			err := service.Send(context.Background(), listener, &Event{ID: 1, Type: webhook.EVENT_CONTENT_UPDATED}, tt.data)
 
 			if tt.expectedError {
-				if err == nil {
-					t.Errorf("Expected error, got nil")
-				} else if err.Error() != tt.expectedErrMsg {
-					t.Errorf("Expected error message '%s', got '%s'", tt.expectedErrMsg, err.Error())
+				assert.EqualError(t, err, tt.expectedErrMsg)
+				var deliveryErr *DeliveryError
+				if errors.As(err, &deliveryErr) {
+					assert.Equal(t, tt.expectedPermanent, deliveryErr.Permanent)
+					assert.Equal(t, tt.expectedRetryAfter, deliveryErr.RetryAfter)
 				}
 			} else {
 				assert.NoError(t, err)
 			}
 
+			assert.Equal(t, []string{tt.expectedStatus}, statuses)
 			if err := mockHttpClient.AllExpectationsDone(); err != nil {
 				t.Error(err)
 			}
 		})
 	}
 }
+
+func TestPing(t *testing.T) {
+	mockLog := logger.NewMock()
+	mockHttpClient := client.NewMock()
+	mockDeliveries := deliveries.NewMock()
+	service := NewRequestsController(mockLog, mockHttpClient, mockDeliveries)
+
+	listener := &listeners.Listener{ID: "listener_id", FileID: "file_id", CallbackEndpoint: "http://example.com"}
+	data := []byte(`{"event":"ping","file_id":"file_id","listener_id":"listener_id"}`)
+
+	t.Run("response of the listener", func(t *testing.T) {
+		mockHttpClient.ExpectPost("http://example.com", data, nil, &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString("pong")),
+		}, nil)
+
+		result, err := service.Ping(context.Background(), listener)
+		assert.NoError(t, err)
+		assert.Equal(t, deliveries.STATUS_SUCCESS, result.Status)
+		assert.Equal(t, http.StatusOK, *result.StatusCode)
+		assert.Equal(t, "pong", *result.Response)
+		assert.Nil(t, result.Error)
+		assert.NoError(t, mockHttpClient.AllExpectationsDone())
+	})
+
+	t.Run("failed request is not retried", func(t *testing.T) {
+		mockHttpClient.ExpectPost("http://example.com", data, fmt.Errorf("network error"), nil, nil)
+
+		result, err := service.Ping(context.Background(), listener)
+		assert.NoError(t, err)
+		assert.Equal(t, deliveries.STATUS_FAILURE, result.Status)
+		assert.Nil(t, result.StatusCode)
+		assert.Equal(t, "network error", *result.Error)
+		assert.NoError(t, mockHttpClient.AllExpectationsDone())
+	})
+
+	mockDeliveries.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 30*time.Second, retryAfter("30", now))
+	assert.Equal(t, time.Minute, retryAfter("Mon, 01 Jan 2024 12:01:00 GMT", now))
+	assert.Zero(t, retryAfter("Mon, 01 Jan 2024 11:00:00 GMT", now))
+	assert.Zero(t, retryAfter("-5", now))
+	assert.Zero(t, retryAfter("soon", now))
+	assert.Zero(t, retryAfter("", now))
+}
@@ -2,8 +2,12 @@ package content_formats
 
 import (
 	"context"
+	"database/sql"
+	"strings"
 
 	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/formats"
+	"github.com/Moranilt/config-keeper/utils"
 	"github.com/Moranilt/http-utils/clients/database"
 	"github.com/Moranilt/http-utils/tiny_errors"
 )
@@ -15,6 +19,19 @@ type client struct {
 type Client interface {
 	// GetMany retrieves multiple content formats entries from the database.
 	GetMany(ctx context.Context) ([]*ContentFormat, tiny_errors.ErrorHandler)
+
+	// Get retrieves a single content format by its ID or by ID of the content which has this format.
+	Get(ctx context.Context, req *GetRequest) (*ContentFormat, tiny_errors.ErrorHandler)
+
+	// Create creates a new content format. Name should be unique and validator should be a name of
+	// a handler registered in formats package.
+	Create(ctx context.Context, req *CreateRequest) (*ContentFormat, tiny_errors.ErrorHandler)
+
+	// Edit updates provided fields of the content format.
+	Edit(ctx context.Context, req *EditRequest) (*ContentFormat, tiny_errors.ErrorHandler)
+
+	// Delete removes a content format from the database. Formats used by file contents can not be removed.
+	Delete(ctx context.Context, req *DeleteRequest) (bool, tiny_errors.ErrorHandler)
 }
 
 // New creates a new instance of the Client interface, which provides methods for
@@ -34,3 +51,164 @@ func (c *client) GetMany(ctx context.Context) ([]*ContentFormat, tiny_errors.Err
 
 	return contentFormats, nil
 }
+
+func (c *client) Get(ctx context.Context, req *GetRequest) (*ContentFormat, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	query, arg := QUERY_GET_FORMAT, req.ID
+	if req.ContentID != "" {
+		query, arg = QUERY_GET_FORMAT_BY_CONTENT, req.ContentID
+	}
+
+	var contentFormat ContentFormat
+	err := c.db.GetContext(ctx, &contentFormat, query, arg)
+	if err == sql.ErrNoRows {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("content format does not exist"))
+	}
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	return &contentFormat, nil
+}
+
+func (c *client) Create(ctx context.Context, req *CreateRequest) (*ContentFormat, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	requiredFields := []utils.RequiredField{
+		{Name: "name", Value: req.Name},
+	}
+	requiredErr := utils.ValidateRequiredFields(requiredFields)
+	if len(requiredErr) > 0 {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, requiredErr...)
+	}
+
+	if err := validateSettings(req.MimeType, req.Validator); err != nil {
+		return nil, err
+	}
+	if err := c.checkName(ctx, req.Name, ""); err != nil {
+		return nil, err
+	}
+
+	mimeType := DEFAULT_MIME_TYPE
+	if req.MimeType != nil {
+		mimeType = *req.MimeType
+	}
+
+	var contentFormat ContentFormat
+	err := c.db.QueryRowxContext(ctx, QUERY_CREATE_FORMAT, req.Name, mimeType, emptyToNil(req.Extension), emptyToNil(req.Validator)).
+		StructScan(&contentFormat)
+	if err != nil {
+		return nil, utils.DatabaseError(err, "content format with the same name already exists")
+	}
+
+	return &contentFormat, nil
+}
+
+func (c *client) Edit(ctx context.Context, req *EditRequest) (*ContentFormat, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	requiredFields := []utils.RequiredField{
+		{Name: "id", Value: req.ID},
+	}
+	requiredErr := utils.ValidateRequiredFields(requiredFields)
+	if len(requiredErr) > 0 {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, requiredErr...)
+	}
+
+	if req.Name == nil && req.MimeType == nil && req.Extension == nil && req.Validator == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, tiny_errors.Detail("name, mime_type, extension or validator", "required"))
+	}
+	if req.Name != nil && *req.Name == "" {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("name", "can not be empty"))
+	}
+
+	if err := validateSettings(req.MimeType, req.Validator); err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		if err := c.checkName(ctx, *req.Name, req.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	var contentFormat ContentFormat
+	err := c.db.QueryRowxContext(ctx, QUERY_EDIT_FORMAT, req.ID, req.Name, req.MimeType, req.Extension, req.Validator).
+		StructScan(&contentFormat)
+	if err == sql.ErrNoRows {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("content format does not exist"))
+	}
+	if err != nil {
+		return nil, utils.DatabaseError(err, "content format with the same name already exists")
+	}
+
+	return &contentFormat, nil
+}
+
+func (c *client) Delete(ctx context.Context, req *DeleteRequest) (bool, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return false, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	var used bool
+	err := c.db.GetContext(ctx, &used, QUERY_FORMAT_IS_USED, req.ID)
+	if err != nil {
+		return false, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	if used {
+		return false, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("content format is used by file contents"))
+	}
+
+	result, err := c.db.ExecContext(ctx, QUERY_DELETE_FORMAT, req.ID)
+	if err != nil {
+		return false, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	return affected > 0, nil
+}
+
+// checkName returns an error if another format with the same name exists.
+func (c *client) checkName(ctx context.Context, name string, id string) tiny_errors.ErrorHandler {
+	var existingID string
+	err := c.db.GetContext(ctx, &existingID, QUERY_GET_FORMAT_ID_BY_NAME, name)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	if existingID != id {
+		return utils.ExistsError("content format with the same name already exists")
+	}
+	return nil
+}
+
+func validateSettings(mimeType *string, validator *string) tiny_errors.ErrorHandler {
+	if mimeType != nil && !strings.Contains(*mimeType, "/") {
+		return tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("mime_type", "should be in type/subtype form"))
+	}
+	if validator != nil && *validator != "" {
+		if _, ok := formats.Get(*validator); !ok {
+			return tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("validator", "should be one of "+strings.Join(formats.Names(), ", ")))
+		}
+	}
+	return nil
+}
+
+func emptyToNil(value *string) *string {
+	if value == nil || *value == "" {
+		return nil
+	}
+	return value
+}
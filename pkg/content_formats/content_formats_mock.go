@@ -0,0 +1,66 @@
+package content_formats
+
+import (
+	"context"
+
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockClient struct {
+	mock.Mock
+}
+
+func NewMock() *MockClient {
+	return new(MockClient)
+}
+
+func (m *MockClient) GetMany(ctx context.Context) ([]*ContentFormat, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx)
+	contentFormats := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return contentFormats.([]*ContentFormat), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockClient) Get(ctx context.Context, req *GetRequest) (*ContentFormat, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	contentFormat := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return contentFormat.(*ContentFormat), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockClient) Create(ctx context.Context, req *CreateRequest) (*ContentFormat, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	contentFormat := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return contentFormat.(*ContentFormat), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockClient) Edit(ctx context.Context, req *EditRequest) (*ContentFormat, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	contentFormat := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return contentFormat.(*ContentFormat), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockClient) Delete(ctx context.Context, req *DeleteRequest) (bool, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	success := args.Bool(0)
+	err := args.Get(1)
+	if err == nil {
+		return success, nil
+	}
+	return success, err.(tiny_errors.ErrorHandler)
+}
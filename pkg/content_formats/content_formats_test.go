@@ -2,12 +2,14 @@ package content_formats
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"regexp"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/utils"
 	"github.com/Moranilt/http-utils/clients/database"
 	database_mock "github.com/Moranilt/http-utils/clients/database/mock"
 	"github.com/Moranilt/http-utils/tiny_errors"
@@ -78,3 +80,331 @@ func TestGetMany(t *testing.T) {
 		})
 	}
 }
+
+func TestGet(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb})
+
+	columns := []string{"id", "name", "mime_type", "extension", "validator", "created_at", "updated_at"}
+
+	tests := []struct {
+		name           string
+		req            *GetRequest
+		mockSetup      func()
+		expectedResult *ContentFormat
+		expectedError  tiny_errors.ErrorHandler
+	}{
+		{
+			name: "by id",
+			req:  &GetRequest{ID: "format_id"},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FORMAT)).
+					WithArgs("format_id").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow("format_id", "xml", "application/xml", "xml", "xml", "2024-01-01", "2024-01-01"))
+			},
+			expectedResult: &ContentFormat{
+				ID:        "format_id",
+				Name:      "xml",
+				MimeType:  "application/xml",
+				Extension: utils.MakePointer("xml"),
+				Validator: utils.MakePointer("xml"),
+				CreatedAt: "2024-01-01",
+				UpdatedAt: "2024-01-01",
+			},
+		},
+		{
+			name: "by content id",
+			req:  &GetRequest{ContentID: "content_id"},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FORMAT_BY_CONTENT)).
+					WithArgs("content_id").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow("format_id", "custom", "text/plain", nil, nil, "2024-01-01", "2024-01-01"))
+			},
+			expectedResult: &ContentFormat{
+				ID:        "format_id",
+				Name:      "custom",
+				MimeType:  "text/plain",
+				CreatedAt: "2024-01-01",
+				UpdatedAt: "2024-01-01",
+			},
+		},
+		{
+			name: "not found",
+			req:  &GetRequest{ID: "format_id"},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FORMAT)).
+					WithArgs("format_id").
+					WillReturnError(sql.ErrNoRows)
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("content format does not exist")),
+		},
+		{
+			name:          "empty request",
+			req:           nil,
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_BodyRequired),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			result, err := client.Get(context.Background(), tt.req)
+
+			assert.Equal(t, tt.expectedResult, result)
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
+				assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb})
+
+	columns := []string{"id", "name", "mime_type", "extension", "validator", "created_at", "updated_at"}
+
+	tests := []struct {
+		name           string
+		req            *CreateRequest
+		mockSetup      func()
+		expectedResult *ContentFormat
+		expectedError  tiny_errors.ErrorHandler
+	}{
+		{
+			name: "success with default mime type",
+			req:  &CreateRequest{Name: "custom", Extension: utils.MakePointer("cfg"), Validator: utils.MakePointer("ini")},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FORMAT_ID_BY_NAME)).
+					WithArgs("custom").
+					WillReturnError(sql.ErrNoRows)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CREATE_FORMAT)).
+					WithArgs("custom", DEFAULT_MIME_TYPE, "cfg", "ini").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow("format_id", "custom", DEFAULT_MIME_TYPE, "cfg", "ini", "2024-01-01", "2024-01-01"))
+			},
+			expectedResult: &ContentFormat{
+				ID:        "format_id",
+				Name:      "custom",
+				MimeType:  DEFAULT_MIME_TYPE,
+				Extension: utils.MakePointer("cfg"),
+				Validator: utils.MakePointer("ini"),
+				CreatedAt: "2024-01-01",
+				UpdatedAt: "2024-01-01",
+			},
+		},
+		{
+			name: "name exists",
+			req:  &CreateRequest{Name: "yaml"},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FORMAT_ID_BY_NAME)).
+					WithArgs("yaml").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("yaml_id"))
+			},
+			expectedError: utils.ExistsError("content format with the same name already exists"),
+		},
+		{
+			name:          "unknown validator",
+			req:           &CreateRequest{Name: "custom", Validator: utils.MakePointer("unknown")},
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotValid),
+		},
+		{
+			name:          "invalid mime type",
+			req:           &CreateRequest{Name: "custom", MimeType: utils.MakePointer("text")},
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotValid),
+		},
+		{
+			name:          "required name",
+			req:           &CreateRequest{},
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			result, err := client.Create(context.Background(), tt.req)
+
+			assert.Equal(t, tt.expectedResult, result)
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
+				assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestEdit(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb})
+
+	columns := []string{"id", "name", "mime_type", "extension", "validator", "created_at", "updated_at"}
+
+	tests := []struct {
+		name           string
+		req            *EditRequest
+		mockSetup      func()
+		expectedResult *ContentFormat
+		expectedError  tiny_errors.ErrorHandler
+	}{
+		{
+			name: "rename and remove validator",
+			req:  &EditRequest{ID: "format_id", Name: utils.MakePointer("conf"), Validator: utils.MakePointer("")},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FORMAT_ID_BY_NAME)).
+					WithArgs("conf").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("format_id"))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_EDIT_FORMAT)).
+					WithArgs("format_id", "conf", nil, nil, "").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow("format_id", "conf", "text/plain", "conf", nil, "2024-01-01", "2024-01-02"))
+			},
+			expectedResult: &ContentFormat{
+				ID:        "format_id",
+				Name:      "conf",
+				MimeType:  "text/plain",
+				Extension: utils.MakePointer("conf"),
+				CreatedAt: "2024-01-01",
+				UpdatedAt: "2024-01-02",
+			},
+		},
+		{
+			name: "not found",
+			req:  &EditRequest{ID: "format_id", MimeType: utils.MakePointer("text/xml")},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_EDIT_FORMAT)).
+					WithArgs("format_id", nil, "text/xml", nil, nil).
+					WillReturnError(sql.ErrNoRows)
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("content format does not exist")),
+		},
+		{
+			name:          "nothing to update",
+			req:           &EditRequest{ID: "format_id"},
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			result, err := client.Edit(context.Background(), tt.req)
+
+			assert.Equal(t, tt.expectedResult, result)
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
+				assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb})
+
+	tests := []struct {
+		name           string
+		req            *DeleteRequest
+		mockSetup      func()
+		expectedResult bool
+		expectedError  tiny_errors.ErrorHandler
+	}{
+		{
+			name: "success",
+			req:  &DeleteRequest{ID: "format_id"},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FORMAT_IS_USED)).
+					WithArgs("format_id").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_DELETE_FORMAT)).
+					WithArgs("format_id").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedResult: true,
+		},
+		{
+			name: "used by contents",
+			req:  &DeleteRequest{ID: "format_id"},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FORMAT_IS_USED)).
+					WithArgs("format_id").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("content format is used by file contents")),
+		},
+		{
+			name: "database error",
+			req:  &DeleteRequest{ID: "format_id"},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FORMAT_IS_USED)).
+					WithArgs("format_id").
+					WillReturnError(errors.New("database error"))
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message("database error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			result, err := client.Delete(context.Background(), tt.req)
+
+			assert.Equal(t, tt.expectedResult, result)
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
+				assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestContentFormat_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator *string
+		data      string
+		wantErr   bool
+	}{
+		{name: "without validator", validator: nil, data: "{"},
+		{name: "valid content", validator: utils.MakePointer("json"), data: `{"key": "value"}`},
+		{name: "not valid content", validator: utils.MakePointer("json"), data: "{", wantErr: true},
+		{name: "unknown validator", validator: utils.MakePointer("unknown"), data: "{}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format := &ContentFormat{Name: "format", Validator: tt.validator}
+			err := format.Validate(tt.data)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
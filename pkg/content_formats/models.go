@@ -1,10 +1,85 @@
 package content_formats
 
+import (
+	"errors"
+
+	"github.com/Moranilt/config-keeper/pkg/formats"
+)
+
 const (
-	QUERY_GET_FORMATS = "SELECT * FROM content_formats"
+	QUERY_GET_FORMATS           = "SELECT id, name, mime_type, extension, validator, created_at, updated_at FROM content_formats"
+	QUERY_GET_FORMAT            = QUERY_GET_FORMATS + " WHERE id = $1"
+	QUERY_GET_FORMAT_BY_CONTENT = "SELECT cf.id, cf.name, cf.mime_type, cf.extension, cf.validator, cf.created_at, cf.updated_at FROM content_formats cf JOIN file_contents fc ON fc.format_id = cf.id WHERE fc.id = $1"
+	QUERY_GET_FORMAT_ID_BY_NAME = "SELECT id FROM content_formats WHERE name = $1"
+	QUERY_FORMAT_IS_USED        = "SELECT EXISTS(SELECT 1 FROM file_contents WHERE format_id = $1)"
+	QUERY_DELETE_FORMAT         = "DELETE FROM content_formats WHERE id = $1"
+	QUERY_CREATE_FORMAT         = "INSERT INTO content_formats (name, mime_type, extension, validator) VALUES ($1, $2, $3, $4) RETURNING id, name, mime_type, extension, validator, created_at, updated_at"
+	QUERY_EDIT_FORMAT           = `UPDATE content_formats SET
+	name = COALESCE($2, name),
+	mime_type = COALESCE($3, mime_type),
+	extension = CASE WHEN $4::text IS NULL THEN extension ELSE NULLIF($4, '') END,
+	validator = CASE WHEN $5::text IS NULL THEN validator ELSE NULLIF($5, '') END,
+	updated_at = now()
+	WHERE id = $1
+	RETURNING id, name, mime_type, extension, validator, created_at, updated_at`
+
+	// DEFAULT_MIME_TYPE is used if MIME type is not provided on creation.
+	DEFAULT_MIME_TYPE = "text/plain"
 )
 
 type ContentFormat struct {
-	ID   string `db:"id" json:"id"`
-	Name string `db:"name" json:"name"`
+	ID        string  `db:"id" json:"id"`
+	Name      string  `db:"name" json:"name"`
+	MimeType  string  `db:"mime_type" json:"mime_type"`
+	Extension *string `db:"extension" json:"extension"`
+	Validator *string `db:"validator" json:"validator"`
+	CreatedAt string  `db:"created_at" json:"created_at"`
+	UpdatedAt string  `db:"updated_at" json:"updated_at"`
+}
+
+// GetRequest finds format by its ID or by ID of the content which has this format.
+type GetRequest struct {
+	ID        string
+	ContentID string
+}
+
+type CreateRequest struct {
+	Name      string
+	MimeType  *string
+	Extension *string
+	Validator *string
+}
+
+// EditRequest updates only provided fields. Empty extension or validator removes it.
+type EditRequest struct {
+	ID        string
+	Name      *string
+	MimeType  *string
+	Extension *string
+	Validator *string
+}
+
+type DeleteRequest struct {
+	ID string
+}
+
+// Handler returns the handler of the format validator. Returns false if the format has no validator
+// or the validator is not registered.
+func (f *ContentFormat) Handler() (formats.FormatHandler, bool) {
+	if f.Validator == nil || *f.Validator == "" {
+		return nil, false
+	}
+	return formats.Get(*f.Validator)
+}
+
+// Validate checks data with the handler of the format. Data is not checked if the format has no validator.
+func (f *ContentFormat) Validate(data string) error {
+	if f.Validator == nil || *f.Validator == "" {
+		return nil
+	}
+	handler, ok := f.Handler()
+	if !ok {
+		return errors.New("unknown validator " + *f.Validator)
+	}
+	return handler.Validate(data)
 }
@@ -0,0 +1,92 @@
+package dead_letters
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/transaction"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/clients/database"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/lib/pq"
+)
+
+type client struct {
+	db *database.Client
+}
+
+type Client interface {
+	// GetMany retrieves dead letters of the listener.
+	// Returns cursor of the next page if there are more dead letters.
+	GetMany(ctx context.Context, req *GetManyRequest) ([]*DeadLetter, *string, tiny_errors.ErrorHandler)
+
+	// Take removes dead letters of the listener and returns them, so they can be sent again.
+	// If ctx has a transaction, dead letters are removed in it.
+	Take(ctx context.Context, req *TakeRequest) ([]*DeadLetter, tiny_errors.ErrorHandler)
+}
+
+// New creates a new instance of the Client interface, which provides methods for
+// interacting with dead letters of listeners in a database.
+func New(db *database.Client) Client {
+	return &client{
+		db: db,
+	}
+}
+
+func (c *client) GetMany(ctx context.Context, req *GetManyRequest) ([]*DeadLetter, *string, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return nil, nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	var orderColumn *string
+	var orderType *string
+	if req.Order != nil {
+		orderColumn = req.Order.Column
+		orderType = req.Order.Type
+	}
+
+	column, orderExpression, err := utils.OrderColumn(orderColumn, ORDER_COLUMNS, "failed_at")
+	if err != nil {
+		return nil, nil, err
+	}
+	order := utils.OrderType(orderType)
+
+	preparedQuery := utils.NewListQuery(QUERY_GET_DEAD_LETTERS).Where("listener_id = ?", req.ListenerID)
+
+	err = req.Filter.Apply(preparedQuery, LIST_COLUMNS, orderExpression, order)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deadLetters := make([]*DeadLetter, 0)
+	dbErr := c.db.SelectContext(ctx, &deadLetters, preparedQuery.String(), preparedQuery.Args()...)
+	if dbErr != nil {
+		return nil, nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(dbErr.Error()))
+	}
+
+	deadLetters, next := utils.NextCursor(req.Filter, deadLetters, func(deadLetter *DeadLetter) (string, string) {
+		return deadLetter.columnValue(column), strconv.FormatInt(deadLetter.ID, 10)
+	})
+
+	return deadLetters, next, nil
+}
+
+func (c *client) Take(ctx context.Context, req *TakeRequest) ([]*DeadLetter, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	query, args := QUERY_TAKE_DEAD_LETTERS, []any{req.ListenerID}
+	if len(req.IDs) > 0 {
+		query, args = QUERY_TAKE_DEAD_LETTERS_BY_ID, append(args, pq.Array(req.IDs))
+	}
+
+	deadLetters := make([]*DeadLetter, 0)
+	err := transaction.From(ctx, c.db).SelectContext(ctx, &deadLetters, query, args...)
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	return deadLetters, nil
+}
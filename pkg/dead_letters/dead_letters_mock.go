@@ -0,0 +1,37 @@
+package dead_letters
+
+import (
+	"context"
+
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockClient struct {
+	mock.Mock
+}
+
+func NewMock() *MockClient {
+	return new(MockClient)
+}
+
+func (m *MockClient) GetMany(ctx context.Context, req *GetManyRequest) ([]*DeadLetter, *string, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	deadLetters := args.Get(0)
+	next, _ := args.Get(1).(*string)
+	err := args.Get(2)
+	if err == nil {
+		return deadLetters.([]*DeadLetter), next, nil
+	}
+	return nil, nil, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockClient) Take(ctx context.Context, req *TakeRequest) ([]*DeadLetter, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	deadLetters := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return deadLetters.([]*DeadLetter), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
@@ -0,0 +1,161 @@
+package dead_letters
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/callback"
+	"github.com/Moranilt/config-keeper/pkg/transaction"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/clients/database"
+	database_mock "github.com/Moranilt/http-utils/clients/database/mock"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var deadLetterColumns = []string{"id", "file_id", "listener_id", "event_id", "event_type", "details", "attempts", "last_error", "created_at", "failed_at"}
+
+func TestClient_GetMany(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb})
+
+	tests := []struct {
+		name                string
+		req                 *GetManyRequest
+		mockSetup           func()
+		expectedDeadLetters []*DeadLetter
+		expectedNext        *string
+		expectedError       tiny_errors.ErrorHandler
+	}{
+		{
+			name: "success with limit",
+			req: &GetManyRequest{
+				ListenerID: "listener1",
+				Order:      &Order{Column: utils.MakePointer("id")},
+				Filter:     &utils.ListFilter{Limit: utils.MakePointer(1)},
+			},
+			mockSetup: func() {
+				preparedQuery := utils.NewListQuery(QUERY_GET_DEAD_LETTERS).Where("listener_id = ?", "listener1").
+					Order("id", utils.ORDER_ASC).Order("id", utils.ORDER_ASC).Limit(2)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WithArgs("listener1").WillReturnRows(
+					sqlmock.NewRows(deadLetterColumns).
+						AddRow(3, "file1", "listener1", 1, webhook.EVENT_CONTENT_DELETED, []byte(`{"content_id":"content1"}`), 10, "timeout", "2024-01-01", "2024-01-02").
+						AddRow(4, "file1", "listener1", 2, webhook.EVENT_CONTENT_UPDATED, nil, 10, nil, "2024-01-01", "2024-01-02"),
+				)
+			},
+			expectedDeadLetters: []*DeadLetter{
+				{
+					ID:         3,
+					FileID:     "file1",
+					ListenerID: "listener1",
+					EventID:    1,
+					EventType:  webhook.EVENT_CONTENT_DELETED,
+					Details:    &callback.EventDetails{ContentID: utils.MakePointer("content1")},
+					Attempts:   10,
+					LastError:  utils.MakePointer("timeout"),
+					CreatedAt:  utils.MakePointer("2024-01-01"),
+					FailedAt:   "2024-01-02",
+				},
+			},
+			expectedNext: utils.MakePointer(utils.EncodeCursor("id ASC", "3", "3")),
+		},
+		{
+			name: "not valid order column",
+			req: &GetManyRequest{
+				ListenerID: "listener1",
+				Order:      &Order{Column: utils.MakePointer("attempts")},
+			},
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotValid),
+		},
+		{
+			name:          "empty request",
+			req:           nil,
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_BodyRequired),
+		},
+		{
+			name: "database error",
+			req: &GetManyRequest{
+				ListenerID: "listener1",
+			},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_DEAD_LETTERS)).WillReturnError(errors.New("database error"))
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message("database error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			deadLetters, next, err := client.GetMany(context.Background(), tt.req)
+
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
+				assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+				assert.Nil(t, deadLetters)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedDeadLetters, deadLetters)
+				assert.Equal(t, tt.expectedNext, next)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestClient_Take(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	db := &database.Client{mockDb}
+	client := New(db)
+
+	t.Run("all dead letters in transaction", func(t *testing.T) {
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_TAKE_DEAD_LETTERS)).WithArgs("listener1").WillReturnRows(
+			sqlmock.NewRows(deadLetterColumns).AddRow(3, "file1", "listener1", 1, webhook.EVENT_CONTENT_UPDATED, nil, 10, nil, nil, "2024-01-02"),
+		)
+		sqlMock.ExpectCommit()
+
+		var deadLetters []*DeadLetter
+		err := transaction.New(db).Run(context.Background(), func(ctx context.Context) tiny_errors.ErrorHandler {
+			var err tiny_errors.ErrorHandler
+			deadLetters, err = client.Take(ctx, &TakeRequest{ListenerID: "listener1"})
+			return err
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []*DeadLetter{{ID: 3, FileID: "file1", ListenerID: "listener1", EventID: 1, EventType: webhook.EVENT_CONTENT_UPDATED, Attempts: 10, FailedAt: "2024-01-02"}}, deadLetters)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("selected dead letters", func(t *testing.T) {
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_TAKE_DEAD_LETTERS_BY_ID)).WithArgs("listener1", "{3,4}").
+			WillReturnRows(sqlmock.NewRows(deadLetterColumns))
+
+		deadLetters, err := client.Take(context.Background(), &TakeRequest{ListenerID: "listener1", IDs: []int64{3, 4}})
+		assert.Nil(t, err)
+		assert.Empty(t, deadLetters)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_TAKE_DEAD_LETTERS)).WillReturnError(errors.New("database error"))
+
+		deadLetters, err := client.Take(context.Background(), &TakeRequest{ListenerID: "listener1"})
+		assert.Equal(t, custom_errors.ERR_CODE_Database, err.GetCode())
+		assert.Nil(t, deadLetters)
+	})
+
+	t.Run("nil request", func(t *testing.T) {
+		_, err := client.Take(context.Background(), nil)
+		assert.Equal(t, custom_errors.ERR_CODE_BodyRequired, err.GetCode())
+	})
+}
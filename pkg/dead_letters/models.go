@@ -0,0 +1,71 @@
+package dead_letters
+
+import (
+	"strconv"
+
+	"github.com/Moranilt/config-keeper/pkg/callback"
+	"github.com/Moranilt/config-keeper/utils"
+)
+
+const DEAD_LETTER_COLUMNS = "id, file_id, listener_id, event_id, event_type, details, attempts, last_error, created_at, failed_at"
+
+const (
+	QUERY_GET_DEAD_LETTERS        = "SELECT " + DEAD_LETTER_COLUMNS + " FROM callback_dead_letters"
+	QUERY_TAKE_DEAD_LETTERS       = "DELETE FROM callback_dead_letters WHERE listener_id = $1 RETURNING " + DEAD_LETTER_COLUMNS
+	QUERY_TAKE_DEAD_LETTERS_BY_ID = "DELETE FROM callback_dead_letters WHERE listener_id = $1 AND id = ANY($2) RETURNING " + DEAD_LETTER_COLUMNS
+)
+
+var (
+	// ORDER_COLUMNS is a list of columns allowed for sorting dead letters.
+	ORDER_COLUMNS = map[string]string{
+		"id":        "id",
+		"failed_at": "failed_at",
+	}
+
+	LIST_COLUMNS = utils.ListColumns{
+		ID:        "id",
+		CreatedAt: "failed_at",
+		UpdatedAt: "failed_at",
+	}
+)
+
+// DeadLetter is an event which was not delivered to the listener after all attempts of its retry policy.
+type DeadLetter struct {
+	ID         int64  `db:"id" json:"id"`
+	FileID     string `db:"file_id" json:"file_id"`
+	ListenerID string `db:"listener_id" json:"listener_id"`
+	// EventID is an ID of the original event used in the delivery log.
+	EventID   int64                  `db:"event_id" json:"event_id"`
+	EventType string                 `db:"event_type" json:"event_type"`
+	Details   *callback.EventDetails `db:"details" json:"details,omitempty"`
+	Attempts  int                    `db:"attempts" json:"attempts"`
+	LastError *string                `db:"last_error" json:"last_error"`
+	CreatedAt *string                `db:"created_at" json:"created_at"`
+	FailedAt  string                 `db:"failed_at" json:"failed_at"`
+}
+
+type Order struct {
+	Column *string
+	Type   *string
+}
+
+type GetManyRequest struct {
+	ListenerID string            `json:"listener_id"`
+	Order      *Order            `json:"-"`
+	Filter     *utils.ListFilter `json:"-"`
+}
+
+type TakeRequest struct {
+	ListenerID string `json:"listener_id"`
+	// IDs limits taken dead letters, all dead letters of the listener are taken if it is empty.
+	IDs []int64 `json:"ids"`
+}
+
+func (d *DeadLetter) columnValue(column string) string {
+	switch column {
+	case "id":
+		return strconv.FormatInt(d.ID, 10)
+	default:
+		return d.FailedAt
+	}
+}
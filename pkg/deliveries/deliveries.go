@@ -0,0 +1,119 @@
+package deliveries
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/clients/database"
+	"github.com/Moranilt/http-utils/tiny_errors"
+)
+
+type client struct {
+	db *database.Client
+}
+
+type Client interface {
+	// Create records an attempt to deliver the event to the listener. Attempt number is assigned by
+	// the number of previous attempts of the same event.
+	Create(ctx context.Context, req *CreateRequest) (*Delivery, tiny_errors.ErrorHandler)
+
+	// EventType returns a type of the event sent to the listener, nil if the event was never sent to it.
+	EventType(ctx context.Context, listenerID string, eventID int64) (*string, tiny_errors.ErrorHandler)
+
+	// GetMany retrieves deliveries of the listener, optionally filtered by status and time of the attempt.
+	GetMany(ctx context.Context, req *GetManyRequest) ([]*Delivery, *string, tiny_errors.ErrorHandler)
+}
+
+// New creates a new instance of the Client interface, which provides methods for
+// interacting with the delivery log of listeners in a database.
+func New(db *database.Client) Client {
+	return &client{
+		db: db,
+	}
+}
+
+func (c *client) Create(ctx context.Context, req *CreateRequest) (*Delivery, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	var delivery Delivery
+	err := c.db.QueryRowxContext(
+		ctx,
+		QUERY_CREATE_DELIVERY,
+		req.ListenerID,
+		req.EventID,
+		req.EventType,
+		req.Status,
+		req.StatusCode,
+		req.Latency.Milliseconds(),
+		req.Response,
+		req.Error,
+	).StructScan(&delivery)
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	return &delivery, nil
+}
+
+func (c *client) EventType(ctx context.Context, listenerID string, eventID int64) (*string, tiny_errors.ErrorHandler) {
+	var eventType string
+	err := c.db.GetContext(ctx, &eventType, QUERY_GET_EVENT_TYPE, listenerID, eventID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	return &eventType, nil
+}
+
+func (c *client) GetMany(ctx context.Context, req *GetManyRequest) ([]*Delivery, *string, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return nil, nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	if req.Status != nil && !slices.Contains(STATUSES, *req.Status) {
+		return nil, nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("status", "should be one of "+strings.Join(STATUSES, ", ")))
+	}
+
+	var orderColumn *string
+	var orderType *string
+	if req.Order != nil {
+		orderColumn = req.Order.Column
+		orderType = req.Order.Type
+	}
+
+	column, orderExpression, err := utils.OrderColumn(orderColumn, ORDER_COLUMNS, "created_at")
+	if err != nil {
+		return nil, nil, err
+	}
+	order := utils.OrderType(orderType)
+
+	preparedQuery := utils.NewListQuery(QUERY_GET_DELIVERIES).Where("listener_id = ?", req.ListenerID)
+	if req.Status != nil {
+		preparedQuery.Where("status = ?", *req.Status)
+	}
+
+	err = req.Filter.Apply(preparedQuery, LIST_COLUMNS, orderExpression, order)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deliveries := make([]*Delivery, 0)
+	dbErr := c.db.SelectContext(ctx, &deliveries, preparedQuery.String(), preparedQuery.Args()...)
+	if dbErr != nil {
+		return nil, nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(dbErr.Error()))
+	}
+
+	deliveries, next := utils.NextCursor(req.Filter, deliveries, func(delivery *Delivery) (string, string) {
+		return delivery.columnValue(column), delivery.ID
+	})
+
+	return deliveries, next, nil
+}
@@ -0,0 +1,47 @@
+package deliveries
+
+import (
+	"context"
+
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockClient struct {
+	mock.Mock
+}
+
+func NewMock() *MockClient {
+	return new(MockClient)
+}
+
+func (m *MockClient) Create(ctx context.Context, req *CreateRequest) (*Delivery, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	delivery := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return delivery.(*Delivery), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockClient) EventType(ctx context.Context, listenerID string, eventID int64) (*string, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, listenerID, eventID)
+	eventType, _ := args.Get(0).(*string)
+	err := args.Get(1)
+	if err == nil {
+		return eventType, nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockClient) GetMany(ctx context.Context, req *GetManyRequest) ([]*Delivery, *string, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	deliveries := args.Get(0)
+	next, _ := args.Get(1).(*string)
+	err := args.Get(2)
+	if err == nil {
+		return deliveries.([]*Delivery), next, nil
+	}
+	return nil, nil, err.(tiny_errors.ErrorHandler)
+}
@@ -0,0 +1,226 @@
+package deliveries
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/clients/database"
+	database_mock "github.com/Moranilt/http-utils/clients/database/mock"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var deliveryColumns = []string{"id", "listener_id", "event_id", "event_type", "attempt", "status", "status_code", "latency_ms", "response", "error", "created_at"}
+
+func TestClient_Create(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb})
+
+	tests := []struct {
+		name             string
+		req              *CreateRequest
+		mockSetup        func()
+		expectedDelivery *Delivery
+		expectedError    tiny_errors.ErrorHandler
+	}{
+		{
+			name: "success",
+			req: &CreateRequest{
+				ListenerID: "listener1",
+				EventID:    10,
+				EventType:  webhook.EVENT_CONTENT_UPDATED,
+				Status:     STATUS_SUCCESS,
+				StatusCode: utils.MakePointer(200),
+				Latency:    150 * time.Millisecond,
+				Response:   utils.MakePointer("ok"),
+			},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CREATE_DELIVERY)).
+					WithArgs("listener1", int64(10), webhook.EVENT_CONTENT_UPDATED, STATUS_SUCCESS, utils.MakePointer(200), int64(150), utils.MakePointer("ok"), nil).
+					WillReturnRows(sqlmock.NewRows(deliveryColumns).
+						AddRow("delivery1", "listener1", 10, webhook.EVENT_CONTENT_UPDATED, 2, STATUS_SUCCESS, 200, 150, "ok", nil, "2024-01-01"))
+			},
+			expectedDelivery: &Delivery{
+				ID:         "delivery1",
+				ListenerID: "listener1",
+				EventID:    10,
+				EventType:  webhook.EVENT_CONTENT_UPDATED,
+				Attempt:    2,
+				Status:     STATUS_SUCCESS,
+				StatusCode: utils.MakePointer(200),
+				LatencyMs:  150,
+				Response:   utils.MakePointer("ok"),
+				CreatedAt:  "2024-01-01",
+			},
+		},
+		{
+			name:          "empty request",
+			req:           nil,
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_BodyRequired),
+		},
+		{
+			name: "database error",
+			req: &CreateRequest{
+				ListenerID: "listener1",
+				EventID:    10,
+				Status:     STATUS_FAILURE,
+			},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CREATE_DELIVERY)).
+					WillReturnError(errors.New("database error"))
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message("database error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			delivery, err := client.Create(context.Background(), tt.req)
+
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
+				assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+				assert.Nil(t, delivery)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedDelivery, delivery)
+			}
+		})
+	}
+}
+
+func TestClient_EventType(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb})
+
+	t.Run("delivered", func(t *testing.T) {
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_EVENT_TYPE)).WithArgs("listener1", int64(10)).
+			WillReturnRows(sqlmock.NewRows([]string{"event_type"}).AddRow(webhook.EVENT_FILE_RENAMED))
+
+		eventType, err := client.EventType(context.Background(), "listener1", 10)
+		assert.Nil(t, err)
+		assert.Equal(t, utils.MakePointer(webhook.EVENT_FILE_RENAMED), eventType)
+	})
+
+	t.Run("not delivered", func(t *testing.T) {
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_EVENT_TYPE)).WithArgs("listener1", int64(10)).
+			WillReturnError(sql.ErrNoRows)
+
+		eventType, err := client.EventType(context.Background(), "listener1", 10)
+		assert.Nil(t, err)
+		assert.Nil(t, eventType)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_EVENT_TYPE)).WithArgs("listener1", int64(10)).
+			WillReturnError(errors.New("database error"))
+
+		eventType, err := client.EventType(context.Background(), "listener1", 10)
+		assert.Equal(t, custom_errors.ERR_CODE_Database, err.GetCode())
+		assert.Nil(t, eventType)
+	})
+
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestClient_GetMany(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb})
+
+	tests := []struct {
+		name               string
+		req                *GetManyRequest
+		mockSetup          func()
+		expectedDeliveries []*Delivery
+		expectedNext       *string
+		expectedError      tiny_errors.ErrorHandler
+	}{
+		{
+			name: "success with status",
+			req: &GetManyRequest{
+				ListenerID: "listener1",
+				Status:     utils.MakePointer(STATUS_FAILURE),
+			},
+			mockSetup: func() {
+				preparedQuery := utils.NewListQuery(QUERY_GET_DELIVERIES).Where("listener_id = ?", "listener1").
+					Where("status = ?", STATUS_FAILURE).
+					Order("created_at", utils.ORDER_ASC).Order("id", utils.ORDER_ASC)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WithArgs("listener1", STATUS_FAILURE).WillReturnRows(
+					sqlmock.NewRows(deliveryColumns).
+						AddRow("delivery1", "listener1", 10, webhook.EVENT_CONTENT_UPDATED, 1, STATUS_FAILURE, 500, 20, "error", nil, "2024-01-01"),
+				)
+			},
+			expectedDeliveries: []*Delivery{
+				{
+					ID:         "delivery1",
+					ListenerID: "listener1",
+					EventID:    10,
+					EventType:  webhook.EVENT_CONTENT_UPDATED,
+					Attempt:    1,
+					Status:     STATUS_FAILURE,
+					StatusCode: utils.MakePointer(500),
+					LatencyMs:  20,
+					Response:   utils.MakePointer("error"),
+					CreatedAt:  "2024-01-01",
+				},
+			},
+		},
+		{
+			name: "not valid status",
+			req: &GetManyRequest{
+				ListenerID: "listener1",
+				Status:     utils.MakePointer("pending"),
+			},
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotValid),
+		},
+		{
+			name:          "empty request",
+			req:           nil,
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_BodyRequired),
+		},
+		{
+			name: "database error",
+			req: &GetManyRequest{
+				ListenerID: "listener1",
+			},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_DELIVERIES)).WillReturnError(errors.New("database error"))
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message("database error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			deliveries, next, err := client.GetMany(context.Background(), tt.req)
+
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
+				assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+				assert.Nil(t, deliveries)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedDeliveries, deliveries)
+				assert.Equal(t, tt.expectedNext, next)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package deliveries
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Moranilt/config-keeper/utils"
+)
+
+const (
+	// attempt is numbered by previous deliveries of the same event to the listener
+	QUERY_CREATE_DELIVERY = `INSERT INTO listener_deliveries (listener_id, event_id, event_type, attempt, status, status_code, latency_ms, response, error)
+	VALUES ($1, $2, $3, (SELECT COUNT(*) + 1 FROM listener_deliveries WHERE listener_id = $1 AND event_id = $2), $4, $5, $6, $7, $8)
+	RETURNING id, listener_id, event_id, event_type, attempt, status, status_code, latency_ms, response, error, created_at`
+	QUERY_GET_EVENT_TYPE = "SELECT event_type FROM listener_deliveries WHERE listener_id = $1 AND event_id = $2 LIMIT 1"
+	QUERY_GET_DELIVERIES = "SELECT id, listener_id, event_id, event_type, attempt, status, status_code, latency_ms, response, error, created_at FROM listener_deliveries"
+)
+
+const (
+	STATUS_SUCCESS = "success"
+	STATUS_FAILURE = "failure"
+
+	// RESPONSE_SNIPPET_SIZE is a maximum number of bytes of the response body kept in the log.
+	RESPONSE_SNIPPET_SIZE = 1024
+)
+
+var (
+	// ORDER_COLUMNS is a list of columns allowed for sorting deliveries.
+	ORDER_COLUMNS = map[string]string{
+		"id":         "id",
+		"attempt":    "attempt",
+		"created_at": "created_at",
+	}
+
+	LIST_COLUMNS = utils.ListColumns{
+		ID:        "id",
+		CreatedAt: "created_at",
+		UpdatedAt: "created_at",
+	}
+
+	STATUSES = []string{STATUS_SUCCESS, STATUS_FAILURE}
+)
+
+// Delivery is a single attempt to send an event to the listener.
+type Delivery struct {
+	ID         string  `db:"id" json:"id"`
+	ListenerID string  `db:"listener_id" json:"listener_id"`
+	EventID    int64   `db:"event_id" json:"event_id"`
+	EventType  string  `db:"event_type" json:"event_type"`
+	Attempt    int     `db:"attempt" json:"attempt"`
+	Status     string  `db:"status" json:"status"`
+	StatusCode *int    `db:"status_code" json:"status_code"`
+	LatencyMs  int64   `db:"latency_ms" json:"latency_ms"`
+	Response   *string `db:"response" json:"response"`
+	Error      *string `db:"error" json:"error"`
+	CreatedAt  string  `db:"created_at" json:"created_at"`
+}
+
+type CreateRequest struct {
+	ListenerID string
+	EventID    int64
+	EventType  string
+	Status     string
+	StatusCode *int
+	Latency    time.Duration
+	Response   *string
+	Error      *string
+}
+
+// Fail marks the attempt as failed with the error.
+func (r *CreateRequest) Fail(err error) {
+	message := err.Error()
+	r.Status = STATUS_FAILURE
+	r.Error = &message
+}
+
+type Order struct {
+	Column *string
+	Type   *string
+}
+
+type GetManyRequest struct {
+	ListenerID string            `json:"listener_id"`
+	Status     *string           `json:"status"`
+	Order      *Order            `json:"-"`
+	Filter     *utils.ListFilter `json:"-"`
+}
+
+func (d *Delivery) columnValue(column string) string {
+	switch column {
+	case "id":
+		return d.ID
+	case "attempt":
+		return strconv.Itoa(d.Attempt)
+	default:
+		return d.CreatedAt
+	}
+}
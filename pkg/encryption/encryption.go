@@ -0,0 +1,73 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KEY_SIZE is a size of AES-256 keys in bytes.
+const KEY_SIZE = 32
+
+var (
+	ErrNoKey     = errors.New("encryption key is not configured")
+	ErrMalformed = errors.New("encrypted value is malformed")
+)
+
+// Cipher encrypts values kept in the database with AES-256-GCM. Every value has a random nonce,
+// so equal values are encrypted differently. A nil Cipher returns ErrNoKey.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New creates a cipher with the key of KEY_SIZE bytes.
+func New(key []byte) (*Cipher, error) {
+	if len(key) != KEY_SIZE {
+		return nil, fmt.Errorf("encryption key should be %d bytes, got %d", KEY_SIZE, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt returns base64 encoded nonce followed by encrypted data.
+func (c *Cipher) Encrypt(data []byte) (string, error) {
+	if c == nil {
+		return "", ErrNoKey
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(c.aead.Seal(nonce, nonce, data, nil)), nil
+}
+
+// Decrypt returns data of the value created by Encrypt with the same key.
+func (c *Cipher) Decrypt(value string) ([]byte, error) {
+	if c == nil {
+		return nil, ErrNoKey
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+
+	nonce, encrypted := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	data, err := c.aead.Open(nil, nonce, encrypted, nil)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	return data, nil
+}
@@ -0,0 +1,42 @@
+package encryption
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCipher(t *testing.T) {
+	c, err := New(bytes.Repeat([]byte("k"), KEY_SIZE))
+	assert.NoError(t, err)
+
+	first, err := c.Encrypt([]byte("token"))
+	assert.NoError(t, err)
+	second, err := c.Encrypt([]byte("token"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	data, err := c.Decrypt(first)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("token"), data)
+
+	other, err := New(bytes.Repeat([]byte("o"), KEY_SIZE))
+	assert.NoError(t, err)
+	_, err = other.Decrypt(first)
+	assert.ErrorIs(t, err, ErrMalformed)
+
+	_, err = c.Decrypt("not base64")
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestCipher_WithoutKey(t *testing.T) {
+	var c *Cipher
+	_, err := c.Encrypt([]byte("token"))
+	assert.ErrorIs(t, err, ErrNoKey)
+	_, err = c.Decrypt("value")
+	assert.ErrorIs(t, err, ErrNoKey)
+
+	_, err = New([]byte("short"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,242 @@
+package envfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Parse reads variables of the env file. Supported syntax:
+//   - empty lines and lines starting with # are ignored
+//   - optional `export ` prefix before the key
+//   - unquoted values, trailing ` # comment` is removed
+//   - single quoted values are taken as is and can span multiple lines
+//   - double quoted values can span multiple lines and support \n, \r, \t, \", \\ and \$ escapes
+//
+// Variables are returned in order of appearance, duplicated keys are kept.
+func Parse(data string) ([]*Variable, error) {
+	p := &parser{data: data, line: 1}
+	var variables []*Variable
+
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return variables, nil
+		}
+		if p.peek() == '#' {
+			p.skipLine()
+			continue
+		}
+
+		variable, err := p.variable()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", p.line, err)
+		}
+		variables = append(variables, variable)
+	}
+}
+
+// Merge combines variables of several env files. Files are passed in order of precedence:
+// value of the latest file wins. Keys are kept in order of their first appearance.
+func Merge(sources ...[]*Variable) []*Variable {
+	index := make(map[string]int)
+	var result []*Variable
+
+	for _, variables := range sources {
+		for _, variable := range variables {
+			if i, ok := index[variable.Key]; ok {
+				result[i] = &Variable{Key: variable.Key, Value: variable.Value}
+				continue
+			}
+			index[variable.Key] = len(result)
+			result = append(result, &Variable{Key: variable.Key, Value: variable.Value})
+		}
+	}
+	return result
+}
+
+// Write writes `export KEY='value'` line for every variable, so the result can be sourced by any POSIX shell.
+func Write(w io.Writer, variables []*Variable) error {
+	writer := bufio.NewWriter(w)
+	for _, variable := range variables {
+		if _, err := fmt.Fprintf(writer, "export %s=%s\n", variable.Key, Quote(variable.Value)); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// Quote wraps value in single quotes. Single quotes inside the value are closed, escaped and reopened,
+// so shell does not expand anything inside the value.
+func Quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+type parser struct {
+	data string
+	pos  int
+	line int
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *parser) peek() byte {
+	return p.data[p.pos]
+}
+
+func (p *parser) next() byte {
+	c := p.data[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+// skipBlank skips whitespaces including new lines.
+func (p *parser) skipBlank() {
+	for !p.eof() && strings.IndexByte(" \t\r\n", p.peek()) >= 0 {
+		p.next()
+	}
+}
+
+// skipSpaces skips whitespaces of the current line.
+func (p *parser) skipSpaces() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.next()
+	}
+}
+
+func (p *parser) skipLine() {
+	for !p.eof() && p.next() != '\n' {
+	}
+}
+
+func (p *parser) variable() (*Variable, error) {
+	if strings.HasPrefix(p.data[p.pos:], "export ") || strings.HasPrefix(p.data[p.pos:], "export\t") {
+		p.pos += len("export")
+		p.skipSpaces()
+	}
+
+	start := p.pos
+	for !p.eof() && strings.IndexByte("= \t\r\n", p.peek()) < 0 {
+		p.next()
+	}
+	key := p.data[start:p.pos]
+	if !keyPattern.MatchString(key) {
+		return nil, fmt.Errorf("invalid key %q", key)
+	}
+
+	p.skipSpaces()
+	if p.eof() || p.peek() != '=' {
+		return nil, fmt.Errorf("expected = after key %q", key)
+	}
+	p.next()
+	p.skipSpaces()
+
+	var value string
+	var err error
+	switch {
+	case p.eof():
+	case p.peek() == '\'':
+		value, err = p.singleQuoted()
+	case p.peek() == '"':
+		value, err = p.doubleQuoted()
+	default:
+		return &Variable{Key: key, Value: p.unquoted()}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("value of %q: %w", key, err)
+	}
+
+	if err := p.endOfLine(); err != nil {
+		return nil, fmt.Errorf("value of %q: %w", key, err)
+	}
+	return &Variable{Key: key, Value: value}, nil
+}
+
+func (p *parser) unquoted() string {
+	start := p.pos
+	for !p.eof() && p.peek() != '\n' {
+		p.next()
+	}
+	value := strings.TrimRight(p.data[start:p.pos], "\r")
+
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			value = value[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(value)
+}
+
+func (p *parser) singleQuoted() (string, error) {
+	p.next()
+	start := p.pos
+	for !p.eof() {
+		if p.peek() == '\'' {
+			value := p.data[start:p.pos]
+			p.next()
+			return value, nil
+		}
+		p.next()
+	}
+	return "", fmt.Errorf("unterminated single quote")
+}
+
+func (p *parser) doubleQuoted() (string, error) {
+	p.next()
+	var value strings.Builder
+	for !p.eof() {
+		c := p.next()
+		switch c {
+		case '"':
+			return value.String(), nil
+		case '\\':
+			if p.eof() {
+				return "", fmt.Errorf("unterminated double quote")
+			}
+			escaped := p.next()
+			switch escaped {
+			case 'n':
+				value.WriteByte('\n')
+			case 'r':
+				value.WriteByte('\r')
+			case 't':
+				value.WriteByte('\t')
+			case '"', '\\', '$':
+				value.WriteByte(escaped)
+			default:
+				value.WriteByte('\\')
+				value.WriteByte(escaped)
+			}
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated double quote")
+}
+
+// endOfLine allows only whitespaces or a comment after the quoted value.
+func (p *parser) endOfLine() error {
+	p.skipSpaces()
+	if p.eof() {
+		return nil
+	}
+	switch p.peek() {
+	case '\r', '\n':
+		p.skipLine()
+		return nil
+	case '#':
+		p.skipLine()
+		return nil
+	}
+	return fmt.Errorf("unexpected %q after closing quote", p.peek())
+}
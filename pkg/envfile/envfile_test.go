@@ -0,0 +1,102 @@
+package envfile
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected []*Variable
+		err      string
+	}{
+		{
+			name: "all supported values",
+			data: "export HOST=localhost\nNAME = my app # inline comment\n" +
+				"SINGLE='a \"b\" $c' # comment\nDOUBLE=\"line\\none \\\"q\\\" \\$HOME\"\nEMPTY=\nURL=http://host/#anchor\r\n" +
+				"MULTI=\"first\nsecond\"\n",
+			expected: []*Variable{
+				{Key: "HOST", Value: "localhost"},
+				{Key: "NAME", Value: "my app"},
+				{Key: "SINGLE", Value: `a "b" $c`},
+				{Key: "DOUBLE", Value: "line\none \"q\" $HOME"},
+				{Key: "EMPTY", Value: ""},
+				{Key: "URL", Value: "http://host/#anchor"},
+				{Key: "MULTI", Value: "first\nsecond"},
+			},
+		},
+		{
+			name: "invalid key",
+			data: "A=1\n1KEY=value",
+			err:  `line 2: invalid key "1KEY"`,
+		},
+		{
+			name: "missing equal sign",
+			data: "KEY value",
+			err:  `line 1: expected = after key "KEY"`,
+		},
+		{
+			name: "text after closing quote",
+			data: "A=1\n\nKEY='it''s'",
+			err:  `line 3: value of "KEY": unexpected '\'' after closing quote`,
+		},
+		{
+			name: "unterminated quote",
+			data: "KEY=\"value",
+			err:  `line 1: value of "KEY": unterminated double quote`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			variables, err := Parse(test.data)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, variables)
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := []*Variable{{Key: "HOST", Value: "localhost"}, {Key: "PORT", Value: "80"}}
+	production := []*Variable{{Key: "PORT", Value: "443"}, {Key: "TLS", Value: "true"}, {Key: "PORT", Value: "8443"}}
+
+	assert.Equal(t, []*Variable{
+		{Key: "HOST", Value: "localhost"},
+		{Key: "PORT", Value: "8443"},
+		{Key: "TLS", Value: "true"},
+	}, Merge(base, production))
+	assert.Equal(t, "80", base[1].Value)
+}
+
+func TestWrite(t *testing.T) {
+	variables := []*Variable{
+		{Key: "NAME", Value: "my app"},
+		{Key: "QUOTE", Value: `it's "quoted" $HOME`},
+		{Key: "EMPTY", Value: ""},
+	}
+
+	var buf bytes.Buffer
+	err := Write(&buf, variables)
+	assert.NoError(t, err)
+	assert.Equal(t, "export NAME='my app'\nexport QUOTE='it'\\''s \"quoted\" $HOME'\nexport EMPTY=''\n", buf.String())
+
+	t.Run("sourced by shell", func(t *testing.T) {
+		sh, err := exec.LookPath("sh")
+		if err != nil {
+			t.Skip("sh is not available")
+		}
+
+		out, err := exec.Command(sh, "-c", buf.String()+`printf '%s|%s|%s' "$NAME" "$QUOTE" "$EMPTY"`).Output()
+		assert.NoError(t, err)
+		assert.Equal(t, `my app|it's "quoted" $HOME|`, string(out))
+	})
+}
@@ -0,0 +1,14 @@
+package envfile
+
+const (
+	// FORMAT is a name of the export format and of the validator of content formats which are parsed as env files.
+	FORMAT = "env"
+
+	// CONTENT_TYPE of the shell script with export statements.
+	CONTENT_TYPE = "text/x-shellscript; charset=utf-8"
+)
+
+type Variable struct {
+	Key   string
+	Value string
+}
@@ -3,8 +3,11 @@ package file_contents
 import (
 	"context"
 	"database/sql"
+	"net/http"
 
 	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/blobs"
+	"github.com/Moranilt/config-keeper/pkg/transaction"
 	"github.com/Moranilt/config-keeper/utils"
 	"github.com/Moranilt/http-utils/clients/database"
 	"github.com/Moranilt/http-utils/query"
@@ -12,15 +15,21 @@ import (
 )
 
 type client struct {
-	db *database.Client
+	db      *database.Client
+	storage *blobs.Storage
 }
 
+// Client works only with contents of files which are not in the trash.
 type Client interface {
-	// Create creates a new file content entry in the database.
+	// Create creates a new file content entry in the database. Returns ERR_CODE_NotFound if the file does not exist or is in the trash.
 	Create(ctx context.Context, req *CreateRequest) (*FileContent, tiny_errors.ErrorHandler)
 
 	// GetMany retrieves multiple file content entries from the database.
-	GetMany(ctx context.Context, req *GetManyRequest) ([]*FileContent, tiny_errors.ErrorHandler)
+	// Returns cursor of the next page if there are more entries. Contents kept in blobs are loaded only if requested.
+	GetMany(ctx context.Context, req *GetManyRequest) ([]*FileContent, *string, tiny_errors.ErrorHandler)
+
+	// Get retrieves a single file content entry without its data.
+	Get(ctx context.Context, req *GetRequest) (*FileContent, tiny_errors.ErrorHandler)
 
 	// Edit updates an existing file content entry in the database.
 	Edit(ctx context.Context, req *EditRequest) (*FileContent, tiny_errors.ErrorHandler)
@@ -30,10 +39,11 @@ type Client interface {
 }
 
 // New creates a new instance of the Client interface, which provides methods for
-// interacting with file contents in a database.
-func New(db *database.Client) Client {
+// interacting with file contents in a database. Storage checks size limits and keeps large contents.
+func New(db *database.Client, storage *blobs.Storage) Client {
 	return &client{
-		db: db,
+		db:      db,
+		storage: storage,
 	}
 }
 
@@ -54,44 +64,96 @@ func (c *client) Create(ctx context.Context, req *CreateRequest) (*FileContent,
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, requiredErr...)
 	}
 
+	var fileID string
+	err := transaction.From(ctx, c.db).GetContext(ctx, &fileID, QUERY_GET_ACTIVE_FILE_ID, req.FileID)
+	if err == sql.ErrNoRows {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("file does not exist"), tiny_errors.HTTPStatus(http.StatusNotFound))
+	}
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
 	var id string
-	err := c.db.GetContext(ctx, &id, QUERY_GET_FILES_CONTENT_ID_BY_VERSION, req.FileID, req.Version)
+	err = transaction.From(ctx, c.db).GetContext(ctx, &id, QUERY_GET_FILES_CONTENT_ID_BY_VERSION, req.FileID, req.Version)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
 	}
 
 	if len(id) > 0 {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Exists, tiny_errors.Message("file content already exists"))
+		return nil, utils.ExistsError("file content already exists")
 	}
 
-	base64Content := utils.StringToBase64(req.Content)
+	stored, storeErr := c.storage.Prepare(ctx, transaction.From(ctx, c.db), req.Content, "")
+	if storeErr != nil {
+		return nil, storeErr
+	}
 
 	var fileContent FileContent
-	err = c.db.QueryRowxContext(ctx, QUERY_CREATE_CONTENT, req.FileID, req.Version, base64Content, req.FormatID).StructScan(&fileContent)
+	err = transaction.From(ctx, c.db).QueryRowxContext(ctx, QUERY_CREATE_CONTENT, req.FileID, req.Version, stored.Content, req.FormatID, stored.Size, stored.BlobKey, stored.Checksum).StructScan(&fileContent)
 	if err != nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		return nil, utils.DatabaseError(err, "file content already exists")
 	}
 
 	return &fileContent, nil
 }
 
-func (c *client) GetMany(ctx context.Context, req *GetManyRequest) ([]*FileContent, tiny_errors.ErrorHandler) {
+func (c *client) GetMany(ctx context.Context, req *GetManyRequest) ([]*FileContent, *string, tiny_errors.ErrorHandler) {
 	if req == nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+		return nil, nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	var orderColumn *string
+	var orderType *string
+	if req.Order != nil {
+		orderColumn = req.Order.Column
+		orderType = req.Order.Type
 	}
 
-	preparedQuery := query.New(QUERY_GET_FILE_CONTENTS).Where().EQ("file_id", req.FileID).Query()
+	column, orderExpression, err := utils.OrderColumn(orderColumn, ORDER_COLUMNS, "created_at")
+	if err != nil {
+		return nil, nil, err
+	}
+	order := utils.OrderType(orderType)
+
+	selectQuery := QUERY_GET_FILE_CONTENTS
+	if req.SkipContent {
+		selectQuery = QUERY_GET_FILE_VERSIONS
+	}
+
+	preparedQuery := utils.NewListQuery(selectQuery).Where("fc.file_id = ?", req.FileID)
 	if req.Version != nil {
-		preparedQuery.Where().EQ("version", req.Version)
+		preparedQuery.Where("fc.version = ?", *req.Version)
 	}
 
-	files := make([]*FileContent, 0)
-	err := c.db.SelectContext(ctx, &files, preparedQuery.String())
+	err = req.Filter.Apply(preparedQuery, LIST_COLUMNS, orderExpression, order)
 	if err != nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		return nil, nil, err
+	}
+
+	files := make([]*FileContent, 0)
+	dbErr := transaction.From(ctx, c.db).SelectContext(ctx, &files, preparedQuery.String(), preparedQuery.Args()...)
+	if dbErr != nil {
+		return nil, nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(dbErr.Error()))
 	}
 
-	return files, nil
+	files, next := utils.NextCursor(req.Filter, files, func(content *FileContent) (string, string) {
+		return content.columnValue(column), content.ID
+	})
+
+	if req.LoadBlobs && !req.SkipContent {
+		for _, content := range files {
+			if content.BlobKey == "" {
+				continue
+			}
+			data, err := c.storage.Load(content.BlobKey)
+			if err != nil {
+				return nil, nil, err
+			}
+			content.Content = utils.StringToBase64(data)
+		}
+	}
+
+	return files, next, nil
 }
 
 func (c *client) Edit(ctx context.Context, req *EditRequest) (*FileContent, tiny_errors.ErrorHandler) {
@@ -110,10 +172,10 @@ func (c *client) Edit(ctx context.Context, req *EditRequest) (*FileContent, tiny
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, tiny_errors.Detail("version or content", "required"))
 	}
 
-	contentQuery := query.New(QUERY_GET_FILE_CONTENTS_ID).Where().EQ("id", req.FileContentID).Query()
+	contentQuery := query.New(QUERY_GET_FILE_CONTENTS_ID).Where().EQ("fc.id", req.FileContentID).Query()
 
 	var id string
-	err := c.db.GetContext(ctx, &id, contentQuery.String())
+	err := transaction.From(ctx, c.db).GetContext(ctx, &id, contentQuery.String())
 	if err != nil && err != sql.ErrNoRows {
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
 	}
@@ -124,19 +186,47 @@ func (c *client) Edit(ctx context.Context, req *EditRequest) (*FileContent, tiny
 
 	queryUpdate := query.New("UPDATE file_contents").Set("updated_at", "now()").
 		Where().EQ("id", req.FileContentID).Query().
-		Returning("id", "file_id", "version", "content", "created_at", "updated_at")
+		Returning("id", "file_id", "version", "content", "size", "blob_key", "checksum", "source_commit", "created_at", "updated_at")
 
 	if req.Version != nil {
 		queryUpdate.Set("version", *req.Version)
 	}
 
 	if req.Content != nil {
-		base64Content := utils.StringToBase64(*req.Content)
-		queryUpdate.Set("content", base64Content)
+		stored, err := c.storage.Prepare(ctx, transaction.From(ctx, c.db), *req.Content, req.FileContentID)
+		if err != nil {
+			return nil, err
+		}
+		queryUpdate.Set("content", stored.Content).Set("size", stored.Size).Set("blob_key", stored.BlobKey).Set("checksum", stored.Checksum)
 	}
 
 	var fileContent FileContent
-	err = c.db.QueryRowxContext(ctx, queryUpdate.String()).StructScan(&fileContent)
+	err = transaction.From(ctx, c.db).QueryRowxContext(ctx, queryUpdate.String()).StructScan(&fileContent)
+	if err != nil {
+		return nil, utils.DatabaseError(err, "file content already exists")
+	}
+
+	return &fileContent, nil
+}
+
+func (c *client) Get(ctx context.Context, req *GetRequest) (*FileContent, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+	requiredFields := []utils.RequiredField{
+		{Name: "id", Value: req.ID},
+	}
+	requiredErr := utils.ValidateRequiredFields(requiredFields)
+	if len(requiredErr) > 0 {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, requiredErr...)
+	}
+
+	preparedQuery := query.New(QUERY_GET_FILE_VERSIONS).Where().EQ("fc.id", req.ID).Query()
+	var fileContent FileContent
+	err := transaction.From(ctx, c.db).GetContext(ctx, &fileContent, preparedQuery.String())
+	if err == sql.ErrNoRows {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("file content does not exist"))
+	}
 	if err != nil {
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
 	}
@@ -156,7 +246,7 @@ func (c *client) Delete(ctx context.Context, req *DeleteRequest) (bool, tiny_err
 		return false, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, requiredErr...)
 	}
 
-	result, err := c.db.ExecContext(ctx, QUERY_DELETE_FILE_CONTENT, req.ID)
+	result, err := transaction.From(ctx, c.db).ExecContext(ctx, QUERY_DELETE_FILE_CONTENT, req.ID)
 	if err != nil {
 		return false, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
 	}
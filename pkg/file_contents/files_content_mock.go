@@ -25,14 +25,15 @@ func (m *MockClient) Create(ctx context.Context, req *CreateRequest) (*FileConte
 	return nil, err.(tiny_errors.ErrorHandler)
 }
 
-func (m *MockClient) GetMany(ctx context.Context, req *GetManyRequest) ([]*FileContent, tiny_errors.ErrorHandler) {
+func (m *MockClient) GetMany(ctx context.Context, req *GetManyRequest) ([]*FileContent, *string, tiny_errors.ErrorHandler) {
 	args := m.Called(ctx, req)
 	contents := args.Get(0)
-	err := args.Get(1)
+	next, _ := args.Get(1).(*string)
+	err := args.Get(2)
 	if err == nil {
-		return contents.([]*FileContent), nil
+		return contents.([]*FileContent), next, nil
 	}
-	return nil, err.(tiny_errors.ErrorHandler)
+	return nil, nil, err.(tiny_errors.ErrorHandler)
 }
 
 func (m *MockClient) Edit(ctx context.Context, req *EditRequest) (*FileContent, tiny_errors.ErrorHandler) {
@@ -45,6 +46,16 @@ func (m *MockClient) Edit(ctx context.Context, req *EditRequest) (*FileContent,
 	return nil, err.(tiny_errors.ErrorHandler)
 }
 
+func (m *MockClient) Get(ctx context.Context, req *GetRequest) (*FileContent, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	fileContent := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return fileContent.(*FileContent), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
+
 func (m *MockClient) Delete(ctx context.Context, req *DeleteRequest) (bool, tiny_errors.ErrorHandler) {
 	args := m.Called(ctx, req)
 	success := args.Bool(0)
@@ -2,12 +2,14 @@ package file_contents
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"regexp"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/blobs"
 	"github.com/Moranilt/config-keeper/utils"
 	"github.com/Moranilt/http-utils/clients/database"
 	database_mock "github.com/Moranilt/http-utils/clients/database/mock"
@@ -19,7 +21,7 @@ import (
 func TestClient_CreateFileContent(t *testing.T) {
 	tiny_errors.Init(custom_errors.ERRORS)
 	mockDb, sqlMock := database_mock.NewSQlMock(t)
-	client := New(&database.Client{mockDb})
+	client := New(&database.Client{mockDb}, blobs.New(&blobs.Config{}))
 
 	tests := []struct {
 		name           string
@@ -37,10 +39,13 @@ func TestClient_CreateFileContent(t *testing.T) {
 				FormatID: "format_id",
 			},
 			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_ACTIVE_FILE_ID)).WithArgs("file_id").WillReturnRows(
+					sqlMock.NewRows([]string{"id"}).AddRow("file_id"),
+				)
 				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FILES_CONTENT_ID_BY_VERSION)).WithArgs("file_id", "v1.0.0").WillReturnRows(
 					sqlMock.NewRows([]string{"id"}),
 				)
-				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CREATE_CONTENT)).WithArgs("file_id", "v1.0.0", utils.StringToBase64("content"), "format_id").WillReturnRows(
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CREATE_CONTENT)).WithArgs("file_id", "v1.0.0", utils.StringToBase64("content"), "format_id", int64(7), "", blobs.Key("content")).WillReturnRows(
 					sqlMock.NewRows([]string{"id", "file_id", "version", "format", "content", "created_at", "updated_at"}).
 						AddRow("file_content_id", "file_id", "v1.0.0", "yaml", utils.StringToBase64("content"), "file_content_created_at", "file_content_updated_at"),
 				)
@@ -72,10 +77,13 @@ func TestClient_CreateFileContent(t *testing.T) {
 				FormatID: "format_id",
 			},
 			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_ACTIVE_FILE_ID)).WithArgs("file_id").WillReturnRows(
+					sqlMock.NewRows([]string{"id"}).AddRow("file_id"),
+				)
 				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FILES_CONTENT_ID_BY_VERSION)).WithArgs("file_id", "v1.0.0").WillReturnRows(
 					sqlMock.NewRows([]string{"id"}),
 				)
-				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CREATE_CONTENT)).WithArgs("file_id", "v1.0.0", utils.StringToBase64("content"), "format_id").WillReturnError(
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CREATE_CONTENT)).WithArgs("file_id", "v1.0.0", utils.StringToBase64("content"), "format_id", int64(7), "", blobs.Key("content")).WillReturnError(
 					assert.AnError,
 				)
 			},
@@ -91,12 +99,31 @@ func TestClient_CreateFileContent(t *testing.T) {
 				FormatID: "format_id",
 			},
 			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_ACTIVE_FILE_ID)).WithArgs("file_id").WillReturnRows(
+					sqlMock.NewRows([]string{"id"}).AddRow("file_id"),
+				)
 				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FILES_CONTENT_ID_BY_VERSION)).WithArgs("file_id", "v1.0.0").WillReturnRows(
 					sqlMock.NewRows([]string{"id"}).AddRow("file_content_id"),
 				)
 			},
 			expectedResult: nil,
-			expectedError:  tiny_errors.New(custom_errors.ERR_CODE_Exists, tiny_errors.Message("file content already exists")),
+			expectedError:  utils.ExistsError("file content already exists"),
+		},
+		{
+			name: "file in trash",
+			req: &CreateRequest{
+				FileID:   "file_id",
+				Version:  "v1.0.0",
+				Content:  "content",
+				FormatID: "format_id",
+			},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_ACTIVE_FILE_ID)).WithArgs("file_id").WillReturnRows(
+					sqlMock.NewRows([]string{"id"}),
+				)
+			},
+			expectedResult: nil,
+			expectedError:  tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("file does not exist")),
 		},
 		{
 			name: "empty fields in request",
@@ -139,12 +166,13 @@ func TestClient_CreateFileContent(t *testing.T) {
 func TestClient_GetFileContents(t *testing.T) {
 	tiny_errors.Init(custom_errors.ERRORS)
 	mockDb, sqlMock := database_mock.NewSQlMock(t)
-	client := New(&database.Client{mockDb})
+	client := New(&database.Client{mockDb}, blobs.New(&blobs.Config{}))
 
 	tests := []struct {
 		name             string
 		req              *GetManyRequest
 		expectedContents []*FileContent
+		expectedNext     *string
 		expectedError    tiny_errors.ErrorHandler
 		mockSetup        func()
 	}{
@@ -172,15 +200,42 @@ func TestClient_GetFileContents(t *testing.T) {
 				},
 			},
 			mockSetup: func() {
-				preparedQuery := query.New(QUERY_GET_FILE_CONTENTS)
-				preparedQuery.Where().EQ("file_id", "file_id")
-				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnRows(
+				preparedQuery := utils.NewListQuery(QUERY_GET_FILE_CONTENTS).Where("fc.file_id = ?", "file_id").
+					Order("fc.created_at", utils.ORDER_ASC).Order("fc.id", utils.ORDER_ASC)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WithArgs("file_id").WillReturnRows(
 					sqlMock.NewRows([]string{"id", "file_id", "version", "content", "created_at", "updated_at"}).
 						AddRow("file_content_id_1", "file_id_1", "v1.0.0", "content_1", "file_content_created_at_1", "file_content_updated_at_1").
 						AddRow("file_content_id_2", "file_id_2", "v1.0.1", "content_2", "file_content_created_at_2", "file_content_updated_at_2"),
 				)
 			},
 		},
+		{
+			name: "success without content",
+			req: &GetManyRequest{
+				FileID:      "file_id",
+				SkipContent: true,
+				LoadBlobs:   true,
+			},
+			expectedContents: []*FileContent{
+				{
+					ID:        "file_content_id_1",
+					FileID:    "file_id_1",
+					Version:   "v1.0.0",
+					Size:      9,
+					Checksum:  "checksum_1",
+					CreatedAt: "file_content_created_at_1",
+					UpdatedAt: "file_content_updated_at_1",
+				},
+			},
+			mockSetup: func() {
+				preparedQuery := utils.NewListQuery(QUERY_GET_FILE_VERSIONS).Where("fc.file_id = ?", "file_id").
+					Order("fc.created_at", utils.ORDER_ASC).Order("fc.id", utils.ORDER_ASC)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WithArgs("file_id").WillReturnRows(
+					sqlMock.NewRows([]string{"id", "file_id", "version", "size", "checksum", "created_at", "updated_at"}).
+						AddRow("file_content_id_1", "file_id_1", "v1.0.0", 9, "checksum_1", "file_content_created_at_1", "file_content_updated_at_1"),
+				)
+			},
+		},
 		{
 			name: "success with version",
 			req: &GetManyRequest{
@@ -198,10 +253,47 @@ func TestClient_GetFileContents(t *testing.T) {
 				},
 			},
 			mockSetup: func() {
-				preparedQuery := query.New(QUERY_GET_FILE_CONTENTS)
-				preparedQuery.Where().EQ("file_id", "file_id").EQ("version", "v1.0.0")
-				sqlMock.ExpectQuery(preparedQuery.String()).WillReturnRows(
+				preparedQuery := utils.NewListQuery(QUERY_GET_FILE_CONTENTS).Where("fc.file_id = ?", "file_id").
+					Where("fc.version = ?", "v1.0.0").
+					Order("fc.created_at", utils.ORDER_ASC).Order("fc.id", utils.ORDER_ASC)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WithArgs("file_id", "v1.0.0").WillReturnRows(
+					sqlMock.NewRows([]string{"id", "file_id", "version", "content", "created_at", "updated_at"}).
+						AddRow("file_content_id_1", "file_id_1", "v1.0.0", "content_1", "file_content_created_at_1", "file_content_updated_at_1"),
+				)
+			},
+		},
+		{
+			name: "success with version prefix, order and next cursor",
+			req: &GetManyRequest{
+				FileID: "file_id",
+				Order: &Order{
+					Column: utils.MakePointer("version"),
+					Type:   utils.MakePointer("desc"),
+				},
+				Filter: &utils.ListFilter{
+					Limit:      utils.MakePointer(1),
+					NamePrefix: utils.MakePointer("v1."),
+				},
+			},
+			expectedContents: []*FileContent{
+				{
+					ID:        "file_content_id_2",
+					FileID:    "file_id_2",
+					Version:   "v1.0.1",
+					Content:   "content_2",
+					CreatedAt: "file_content_created_at_2",
+					UpdatedAt: "file_content_updated_at_2",
+				},
+			},
+			expectedNext: utils.MakePointer(utils.EncodeCursor("fc.version DESC", "v1.0.1", "file_content_id_2")),
+			mockSetup: func() {
+				preparedQuery := utils.NewListQuery(QUERY_GET_FILE_CONTENTS).Where("fc.file_id = ?", "file_id").
+					Where("fc.version LIKE ?", "v1.%").
+					Order("fc.version", utils.ORDER_DESC).Order("fc.id", utils.ORDER_DESC).
+					Limit(2)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WithArgs("file_id", "v1.%").WillReturnRows(
 					sqlMock.NewRows([]string{"id", "file_id", "version", "content", "created_at", "updated_at"}).
+						AddRow("file_content_id_2", "file_id_2", "v1.0.1", "content_2", "file_content_created_at_2", "file_content_updated_at_2").
 						AddRow("file_content_id_1", "file_id_1", "v1.0.0", "content_1", "file_content_created_at_1", "file_content_updated_at_1"),
 				)
 			},
@@ -213,8 +305,8 @@ func TestClient_GetFileContents(t *testing.T) {
 			},
 			expectedError: tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message("sql error")),
 			mockSetup: func() {
-				preparedQuery := query.New(QUERY_GET_FILE_CONTENTS)
-				preparedQuery.Where().EQ("file_id", "file_id")
+				preparedQuery := utils.NewListQuery(QUERY_GET_FILE_CONTENTS).Where("fc.file_id = ?", "file_id").
+					Order("fc.created_at", utils.ORDER_ASC).Order("fc.id", utils.ORDER_ASC)
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnError(errors.New("sql error"))
 			},
 		},
@@ -224,7 +316,7 @@ func TestClient_GetFileContents(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockSetup()
 
-			fileContents, err := client.GetMany(context.Background(), tt.req)
+			fileContents, next, err := client.GetMany(context.Background(), tt.req)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
@@ -234,6 +326,7 @@ func TestClient_GetFileContents(t *testing.T) {
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expectedContents, fileContents)
+				assert.Equal(t, tt.expectedNext, next)
 			}
 		})
 	}
@@ -242,7 +335,7 @@ func TestClient_GetFileContents(t *testing.T) {
 func TestClient_EditFileContents(t *testing.T) {
 	tiny_errors.Init(custom_errors.ERRORS)
 	mockDb, sqlMock := database_mock.NewSQlMock(t)
-	client := New(&database.Client{mockDb})
+	client := New(&database.Client{mockDb}, blobs.New(&blobs.Config{}))
 	base64Content := utils.StringToBase64("content")
 
 	tests := []struct {
@@ -261,14 +354,14 @@ func TestClient_EditFileContents(t *testing.T) {
 			},
 			mockSetup: func() {
 				contentQuery := query.New(QUERY_GET_FILE_CONTENTS_ID)
-				contentQuery.Where().EQ("id", "file_content_id")
+				contentQuery.Where().EQ("fc.id", "file_content_id")
 				sqlMock.ExpectQuery(regexp.QuoteMeta(contentQuery.String())).WillReturnRows(
 					sqlMock.NewRows([]string{"id"}).AddRow("file_content_id"),
 				)
 
-				preparedQueryUpdate := query.New("UPDATE file_contents").Set("updated_at", "now()").Set("version", "v1.0.0").Set("content", base64Content).
+				preparedQueryUpdate := query.New("UPDATE file_contents").Set("updated_at", "now()").Set("version", "v1.0.0").Set("content", base64Content).Set("size", int64(7)).Set("blob_key", "").Set("checksum", blobs.Key("content")).
 					Where().EQ("id", "file_content_id").Query().
-					Returning("id", "file_id", "version", "content", "created_at", "updated_at")
+					Returning("id", "file_id", "version", "content", "size", "blob_key", "checksum", "source_commit", "created_at", "updated_at")
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQueryUpdate.String())).WillReturnRows(
 					sqlMock.NewRows([]string{"id", "file_id", "version", "content", "created_at", "updated_at"}).AddRow(
 						"file_content_id", "file_id", "v1.0.0", base64Content, "file_content_created_at", "file_content_updated_at",
@@ -293,13 +386,13 @@ func TestClient_EditFileContents(t *testing.T) {
 			},
 			mockSetup: func() {
 				contentQuery := query.New(QUERY_GET_FILE_CONTENTS_ID)
-				contentQuery.Where().EQ("id", "file_content_id")
+				contentQuery.Where().EQ("fc.id", "file_content_id")
 				sqlMock.ExpectQuery(regexp.QuoteMeta(contentQuery.String())).WillReturnRows(
 					sqlMock.NewRows([]string{"id"}).AddRow("file_content_id"),
 				)
 				preparedQueryUpdate := query.New("UPDATE file_contents").Set("updated_at", "now()").Set("version", "v1.0.0").
 					Where().EQ("id", "file_content_id").Query().
-					Returning("id", "file_id", "version", "content", "created_at", "updated_at")
+					Returning("id", "file_id", "version", "content", "size", "blob_key", "checksum", "source_commit", "created_at", "updated_at")
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQueryUpdate.String())).WillReturnRows(
 					sqlMock.NewRows([]string{"id", "file_id", "version", "content", "created_at", "updated_at"}).AddRow(
 						"file_content_id", "file_id", "v1.0.0", base64Content, "file_content_created_at", "file_content_updated_at",
@@ -324,13 +417,13 @@ func TestClient_EditFileContents(t *testing.T) {
 			},
 			mockSetup: func() {
 				contentQuery := query.New(QUERY_GET_FILE_CONTENTS_ID)
-				contentQuery.Where().EQ("id", "file_content_id")
+				contentQuery.Where().EQ("fc.id", "file_content_id")
 				sqlMock.ExpectQuery(regexp.QuoteMeta(contentQuery.String())).WillReturnRows(
 					sqlMock.NewRows([]string{"id"}).AddRow("file_content_id"),
 				)
-				preparedQueryUpdate := query.New("UPDATE file_contents").Set("updated_at", "now()").Set("content", base64Content).
+				preparedQueryUpdate := query.New("UPDATE file_contents").Set("updated_at", "now()").Set("content", base64Content).Set("size", int64(7)).Set("blob_key", "").Set("checksum", blobs.Key("content")).
 					Where().EQ("id", "file_content_id").Query().
-					Returning("id", "file_id", "version", "content", "created_at", "updated_at")
+					Returning("id", "file_id", "version", "content", "size", "blob_key", "checksum", "source_commit", "created_at", "updated_at")
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQueryUpdate.String())).WillReturnRows(
 					sqlMock.NewRows([]string{"id", "file_id", "version", "content", "created_at", "updated_at"}).AddRow(
 						"file_content_id", "file_id", "v1.0.0", base64Content, "file_content_created_at", "file_content_updated_at",
@@ -364,7 +457,7 @@ func TestClient_EditFileContents(t *testing.T) {
 			},
 			mockSetup: func() {
 				contentQuery := query.New(QUERY_GET_FILE_CONTENTS_ID)
-				contentQuery.Where().EQ("id", "file_content_id")
+				contentQuery.Where().EQ("fc.id", "file_content_id")
 				sqlMock.ExpectQuery(regexp.QuoteMeta(contentQuery.String())).WillReturnError(errors.New("sql error"))
 			},
 			expectedContent: nil,
@@ -378,14 +471,14 @@ func TestClient_EditFileContents(t *testing.T) {
 			},
 			mockSetup: func() {
 				contentQuery := query.New(QUERY_GET_FILE_CONTENTS_ID)
-				contentQuery.Where().EQ("id", "file_content_id")
+				contentQuery.Where().EQ("fc.id", "file_content_id")
 				sqlMock.ExpectQuery(regexp.QuoteMeta(contentQuery.String())).WillReturnRows(
 					sqlMock.NewRows([]string{"id"}).AddRow("file_content_id"),
 				)
 
-				preparedQueryUpdate := query.New("UPDATE file_contents").Set("updated_at", "now()").Set("content", base64Content).
+				preparedQueryUpdate := query.New("UPDATE file_contents").Set("updated_at", "now()").Set("content", base64Content).Set("size", int64(7)).Set("blob_key", "").Set("checksum", blobs.Key("content")).
 					Where().EQ("id", "file_content_id").Query().
-					Returning("id", "file_id", "version", "content", "created_at", "updated_at")
+					Returning("id", "file_id", "version", "content", "size", "blob_key", "checksum", "source_commit", "created_at", "updated_at")
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQueryUpdate.String())).WillReturnError(errors.New("sql error"))
 			},
 			expectedContent: nil,
@@ -410,10 +503,43 @@ func TestClient_EditFileContents(t *testing.T) {
 	}
 }
 
+func TestClient_Get(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb}, blobs.New(&blobs.Config{}))
+	preparedQuery := query.New(QUERY_GET_FILE_VERSIONS).Where().EQ("fc.id", "123").Query()
+
+	t.Run("success", func(t *testing.T) {
+		sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnRows(
+			sqlMock.NewRows([]string{"id", "file_id", "version"}).AddRow("123", "file_id", "v1.0.0"),
+		)
+
+		fileContent, err := client.Get(context.Background(), &GetRequest{ID: "123"})
+		assert.Nil(t, err)
+		assert.Equal(t, &FileContent{ID: "123", FileID: "file_id", Version: "v1.0.0"}, fileContent)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnError(sql.ErrNoRows)
+
+		fileContent, err := client.Get(context.Background(), &GetRequest{ID: "123"})
+		assert.Nil(t, fileContent)
+		assert.Equal(t, custom_errors.ERR_CODE_NotFound, err.GetCode())
+	})
+
+	t.Run("empty id", func(t *testing.T) {
+		fileContent, err := client.Get(context.Background(), &GetRequest{})
+		assert.Nil(t, fileContent)
+		assert.Equal(t, custom_errors.ERR_CODE_REQUIRED_FIELD, err.GetCode())
+	})
+
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
 func TestClient_Delete(t *testing.T) {
 	tiny_errors.Init(custom_errors.ERRORS)
 	mockDb, sqlMock := database_mock.NewSQlMock(t)
-	client := New(&database.Client{mockDb})
+	client := New(&database.Client{mockDb}, blobs.New(&blobs.Config{}))
 
 	tests := []struct {
 		name            string
@@ -479,3 +605,68 @@ func TestClient_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_Blobs(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb}, blobs.New(&blobs.Config{Dir: t.TempDir(), MaxSize: 20, InlineSize: 4}))
+	key := blobs.Key("content")
+
+	t.Run("large content is kept in a blob", func(t *testing.T) {
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_ACTIVE_FILE_ID)).WithArgs("file_id").WillReturnRows(
+			sqlMock.NewRows([]string{"id"}).AddRow("file_id"),
+		)
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FILES_CONTENT_ID_BY_VERSION)).WithArgs("file_id", "v1.0.0").WillReturnRows(
+			sqlMock.NewRows([]string{"id"}),
+		)
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CREATE_CONTENT)).WithArgs("file_id", "v1.0.0", "", "format_id", int64(7), key, key).WillReturnRows(
+			sqlMock.NewRows([]string{"id", "file_id", "version", "content", "size", "blob_key"}).
+				AddRow("file_content_id", "file_id", "v1.0.0", "", 7, key),
+		)
+
+		fileContent, err := client.Create(context.Background(), &CreateRequest{
+			FileID:   "file_id",
+			Version:  "v1.0.0",
+			Content:  "content",
+			FormatID: "format_id",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, &FileContent{ID: "file_content_id", FileID: "file_id", Version: "v1.0.0", Size: 7, BlobKey: key}, fileContent)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("blobs are loaded if requested", func(t *testing.T) {
+		preparedQuery := utils.NewListQuery(QUERY_GET_FILE_CONTENTS).Where("fc.file_id = ?", "file_id").
+			Order("fc.created_at", utils.ORDER_ASC).Order("fc.id", utils.ORDER_ASC)
+		sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WithArgs("file_id").WillReturnRows(
+			sqlMock.NewRows([]string{"id", "file_id", "version", "content", "size", "blob_key"}).
+				AddRow("file_content_id", "file_id", "v1.0.0", "", 7, key),
+		)
+
+		fileContents, _, err := client.GetMany(context.Background(), &GetManyRequest{FileID: "file_id", LoadBlobs: true})
+		assert.NoError(t, err)
+		assert.Equal(t, []*FileContent{
+			{ID: "file_content_id", FileID: "file_id", Version: "v1.0.0", Content: utils.StringToBase64("content"), Size: 7, BlobKey: key},
+		}, fileContents)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("content is too large", func(t *testing.T) {
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_ACTIVE_FILE_ID)).WithArgs("file_id").WillReturnRows(
+			sqlMock.NewRows([]string{"id"}).AddRow("file_id"),
+		)
+		sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_FILES_CONTENT_ID_BY_VERSION)).WithArgs("file_id", "v1.0.1").WillReturnRows(
+			sqlMock.NewRows([]string{"id"}),
+		)
+
+		fileContent, err := client.Create(context.Background(), &CreateRequest{
+			FileID:   "file_id",
+			Version:  "v1.0.1",
+			Content:  "content which is too large",
+			FormatID: "format_id",
+		})
+		assert.Nil(t, fileContent)
+		assert.Equal(t, custom_errors.ERR_CODE_TooLarge, err.GetCode())
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
@@ -1,37 +1,72 @@
 package file_contents
 
+import "github.com/Moranilt/config-keeper/utils"
+
 const (
 	QUERY_CREATE_CONTENT = `WITH inserted_row AS (
-    INSERT INTO file_contents (file_id, version, content, format_id)
-    VALUES ($1, $2, $3, $4)
-    RETURNING id, file_id, version, format_id, content, created_at, updated_at
+    INSERT INTO file_contents (file_id, version, content, format_id, size, blob_key, checksum)
+    VALUES ($1, $2, $3, $4, $5, $6, $7)
+    RETURNING id, file_id, version, format_id, content, size, blob_key, checksum, source_commit, created_at, updated_at
 	)
 	SELECT 
 			i.id, 
 			i.file_id, 
 			i.version,
 			i.content, 
+			i.size,
+			i.blob_key,
+			i.checksum,
+			i.source_commit,
 			i.created_at, 
 			i.updated_at,
 			cf.name AS format
 	FROM inserted_row i
 	LEFT JOIN content_formats cf ON i.format_id = cf.id`
 	QUERY_GET_FILES_CONTENT_ID_BY_VERSION = "SELECT id FROM file_contents WHERE file_id = $1 AND version = $2"
-	QUERY_GET_FILE_CONTENTS               = `SELECT fc.id, file_id, cf.name AS format, version, content, created_at, updated_at 
+	QUERY_GET_ACTIVE_FILE_ID              = "SELECT id FROM files WHERE id = $1 AND deleted_at IS NULL"
+	// QUERY_GET_FILE_CONTENTS skips contents of files in the trash, as all queries of contents do.
+	QUERY_GET_FILE_CONTENTS = `SELECT fc.id, fc.file_id, cf.name AS format, fc.version, fc.content, fc.size, fc.blob_key, fc.checksum, fc.source_commit, fc.created_at, fc.updated_at 
+	FROM file_contents AS fc 
+	JOIN files AS f ON f.id = fc.file_id AND f.deleted_at IS NULL
+	LEFT JOIN content_formats AS cf ON cf.id = fc.format_id`
+	// QUERY_GET_FILE_VERSIONS selects the same columns as QUERY_GET_FILE_CONTENTS except data of contents.
+	QUERY_GET_FILE_VERSIONS = `SELECT fc.id, fc.file_id, cf.name AS format, fc.version, fc.size, fc.blob_key, fc.checksum, fc.source_commit, fc.created_at, fc.updated_at 
 	FROM file_contents AS fc 
+	JOIN files AS f ON f.id = fc.file_id AND f.deleted_at IS NULL
 	LEFT JOIN content_formats AS cf ON cf.id = fc.format_id`
-	QUERY_GET_FILE_CONTENTS_ID = "SELECT id FROM file_contents"
-	QUERY_DELETE_FILE_CONTENT  = "DELETE FROM file_contents WHERE id = $1"
+	QUERY_GET_FILE_CONTENTS_ID = "SELECT fc.id FROM file_contents AS fc JOIN files AS f ON f.id = fc.file_id AND f.deleted_at IS NULL"
+	QUERY_DELETE_FILE_CONTENT  = "DELETE FROM file_contents AS fc USING files AS f WHERE fc.id = $1 AND f.id = fc.file_id AND f.deleted_at IS NULL"
+)
+
+var (
+	// ORDER_COLUMNS is a list of columns allowed for sorting file contents.
+	ORDER_COLUMNS = map[string]string{
+		"id":         "fc.id",
+		"version":    "fc.version",
+		"created_at": "fc.created_at",
+		"updated_at": "fc.updated_at",
+	}
+
+	LIST_COLUMNS = utils.ListColumns{
+		ID:        "fc.id",
+		Name:      "fc.version",
+		CreatedAt: "fc.created_at",
+		UpdatedAt: "fc.updated_at",
+	}
 )
 
 type FileContent struct {
-	ID        string `json:"id" db:"id"`
-	Content   string `json:"content" db:"content"`
-	Version   string `json:"version" db:"version"`
-	FileID    string `json:"file_id" db:"file_id"`
-	Format    string `json:"format" db:"format"`
-	CreatedAt string `json:"created_at" db:"created_at"`
-	UpdatedAt string `json:"updated_at" db:"updated_at"`
+	ID           string  `json:"id" db:"id"`
+	Content      string  `json:"content,omitempty" db:"content"`
+	Version      string  `json:"version" db:"version"`
+	FileID       string  `json:"file_id" db:"file_id"`
+	Format       string  `json:"format" db:"format"`
+	Size         int64   `json:"size" db:"size"`
+	BlobKey      string  `json:"blob_key,omitempty" db:"blob_key"`
+	Checksum     string  `json:"checksum" db:"checksum"`
+	SourceCommit *string `json:"source_commit" db:"source_commit"`
+	CreatedAt    string  `json:"created_at" db:"created_at"`
+	UpdatedAt    string  `json:"updated_at" db:"updated_at"`
 }
 
 type CreateRequest struct {
@@ -41,9 +76,20 @@ type CreateRequest struct {
 	FormatID string
 }
 
+type Order struct {
+	Column *string
+	Type   *string
+}
+
 type GetManyRequest struct {
 	FileID  string
 	Version *string
+	Order   *Order
+	Filter  *utils.ListFilter
+	// LoadBlobs replaces empty content of contents kept in blobs with their data.
+	LoadBlobs bool
+	// SkipContent selects contents without their data.
+	SkipContent bool
 }
 
 type EditRequest struct {
@@ -52,6 +98,23 @@ type EditRequest struct {
 	Version       *string
 }
 
+type GetRequest struct {
+	ID string
+}
+
 type DeleteRequest struct {
 	ID string
 }
+
+func (f *FileContent) columnValue(column string) string {
+	switch column {
+	case "id":
+		return f.ID
+	case "version":
+		return f.Version
+	case "updated_at":
+		return f.UpdatedAt
+	default:
+		return f.CreatedAt
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"net/http"
 
 	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/folders"
+	"github.com/Moranilt/config-keeper/pkg/transaction"
 	"github.com/Moranilt/config-keeper/utils"
 	"github.com/Moranilt/http-utils/clients/database"
 	"github.com/Moranilt/http-utils/query"
@@ -18,12 +20,13 @@ type client struct {
 
 type Client interface {
 	// GetMany retrieves multiple files based on the provided request parameters.
-	GetMany(ctx context.Context, req *GetManyRequest) ([]*File, tiny_errors.ErrorHandler)
+	// Returns cursor of the next page if there are more files.
+	GetMany(ctx context.Context, req *GetManyRequest) ([]*File, *string, tiny_errors.ErrorHandler)
 
 	// Create adds a new file to the system.
 	Create(ctx context.Context, req *CreateRequest) (*File, tiny_errors.ErrorHandler)
 
-	// Delete removes a file from the system.
+	// Delete moves a file to the trash.
 	Delete(ctx context.Context, req *DeleteRequest) (bool, tiny_errors.ErrorHandler)
 
 	// Edit modifies an existing file.
@@ -31,6 +34,9 @@ type Client interface {
 
 	// Get retrieves a single file.
 	Get(ctx context.Context, req *GetRequest) (*File, tiny_errors.ErrorHandler)
+
+	// GetNested retrieves all files of the folder and of its nested folders which are not in the trash.
+	GetNested(ctx context.Context, req *GetNestedRequest) ([]*File, tiny_errors.ErrorHandler)
 }
 
 // New creates a new instance of the Client interface, which provides methods for
@@ -42,35 +48,44 @@ func New(db *database.Client) Client {
 	}
 }
 
-func (c *client) GetMany(ctx context.Context, req *GetManyRequest) ([]*File, tiny_errors.ErrorHandler) {
+func (c *client) GetMany(ctx context.Context, req *GetManyRequest) ([]*File, *string, tiny_errors.ErrorHandler) {
 	if req == nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+		return nil, nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
 	}
 
-	preparedQuery := query.New(QUERY_GET_FILES)
-	if req.Order != nil && req.Order.Column != nil && req.Order.Type != nil {
-		order := query.ASC
-		if *req.Order.Type == query.DESC || *req.Order.Type == query.ASC {
-			order = *req.Order.Type
-		}
-		preparedQuery.Order(*req.Order.Column, order)
-	} else {
-		preparedQuery.Order("name", query.ASC)
+	var orderColumn *string
+	var orderType *string
+	if req.Order != nil {
+		orderColumn = req.Order.Column
+		orderType = req.Order.Type
 	}
 
-	if req.FolderID == nil {
-		preparedQuery.Where().IS("folder_id", nil)
-	} else {
-		preparedQuery.Where().EQ("folder_id", req.FolderID)
+	column, orderExpression, err := utils.OrderColumn(orderColumn, ORDER_COLUMNS, "name")
+	if err != nil {
+		return nil, nil, err
 	}
+	order := utils.OrderType(orderType)
 
-	files := make([]*File, 0)
-	err := c.db.SelectContext(ctx, &files, preparedQuery.String())
+	preparedQuery := utils.NewListQuery(QUERY_GET_FILES).Where("deleted_at IS NULL").
+		Where("folder_id = ?", folders.ResolveParentID(req.FolderID))
+	req.Metadata.Apply(preparedQuery)
+
+	err = req.Filter.Apply(preparedQuery, LIST_COLUMNS, orderExpression, order)
 	if err != nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		return nil, nil, err
 	}
 
-	return files, nil
+	files := make([]*File, 0)
+	dbErr := transaction.From(ctx, c.db).SelectContext(ctx, &files, preparedQuery.String(), preparedQuery.Args()...)
+	if dbErr != nil {
+		return nil, nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(dbErr.Error()))
+	}
+
+	files, next := utils.NextCursor(req.Filter, files, func(file *File) (string, string) {
+		return file.columnValue(column), file.ID
+	})
+
+	return files, next, nil
 }
 
 func (c *client) Create(ctx context.Context, req *CreateRequest) (*File, tiny_errors.ErrorHandler) {
@@ -87,27 +102,23 @@ func (c *client) Create(ctx context.Context, req *CreateRequest) (*File, tiny_er
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, requiredErr...)
 	}
 
-	preparedQuery := query.New(QUERY_GET_FILES).Where().EQ("name", req.Name).Query()
-	if req.FolderID != nil {
-		preparedQuery.Where().EQ("folder_id", req.FolderID)
-	} else {
-		preparedQuery.Where().IS("folder_id", nil)
-	}
+	folderID := folders.ResolveParentID(req.FolderID)
+	preparedQuery := query.New(QUERY_GET_FILES).Where().IS("deleted_at", nil).EQ("name", req.Name).EQ("folder_id", folderID).Query()
 
 	var existsFile File
-	err := c.db.GetContext(ctx, &existsFile, preparedQuery.String())
+	err := transaction.From(ctx, c.db).GetContext(ctx, &existsFile, preparedQuery.String())
 	if err != nil && err != sql.ErrNoRows {
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
 	}
 
 	if existsFile.ID != "" {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Exists, tiny_errors.Message("file already exists"))
+		return nil, utils.ExistsError("file already exists")
 	}
 
 	var file File
-	err = c.db.QueryRowxContext(ctx, QUERY_CREATE_FILE, req.FolderID, req.Name).StructScan(&file)
+	err = transaction.From(ctx, c.db).QueryRowxContext(ctx, QUERY_CREATE_FILE, folderID, req.Name).StructScan(&file)
 	if err != nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		return nil, utils.DatabaseError(err, "file already exists")
 	}
 
 	return &file, nil
@@ -127,7 +138,7 @@ func (c *client) Delete(ctx context.Context, req *DeleteRequest) (bool, tiny_err
 		return false, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, requiredErr...)
 	}
 
-	result, err := c.db.ExecContext(ctx, QUERY_DELETE_FILE, req.ID)
+	result, err := transaction.From(ctx, c.db).ExecContext(ctx, QUERY_DELETE_FILE, req.ID)
 	if err != nil {
 		return false, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
 	}
@@ -154,10 +165,6 @@ func (c *client) Edit(ctx context.Context, req *EditRequest) (*File, tiny_errors
 			Name:  "file_id",
 			Value: req.FileID,
 		},
-		{
-			Name:  "name",
-			Value: req.Name,
-		},
 	}
 
 	errFields := utils.ValidateRequiredFields(requiredFields)
@@ -165,31 +172,52 @@ func (c *client) Edit(ctx context.Context, req *EditRequest) (*File, tiny_errors
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, errFields...)
 	}
 
-	var exists bool
-	err := c.db.QueryRowxContext(
-		ctx,
-		QUERY_CHECK_FILE_EXISTS_BY_FOLDER_ID_AND_NAME,
-		req.Name,
-		req.FileID,
-	).Scan(&exists)
-	if err != nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	if req.Name == nil && req.Metadata.IsEmpty() {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, tiny_errors.Detail("name or metadata", "required"))
 	}
 
-	if exists {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Exists, tiny_errors.Message("file with such name already exists"))
+	if err := req.Metadata.Validate(); err != nil {
+		return nil, err
+	}
+
+	updateQuery := utils.NewUpdateQuery("files").SetRaw("updated_at = now()")
+
+	if req.Name != nil {
+		if *req.Name == "" {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("name", "should not be empty"))
+		}
+
+		var exists bool
+		err := transaction.From(ctx, c.db).QueryRowxContext(
+			ctx,
+			QUERY_CHECK_FILE_EXISTS_BY_FOLDER_ID_AND_NAME,
+			*req.Name,
+			req.FileID,
+		).Scan(&exists)
+		if err != nil {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		}
+
+		if exists {
+			return nil, utils.ExistsError("file with such name already exists")
+		}
+
+		updateQuery.Set("name", *req.Name)
 	}
 
-	var folder File
-	err = c.db.QueryRowxContext(ctx, QUERY_UPDATE_FILE, req.Name, req.FileID).StructScan(&folder)
+	req.Metadata.Apply(updateQuery)
+	updateQuery.Where("id = ?", req.FileID).Where("deleted_at IS NULL").Returning(RETURNING_COLUMNS...)
+
+	var file File
+	err := transaction.From(ctx, c.db).QueryRowxContext(ctx, updateQuery.String(), updateQuery.Args()...).StructScan(&file)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.HTTPStatus(http.StatusNotFound))
 		}
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		return nil, utils.DatabaseError(err, "file with such name already exists")
 	}
 
-	return &folder, nil
+	return &file, nil
 }
 
 func (c *client) Get(ctx context.Context, req *GetRequest) (*File, tiny_errors.ErrorHandler) {
@@ -204,9 +232,9 @@ func (c *client) Get(ctx context.Context, req *GetRequest) (*File, tiny_errors.E
 	if len(requiredErr) > 0 {
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, requiredErr...)
 	}
-	preparedQuery := query.New(QUERY_GET_FILES).Where().EQ("id", req.ID).Query()
+	preparedQuery := query.New(QUERY_GET_FILES).Where().IS("deleted_at", nil).EQ("id", req.ID).Query()
 	var file File
-	err := c.db.GetContext(ctx, &file, preparedQuery.String())
+	err := transaction.From(ctx, c.db).GetContext(ctx, &file, preparedQuery.String())
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.HTTPStatus(http.StatusNotFound))
@@ -216,3 +244,16 @@ func (c *client) Get(ctx context.Context, req *GetRequest) (*File, tiny_errors.E
 
 	return &file, nil
 }
+
+func (c *client) GetNested(ctx context.Context, req *GetNestedRequest) ([]*File, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	files := make([]*File, 0)
+	err := transaction.From(ctx, c.db).SelectContext(ctx, &files, QUERY_GET_NESTED_FILES, folders.ResolveID(req.FolderID))
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	return files, nil
+}
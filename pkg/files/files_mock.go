@@ -15,14 +15,15 @@ func NewMock() *MockClient {
 	return new(MockClient)
 }
 
-func (m *MockClient) GetMany(ctx context.Context, req *GetManyRequest) ([]*File, tiny_errors.ErrorHandler) {
+func (m *MockClient) GetMany(ctx context.Context, req *GetManyRequest) ([]*File, *string, tiny_errors.ErrorHandler) {
 	args := m.Called(ctx, req)
 	files := args.Get(0)
-	err := args.Get(1)
+	next, _ := args.Get(1).(*string)
+	err := args.Get(2)
 	if err == nil {
-		return files.([]*File), nil
+		return files.([]*File), next, nil
 	}
-	return nil, err.(tiny_errors.ErrorHandler)
+	return nil, nil, err.(tiny_errors.ErrorHandler)
 }
 
 func (m *MockClient) Create(ctx context.Context, req *CreateRequest) (*File, tiny_errors.ErrorHandler) {
@@ -64,3 +65,13 @@ func (m *MockClient) Get(ctx context.Context, req *GetRequest) (*File, tiny_erro
 	}
 	return nil, err.(tiny_errors.ErrorHandler)
 }
+
+func (m *MockClient) GetNested(ctx context.Context, req *GetNestedRequest) ([]*File, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	files := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return files.([]*File), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
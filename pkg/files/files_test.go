@@ -8,6 +8,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/folders"
 	"github.com/Moranilt/config-keeper/utils"
 	"github.com/Moranilt/http-utils/clients/database"
 	database_mock "github.com/Moranilt/http-utils/clients/database/mock"
@@ -25,6 +26,7 @@ func TestClient_GetMany(t *testing.T) {
 		name          string
 		req           *GetManyRequest
 		expectedFiles []*File
+		expectedNext  *string
 		expectedError tiny_errors.ErrorHandler
 		mockSetup     func()
 	}{
@@ -50,8 +52,8 @@ func TestClient_GetMany(t *testing.T) {
 				},
 			},
 			mockSetup: func() {
-				preparedQuery := query.New(QUERY_GET_FILES).Order("name", query.ASC)
-				preparedQuery.Where().IS("folder_id", nil)
+				preparedQuery := utils.NewListQuery(QUERY_GET_FILES).Where("deleted_at IS NULL").Where("folder_id = ?", folders.ROOT_ID).
+					Order("name", utils.ORDER_ASC).Order("id", utils.ORDER_ASC)
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).
 					WillReturnRows(
 						sqlMock.NewRows([]string{"id", "name", "folder_id", "created_at", "updated_at"}).
@@ -82,9 +84,10 @@ func TestClient_GetMany(t *testing.T) {
 				},
 			},
 			mockSetup: func() {
-				preparedQuery := query.New(QUERY_GET_FILES).Order("name", query.ASC)
-				preparedQuery.Where().EQ("folder_id", "folder_id")
+				preparedQuery := utils.NewListQuery(QUERY_GET_FILES).Where("deleted_at IS NULL").Where("folder_id = ?", "folder_id").
+					Order("name", utils.ORDER_ASC).Order("id", utils.ORDER_ASC)
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).
+					WithArgs("folder_id").
 					WillReturnRows(
 						sqlMock.NewRows([]string{"id", "name", "folder_id", "created_at", "updated_at"}).
 							AddRow("file_id_1", "file_name_1", "folder_id", "file_created_at", "file_updated_at").
@@ -118,9 +121,10 @@ func TestClient_GetMany(t *testing.T) {
 				},
 			},
 			mockSetup: func() {
-				preparedQuery := query.New(QUERY_GET_FILES).Order("created_at", query.DESC)
-				preparedQuery.Where().EQ("folder_id", "folder_id")
+				preparedQuery := utils.NewListQuery(QUERY_GET_FILES).Where("deleted_at IS NULL").Where("folder_id = ?", "folder_id").
+					Order("created_at", utils.ORDER_DESC).Order("id", utils.ORDER_DESC)
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).
+					WithArgs("folder_id").
 					WillReturnRows(
 						sqlMock.NewRows([]string{"id", "name", "folder_id", "created_at", "updated_at"}).
 							AddRow("file_id_1", "file_name_1", "folder_id", "file_created_at", "file_updated_at").
@@ -128,6 +132,49 @@ func TestClient_GetMany(t *testing.T) {
 					)
 			},
 		},
+		{
+			name: "success with limit and next cursor",
+			req: &GetManyRequest{
+				FolderID: utils.MakePointer("folder_id"),
+				Filter: &utils.ListFilter{
+					Limit:         utils.MakePointer(1),
+					UpdatedBefore: utils.MakePointer("2030-01-01T00:00:00Z"),
+				},
+			},
+			expectedFiles: []*File{
+				{
+					ID:        "file_id_1",
+					Name:      "file_name_1",
+					FolderID:  utils.MakePointer("folder_id"),
+					CreatedAt: "file_created_at",
+					UpdatedAt: "file_updated_at",
+				},
+			},
+			expectedNext: utils.MakePointer(utils.EncodeCursor("name ASC", "file_name_1", "file_id_1")),
+			mockSetup: func() {
+				preparedQuery := utils.NewListQuery(QUERY_GET_FILES).Where("deleted_at IS NULL").Where("folder_id = ?", "folder_id").
+					Where("updated_at < ?", "2030-01-01T00:00:00Z").
+					Order("name", utils.ORDER_ASC).Order("id", utils.ORDER_ASC).
+					Limit(2)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).
+					WithArgs("folder_id", "2030-01-01T00:00:00Z").
+					WillReturnRows(
+						sqlMock.NewRows([]string{"id", "name", "folder_id", "created_at", "updated_at"}).
+							AddRow("file_id_1", "file_name_1", "folder_id", "file_created_at", "file_updated_at").
+							AddRow("file_id_2", "file_name_2", "folder_id", "file_created_at", "file_updated_at"),
+					)
+			},
+		},
+		{
+			name: "not valid order column",
+			req: &GetManyRequest{
+				Order: &Order{
+					Column: utils.MakePointer("content"),
+					Type:   utils.MakePointer(query.ASC),
+				},
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotValid),
+		},
 		{
 			name:          "empty request",
 			req:           nil,
@@ -140,8 +187,8 @@ func TestClient_GetMany(t *testing.T) {
 			},
 			expectedError: tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(assert.AnError.Error())),
 			mockSetup: func() {
-				preparedQuery := query.New(QUERY_GET_FILES).Order("name", query.ASC)
-				preparedQuery.Where().EQ("folder_id", "folder_id")
+				preparedQuery := utils.NewListQuery(QUERY_GET_FILES).Where("deleted_at IS NULL").Where("folder_id = ?", "folder_id").
+					Order("name", utils.ORDER_ASC).Order("id", utils.ORDER_ASC)
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).
 					WillReturnError(assert.AnError)
 			},
@@ -154,15 +201,18 @@ func TestClient_GetMany(t *testing.T) {
 				tt.mockSetup()
 			}
 
-			files, err := client.GetMany(context.Background(), tt.req)
+			files, next, err := client.GetMany(context.Background(), tt.req)
 
 			if tt.expectedError != nil {
 				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
-				assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+				if tt.expectedError.GetMessage() != "" {
+					assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+				}
 				assert.Nil(t, files)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expectedFiles, files)
+				assert.Equal(t, tt.expectedNext, next)
 			}
 		})
 	}
@@ -188,7 +238,7 @@ func TestClient_CreateFile(t *testing.T) {
 			},
 			mockSetup: func() {
 				preparedQuery := query.New(QUERY_GET_FILES)
-				preparedQuery.Where().EQ("name", "file_name")
+				preparedQuery.Where().IS("deleted_at", nil).EQ("name", "file_name")
 				preparedQuery.Where().EQ("folder_id", "folder_id")
 
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).
@@ -217,23 +267,23 @@ func TestClient_CreateFile(t *testing.T) {
 			},
 			mockSetup: func() {
 				preparedQuery := query.New(QUERY_GET_FILES)
-				preparedQuery.Where().EQ("name", "file_name")
-				preparedQuery.Where().IS("folder_id", nil)
+				preparedQuery.Where().IS("deleted_at", nil).EQ("name", "file_name")
+				preparedQuery.Where().EQ("folder_id", folders.ROOT_ID)
 
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).
 					WillReturnRows(
 						sqlMock.NewRows([]string{"id", "name", "folder_id", "created_at", "updated_at"}),
 					)
 
-				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CREATE_FILE)).WithArgs(nil, "file_name").WillReturnRows(
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CREATE_FILE)).WithArgs(folders.ROOT_ID, "file_name").WillReturnRows(
 					sqlMock.NewRows([]string{"id", "name", "folder_id", "created_at", "updated_at"}).
-						AddRow("file_id", "file_name", "folder_id", "file_created_at", "file_updated_at"),
+						AddRow("file_id", "file_name", folders.ROOT_ID, "file_created_at", "file_updated_at"),
 				)
 			},
 			expectedFile: &File{
 				ID:        "file_id",
 				Name:      "file_name",
-				FolderID:  utils.MakePointer("folder_id"),
+				FolderID:  utils.MakePointer(folders.ROOT_ID),
 				CreatedAt: "file_created_at",
 				UpdatedAt: "file_updated_at",
 			},
@@ -259,7 +309,7 @@ func TestClient_CreateFile(t *testing.T) {
 			},
 			mockSetup: func() {
 				preparedQuery := query.New(QUERY_GET_FILES)
-				preparedQuery.Where().EQ("name", "file_name")
+				preparedQuery.Where().IS("deleted_at", nil).EQ("name", "file_name")
 				preparedQuery.Where().EQ("folder_id", "folder_id")
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).
 					WillReturnRows(
@@ -267,7 +317,7 @@ func TestClient_CreateFile(t *testing.T) {
 							AddRow("file_id", "file_name", "folder_id", "file_created_at", "file_updated_at"),
 					)
 			},
-			expectedError: tiny_errors.New(custom_errors.ERR_CODE_Exists, tiny_errors.Message("file already exists")),
+			expectedError: utils.ExistsError("file already exists"),
 		},
 		{
 			name: "file existence error",
@@ -277,7 +327,7 @@ func TestClient_CreateFile(t *testing.T) {
 			},
 			mockSetup: func() {
 				preparedQuery := query.New(QUERY_GET_FILES)
-				preparedQuery.Where().EQ("name", "file_name")
+				preparedQuery.Where().IS("deleted_at", nil).EQ("name", "file_name")
 				preparedQuery.Where().EQ("folder_id", "folder_id")
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).
 					WillReturnError(assert.AnError)
@@ -292,7 +342,7 @@ func TestClient_CreateFile(t *testing.T) {
 			},
 			mockSetup: func() {
 				preparedQuery := query.New(QUERY_GET_FILES)
-				preparedQuery.Where().EQ("name", "file_name")
+				preparedQuery.Where().IS("deleted_at", nil).EQ("name", "file_name")
 				preparedQuery.Where().EQ("folder_id", "folder_id")
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).
 					WillReturnRows(
@@ -402,6 +452,9 @@ func TestClient_EditFile(t *testing.T) {
 	mockDb, sqlMock := database_mock.NewSQlMock(t)
 	client := New(&database.Client{mockDb})
 
+	nameUpdate := utils.NewUpdateQuery("files").SetRaw("updated_at = now()").Set("name", "new_name").
+		Where("id = ?", "123").Where("deleted_at IS NULL").Returning(RETURNING_COLUMNS...)
+
 	tests := []struct {
 		name           string
 		req            *EditRequest
@@ -413,13 +466,13 @@ func TestClient_EditFile(t *testing.T) {
 			name: "success",
 			req: &EditRequest{
 				FileID: "123",
-				Name:   "new_name",
+				Name:   utils.MakePointer("new_name"),
 			},
 			mockSetup: func() {
 				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CHECK_FILE_EXISTS_BY_FOLDER_ID_AND_NAME)).WithArgs("new_name", "123").WillReturnRows(
 					sqlMock.NewRows([]string{"exists"}).AddRow(false),
 				)
-				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_UPDATE_FILE)).WithArgs("new_name", "123").WillReturnRows(
+				sqlMock.ExpectQuery(regexp.QuoteMeta(nameUpdate.String())).WithArgs("new_name", "123").WillReturnRows(
 					sqlMock.NewRows([]string{"id", "name", "folder_id", "created_at", "updated_at"}).
 						AddRow("123", "new_name", nil, "2020-01-01T00:00:00Z", "2020-02-01T00:00:00Z"),
 				)
@@ -444,13 +497,13 @@ func TestClient_EditFile(t *testing.T) {
 			name: "sql error",
 			req: &EditRequest{
 				FileID: "123",
-				Name:   "new_name",
+				Name:   utils.MakePointer("new_name"),
 			},
 			mockSetup: func() {
 				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CHECK_FILE_EXISTS_BY_FOLDER_ID_AND_NAME)).WithArgs("new_name", "123").WillReturnRows(
 					sqlMock.NewRows([]string{"exists"}).AddRow(false),
 				)
-				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_UPDATE_FILE)).WithArgs("new_name", "123").WillReturnError(
+				sqlMock.ExpectQuery(regexp.QuoteMeta(nameUpdate.String())).WithArgs("new_name", "123").WillReturnError(
 					assert.AnError,
 				)
 			},
@@ -461,13 +514,13 @@ func TestClient_EditFile(t *testing.T) {
 			name: "not found",
 			req: &EditRequest{
 				FileID: "123",
-				Name:   "new_name",
+				Name:   utils.MakePointer("new_name"),
 			},
 			mockSetup: func() {
 				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CHECK_FILE_EXISTS_BY_FOLDER_ID_AND_NAME)).WithArgs("new_name", "123").WillReturnRows(
 					sqlMock.NewRows([]string{"exists"}).AddRow(false),
 				)
-				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_UPDATE_FILE)).WithArgs("new_name", "123").WillReturnError(
+				sqlMock.ExpectQuery(regexp.QuoteMeta(nameUpdate.String())).WithArgs("new_name", "123").WillReturnError(
 					sql.ErrNoRows,
 				)
 			},
@@ -478,7 +531,7 @@ func TestClient_EditFile(t *testing.T) {
 			name: "found the same name in the same folder",
 			req: &EditRequest{
 				FileID: "123",
-				Name:   "new_name",
+				Name:   utils.MakePointer("new_name"),
 			},
 			mockSetup: func() {
 				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CHECK_FILE_EXISTS_BY_FOLDER_ID_AND_NAME)).WithArgs("new_name", "123").WillReturnRows(
@@ -486,19 +539,55 @@ func TestClient_EditFile(t *testing.T) {
 				)
 			},
 			expectedFolder: nil,
-			expectedError:  tiny_errors.New(custom_errors.ERR_CODE_Exists, tiny_errors.Message("file with such name already exists")),
+			expectedError:  utils.ExistsError("file with such name already exists"),
+		},
+		{
+			name: "success with description",
+			req: &EditRequest{
+				FileID:   "123",
+				Metadata: &utils.MetadataUpdate{Description: utils.MakePointer("database settings")},
+			},
+			mockSetup: func() {
+				q := utils.NewUpdateQuery("files").SetRaw("updated_at = now()").Set("description", "database settings").
+					Where("id = ?", "123").Where("deleted_at IS NULL").Returning(RETURNING_COLUMNS...)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(q.String())).WithArgs("database settings", "123").WillReturnRows(
+					sqlMock.NewRows([]string{"id", "name", "folder_id", "description", "labels", "created_at", "updated_at"}).
+						AddRow("123", "name", nil, "database settings", []byte("{}"), "2020-01-01T00:00:00Z", "2020-02-01T00:00:00Z"),
+				)
+			},
+			expectedFolder: &File{
+				ID:   "123",
+				Name: "name",
+				Metadata: utils.Metadata{
+					Description: utils.MakePointer("database settings"),
+					Labels:      utils.Labels{},
+				},
+				CreatedAt: "2020-01-01T00:00:00Z",
+				UpdatedAt: "2020-02-01T00:00:00Z",
+			},
+			expectedError: nil,
 		},
 		{
-			name: "empty id or name",
+			name: "empty id",
 			req: &EditRequest{
 				FileID: "",
-				Name:   "",
+				Name:   utils.MakePointer("new_name"),
 			},
 			mockSetup:      func() {},
 			expectedFolder: nil,
 			expectedError: tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD,
 				tiny_errors.Detail("file_id", "required"),
-				tiny_errors.Detail("name", "required"),
+			),
+		},
+		{
+			name: "nothing to update",
+			req: &EditRequest{
+				FileID: "123",
+			},
+			mockSetup:      func() {},
+			expectedFolder: nil,
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD,
+				tiny_errors.Detail("name or metadata", "required"),
 			),
 		},
 	}
@@ -547,7 +636,7 @@ func TestClient_GetFile(t *testing.T) {
 					UpdatedAt: "2020-02-01T00:00:00Z",
 				}
 				preparedQuery := query.New(QUERY_GET_FILES)
-				preparedQuery.Where().EQ("id", "root_id")
+				preparedQuery.Where().IS("deleted_at", nil).EQ("id", "root_id")
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnRows(
 					sqlMock.NewRows([]string{"id", "name", "folder_id", "created_at", "updated_at"}).AddRow(
 						expectedFile.ID,
@@ -581,7 +670,7 @@ func TestClient_GetFile(t *testing.T) {
 			},
 			mockSetup: func() {
 				preparedQuery := query.New(QUERY_GET_FILES)
-				preparedQuery.Where().EQ("id", "root_id")
+				preparedQuery.Where().IS("deleted_at", nil).EQ("id", "root_id")
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnError(assert.AnError)
 			},
 			expectedFile:  nil,
@@ -603,3 +692,66 @@ func TestClient_GetFile(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_GetNested(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb})
+
+	tests := []struct {
+		name          string
+		req           *GetNestedRequest
+		mockSetup     func()
+		expectedFiles []*File
+		expectedError tiny_errors.ErrorHandler
+	}{
+		{
+			name: "success",
+			req: &GetNestedRequest{
+				FolderID: "folder_id",
+			},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_NESTED_FILES)).WithArgs("folder_id").WillReturnRows(
+					sqlMock.NewRows([]string{"id", "name", "folder_id"}).
+						AddRow("file_1", "name_1", "folder_id").
+						AddRow("file_2", "name_2", "nested_id"),
+				)
+			},
+			expectedFiles: []*File{
+				{ID: "file_1", Name: "name_1", FolderID: utils.MakePointer("folder_id")},
+				{ID: "file_2", Name: "name_2", FolderID: utils.MakePointer("nested_id")},
+			},
+		},
+		{
+			name:          "empty request",
+			req:           nil,
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_BodyRequired, tiny_errors.Message("body required")),
+		},
+		{
+			name: "sql error",
+			req: &GetNestedRequest{
+				FolderID: "folder_id",
+			},
+			mockSetup: func() {
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_NESTED_FILES)).WithArgs("folder_id").WillReturnError(assert.AnError)
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(assert.AnError.Error())),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			files, err := client.GetNested(context.Background(), tt.req)
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
+				assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedFiles, files)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
@@ -1,27 +1,58 @@
 package files
 
+import "github.com/Moranilt/config-keeper/utils"
+
 const (
-	QUERY_GET_FILES                               = "SELECT id, folder_id, name, created_at, updated_at FROM files"
-	QUERY_CREATE_FILE                             = "INSERT INTO files (folder_id, name) VALUES ($1, $2) RETURNING id, folder_id, name, created_at, updated_at"
-	QUERY_FILE_EXISTS                             = "SELECT EXISTS(SELECT 1 FROM files WHERE id=$1)"
-	QUERY_DELETE_FILE                             = "DELETE FROM files WHERE id=$1"
+	QUERY_GET_FILES                               = "SELECT id, folder_id, name, description, owner_team, contact, labels, created_at, updated_at FROM files"
+	QUERY_CREATE_FILE                             = "INSERT INTO files (folder_id, name) VALUES ($1, $2) RETURNING id, folder_id, name, description, owner_team, contact, labels, created_at, updated_at"
+	QUERY_FILE_EXISTS                             = "SELECT EXISTS(SELECT 1 FROM files WHERE id=$1 AND deleted_at IS NULL)"
+	QUERY_DELETE_FILE                             = "UPDATE files SET deleted_at = now() WHERE id=$1 AND deleted_at IS NULL"
 	QUERY_CHECK_FILE_EXISTS_BY_FOLDER_ID_AND_NAME = `SELECT EXISTS(
 		SELECT 1 FROM files 
 		WHERE name = $1
+		AND deleted_at IS NULL
 		AND (
 				(folder_id IS NULL AND (SELECT folder_id FROM files WHERE id = $2) is NULL)
 				OR
 				(folder_id = (SELECT folder_id FROM files WHERE id = $2))
 		))`
-	QUERY_UPDATE_FILE = "UPDATE files SET name = $1, updated_at = now() WHERE id = $2 RETURNING id, folder_id, name, created_at, updated_at"
+	// QUERY_GET_NESTED_FILES selects files of the folder and of all its nested folders which are not in the trash.
+	QUERY_GET_NESTED_FILES = `WITH RECURSIVE subtree AS (
+		SELECT id FROM folders WHERE id = $1 AND deleted_at IS NULL
+		UNION ALL
+		SELECT f.id FROM folders f JOIN subtree s ON f.parent_id = s.id WHERE f.deleted_at IS NULL
+	)
+	SELECT id, folder_id, name, description, owner_team, contact, labels, created_at, updated_at FROM files
+	WHERE folder_id IN (SELECT id FROM subtree) AND deleted_at IS NULL ORDER BY id`
+)
+
+var (
+	// RETURNING_COLUMNS is a list of columns returned after file update.
+	RETURNING_COLUMNS = []string{"id", "folder_id", "name", "description", "owner_team", "contact", "labels", "created_at", "updated_at"}
+
+	// ORDER_COLUMNS is a list of columns allowed for sorting files.
+	ORDER_COLUMNS = map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	}
+
+	LIST_COLUMNS = utils.ListColumns{
+		ID:        "id",
+		Name:      "name",
+		CreatedAt: "created_at",
+		UpdatedAt: "updated_at",
+	}
 )
 
 type File struct {
-	ID        string  `json:"id" db:"id"`
-	FolderID  *string `json:"folder_id" db:"folder_id"`
-	Name      string  `json:"name" db:"name"`
-	CreatedAt string  `json:"created_at" db:"created_at"`
-	UpdatedAt string  `json:"updated_at" db:"updated_at"`
+	ID       string  `json:"id" db:"id"`
+	FolderID *string `json:"folder_id" db:"folder_id"`
+	Name     string  `json:"name" db:"name"`
+	utils.Metadata
+	CreatedAt string `json:"created_at" db:"created_at"`
+	UpdatedAt string `json:"updated_at" db:"updated_at"`
 }
 
 type Order struct {
@@ -32,6 +63,8 @@ type Order struct {
 type GetManyRequest struct {
 	FolderID *string
 	Order    *Order
+	Filter   *utils.ListFilter
+	Metadata *utils.MetadataFilter
 }
 
 type CreateRequest struct {
@@ -43,11 +76,30 @@ type DeleteRequest struct {
 	ID string
 }
 
+// EditRequest changes only provided fields.
 type EditRequest struct {
-	Name   string
-	FileID string
+	Name     *string
+	FileID   string
+	Metadata *utils.MetadataUpdate
 }
 
 type GetRequest struct {
 	ID string
 }
+
+type GetNestedRequest struct {
+	FolderID string
+}
+
+func (f *File) columnValue(column string) string {
+	switch column {
+	case "id":
+		return f.ID
+	case "created_at":
+		return f.CreatedAt
+	case "updated_at":
+		return f.UpdatedAt
+	default:
+		return f.Name
+	}
+}
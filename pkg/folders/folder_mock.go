@@ -45,14 +45,15 @@ func (m *MockClient) Get(ctx context.Context, req *GetRequest) (*FolderWithPath,
 	return nil, err.(tiny_errors.ErrorHandler)
 }
 
-func (m *MockClient) GetMany(ctx context.Context, req *GetManyRequest) ([]*Folder, tiny_errors.ErrorHandler) {
+func (m *MockClient) GetMany(ctx context.Context, req *GetManyRequest) ([]*Folder, *string, tiny_errors.ErrorHandler) {
 	args := m.Called(ctx, req)
 	folders := args.Get(0)
-	err := args.Get(1)
+	next, _ := args.Get(1).(*string)
+	err := args.Get(2)
 	if err == nil {
-		return folders.([]*Folder), nil
+		return folders.([]*Folder), next, nil
 	}
-	return nil, err.(tiny_errors.ErrorHandler)
+	return nil, nil, err.(tiny_errors.ErrorHandler)
 }
 
 func (m *MockClient) Delete(ctx context.Context, req *DeleteRequest) (bool, tiny_errors.ErrorHandler) {
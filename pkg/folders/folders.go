@@ -4,9 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"net/http"
-	"strings"
 
 	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/transaction"
+	"github.com/Moranilt/config-keeper/utils"
 	"github.com/Moranilt/http-utils/clients/database"
 	"github.com/Moranilt/http-utils/query"
 	"github.com/Moranilt/http-utils/tiny_errors"
@@ -26,13 +27,13 @@ type Client interface {
 	// Get retrieves a folder with its path.
 	Get(ctx context.Context, req *GetRequest) (*FolderWithPath, tiny_errors.ErrorHandler)
 
-	// GetMany retrieves multiple folders.
-	GetMany(ctx context.Context, req *GetManyRequest) ([]*Folder, tiny_errors.ErrorHandler)
+	// GetMany retrieves multiple folders. Returns cursor of the next page if there are more folders.
+	GetMany(ctx context.Context, req *GetManyRequest) ([]*Folder, *string, tiny_errors.ErrorHandler)
 
-	// Delete removes a folder.
+	// Delete moves a folder with all nested folders and files to the trash.
 	Delete(ctx context.Context, req *DeleteRequest) (bool, tiny_errors.ErrorHandler)
 
-	// Edit modifies an existing folder.
+	// Edit modifies name and metadata of an existing folder.
 	Edit(ctx context.Context, req *EditRequest) (*Folder, tiny_errors.ErrorHandler)
 }
 
@@ -49,9 +50,9 @@ func (c *client) Create(ctx context.Context, req *CreateRequest) (*Folder, tiny_
 	}
 
 	var folder Folder
-	err := c.db.QueryRowxContext(ctx, QUERY_INSERT_FOLDER, req.Name, req.ParentID).StructScan(&folder)
+	err := transaction.From(ctx, c.db).QueryRowxContext(ctx, QUERY_INSERT_FOLDER, req.Name, ResolveParentID(req.ParentID)).StructScan(&folder)
 	if err != nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		return nil, utils.DatabaseError(err, "folder with such name already exists")
 	}
 
 	return &folder, nil
@@ -63,18 +64,14 @@ func (c *client) Exists(ctx context.Context, req *ExistsRequest) (bool, tiny_err
 	}
 
 	preparedQuery := query.New(QUERY_DEFAULT_SELECT_FOLDERS_ID)
-	if req.ParentID == nil {
-		preparedQuery.Where().IS("parent_id", nil)
-	} else {
-		preparedQuery.Where().EQ("parent_id", req.ParentID)
-	}
+	preparedQuery.Where().IS("deleted_at", nil).EQ("parent_id", ResolveParentID(req.ParentID))
 
 	if req.Name != nil {
 		preparedQuery.Where().EQ("name", req.Name)
 	}
 
 	var id string
-	err := c.db.QueryRowxContext(ctx, preparedQuery.String()).Scan(&id)
+	err := transaction.From(ctx, c.db).QueryRowxContext(ctx, preparedQuery.String()).Scan(&id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil
@@ -90,10 +87,10 @@ func (c *client) Get(ctx context.Context, req *GetRequest) (*FolderWithPath, tin
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
 	}
 
-	preparedQuery := query.New(QUERY_GET_FOLDER_WITH_PATH).Where().EQ("id", req.ID).Query()
+	preparedQuery := query.New(QUERY_GET_FOLDER_WITH_PATH).Where().EQ("id", ResolveID(req.ID)).Query()
 
 	var folder FolderWithPath
-	err := c.db.GetContext(ctx, &folder, preparedQuery.String())
+	err := transaction.From(ctx, c.db).GetContext(ctx, &folder, preparedQuery.String())
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.HTTPStatus(http.StatusNotFound))
@@ -104,36 +101,44 @@ func (c *client) Get(ctx context.Context, req *GetRequest) (*FolderWithPath, tin
 	return &folder, nil
 }
 
-func (c *client) GetMany(ctx context.Context, req *GetManyRequest) ([]*Folder, tiny_errors.ErrorHandler) {
+func (c *client) GetMany(ctx context.Context, req *GetManyRequest) ([]*Folder, *string, tiny_errors.ErrorHandler) {
 	if req == nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+		return nil, nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
 	}
 
-	preparedQuery := query.New(QUERY_GET_FOLDERS)
-	if req.Order != nil && req.Order.Column != nil && req.Order.Type != nil {
-		order := query.ASC
-		orderType := strings.ToUpper(*req.Order.Type)
-		if orderType == query.DESC || orderType == query.ASC {
-			order = orderType
-		}
-		preparedQuery = preparedQuery.Order(*req.Order.Column, order)
-	} else {
-		preparedQuery = preparedQuery.Order("name", query.ASC)
+	var orderColumn *string
+	var orderType *string
+	if req.Order != nil {
+		orderColumn = req.Order.Column
+		orderType = req.Order.Type
 	}
 
-	if req.ParentID == nil {
-		preparedQuery.Where().IS("parent_id", nil)
-	} else {
-		preparedQuery.Where().EQ("parent_id", req.ParentID)
+	column, orderExpression, err := utils.OrderColumn(orderColumn, ORDER_COLUMNS, "name")
+	if err != nil {
+		return nil, nil, err
 	}
+	order := utils.OrderType(orderType)
 
-	folders := make([]*Folder, 0)
-	err := c.db.SelectContext(ctx, &folders, preparedQuery.String())
+	preparedQuery := utils.NewListQuery(QUERY_GET_FOLDERS).Where("deleted_at IS NULL").
+		Where("parent_id = ?", ResolveParentID(req.ParentID))
+	req.Metadata.Apply(preparedQuery)
+
+	err = req.Filter.Apply(preparedQuery, LIST_COLUMNS, orderExpression, order)
 	if err != nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		return nil, nil, err
 	}
 
-	return folders, nil
+	folders := make([]*Folder, 0)
+	dbErr := transaction.From(ctx, c.db).SelectContext(ctx, &folders, preparedQuery.String(), preparedQuery.Args()...)
+	if dbErr != nil {
+		return nil, nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(dbErr.Error()))
+	}
+
+	folders, next := utils.NextCursor(req.Filter, folders, func(folder *Folder) (string, string) {
+		return folder.columnValue(column), folder.ID
+	})
+
+	return folders, next, nil
 }
 
 func (c *client) Delete(ctx context.Context, req *DeleteRequest) (bool, tiny_errors.ErrorHandler) {
@@ -141,7 +146,11 @@ func (c *client) Delete(ctx context.Context, req *DeleteRequest) (bool, tiny_err
 		return false, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
 	}
 
-	result, err := c.db.ExecContext(ctx, QUERY_DELETE_FOLDER, req.ID)
+	if ResolveID(req.ID) == ROOT_ID {
+		return false, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("root folder can not be deleted"))
+	}
+
+	result, err := transaction.From(ctx, c.db).ExecContext(ctx, QUERY_DELETE_FOLDER, req.ID)
 	if err != nil {
 		return false, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
 	}
@@ -163,36 +172,58 @@ func (c *client) Edit(ctx context.Context, req *EditRequest) (*Folder, tiny_erro
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
 	}
 
-	if req.ID == "" || req.Name == "" {
-		return nil, tiny_errors.New(
-			custom_errors.ERR_CODE_NotValid,
-			tiny_errors.Detail("id", "required"),
-			tiny_errors.Detail("name", "required"),
-		)
+	if req.ID == "" {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("id", "required"))
 	}
 
-	var exists bool
-	err := c.db.QueryRowxContext(
-		ctx,
-		QUERY_CHECK_FOLDER_EXISTS_BY_PARENT_ID_AND_NAME,
-		req.Name,
-		req.ID,
-	).Scan(&exists)
-	if err != nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	if req.Name == nil && req.Metadata.IsEmpty() {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, tiny_errors.Detail("name or metadata", "required"))
 	}
 
-	if exists {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Exists, tiny_errors.Message("folder with such name already exists"))
+	if err := req.Metadata.Validate(); err != nil {
+		return nil, err
 	}
 
+	updateQuery := utils.NewUpdateQuery("folders").SetRaw("updated_at = now()")
+
+	id := ResolveID(req.ID)
+	if req.Name != nil {
+		if *req.Name == "" {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("name", "should not be empty"))
+		}
+
+		if id == ROOT_ID {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("root folder can not be renamed"))
+		}
+
+		var exists bool
+		err := transaction.From(ctx, c.db).QueryRowxContext(
+			ctx,
+			QUERY_CHECK_FOLDER_EXISTS_BY_PARENT_ID_AND_NAME,
+			*req.Name,
+			id,
+		).Scan(&exists)
+		if err != nil {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		}
+
+		if exists {
+			return nil, utils.ExistsError("folder with such name already exists")
+		}
+
+		updateQuery.Set("name", *req.Name)
+	}
+
+	req.Metadata.Apply(updateQuery)
+	updateQuery.Where("id = ?", id).Where("deleted_at IS NULL").Returning(RETURNING_COLUMNS...)
+
 	var folder Folder
-	err = c.db.QueryRowxContext(ctx, QUERY_UPDATE_FOLDER, req.Name, req.ID).StructScan(&folder)
+	err := transaction.From(ctx, c.db).QueryRowxContext(ctx, updateQuery.String(), updateQuery.Args()...).StructScan(&folder)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.HTTPStatus(http.StatusNotFound))
 		}
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		return nil, utils.DatabaseError(err, "folder with such name already exists")
 	}
 
 	return &folder, nil
@@ -112,7 +112,7 @@ func TestClient_ExistsFolder(t *testing.T) {
 			},
 			setupMock: func() {
 				preparedQuery := query.New(QUERY_DEFAULT_SELECT_FOLDERS_ID)
-				preparedQuery.Where().EQ("parent_id", utils.MakePointer("folder_parent_id")).EQ("name", utils.MakePointer("folder_name"))
+				preparedQuery.Where().IS("deleted_at", nil).EQ("parent_id", utils.MakePointer("folder_parent_id")).EQ("name", utils.MakePointer("folder_name"))
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnRows(
 					sqlMock.NewRows([]string{"id"}).
 						AddRow("folder_id"),
@@ -129,7 +129,7 @@ func TestClient_ExistsFolder(t *testing.T) {
 			},
 			setupMock: func() {
 				preparedQuery := query.New(QUERY_DEFAULT_SELECT_FOLDERS_ID)
-				preparedQuery.Where().IS("parent_id", nil).EQ("name", utils.MakePointer("folder_name"))
+				preparedQuery.Where().IS("deleted_at", nil).EQ("parent_id", ROOT_ID).EQ("name", utils.MakePointer("folder_name"))
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnRows(
 					sqlMock.NewRows([]string{"id"}).
 						AddRow("folder_id"),
@@ -153,7 +153,7 @@ func TestClient_ExistsFolder(t *testing.T) {
 			},
 			setupMock: func() {
 				preparedQuery := query.New(QUERY_DEFAULT_SELECT_FOLDERS_ID)
-				preparedQuery.Where().EQ("parent_id", utils.MakePointer("folder_parent_id")).EQ("name", utils.MakePointer("folder_name"))
+				preparedQuery.Where().IS("deleted_at", nil).EQ("parent_id", utils.MakePointer("folder_parent_id")).EQ("name", utils.MakePointer("folder_name"))
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnError(
 					assert.AnError,
 				)
@@ -169,7 +169,7 @@ func TestClient_ExistsFolder(t *testing.T) {
 			},
 			setupMock: func() {
 				preparedQuery := query.New(QUERY_DEFAULT_SELECT_FOLDERS_ID)
-				preparedQuery.Where().EQ("parent_id", utils.MakePointer("folder_parent_id")).EQ("name", utils.MakePointer("folder_name"))
+				preparedQuery.Where().IS("deleted_at", nil).EQ("parent_id", utils.MakePointer("folder_parent_id")).EQ("name", utils.MakePointer("folder_name"))
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnRows(
 					sqlMock.NewRows([]string{"id"}),
 				)
@@ -284,10 +284,34 @@ func TestClient_GetFolders(t *testing.T) {
 	mockDb, sqlMock := database_mock.NewSQlMock(t)
 	client := New(&database.Client{mockDb})
 
+	folderRows := func() *sqlmock.Rows {
+		return sqlMock.NewRows([]string{"id", "name", "parent_id", "created_at", "updated_at"}).
+			AddRow("folder_id_1", "folder_name_1", nil, "2020-01-01T00:00:00Z", "2020-02-01T00:00:00Z").
+			AddRow("folder_id_2", "folder_name_2", nil, "2020-01-01T00:00:00Z", "2020-02-01T00:00:00Z")
+	}
+
+	expectedFolders := []*Folder{
+		{
+			ID:        "folder_id_1",
+			Name:      "folder_name_1",
+			ParentID:  nil,
+			CreatedAt: "2020-01-01T00:00:00Z",
+			UpdatedAt: "2020-02-01T00:00:00Z",
+		},
+		{
+			ID:        "folder_id_2",
+			Name:      "folder_name_2",
+			ParentID:  nil,
+			CreatedAt: "2020-01-01T00:00:00Z",
+			UpdatedAt: "2020-02-01T00:00:00Z",
+		},
+	}
+
 	tests := []struct {
 		name           string
 		req            *GetManyRequest
 		expectedResult []*Folder
+		expectedNext   *string
 		expectedError  tiny_errors.ErrorHandler
 		mockSetup      func()
 	}{
@@ -296,30 +320,11 @@ func TestClient_GetFolders(t *testing.T) {
 			req: &GetManyRequest{
 				ParentID: nil,
 			},
-			expectedResult: []*Folder{
-				{
-					ID:        "folder_id_1",
-					Name:      "folder_name_1",
-					ParentID:  nil,
-					CreatedAt: "2020-01-01T00:00:00Z",
-					UpdatedAt: "2020-02-01T00:00:00Z",
-				},
-				{
-					ID:        "folder_id_2",
-					Name:      "folder_name_2",
-					ParentID:  nil,
-					CreatedAt: "2020-01-01T00:00:00Z",
-					UpdatedAt: "2020-02-01T00:00:00Z",
-				},
-			},
+			expectedResult: expectedFolders,
 			mockSetup: func() {
-				preparedQuery := query.New(QUERY_GET_FOLDERS).Order("name", query.ASC)
-				preparedQuery.Where().IS("parent_id", nil)
-				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnRows(
-					sqlMock.NewRows([]string{"id", "name", "parent_id", "created_at", "updated_at"}).
-						AddRow("folder_id_1", "folder_name_1", nil, "2020-01-01T00:00:00Z", "2020-02-01T00:00:00Z").
-						AddRow("folder_id_2", "folder_name_2", nil, "2020-01-01T00:00:00Z", "2020-02-01T00:00:00Z"),
-				)
+				preparedQuery := utils.NewListQuery(QUERY_GET_FOLDERS).Where("deleted_at IS NULL").Where("parent_id = ?", ROOT_ID).
+					Order("name", utils.ORDER_ASC).Order("id", utils.ORDER_ASC)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnRows(folderRows())
 			},
 		},
 		{
@@ -327,30 +332,11 @@ func TestClient_GetFolders(t *testing.T) {
 			req: &GetManyRequest{
 				ParentID: utils.MakePointer("123"),
 			},
-			expectedResult: []*Folder{
-				{
-					ID:        "folder_id_1",
-					Name:      "folder_name_1",
-					ParentID:  nil,
-					CreatedAt: "2020-01-01T00:00:00Z",
-					UpdatedAt: "2020-02-01T00:00:00Z",
-				},
-				{
-					ID:        "folder_id_2",
-					Name:      "folder_name_2",
-					ParentID:  nil,
-					CreatedAt: "2020-01-01T00:00:00Z",
-					UpdatedAt: "2020-02-01T00:00:00Z",
-				},
-			},
+			expectedResult: expectedFolders,
 			mockSetup: func() {
-				preparedQuery := query.New(QUERY_GET_FOLDERS).Order("name", query.ASC)
-				preparedQuery.Where().EQ("parent_id", "123")
-				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnRows(
-					sqlMock.NewRows([]string{"id", "name", "parent_id", "created_at", "updated_at"}).
-						AddRow("folder_id_1", "folder_name_1", nil, "2020-01-01T00:00:00Z", "2020-02-01T00:00:00Z").
-						AddRow("folder_id_2", "folder_name_2", nil, "2020-01-01T00:00:00Z", "2020-02-01T00:00:00Z"),
-				)
+				preparedQuery := utils.NewListQuery(QUERY_GET_FOLDERS).Where("deleted_at IS NULL").Where("parent_id = ?", "123").
+					Order("name", utils.ORDER_ASC).Order("id", utils.ORDER_ASC)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WithArgs("123").WillReturnRows(folderRows())
 			},
 		},
 		{
@@ -362,31 +348,73 @@ func TestClient_GetFolders(t *testing.T) {
 					Type:   utils.MakePointer(query.DESC),
 				},
 			},
-			expectedResult: []*Folder{
-				{
-					ID:        "folder_id_1",
-					Name:      "folder_name_1",
-					ParentID:  nil,
-					CreatedAt: "2020-01-01T00:00:00Z",
-					UpdatedAt: "2020-02-01T00:00:00Z",
+			expectedResult: expectedFolders,
+			mockSetup: func() {
+				preparedQuery := utils.NewListQuery(QUERY_GET_FOLDERS).Where("deleted_at IS NULL").Where("parent_id = ?", "123").
+					Order("created_at", utils.ORDER_DESC).Order("id", utils.ORDER_DESC)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WithArgs("123").WillReturnRows(folderRows())
+			},
+		},
+		{
+			name: "success with limit, filters and next cursor",
+			req: &GetManyRequest{
+				ParentID: utils.MakePointer("123"),
+				Filter: &utils.ListFilter{
+					Limit:        utils.MakePointer(1),
+					NamePrefix:   utils.MakePointer("folder_"),
+					CreatedAfter: utils.MakePointer("2019-01-01T00:00:00Z"),
 				},
-				{
-					ID:        "folder_id_2",
-					Name:      "folder_name_2",
-					ParentID:  nil,
-					CreatedAt: "2020-01-01T00:00:00Z",
-					UpdatedAt: "2020-02-01T00:00:00Z",
+			},
+			expectedResult: expectedFolders[:1],
+			expectedNext:   utils.MakePointer(utils.EncodeCursor("name ASC", "folder_name_1", "folder_id_1")),
+			mockSetup: func() {
+				preparedQuery := utils.NewListQuery(QUERY_GET_FOLDERS).Where("deleted_at IS NULL").Where("parent_id = ?", "123").
+					Where("name LIKE ?", "folder\\_%").
+					Where("created_at >= ?", "2019-01-01T00:00:00Z").
+					Order("name", utils.ORDER_ASC).Order("id", utils.ORDER_ASC).
+					Limit(2)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).
+					WithArgs("123", "folder\\_%", "2019-01-01T00:00:00Z").
+					WillReturnRows(folderRows())
+			},
+		},
+		{
+			name: "success with cursor",
+			req: &GetManyRequest{
+				ParentID: utils.MakePointer("123"),
+				Filter: &utils.ListFilter{
+					Limit:  utils.MakePointer(2),
+					Cursor: utils.MakePointer(utils.EncodeCursor("name ASC", "folder_name_0", "folder_id_0")),
 				},
 			},
+			expectedResult: expectedFolders,
 			mockSetup: func() {
-				preparedQuery := query.New(QUERY_GET_FOLDERS).Order("created_at", query.DESC)
-				preparedQuery.Where().EQ("parent_id", "123")
-				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnRows(
-					sqlMock.NewRows([]string{"id", "name", "parent_id", "created_at", "updated_at"}).
-						AddRow("folder_id_1", "folder_name_1", nil, "2020-01-01T00:00:00Z", "2020-02-01T00:00:00Z").
-						AddRow("folder_id_2", "folder_name_2", nil, "2020-01-01T00:00:00Z", "2020-02-01T00:00:00Z"),
-				)
+				preparedQuery := utils.NewListQuery(QUERY_GET_FOLDERS).Where("deleted_at IS NULL").Where("parent_id = ?", "123").
+					Where("(name, id) > (?, ?)", "folder_name_0", "folder_id_0").
+					Order("name", utils.ORDER_ASC).Order("id", utils.ORDER_ASC).
+					Limit(3)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).
+					WithArgs("123", "folder_name_0", "folder_id_0").
+					WillReturnRows(folderRows())
+			},
+		},
+		{
+			name: "not valid order column",
+			req: &GetManyRequest{
+				Order: &Order{
+					Column: utils.MakePointer("name; DROP TABLE folders"),
+				},
 			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotValid),
+		},
+		{
+			name: "not valid cursor",
+			req: &GetManyRequest{
+				Filter: &utils.ListFilter{
+					Cursor: utils.MakePointer("not-a-cursor"),
+				},
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotValid),
 		},
 		{
 			name:           "empty request",
@@ -402,8 +430,8 @@ func TestClient_GetFolders(t *testing.T) {
 			expectedResult: nil,
 			expectedError:  tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(assert.AnError.Error())),
 			mockSetup: func() {
-				preparedQuery := query.New(QUERY_GET_FOLDERS)
-				preparedQuery.Where().EQ("parent_id", "123")
+				preparedQuery := utils.NewListQuery(QUERY_GET_FOLDERS).Where("deleted_at IS NULL").Where("parent_id = ?", "123").
+					Order("name", utils.ORDER_ASC).Order("id", utils.ORDER_ASC)
 				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnError(assert.AnError)
 			},
 		},
@@ -415,16 +443,18 @@ func TestClient_GetFolders(t *testing.T) {
 				tt.mockSetup()
 			}
 
-			folders, err := client.GetMany(context.Background(), tt.req)
+			folders, next, err := client.GetMany(context.Background(), tt.req)
 
 			if tt.expectedError != nil {
 				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
-				assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+				if tt.expectedError.GetMessage() != "" {
+					assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+				}
 				assert.Nil(t, folders)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expectedResult, folders)
-				assert.Equal(t, len(tt.expectedResult), len(folders))
+				assert.Equal(t, tt.expectedNext, next)
 			}
 		})
 	}
@@ -466,6 +496,13 @@ func TestClient_Delete(t *testing.T) {
 			mockSetup:      func() {},
 			expectedResult: false,
 			expectedError:  tiny_errors.New(custom_errors.ERR_CODE_BodyRequired, tiny_errors.Message("body required"))},
+		{
+			name:           "root folder",
+			req:            &DeleteRequest{ID: ROOT_ALIAS},
+			mockSetup:      func() {},
+			expectedResult: false,
+			expectedError:  tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("root folder can not be deleted")),
+		},
 		{
 			name: "sql error",
 			req:  &DeleteRequest{ID: "123"},
@@ -497,6 +534,16 @@ func TestClient_EditFolder(t *testing.T) {
 	mockDb, sqlMock := database_mock.NewSQlMock(t)
 	client := New(&database.Client{mockDb})
 
+	updateQuery := func(name *string, metadata *utils.MetadataUpdate) *utils.UpdateQuery {
+		q := utils.NewUpdateQuery("folders").SetRaw("updated_at = now()")
+		if name != nil {
+			q.Set("name", *name)
+		}
+		metadata.Apply(q)
+		return q.Where("id = ?", "123").Where("deleted_at IS NULL").Returning(RETURNING_COLUMNS...)
+	}
+	nameUpdate := updateQuery(utils.MakePointer("new_name"), nil)
+
 	tests := []struct {
 		name           string
 		req            *EditRequest
@@ -508,13 +555,13 @@ func TestClient_EditFolder(t *testing.T) {
 			name: "success",
 			req: &EditRequest{
 				ID:   "123",
-				Name: "new_name",
+				Name: utils.MakePointer("new_name"),
 			},
 			mockSetup: func() {
 				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CHECK_FOLDER_EXISTS_BY_PARENT_ID_AND_NAME)).WithArgs("new_name", "123").WillReturnRows(
 					sqlMock.NewRows([]string{"exists"}).AddRow(false),
 				)
-				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_UPDATE_FOLDER)).WithArgs("new_name", "123").WillReturnRows(
+				sqlMock.ExpectQuery(regexp.QuoteMeta(nameUpdate.String())).WithArgs("new_name", "123").WillReturnRows(
 					sqlMock.NewRows([]string{"id", "name", "parent_id", "created_at", "updated_at"}).
 						AddRow("123", "new_name", nil, "2020-01-01T00:00:00Z", "2020-02-01T00:00:00Z"),
 				)
@@ -539,13 +586,13 @@ func TestClient_EditFolder(t *testing.T) {
 			name: "sql error",
 			req: &EditRequest{
 				ID:   "123",
-				Name: "new_name",
+				Name: utils.MakePointer("new_name"),
 			},
 			mockSetup: func() {
 				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CHECK_FOLDER_EXISTS_BY_PARENT_ID_AND_NAME)).WithArgs("new_name", "123").WillReturnRows(
 					sqlMock.NewRows([]string{"exists"}).AddRow(false),
 				)
-				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_UPDATE_FOLDER)).WithArgs("new_name", "123").WillReturnError(assert.AnError)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(nameUpdate.String())).WithArgs("new_name", "123").WillReturnError(assert.AnError)
 			},
 			expectedFolder: nil,
 			expectedError:  tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(assert.AnError.Error())),
@@ -554,13 +601,13 @@ func TestClient_EditFolder(t *testing.T) {
 			name: "not found",
 			req: &EditRequest{
 				ID:   "123",
-				Name: "new_name",
+				Name: utils.MakePointer("new_name"),
 			},
 			mockSetup: func() {
 				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CHECK_FOLDER_EXISTS_BY_PARENT_ID_AND_NAME)).WithArgs("new_name", "123").WillReturnRows(
 					sqlMock.NewRows([]string{"exists"}).AddRow(false),
 				)
-				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_UPDATE_FOLDER)).WithArgs("new_name", "123").WillReturnError(sql.ErrNoRows)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(nameUpdate.String())).WithArgs("new_name", "123").WillReturnError(sql.ErrNoRows)
 			},
 			expectedFolder: nil,
 			expectedError:  tiny_errors.New(custom_errors.ERR_CODE_NotFound),
@@ -569,7 +616,7 @@ func TestClient_EditFolder(t *testing.T) {
 			name: "found the same name in the same folder",
 			req: &EditRequest{
 				ID:   "123",
-				Name: "new_name",
+				Name: utils.MakePointer("new_name"),
 			},
 			mockSetup: func() {
 				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_CHECK_FOLDER_EXISTS_BY_PARENT_ID_AND_NAME)).WithArgs("new_name", "123").WillReturnRows(
@@ -577,13 +624,75 @@ func TestClient_EditFolder(t *testing.T) {
 				)
 			},
 			expectedFolder: nil,
-			expectedError:  tiny_errors.New(custom_errors.ERR_CODE_Exists, tiny_errors.Message("folder with such name already exists")),
+			expectedError:  utils.ExistsError("folder with such name already exists"),
 		},
 		{
-			name: "empty id or name",
+			name: "success with metadata only",
+			req: &EditRequest{
+				ID: "123",
+				Metadata: &utils.MetadataUpdate{
+					OwnerTeam: utils.MakePointer("platform"),
+					Contact:   utils.MakePointer(""),
+					Labels:    utils.Labels{"env": "prod"},
+				},
+			},
+			mockSetup: func() {
+				q := updateQuery(nil, &utils.MetadataUpdate{
+					OwnerTeam: utils.MakePointer("platform"),
+					Contact:   utils.MakePointer(""),
+					Labels:    utils.Labels{"env": "prod"},
+				})
+				sqlMock.ExpectQuery(regexp.QuoteMeta(q.String())).WithArgs("platform", nil, `{"env":"prod"}`, "123").WillReturnRows(
+					sqlMock.NewRows([]string{"id", "name", "parent_id", "owner_team", "contact", "labels", "created_at", "updated_at"}).
+						AddRow("123", "name", nil, "platform", nil, []byte(`{"env":"prod"}`), "2020-01-01T00:00:00Z", "2020-02-01T00:00:00Z"),
+				)
+			},
+			expectedFolder: &Folder{
+				ID:   "123",
+				Name: "name",
+				Metadata: utils.Metadata{
+					OwnerTeam: utils.MakePointer("platform"),
+					Labels:    utils.Labels{"env": "prod"},
+				},
+				CreatedAt: "2020-01-01T00:00:00Z",
+				UpdatedAt: "2020-02-01T00:00:00Z",
+			},
+			expectedError: nil,
+		},
+		{
+			name: "rename root folder",
+			req: &EditRequest{
+				ID:   ROOT_ALIAS,
+				Name: utils.MakePointer("new_name"),
+			},
+			mockSetup:      func() {},
+			expectedFolder: nil,
+			expectedError:  tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("root folder can not be renamed")),
+		},
+		{
+			name: "empty id",
 			req: &EditRequest{
 				ID:   "",
-				Name: "",
+				Name: utils.MakePointer("new_name"),
+			},
+			mockSetup:      func() {},
+			expectedFolder: nil,
+			expectedError:  tiny_errors.New(custom_errors.ERR_CODE_NotValid),
+		},
+		{
+			name: "nothing to update",
+			req: &EditRequest{
+				ID: "123",
+			},
+			mockSetup:      func() {},
+			expectedFolder: nil,
+			expectedError:  tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD),
+		},
+		{
+			name: "not valid label",
+			req: &EditRequest{
+				ID:       "123",
+				Metadata: &utils.MetadataUpdate{Labels: utils.Labels{"not valid": "value"}},
 			},
 			mockSetup:      func() {},
 			expectedFolder: nil,
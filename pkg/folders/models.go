@@ -1,7 +1,16 @@
 package folders
 
+import "github.com/Moranilt/config-keeper/utils"
+
 const (
-	QUERY_INSERT_FOLDER                  = "INSERT INTO folders (name, parent_id) VALUES($1, $2) RETURNING id, name, parent_id, created_at, updated_at"
+	// ROOT_ID is an id of the root folder created by migrations. Root is the only folder without parent.
+	ROOT_ID = "00000000-0000-0000-0000-000000000000"
+	// ROOT_ALIAS can be used in requests instead of ROOT_ID.
+	ROOT_ALIAS = "root"
+)
+
+const (
+	QUERY_INSERT_FOLDER                  = "INSERT INTO folders (name, parent_id) VALUES($1, $2) RETURNING id, name, parent_id, description, owner_team, contact, labels, created_at, updated_at"
 	QUERY_DEFAULT_SELECT_FOLDERS_ID      = "SELECT id FROM folders"
 	QUERY_GET_FOLDER_WITH_PARENT_ID_NULL = "SELECT id FROM folders WHERE name = $1 AND parent_id IS NULL"
 	QUERY_GET_FOLDER_WITH_PARENT_ID      = "SELECT id FROM folders WHERE name = $1 AND parent_id = $2"
@@ -11,40 +20,63 @@ const (
 			parent_id,
 			name,
 			CAST(name AS VARCHAR) AS path,
+			description,
+			owner_team,
+			contact,
+			labels,
 			created_at,
 			updated_at
 		FROM 
 			folders
 		WHERE 
 			parent_id IS NULL
+			AND deleted_at IS NULL
 		
 		UNION ALL
 		SELECT 
 			f.id,
 			f.parent_id,
 			f.name,
-			CONCAT(fp.path, '/', f.name) AS path,
+			CASE WHEN fp.parent_id IS NULL THEN CAST(f.name AS VARCHAR) ELSE CONCAT(fp.path, '/', f.name) END AS path,
+			f.description,
+			f.owner_team,
+			f.contact,
+			f.labels,
 			f.created_at,
 			f.updated_at
 		FROM 
 			folders f
 			JOIN folder_path fp ON f.parent_id = fp.id
+		WHERE
+			f.deleted_at IS NULL
 	)
 	SELECT 
 		id,
 		parent_id,
 		name,
 		path,
+		description,
+		owner_team,
+		contact,
+		labels,
 		created_at,
 		updated_at
 	FROM 
 		folder_path`
-	QUERY_GET_FOLDERS                               = "SELECT id, name, parent_id, created_at, updated_at FROM folders"
-	QUERY_DELETE_FOLDER                             = "DELETE FROM folders WHERE id = $1"
-	QUERY_UPDATE_FOLDER                             = "UPDATE folders SET name = $1, updated_at = now() WHERE id = $2 RETURNING id, name, parent_id, created_at, updated_at"
+	QUERY_GET_FOLDERS   = "SELECT id, name, parent_id, description, owner_team, contact, labels, created_at, updated_at FROM folders"
+	QUERY_DELETE_FOLDER = `WITH RECURSIVE subtree AS (
+		SELECT id FROM folders WHERE id = $1 AND deleted_at IS NULL
+		UNION ALL
+		SELECT f.id FROM folders f JOIN subtree s ON f.parent_id = s.id WHERE f.deleted_at IS NULL
+	),
+	deleted_files AS (
+		UPDATE files SET deleted_at = now() WHERE folder_id IN (SELECT id FROM subtree) AND deleted_at IS NULL
+	)
+	UPDATE folders SET deleted_at = now() WHERE id IN (SELECT id FROM subtree)`
 	QUERY_CHECK_FOLDER_EXISTS_BY_PARENT_ID_AND_NAME = `SELECT EXISTS(
 	SELECT 1 FROM folders 
 	WHERE name = $1
+	AND deleted_at IS NULL
 	AND (
 			(parent_id IS NULL AND (SELECT parent_id FROM folders WHERE id = $2) is NULL)
 			OR
@@ -52,17 +84,54 @@ const (
 	))`
 )
 
+var (
+	// RETURNING_COLUMNS is a list of columns returned after folder update.
+	RETURNING_COLUMNS = []string{"id", "name", "parent_id", "description", "owner_team", "contact", "labels", "created_at", "updated_at"}
+
+	// ORDER_COLUMNS is a list of columns allowed for sorting folders.
+	ORDER_COLUMNS = map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	}
+
+	LIST_COLUMNS = utils.ListColumns{
+		ID:        "id",
+		Name:      "name",
+		CreatedAt: "created_at",
+		UpdatedAt: "updated_at",
+	}
+)
+
+// ResolveID replaces ROOT_ALIAS and empty id with ROOT_ID.
+func ResolveID(id string) string {
+	if id == "" || id == ROOT_ALIAS {
+		return ROOT_ID
+	}
+	return id
+}
+
+// ResolveParentID returns ROOT_ID when parent is not provided or ROOT_ALIAS is used.
+func ResolveParentID(id *string) string {
+	if id == nil {
+		return ROOT_ID
+	}
+	return ResolveID(*id)
+}
+
 type CreateRequest struct {
 	Name     string
 	ParentID *string
 }
 
 type Folder struct {
-	ID        string  `json:"id" db:"id"`
-	Name      string  `json:"name" db:"name"`
-	ParentID  *string `json:"parent_id" db:"parent_id"`
-	CreatedAt string  `json:"created_at" db:"created_at"`
-	UpdatedAt string  `json:"updated_at" db:"updated_at"`
+	ID       string  `json:"id" db:"id"`
+	Name     string  `json:"name" db:"name"`
+	ParentID *string `json:"parent_id" db:"parent_id"`
+	utils.Metadata
+	CreatedAt string `json:"created_at" db:"created_at"`
+	UpdatedAt string `json:"updated_at" db:"updated_at"`
 }
 
 type FolderWithPath struct {
@@ -87,13 +156,30 @@ type Order struct {
 type GetManyRequest struct {
 	ParentID *string
 	Order    *Order
+	Filter   *utils.ListFilter
+	Metadata *utils.MetadataFilter
 }
 
 type DeleteRequest struct {
 	ID string
 }
 
+// EditRequest changes only provided fields.
 type EditRequest struct {
-	ID   string
-	Name string
+	ID       string
+	Name     *string
+	Metadata *utils.MetadataUpdate
+}
+
+func (f *Folder) columnValue(column string) string {
+	switch column {
+	case "id":
+		return f.ID
+	case "created_at":
+		return f.CreatedAt
+	case "updated_at":
+		return f.UpdatedAt
+	default:
+		return f.Name
+	}
 }
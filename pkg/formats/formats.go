@@ -0,0 +1,256 @@
+package formats
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// FormatHandler implements behavior of a content format. Contents are decoded into a tree of
+// map[string]any, []any and scalar values, so conversion, diffing and masking
+// work the same way for every format.
+type FormatHandler interface {
+	// Validate returns an error if data can not be parsed.
+	Validate(data string) error
+
+	// Decode parses data into a tree.
+	Decode(data string) (map[string]any, error)
+
+	// Encode renders the tree in the format.
+	Encode(tree map[string]any) (string, error)
+}
+
+var (
+	mu       sync.RWMutex
+	handlers = map[string]FormatHandler{
+		"yaml":       yamlHandler{},
+		"json":       jsonHandler{},
+		"toml":       tomlHandler{},
+		"env":        envHandler{},
+		"properties": propertiesHandler{},
+		"ini":        iniHandler{},
+		"hcl":        hclHandler{},
+		"xml":        xmlHandler{},
+	}
+)
+
+// Register adds a handler or replaces the handler registered with the same name.
+// Name is used as a validator of content formats.
+func Register(name string, handler FormatHandler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[name] = handler
+}
+
+// Get returns the handler registered with the name.
+func Get(name string) (FormatHandler, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	handler, ok := handlers[name]
+	return handler, ok
+}
+
+// Names returns sorted names of registered handlers.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(handlers))
+	for name := range handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Convert decodes data with one handler and encodes it with another one.
+func Convert(from, to string, data string) (string, error) {
+	source, err := handler(from)
+	if err != nil {
+		return "", err
+	}
+	target, err := handler(to)
+	if err != nil {
+		return "", err
+	}
+
+	tree, err := source.Decode(data)
+	if err != nil {
+		return "", err
+	}
+	return target.Encode(tree)
+}
+
+// Diff compares leaf values of two contents of the same format. Changes are sorted by key.
+func Diff(name string, oldData, newData string) ([]*Change, error) {
+	oldTree, err := decode(name, oldData)
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := decode(name, newData)
+	if err != nil {
+		return nil, err
+	}
+	return DiffTrees(oldTree, newTree), nil
+}
+
+// DiffTrees compares leaf values of two trees. Changes are sorted by key.
+func DiffTrees(oldTree, newTree map[string]any) []*Change {
+	oldLeaves := Flatten(oldTree)
+	newLeaves := Flatten(newTree)
+
+	changes := make([]*Change, 0)
+	for key, oldValue := range oldLeaves {
+		newValue, ok := newLeaves[key]
+		switch {
+		case !ok:
+			changes = append(changes, &Change{Key: key, Type: CHANGE_REMOVED, Old: oldValue})
+		case !reflect.DeepEqual(oldValue, newValue):
+			changes = append(changes, &Change{Key: key, Type: CHANGE_CHANGED, Old: oldValue, New: newValue})
+		}
+	}
+	for key, newValue := range newLeaves {
+		if _, ok := oldLeaves[key]; !ok {
+			changes = append(changes, &Change{Key: key, Type: CHANGE_ADDED, New: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// Mask replaces leaf values whose keys match with MASK and encodes the result in the same format.
+// Comments and formatting of the original content are not kept.
+func Mask(name string, data string, match func(key string) bool) (string, error) {
+	h, err := handler(name)
+	if err != nil {
+		return "", err
+	}
+	tree, err := h.Decode(data)
+	if err != nil {
+		return "", err
+	}
+
+	masked := mask(tree, "", match).(map[string]any)
+	return h.Encode(masked)
+}
+
+// Flatten returns leaf values of the tree by their keys. Nested keys are joined with dots, items of lists are
+// addressed by their index, e.g. `servers.0.host`.
+func Flatten(tree map[string]any) map[string]any {
+	leaves := make(map[string]any)
+	flatten(tree, "", leaves)
+	return leaves
+}
+
+func flatten(value any, prefix string, leaves map[string]any) {
+	switch value := value.(type) {
+	case map[string]any:
+		if len(value) == 0 && prefix != "" {
+			leaves[prefix] = value
+		}
+		for key, nested := range value {
+			flatten(nested, joinKey(prefix, key), leaves)
+		}
+	case []any:
+		if len(value) == 0 {
+			leaves[prefix] = value
+		}
+		for i, nested := range value {
+			flatten(nested, joinKey(prefix, strconv.Itoa(i)), leaves)
+		}
+	default:
+		leaves[prefix] = value
+	}
+}
+
+func mask(value any, prefix string, match func(key string) bool) any {
+	switch value := value.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(value))
+		for key, nested := range value {
+			result[key] = mask(nested, joinKey(prefix, key), match)
+		}
+		return result
+	case []any:
+		result := make([]any, len(value))
+		for i, nested := range value {
+			result[i] = mask(nested, joinKey(prefix, strconv.Itoa(i)), match)
+		}
+		return result
+	default:
+		if match(prefix) {
+			return MASK
+		}
+		return value
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func handler(name string) (FormatHandler, error) {
+	h, ok := Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, name)
+	}
+	return h, nil
+}
+
+func decode(name string, data string) (map[string]any, error) {
+	h, err := handler(name)
+	if err != nil {
+		return nil, err
+	}
+	return h.Decode(data)
+}
+
+// normalize converts maps with non-string keys and typed slices into map[string]any and []any.
+func normalize(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, nested := range value {
+			value[key] = normalize(nested)
+		}
+		return value
+	case map[any]any:
+		result := make(map[string]any, len(value))
+		for key, nested := range value {
+			result[fmt.Sprint(key)] = normalize(nested)
+		}
+		return result
+	case []map[string]any:
+		result := make([]any, len(value))
+		for i, nested := range value {
+			result[i] = normalize(nested)
+		}
+		return result
+	case []any:
+		for i, nested := range value {
+			value[i] = normalize(nested)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// scalarString converts a leaf value into a string for formats which store only strings.
+func scalarString(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,159 @@
+package formats
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		format  string
+		valid   string
+		invalid string
+	}{
+		{format: "yaml", valid: "a: 1\n---\nb: [1, 2]\n", invalid: "a: 1\n---\nb: [1, 2\n"},
+		{format: "json", valid: `{"a": [1, 2]}`, invalid: `{"a": }`},
+		{format: "toml", valid: "[server]\nport = 8080\n", invalid: "[server\nport = 8080\n"},
+		{format: "env", valid: "export A='b c'\nD=e\n", invalid: "1A=b\n"},
+		{format: "properties", valid: "app.name = config keeper\napp.port: 8080\n", invalid: "a = ${a}\n"},
+		{format: "ini", valid: "[server]\nport = 8080\n", invalid: "[server\nport = 8080\n"},
+		{format: "hcl", valid: "server {\n  port = 8080\n}\n", invalid: "server {\n  port = \n"},
+		{format: "xml", valid: "<?xml version=\"1.0\"?>\n<config><port>8080</port></config>", invalid: "<a/><b/>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			handler, ok := Get(tt.format)
+			assert.True(t, ok)
+			assert.NoError(t, handler.Validate(tt.valid))
+			assert.Error(t, handler.Validate(tt.invalid))
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tree := map[string]any{
+		"server": map[string]any{
+			"host": "localhost",
+			"port": "8080",
+		},
+		"name": "config keeper",
+	}
+
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			handler, _ := Get(name)
+			data, err := handler.Encode(tree)
+			assert.NoError(t, err)
+			assert.NoError(t, handler.Validate(data), data)
+
+			decoded, err := handler.Decode(data)
+			assert.NoError(t, err)
+			keys := make([]string, 0)
+			for key := range Flatten(decoded) {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			switch name {
+			case "env":
+				assert.Equal(t, []string{"NAME", "SERVER_HOST", "SERVER_PORT"}, keys)
+			case "xml":
+				assert.Equal(t, []string{"config.name", "config.server.host", "config.server.port"}, keys)
+			default:
+				assert.Equal(t, []string{"name", "server.host", "server.port"}, keys)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	t.Run("yaml to json", func(t *testing.T) {
+		result, err := Convert("yaml", "json", "server:\n  port: 8080\n  debug: false\nhosts: [a, b]\n")
+		assert.NoError(t, err)
+		assert.Equal(t, "{\n  \"hosts\": [\n    \"a\",\n    \"b\"\n  ],\n  \"server\": {\n    \"debug\": false,\n    \"port\": 8080\n  }\n}\n", result)
+	})
+
+	t.Run("json to env", func(t *testing.T) {
+		result, err := Convert("json", "env", `{"db": {"password": "it's \"$ecret\"", "port": 5432}}`)
+		assert.NoError(t, err)
+		assert.Equal(t, "DB_PASSWORD=\"it's \\\"\\$ecret\\\"\"\nDB_PORT=5432\n", result)
+
+		tree, err := envHandler{}.Decode(result)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"DB_PASSWORD": `it's "$ecret"`, "DB_PORT": "5432"}, tree)
+	})
+
+	t.Run("hcl to yaml", func(t *testing.T) {
+		result, err := Convert("hcl", "yaml", "server {\n  port = 8080\n}\nname = \"app\"\n")
+		assert.NoError(t, err)
+		assert.Equal(t, "name: app\nserver:\n  port: 8080\n", result)
+	})
+
+	t.Run("xml to json", func(t *testing.T) {
+		result, err := Convert("xml", "json", `<config env="prod"><host>a</host><host>b</host>text</config>`)
+		assert.NoError(t, err)
+		assert.Equal(t, "{\n  \"config\": {\n    \"#text\": \"text\",\n    \"@env\": \"prod\",\n    \"host\": [\n      \"a\",\n      \"b\"\n    ]\n  }\n}\n", result)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := Convert("yaml", "unknown", "a: 1")
+		assert.ErrorIs(t, err, ErrUnknownFormat)
+	})
+
+	t.Run("not an object", func(t *testing.T) {
+		_, err := Convert("json", "yaml", "[1, 2]")
+		assert.ErrorIs(t, err, ErrNotObject)
+	})
+}
+
+func TestDiff(t *testing.T) {
+	changes, err := Diff("yaml", "a: 1\nb:\n  c: x\nlist: [1, 2]\n", "a: 2\nb:\n  d: y\nlist: [1]\n")
+	assert.NoError(t, err)
+	assert.Equal(t, []*Change{
+		{Key: "a", Type: CHANGE_CHANGED, Old: 1, New: 2},
+		{Key: "b.c", Type: CHANGE_REMOVED, Old: "x"},
+		{Key: "b.d", Type: CHANGE_ADDED, New: "y"},
+		{Key: "list.1", Type: CHANGE_REMOVED, Old: 2},
+	}, changes)
+
+	changes, err = Diff("env", "A=1", "A=1")
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestMask(t *testing.T) {
+	result, err := Mask("yaml", "db:\n  user: app\n  password: secret\n", func(key string) bool {
+		return strings.HasSuffix(key, "password")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "db:\n  password: '******'\n  user: app\n", result)
+}
+
+type upperHandler struct{}
+
+func (upperHandler) Validate(data string) error { return nil }
+
+func (upperHandler) Decode(data string) (map[string]any, error) {
+	return map[string]any{"value": data}, nil
+}
+
+func (upperHandler) Encode(tree map[string]any) (string, error) {
+	return strings.ToUpper(scalarString(tree["value"])), nil
+}
+
+func TestRegister(t *testing.T) {
+	Register("upper", upperHandler{})
+	defer func() {
+		mu.Lock()
+		delete(handlers, "upper")
+		mu.Unlock()
+	}()
+
+	assert.Contains(t, Names(), "upper")
+	result, err := Convert("upper", "upper", "text")
+	assert.NoError(t, err)
+	assert.Equal(t, "TEXT", result)
+}
@@ -0,0 +1,579 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Moranilt/config-keeper/pkg/envfile"
+	"github.com/hashicorp/hcl"
+	"github.com/magiconair/properties"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/ini.v1"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	invalidEnvKeyChars  = regexp.MustCompile(`[^A-Za-z0-9_]`)
+	plainEnvValue       = regexp.MustCompile(`^[A-Za-z0-9_./:@,+-]*$`)
+	hclIdentifier       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+	invalidXMLNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+)
+
+type yamlHandler struct{}
+
+// Validate checks every document of the stream.
+func (yamlHandler) Validate(data string) error {
+	decoder := yaml.NewDecoder(strings.NewReader(data))
+	for {
+		var value any
+		err := decoder.Decode(&value)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Decode parses only the first document of the stream.
+func (yamlHandler) Decode(data string) (map[string]any, error) {
+	var value any
+	err := yaml.NewDecoder(strings.NewReader(data)).Decode(&value)
+	if err == io.EOF {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return object(normalize(value))
+}
+
+func (yamlHandler) Encode(tree map[string]any) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(tree); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+type jsonHandler struct{}
+
+func (jsonHandler) Validate(data string) error {
+	var value any
+	return json.Unmarshal([]byte(data), &value)
+}
+
+// Decode keeps integers as int64 instead of float64.
+func (jsonHandler) Decode(data string) (map[string]any, error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return object(jsonNumbers(value))
+}
+
+func (jsonHandler) Encode(tree map[string]any) (string, error) {
+	data, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+type tomlHandler struct{}
+
+func (h tomlHandler) Validate(data string) error {
+	_, err := h.Decode(data)
+	return err
+}
+
+func (tomlHandler) Decode(data string) (map[string]any, error) {
+	tree := make(map[string]any)
+	if err := toml.Unmarshal([]byte(data), &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func (tomlHandler) Encode(tree map[string]any) (string, error) {
+	data, err := toml.Marshal(tree)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+type envHandler struct{}
+
+func (envHandler) Validate(data string) error {
+	_, err := envfile.Parse(data)
+	return err
+}
+
+// Decode returns a flat tree, the latest value of duplicated keys wins.
+func (envHandler) Decode(data string) (map[string]any, error) {
+	variables, err := envfile.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := make(map[string]any, len(variables))
+	for _, variable := range variables {
+		tree[variable.Key] = variable.Value
+	}
+	return tree, nil
+}
+
+// Encode flattens the tree, nested keys are joined with underscores and converted to upper case.
+func (envHandler) Encode(tree map[string]any) (string, error) {
+	leaves := Flatten(tree)
+	variables := make(map[string]string, len(leaves))
+	for key, value := range leaves {
+		envKey := strings.ToUpper(invalidEnvKeyChars.ReplaceAllString(key, "_"))
+		if envKey == "" || (envKey[0] >= '0' && envKey[0] <= '9') {
+			envKey = "_" + envKey
+		}
+		if _, ok := variables[envKey]; ok {
+			return "", fmt.Errorf("key %q is used by several values", envKey)
+		}
+		variables[envKey] = scalarString(value)
+	}
+
+	var result strings.Builder
+	for _, key := range sortedKeys(variables) {
+		value := variables[key]
+		if !plainEnvValue.MatchString(value) {
+			value = envQuote(value)
+		}
+		result.WriteString(key + "=" + value + "\n")
+	}
+	return result.String(), nil
+}
+
+// envQuote wraps value in double quotes which are supported by envfile.Parse.
+func envQuote(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+type propertiesHandler struct{}
+
+func (propertiesHandler) Validate(data string) error {
+	_, err := properties.LoadString(data)
+	return err
+}
+
+// Decode returns a flat tree with dotted keys as they are written in the file.
+func (propertiesHandler) Decode(data string) (map[string]any, error) {
+	props, err := properties.LoadString(data)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := make(map[string]any, props.Len())
+	for key, value := range props.Map() {
+		tree[key] = value
+	}
+	return tree, nil
+}
+
+func (propertiesHandler) Encode(tree map[string]any) (string, error) {
+	leaves := Flatten(tree)
+	props := properties.NewProperties()
+	props.DisableExpansion = true
+	for _, key := range sortedKeys(leaves) {
+		if _, _, err := props.Set(key, scalarString(leaves[key])); err != nil {
+			return "", err
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := props.Write(&buf, properties.UTF8); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+type iniHandler struct{}
+
+func (iniHandler) Validate(data string) error {
+	_, err := ini.Load([]byte(data))
+	return err
+}
+
+// Decode puts keys of the default section on the top level and keys of other sections into nested objects.
+func (iniHandler) Decode(data string) (map[string]any, error) {
+	file, err := ini.Load([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+
+	tree := make(map[string]any)
+	for _, section := range file.Sections() {
+		target := tree
+		if section.Name() != ini.DefaultSection {
+			target = make(map[string]any)
+			tree[section.Name()] = target
+		}
+		for _, key := range section.Keys() {
+			target[key.Name()] = key.Value()
+		}
+	}
+	return tree, nil
+}
+
+// Encode writes top-level objects as sections, deeper levels are flattened into dotted keys.
+func (iniHandler) Encode(tree map[string]any) (string, error) {
+	file := ini.Empty()
+	for _, key := range sortedKeys(tree) {
+		nested, ok := tree[key].(map[string]any)
+		if !ok {
+			if _, err := file.Section("").NewKey(key, iniValue(tree[key])); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		section, err := file.NewSection(key)
+		if err != nil {
+			return "", err
+		}
+		leaves := Flatten(nested)
+		for _, leafKey := range sortedKeys(leaves) {
+			if _, err := section.NewKey(leafKey, iniValue(leaves[leafKey])); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := file.WriteTo(&buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// iniValue joins lists with commas, ini has no lists.
+func iniValue(value any) string {
+	list, ok := value.([]any)
+	if !ok {
+		return scalarString(value)
+	}
+	items := make([]string, len(list))
+	for i, item := range list {
+		items[i] = scalarString(item)
+	}
+	return strings.Join(items, ",")
+}
+
+type hclHandler struct{}
+
+func (hclHandler) Validate(data string) error {
+	_, err := hcl.Parse(data)
+	return err
+}
+
+// Decode unwraps blocks, which HCL decodes as lists with a single object.
+func (hclHandler) Decode(data string) (map[string]any, error) {
+	tree := make(map[string]any)
+	if err := hcl.Unmarshal([]byte(data), &tree); err != nil {
+		return nil, err
+	}
+	return unwrapBlocks(normalize(tree)).(map[string]any), nil
+}
+
+func (hclHandler) Encode(tree map[string]any) (string, error) {
+	var result strings.Builder
+	writeHCL(&result, tree, "")
+	return result.String(), nil
+}
+
+func unwrapBlocks(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, nested := range value {
+			value[key] = unwrapBlocks(nested)
+		}
+		return value
+	case []any:
+		if len(value) == 1 {
+			if block, ok := value[0].(map[string]any); ok {
+				return unwrapBlocks(block)
+			}
+		}
+		for i, nested := range value {
+			value[i] = unwrapBlocks(nested)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+func writeHCL(result *strings.Builder, tree map[string]any, indent string) {
+	for _, key := range sortedKeys(tree) {
+		name := key
+		if !hclIdentifier.MatchString(key) {
+			name = strconv.Quote(key)
+		}
+
+		switch value := tree[key].(type) {
+		case map[string]any:
+			result.WriteString(indent + name + " {\n")
+			writeHCL(result, value, indent+"  ")
+			result.WriteString(indent + "}\n")
+		default:
+			result.WriteString(indent + name + " = " + hclValue(value) + "\n")
+		}
+	}
+}
+
+func hclValue(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return `""`
+	case string:
+		return strconv.Quote(value)
+	case []any:
+		items := make([]string, len(value))
+		for i, item := range value {
+			items[i] = hclValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]any:
+		items := make([]string, 0, len(value))
+		for _, key := range sortedKeys(value) {
+			items = append(items, strconv.Quote(key)+" = "+hclValue(value[key]))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+type xmlHandler struct{}
+
+func (h xmlHandler) Validate(data string) error {
+	_, err := h.Decode(data)
+	return err
+}
+
+// Decode returns the root element as the only top-level key. Attributes are stored with @ prefix,
+// text of elements with children or attributes is stored as #text. Repeated elements become lists.
+func (xmlHandler) Decode(data string) (map[string]any, error) {
+	decoder := xml.NewDecoder(strings.NewReader(data))
+	root := &xmlNode{children: make(map[string]any)}
+	stack := []*xmlNode{root}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		top := stack[len(stack)-1]
+		switch token := token.(type) {
+		case xml.StartElement:
+			if top == root && len(root.children) > 0 {
+				return nil, errors.New("document should have exactly one root element")
+			}
+			node := &xmlNode{name: token.Name.Local, children: make(map[string]any)}
+			for _, attr := range token.Attr {
+				node.children["@"+attr.Name.Local] = attr.Value
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			stack[len(stack)-1].add(top.name, top.value())
+		case xml.CharData:
+			if top == root {
+				if len(strings.TrimSpace(string(token))) > 0 {
+					return nil, errors.New("text outside of the root element")
+				}
+				continue
+			}
+			top.text.Write(token)
+		}
+	}
+
+	if len(root.children) == 0 {
+		return nil, errors.New("document should have exactly one root element")
+	}
+	return root.children, nil
+}
+
+// Encode uses the only top-level key as the root element, otherwise the tree is wrapped into XML_ROOT.
+func (xmlHandler) Encode(tree map[string]any) (string, error) {
+	rootName, rootValue := XML_ROOT, any(tree)
+	if len(tree) == 1 {
+		for key, value := range tree {
+			rootName, rootValue = key, value
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := writeXML(&buf, rootName, rootValue, ""); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+type xmlNode struct {
+	name     string
+	children map[string]any
+	text     strings.Builder
+}
+
+func (n *xmlNode) add(name string, value any) {
+	existing, ok := n.children[name]
+	if !ok {
+		n.children[name] = value
+		return
+	}
+	if list, ok := existing.([]any); ok {
+		n.children[name] = append(list, value)
+		return
+	}
+	n.children[name] = []any{existing, value}
+}
+
+func (n *xmlNode) value() any {
+	text := strings.TrimSpace(n.text.String())
+	if len(n.children) == 0 {
+		return text
+	}
+	if text != "" {
+		n.children["#text"] = text
+	}
+	return n.children
+}
+
+func writeXML(buf *bytes.Buffer, name string, value any, indent string) error {
+	name = xmlName(name)
+
+	switch value := value.(type) {
+	case []any:
+		for _, item := range value {
+			if err := writeXML(buf, name, item, indent); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]any:
+		buf.WriteString(indent + "<" + name)
+		var children []string
+		for _, key := range sortedKeys(value) {
+			if attr, ok := strings.CutPrefix(key, "@"); ok {
+				buf.WriteString(" " + xmlName(attr) + `="`)
+				if err := xml.EscapeText(buf, []byte(scalarString(value[key]))); err != nil {
+					return err
+				}
+				buf.WriteString(`"`)
+				continue
+			}
+			if key != "#text" {
+				children = append(children, key)
+			}
+		}
+		buf.WriteString(">")
+
+		if text, ok := value["#text"]; ok {
+			if err := xml.EscapeText(buf, []byte(scalarString(text))); err != nil {
+				return err
+			}
+		}
+		if len(children) > 0 {
+			buf.WriteString("\n")
+			for _, key := range children {
+				if err := writeXML(buf, key, value[key], indent+"  "); err != nil {
+					return err
+				}
+			}
+			buf.WriteString(indent)
+		}
+		buf.WriteString("</" + name + ">\n")
+		return nil
+	default:
+		buf.WriteString(indent + "<" + name + ">")
+		if err := xml.EscapeText(buf, []byte(scalarString(value))); err != nil {
+			return err
+		}
+		buf.WriteString("</" + name + ">\n")
+		return nil
+	}
+}
+
+// xmlName replaces characters which are not allowed in element names.
+func xmlName(name string) string {
+	name = invalidXMLNameChars.ReplaceAllString(name, "_")
+	if name == "" || strings.IndexByte("0123456789.-", name[0]) >= 0 {
+		name = "_" + name
+	}
+	return name
+}
+
+// object returns the value if it is an object, content of other formats can have lists or scalars on the top level.
+func object(value any) (map[string]any, error) {
+	if value == nil {
+		return map[string]any{}, nil
+	}
+	tree, ok := value.(map[string]any)
+	if !ok {
+		return nil, ErrNotObject
+	}
+	return tree, nil
+}
+
+// jsonNumbers converts json.Number into int64 or float64.
+func jsonNumbers(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, nested := range value {
+			value[key] = jsonNumbers(nested)
+		}
+		return value
+	case []any:
+		for i, nested := range value {
+			value[i] = jsonNumbers(nested)
+		}
+		return value
+	case json.Number:
+		if integer, err := value.Int64(); err == nil {
+			return integer
+		}
+		float, _ := value.Float64()
+		return float
+	default:
+		return value
+	}
+}
+
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,31 @@
+package formats
+
+import "errors"
+
+const (
+	CHANGE_ADDED   = "added"
+	CHANGE_REMOVED = "removed"
+	CHANGE_CHANGED = "changed"
+
+	// MASK replaces masked values.
+	MASK = "******"
+
+	// CONTENT_TYPE of contents converted into another format.
+	CONTENT_TYPE = "text/plain; charset=utf-8"
+
+	// XML_ROOT is a name of the root element if the tree has several top-level keys.
+	XML_ROOT = "config"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format")
+	ErrNotObject     = errors.New("top-level value should be an object")
+)
+
+// Change describes a difference of a single key between two contents.
+type Change struct {
+	Key  string `json:"key"`
+	Type string `json:"type"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
@@ -0,0 +1,102 @@
+package gitsync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// gitRepository reads a local bare or working-tree repository using git binary.
+// Only committed data is read, so uncommitted changes of a working tree are ignored.
+type gitRepository struct {
+	path string
+}
+
+type blob struct {
+	Path string
+	Hash string
+}
+
+func openRepository(ctx context.Context, path string) (*gitRepository, error) {
+	repo := &gitRepository{path: path}
+	if _, err := repo.run(ctx, "rev-parse", "--git-dir"); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// resolveCommit returns full hash of the commit the ref points to.
+func (r *gitRepository) resolveCommit(ctx context.Context, ref string) (string, error) {
+	out, err := r.run(ctx, "rev-parse", "--verify", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// tags returns sorted names of tags pointing to the commit.
+func (r *gitRepository) tags(ctx context.Context, commit string) ([]string, error) {
+	out, err := r.run(ctx, "tag", "--points-at", commit)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := strings.Fields(string(out))
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// blobs returns all files of the commit inside dir. Empty dir means the whole repository.
+func (r *gitRepository) blobs(ctx context.Context, commit, dir string) ([]*blob, error) {
+	args := []string{"ls-tree", "-r", "-z", "--full-tree", commit}
+	if dir != "" {
+		args = append(args, "--", dir)
+	}
+
+	out, err := r.run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*blob
+	for _, line := range strings.Split(string(out), "\x00") {
+		if line == "" {
+			continue
+		}
+
+		// <mode> SP <type> SP <object> TAB <file>
+		info, filePath, ok := strings.Cut(line, "\t")
+		fields := strings.Fields(info)
+		if !ok || len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected ls-tree output %q", line)
+		}
+		if fields[1] != "blob" {
+			continue
+		}
+		result = append(result, &blob{Path: filePath, Hash: fields[2]})
+	}
+	return result, nil
+}
+
+func (r *gitRepository) readBlob(ctx context.Context, hash string) (string, error) {
+	out, err := r.run(ctx, "cat-file", "blob", hash)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (r *gitRepository) run(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", r.path}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
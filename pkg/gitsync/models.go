@@ -0,0 +1,44 @@
+package gitsync
+
+import (
+	"time"
+
+	"github.com/Moranilt/config-keeper/pkg/archive"
+)
+
+const (
+	DEFAULT_REF = "HEAD"
+
+	// SHORT_COMMIT_LENGTH is a length of the commit hash used as a version when commit has no tags.
+	SHORT_COMMIT_LENGTH = 12
+)
+
+// Config describes which repository is synced and where its files are placed.
+type Config struct {
+	// RepoPath is a path of a local bare or working-tree repository.
+	RepoPath string
+	// Ref is a branch, tag or commit which is synced by schedule and by default.
+	Ref string
+	// Dir is a directory inside the repository to sync. Empty value means the whole repository.
+	Dir string
+	// FolderID is a folder where directories and files of the repository are created.
+	FolderID string
+	// Interval of scheduled syncs. Zero disables the schedule, so sync runs only on demand.
+	Interval time.Duration
+}
+
+type SyncRequest struct {
+	// Ref overrides configured ref.
+	Ref    *string
+	DryRun bool
+}
+
+type SyncResponse struct {
+	Commit string
+	// Version is a tag of the commit or its short hash if the commit has no tags.
+	Version   string
+	Changes   []*archive.Change
+	Unchanged int
+	// Skipped contains paths of files with unknown formats.
+	Skipped []string
+}
@@ -0,0 +1,274 @@
+package gitsync
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/archive"
+	"github.com/Moranilt/config-keeper/pkg/callback"
+	"github.com/Moranilt/config-keeper/pkg/content_formats"
+	"github.com/Moranilt/config-keeper/pkg/transaction"
+	"github.com/Moranilt/http-utils/logger"
+	"github.com/Moranilt/http-utils/tiny_errors"
+)
+
+type Syncer interface {
+	// Sync imports files of the ref into the configured folder. Directories become folders and every file gets
+	// a version named after the tag or the short hash of the commit. A new version is created only if the content
+	// differs from the latest version of the file. Source commit is recorded in every created version.
+	Sync(ctx context.Context, req *SyncRequest) (*SyncResponse, tiny_errors.ErrorHandler)
+
+	// Run periodically syncs configured ref if it points to a new commit. Returns immediately if interval is not set,
+	// otherwise the loop will continue until the provided context is canceled.
+	Run(ctx context.Context)
+}
+
+type syncer struct {
+	log      logger.Logger
+	tx       transaction.Manager
+	archive  archive.Client
+	formats  content_formats.Client
+	callback callback.Outbox
+	cfg      *Config
+
+	mu sync.Mutex
+	// lastCommit is the latest commit of the configured ref which was synced.
+	lastCommit string
+}
+
+func NewSyncer(log logger.Logger, tx transaction.Manager, archive archive.Client, formats content_formats.Client, callback callback.Outbox, cfg *Config) Syncer {
+	return &syncer{
+		log:      log,
+		tx:       tx,
+		archive:  archive,
+		formats:  formats,
+		callback: callback,
+		cfg:      cfg,
+	}
+}
+
+func (s *syncer) Sync(ctx context.Context, req *SyncRequest) (*SyncResponse, tiny_errors.ErrorHandler) {
+	if req == nil {
+		req = &SyncRequest{}
+	}
+
+	ref := s.cfg.Ref
+	if req.Ref != nil && *req.Ref != "" {
+		ref = *req.Ref
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, err := openRepository(ctx, s.cfg.RepoPath)
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_InvalidPath, tiny_errors.Message(err.Error()))
+	}
+
+	commit, err := repo.resolveCommit(ctx, ref)
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message(err.Error()), tiny_errors.Detail("ref", ref))
+	}
+
+	version, err := commitVersion(ctx, repo, commit)
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_InvalidPath, tiny_errors.Message(err.Error()))
+	}
+
+	contentFormats, tErr := s.formats.GetMany(ctx)
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	manifest, skipped, err := buildManifest(ctx, repo, commit, s.cfg.Dir, version, formatsByExtension(contentFormats))
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_InvalidPath, tiny_errors.Message(err.Error()))
+	}
+
+	importReq := &archive.ImportRequest{
+		FolderID:      s.cfg.FolderID,
+		Manifest:      manifest,
+		DryRun:        req.DryRun,
+		SourceCommit:  &commit,
+		SkipUnchanged: true,
+	}
+
+	var result *archive.ImportResponse
+	if req.DryRun {
+		result, tErr = s.archive.Import(ctx, importReq)
+	} else {
+		// events of changed contents are stored in the outbox with the imported changes
+		tErr = s.tx.Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+			var err tiny_errors.ErrorHandler
+			result, err = s.archive.Import(ctx, importReq)
+			if err != nil {
+				return err
+			}
+			for _, event := range result.Events {
+				if err := s.callback.Send(ctx, event); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	if !req.DryRun && ref == s.cfg.Ref {
+		s.lastCommit = commit
+	}
+
+	return &SyncResponse{
+		Commit:    commit,
+		Version:   version,
+		Changes:   result.Changes,
+		Unchanged: result.Unchanged,
+		Skipped:   skipped,
+	}, nil
+}
+
+func (s *syncer) Run(ctx context.Context) {
+	if s.cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Info("Stopping git syncer")
+			return
+		case <-ticker.C:
+			if !s.hasNewCommit(ctx) {
+				continue
+			}
+
+			result, err := s.Sync(ctx, &SyncRequest{})
+			if err != nil {
+				s.log.Errorf("Error while syncing git repository: %s", err)
+				continue
+			}
+			s.log.Infof("Synced commit %s from git repository: %d changes", result.Commit, len(result.Changes))
+		}
+	}
+}
+
+func (s *syncer) hasNewCommit(ctx context.Context) bool {
+	repo, err := openRepository(ctx, s.cfg.RepoPath)
+	if err != nil {
+		s.log.Errorf("Error while opening git repository: %s", err)
+		return false
+	}
+
+	commit, err := repo.resolveCommit(ctx, s.cfg.Ref)
+	if err != nil {
+		s.log.Errorf("Error while resolving git ref %q: %s", s.cfg.Ref, err)
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return commit != s.lastCommit
+}
+
+// commitVersion returns the first tag of the commit or its short hash.
+func commitVersion(ctx context.Context, repo *gitRepository, commit string) (string, error) {
+	tags, err := repo.tags(ctx, commit)
+	if err != nil {
+		return "", err
+	}
+	if len(tags) > 0 {
+		return tags[0], nil
+	}
+	if len(commit) > SHORT_COMMIT_LENGTH {
+		return commit[:SHORT_COMMIT_LENGTH], nil
+	}
+	return commit, nil
+}
+
+// formatsByExtension maps extensions of content formats to their names. Formats without extension are omitted.
+func formatsByExtension(contentFormats []*content_formats.ContentFormat) map[string]string {
+	extensions := make(map[string]string, len(contentFormats))
+	for _, format := range contentFormats {
+		if format.Extension == nil || *format.Extension == "" {
+			continue
+		}
+		extensions["."+strings.ToLower(strings.TrimPrefix(*format.Extension, "."))] = format.Name
+	}
+	return extensions
+}
+
+// buildManifest maps directories of the commit to folders and files with extensions of content formats to files
+// with a single version. Returns paths of skipped files.
+func buildManifest(ctx context.Context, repo *gitRepository, commit, dir, version string, extensions map[string]string) (*archive.Manifest, []string, error) {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+
+	blobs, err := repo.blobs(ctx, commit, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifest := &archive.Manifest{Version: archive.MANIFEST_VERSION}
+	folders := make(map[string]*archive.Folder)
+	skipped := make([]string, 0)
+
+	for _, b := range blobs {
+		relPath := b.Path
+		if dir != "" {
+			relPath = strings.TrimPrefix(relPath, dir+"/")
+		}
+
+		format, ok := extensions[strings.ToLower(path.Ext(relPath))]
+		if !ok {
+			skipped = append(skipped, b.Path)
+			continue
+		}
+
+		data, err := repo.readBlob(ctx, b.Hash)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		file := &archive.File{
+			Name:     path.Base(relPath),
+			Contents: []*archive.Content{{Version: version, Format: format, Data: data}},
+		}
+
+		parentDir := path.Dir(relPath)
+		if parentDir == "." {
+			manifest.Files = append(manifest.Files, file)
+			continue
+		}
+		parent := folderByPath(manifest, folders, parentDir)
+		parent.Files = append(parent.Files, file)
+	}
+
+	return manifest, skipped, nil
+}
+
+// folderByPath returns folder of the manifest by its path, missing folders are created.
+func folderByPath(manifest *archive.Manifest, folders map[string]*archive.Folder, folderPath string) *archive.Folder {
+	if folder, ok := folders[folderPath]; ok {
+		return folder
+	}
+
+	folder := &archive.Folder{Name: path.Base(folderPath)}
+	folders[folderPath] = folder
+
+	parentDir := path.Dir(folderPath)
+	if parentDir == "." {
+		manifest.Folders = append(manifest.Folders, folder)
+	} else {
+		parent := folderByPath(manifest, folders, parentDir)
+		parent.Folders = append(parent.Folders, folder)
+	}
+	return folder
+}
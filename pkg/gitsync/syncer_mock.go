@@ -0,0 +1,30 @@
+package gitsync
+
+import (
+	"context"
+
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSyncer struct {
+	mock.Mock
+}
+
+func NewMock() *MockSyncer {
+	return new(MockSyncer)
+}
+
+func (m *MockSyncer) Sync(ctx context.Context, req *SyncRequest) (*SyncResponse, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	response := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return response.(*SyncResponse), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockSyncer) Run(ctx context.Context) {
+	m.Called(ctx)
+}
@@ -0,0 +1,180 @@
+package gitsync
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/archive"
+	"github.com/Moranilt/config-keeper/pkg/callback"
+	"github.com/Moranilt/config-keeper/pkg/content_formats"
+	"github.com/Moranilt/config-keeper/pkg/transaction"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/logger"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// initRepository creates a repository with one commit and returns its path and hash of the commit.
+func initRepository(t *testing.T, files map[string]string) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return string(out)
+	}
+
+	git("init", "--quiet")
+	for name, content := range files {
+		filePath := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	}
+	git("add", ".")
+	git("commit", "--quiet", "-m", "init")
+
+	commit := git("rev-parse", "HEAD")
+	return dir, commit[:len(commit)-1]
+}
+
+// formatsMock returns content formats with extensions of yaml, json and env files and a format without extension.
+func formatsMock() *content_formats.MockClient {
+	formats := content_formats.NewMock()
+	formats.On("GetMany", mock.Anything).Return([]*content_formats.ContentFormat{
+		{Name: "yaml", Extension: utils.MakePointer("yaml")},
+		{Name: "json", Extension: utils.MakePointer(".JSON")},
+		{Name: "env", Extension: utils.MakePointer("env")},
+		{Name: "text"},
+	}, nil)
+	return formats
+}
+
+func TestSyncer_Sync(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	repoPath, commit := initRepository(t, map[string]string{
+		"configs/billing/app.yaml": "port: 8080",
+		"configs/billing/.env":     "KEY=value",
+		"configs/common.json":      `{"debug": false}`,
+		"configs/README.md":        "docs",
+		"scripts/deploy.yaml":      "steps: []",
+	})
+
+	expectedManifest := &archive.Manifest{
+		Version: archive.MANIFEST_VERSION,
+		Folders: []*archive.Folder{
+			{
+				Name: "billing",
+				Files: []*archive.File{
+					{Name: ".env", Contents: []*archive.Content{{Version: commit[:SHORT_COMMIT_LENGTH], Format: "env", Data: "KEY=value"}}},
+					{Name: "app.yaml", Contents: []*archive.Content{{Version: commit[:SHORT_COMMIT_LENGTH], Format: "yaml", Data: "port: 8080"}}},
+				},
+			},
+		},
+		Files: []*archive.File{
+			{Name: "common.json", Contents: []*archive.Content{{Version: commit[:SHORT_COMMIT_LENGTH], Format: "json", Data: `{"debug": false}`}}},
+		},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		archiveClient := archive.NewMock()
+		txManager := transaction.NewMock()
+		outbox := callback.NewMock()
+		s := NewSyncer(logger.NewMock(), txManager, archiveClient, formatsMock(), outbox, &Config{
+			RepoPath: repoPath,
+			Ref:      DEFAULT_REF,
+			Dir:      "configs/",
+			FolderID: "folder_id",
+		})
+
+		changes := []*archive.Change{{Action: archive.ACTION_CREATE, Type: archive.ITEM_TYPE_FILE, Path: "common.json"}}
+		archiveClient.On("Import", mock.Anything, &archive.ImportRequest{
+			FolderID:      "folder_id",
+			Manifest:      expectedManifest,
+			SourceCommit:  utils.MakePointer(commit),
+			SkipUnchanged: true,
+		}).Return(&archive.ImportResponse{Changes: changes, Unchanged: 2, Events: []*callback.CallbackRequest{
+			{
+				FileID:  "file_id",
+				Event:   webhook.EVENT_CONTENT_CREATED,
+				Details: &callback.EventDetails{ContentID: utils.MakePointer("content_id"), Version: utils.MakePointer(commit[:SHORT_COMMIT_LENGTH])},
+			},
+		}}, nil)
+		txManager.On("Run", mock.Anything).Return()
+		outbox.On("Send", mock.Anything, &callback.CallbackRequest{
+			FileID:  "file_id",
+			Event:   webhook.EVENT_CONTENT_CREATED,
+			Details: &callback.EventDetails{ContentID: utils.MakePointer("content_id"), Version: utils.MakePointer(commit[:SHORT_COMMIT_LENGTH])},
+		}).Return(nil)
+
+		result, err := s.Sync(context.Background(), &SyncRequest{})
+		assert.Nil(t, err)
+		assert.Equal(t, &SyncResponse{
+			Commit:    commit,
+			Version:   commit[:SHORT_COMMIT_LENGTH],
+			Changes:   changes,
+			Unchanged: 2,
+			Skipped:   []string{"configs/README.md"},
+		}, result)
+		archiveClient.AssertExpectations(t)
+		txManager.AssertExpectations(t)
+		outbox.AssertExpectations(t)
+	})
+
+	t.Run("tag is used as a version", func(t *testing.T) {
+		out, err := exec.Command("git", "-C", repoPath, "tag", "v1.0.0").CombinedOutput()
+		require.NoError(t, err, string(out))
+
+		archiveClient := archive.NewMock()
+		s := NewSyncer(logger.NewMock(), transaction.NewMock(), archiveClient, formatsMock(), callback.NewMock(), &Config{
+			RepoPath: repoPath,
+			Ref:      DEFAULT_REF,
+			Dir:      "configs/billing",
+			FolderID: "folder_id",
+		})
+
+		archiveClient.On("Import", mock.Anything, mock.MatchedBy(func(req *archive.ImportRequest) bool {
+			return req.DryRun && len(req.Manifest.Files) == 2 && req.Manifest.Files[0].Contents[0].Version == "v1.0.0"
+		})).Return(&archive.ImportResponse{Changes: []*archive.Change{}}, nil)
+
+		result, tErr := s.Sync(context.Background(), &SyncRequest{Ref: utils.MakePointer("v1.0.0"), DryRun: true})
+		assert.Nil(t, tErr)
+		assert.Equal(t, "v1.0.0", result.Version)
+		archiveClient.AssertExpectations(t)
+	})
+
+	t.Run("unknown ref", func(t *testing.T) {
+		s := NewSyncer(logger.NewMock(), transaction.NewMock(), archive.NewMock(), formatsMock(), callback.NewMock(), &Config{RepoPath: repoPath, Ref: DEFAULT_REF})
+
+		_, err := s.Sync(context.Background(), &SyncRequest{Ref: utils.MakePointer("unknown")})
+		assert.Equal(t, custom_errors.ERR_CODE_NotValid, err.GetCode())
+	})
+
+	t.Run("not a repository", func(t *testing.T) {
+		s := NewSyncer(logger.NewMock(), transaction.NewMock(), archive.NewMock(), content_formats.NewMock(), callback.NewMock(), &Config{RepoPath: t.TempDir(), Ref: DEFAULT_REF})
+
+		_, err := s.Sync(context.Background(), nil)
+		assert.Equal(t, custom_errors.ERR_CODE_InvalidPath, err.GetCode())
+	})
+
+	t.Run("formats error", func(t *testing.T) {
+		formats := content_formats.NewMock()
+		formats.On("GetMany", mock.Anything).Return(nil, tiny_errors.New(custom_errors.ERR_CODE_Database))
+		s := NewSyncer(logger.NewMock(), transaction.NewMock(), archive.NewMock(), formats, callback.NewMock(), &Config{RepoPath: repoPath, Ref: DEFAULT_REF})
+
+		_, err := s.Sync(context.Background(), nil)
+		assert.Equal(t, custom_errors.ERR_CODE_Database, err.GetCode())
+		formats.AssertExpectations(t)
+	})
+}
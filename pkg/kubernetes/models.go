@@ -0,0 +1,72 @@
+package kubernetes
+
+const (
+	// FORMAT is an export format which renders Kubernetes manifests.
+	FORMAT = "kubernetes"
+
+	// SECRET_LABEL marks files which are rendered as Secret instead of ConfigMap, e.g. "config-keeper.io/secret=true".
+	SECRET_LABEL       = "config-keeper.io/secret"
+	SECRET_LABEL_VALUE = "true"
+
+	// PATH_ANNOTATION keeps path of the folder the manifest was rendered from.
+	PATH_ANNOTATION  = "config-keeper.io/path"
+	MANAGED_BY_LABEL = "app.kubernetes.io/managed-by"
+	MANAGED_BY       = "config-keeper"
+
+	DEFAULT_NAME_TEMPLATE = "{{ .Path }}"
+
+	KIND_CONFIG_MAP = "ConfigMap"
+	KIND_SECRET     = "Secret"
+
+	MAX_NAME_LENGTH      = 253
+	MAX_NAMESPACE_LENGTH = 63
+
+	CONTENT_TYPE = "application/yaml"
+)
+
+// Options describes how names and namespaces of manifests are built. Templates use text/template
+// syntax with TemplateData, e.g. "{{ index .Segments 0 }}" or "{{ .Name }}-config".
+type Options struct {
+	// NamespaceTemplate is optional, manifests are rendered without namespace if it is empty.
+	NamespaceTemplate string
+	// NameTemplate is DEFAULT_NAME_TEMPLATE if empty.
+	NameTemplate string
+}
+
+// TemplateData is available in namespace and name templates.
+type TemplateData struct {
+	// Path is a path of the folder, e.g. "services/billing". Path of the root folder is "root".
+	Path string
+	// Name is a name of the folder.
+	Name string
+	// Segments are parts of the path.
+	Segments []string
+}
+
+// Group is a set of files from the same folder. Every group is rendered as a ConfigMap with
+// regular files and a Secret with secret files, both named after the folder.
+type Group struct {
+	Path  string
+	Files []*File
+}
+
+type File struct {
+	Name   string
+	Data   string
+	Secret bool
+}
+
+type objectMeta struct {
+	Name        string            `yaml:"name"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+type object struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   objectMeta        `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data"`
+}
@@ -0,0 +1,195 @@
+package kubernetes
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/Moranilt/config-keeper/pkg/archive"
+	"github.com/Moranilt/config-keeper/pkg/folders"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+	invalidKeyChars  = regexp.MustCompile(`[^-._a-zA-Z0-9]+`)
+)
+
+// GroupsFromManifest groups files of the exported tree by folders. basePath is a path of the exported folder.
+// If version is provided, files without this version are skipped, otherwise the latest version of every file is used.
+func GroupsFromManifest(manifest *archive.Manifest, basePath string, version *string) []*Group {
+	var groups []*Group
+
+	var walk func(folderPath string, nested []*archive.Folder, files []*archive.File)
+	walk = func(folderPath string, nested []*archive.Folder, files []*archive.File) {
+		group := &Group{Path: folderPath}
+		for _, file := range files {
+			content := selectContent(file.Contents, version)
+			if content == nil {
+				continue
+			}
+			group.Files = append(group.Files, &File{
+				Name:   file.Name,
+				Data:   content.Data,
+				Secret: IsSecret(file.Labels),
+			})
+		}
+		if len(group.Files) > 0 {
+			groups = append(groups, group)
+		}
+
+		for _, folder := range nested {
+			walk(childPath(folderPath, folder.Name), folder.Folders, folder.Files)
+		}
+	}
+	walk(basePath, manifest.Folders, manifest.Files)
+
+	return groups
+}
+
+// IsSecret returns true if file labels mark it as a secret.
+func IsSecret(labels map[string]string) bool {
+	return labels[SECRET_LABEL] == SECRET_LABEL_VALUE
+}
+
+// Render writes ConfigMap and Secret manifests of all groups as a multi-document YAML.
+func Render(w io.Writer, groups []*Group, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	nameTemplate := opts.NameTemplate
+	if nameTemplate == "" {
+		nameTemplate = DEFAULT_NAME_TEMPLATE
+	}
+	nameTmpl, err := template.New("name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return fmt.Errorf("name template: %w", err)
+	}
+
+	var namespaceTmpl *template.Template
+	if opts.NamespaceTemplate != "" {
+		namespaceTmpl, err = template.New("namespace").Option("missingkey=error").Parse(opts.NamespaceTemplate)
+		if err != nil {
+			return fmt.Errorf("namespace template: %w", err)
+		}
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	for _, group := range groups {
+		data := templateData(group.Path)
+
+		name, err := execute(nameTmpl, data, MAX_NAME_LENGTH, true)
+		if err != nil {
+			return fmt.Errorf("name of %q: %w", group.Path, err)
+		}
+
+		var namespace string
+		if namespaceTmpl != nil {
+			namespace, err = execute(namespaceTmpl, data, MAX_NAMESPACE_LENGTH, false)
+			if err != nil {
+				return fmt.Errorf("namespace of %q: %w", group.Path, err)
+			}
+		}
+
+		configData := make(map[string]string)
+		secretData := make(map[string]string)
+		for _, file := range group.Files {
+			key := invalidKeyChars.ReplaceAllString(file.Name, "_")
+			if _, ok := configData[key]; ok {
+				return fmt.Errorf("file %q of %q: key %q is used by another file", file.Name, group.Path, key)
+			}
+			if _, ok := secretData[key]; ok {
+				return fmt.Errorf("file %q of %q: key %q is used by another file", file.Name, group.Path, key)
+			}
+
+			if file.Secret {
+				secretData[key] = base64.StdEncoding.EncodeToString([]byte(file.Data))
+			} else {
+				configData[key] = file.Data
+			}
+		}
+
+		meta := objectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      map[string]string{MANAGED_BY_LABEL: MANAGED_BY},
+			Annotations: map[string]string{PATH_ANNOTATION: group.Path},
+		}
+
+		if len(configData) > 0 {
+			err = encoder.Encode(&object{APIVersion: "v1", Kind: KIND_CONFIG_MAP, Metadata: meta, Data: configData})
+			if err != nil {
+				return err
+			}
+		}
+		if len(secretData) > 0 {
+			err = encoder.Encode(&object{APIVersion: "v1", Kind: KIND_SECRET, Metadata: meta, Type: "Opaque", Data: secretData})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return encoder.Close()
+}
+
+func selectContent(contents []*archive.Content, version *string) *archive.Content {
+	if version == nil {
+		if len(contents) == 0 {
+			return nil
+		}
+		return contents[len(contents)-1]
+	}
+
+	for _, content := range contents {
+		if content.Version == *version {
+			return content
+		}
+	}
+	return nil
+}
+
+func childPath(folderPath, name string) string {
+	if folderPath == "" || folderPath == folders.ROOT_ALIAS {
+		return name
+	}
+	return path.Join(folderPath, name)
+}
+
+func templateData(folderPath string) *TemplateData {
+	return &TemplateData{
+		Path:     folderPath,
+		Name:     path.Base(folderPath),
+		Segments: strings.Split(folderPath, "/"),
+	}
+}
+
+// execute renders the template and converts result to a DNS-1123 name. Dots are allowed only in names, not in namespaces.
+func execute(tmpl *template.Template, data *TemplateData, maxLength int, allowDots bool) (string, error) {
+	var result strings.Builder
+	if err := tmpl.Execute(&result, data); err != nil {
+		return "", err
+	}
+
+	name := strings.ReplaceAll(strings.ToLower(result.String()), "/", "-")
+	if !allowDots {
+		name = strings.ReplaceAll(name, ".", "-")
+	}
+	name = invalidNameChars.ReplaceAllString(name, "-")
+	if len(name) > maxLength {
+		name = name[:maxLength]
+	}
+	name = strings.Trim(name, "-.")
+
+	if name == "" {
+		return "", fmt.Errorf("template %q produced an empty name", tmpl.Name())
+	}
+	return name, nil
+}
@@ -0,0 +1,109 @@
+package kubernetes
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Moranilt/config-keeper/pkg/archive"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupsFromManifest(t *testing.T) {
+	manifest := &archive.Manifest{
+		Files: []*archive.File{
+			{Name: "common.env", Contents: []*archive.Content{{Version: "v1", Data: "A=1"}, {Version: "v2", Data: "A=2"}}},
+			{Name: "empty.yaml"},
+		},
+		Folders: []*archive.Folder{
+			{
+				Name: "billing",
+				Files: []*archive.File{
+					{
+						Name:     "db.env",
+						Metadata: utils.Metadata{Labels: utils.Labels{SECRET_LABEL: SECRET_LABEL_VALUE}},
+						Contents: []*archive.Content{{Version: "v1", Data: "PASSWORD=secret"}},
+					},
+				},
+			},
+		},
+	}
+
+	t.Run("latest versions", func(t *testing.T) {
+		groups := GroupsFromManifest(manifest, "services", nil)
+		assert.Equal(t, []*Group{
+			{Path: "services", Files: []*File{{Name: "common.env", Data: "A=2"}}},
+			{Path: "services/billing", Files: []*File{{Name: "db.env", Data: "PASSWORD=secret", Secret: true}}},
+		}, groups)
+	})
+
+	t.Run("exact version from the root folder", func(t *testing.T) {
+		groups := GroupsFromManifest(manifest, "root", utils.MakePointer("v2"))
+		assert.Equal(t, []*Group{
+			{Path: "root", Files: []*File{{Name: "common.env", Data: "A=2"}}},
+		}, groups)
+	})
+}
+
+func TestRender(t *testing.T) {
+	groups := []*Group{
+		{Path: "production/Billing_API", Files: []*File{
+			{Name: "app.yaml", Data: "port: 8080\ndebug: false\n"},
+			{Name: "db password.env", Data: "PASSWORD=secret", Secret: true},
+		}},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := Render(&buf, groups, &Options{
+			NamespaceTemplate: "{{ index .Segments 0 }}",
+			NameTemplate:      "{{ .Name }}-config",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: billing-api-config
+  namespace: production
+  labels:
+    app.kubernetes.io/managed-by: config-keeper
+  annotations:
+    config-keeper.io/path: production/Billing_API
+data:
+  app.yaml: |
+    port: 8080
+    debug: false
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: billing-api-config
+  namespace: production
+  labels:
+    app.kubernetes.io/managed-by: config-keeper
+  annotations:
+    config-keeper.io/path: production/Billing_API
+type: Opaque
+data:
+  db_password.env: UEFTU1dPUkQ9c2VjcmV0
+`, buf.String())
+	})
+
+	t.Run("default name template", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := Render(&buf, groups[:1], nil)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "name: production-billing-api\n")
+		assert.NotContains(t, buf.String(), "namespace:")
+	})
+
+	t.Run("empty name", func(t *testing.T) {
+		err := Render(&bytes.Buffer{}, groups, &Options{NameTemplate: "{{ if false }}name{{ end }}"})
+		assert.EqualError(t, err, `name of "production/Billing_API": template "name" produced an empty name`)
+	})
+
+	t.Run("broken template", func(t *testing.T) {
+		err := Render(&bytes.Buffer{}, groups, &Options{NamespaceTemplate: "{{ .Unknown }"})
+		assert.Error(t, err)
+	})
+}
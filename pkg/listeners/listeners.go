@@ -2,25 +2,38 @@ package listeners
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/encryption"
+	"github.com/Moranilt/config-keeper/pkg/transaction"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
 	"github.com/Moranilt/config-keeper/utils"
 	"github.com/Moranilt/http-utils/clients/database"
 	"github.com/Moranilt/http-utils/query"
 	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/lib/pq"
 )
 
 type client struct {
-	db *database.Client
+	db     *database.Client
+	cipher *encryption.Cipher
+	health HealthConfig
 }
 
 type Client interface {
-	// Create creates a new listener in the database
+	// Create creates a new listener in the database. Custom headers and auth are encrypted with the cipher of the client.
 	Create(ctx context.Context, req *CreateRequest) (*Listener, tiny_errors.ErrorHandler)
 
-	// GetMany retrieves multiple listeners from the database
-	GetMany(ctx context.Context, req *GetManyRequest) ([]*Listener, tiny_errors.ErrorHandler)
+	// GetMany retrieves multiple listeners from the database.
+	// Returns cursor of the next page if there are more listeners.
+	GetMany(ctx context.Context, req *GetManyRequest) ([]*Listener, *string, tiny_errors.ErrorHandler)
 
 	// Get retrieves a single listener from the database
 	Get(ctx context.Context, req *GetRequest) (*Listener, tiny_errors.ErrorHandler)
@@ -28,15 +41,40 @@ type Client interface {
 	// Delete removes a listener from the database
 	Delete(ctx context.Context, req *DeleteRequest) (bool, tiny_errors.ErrorHandler)
 
-	// Edit updates an existing listener in the database
+	// Edit updates an existing listener in the database. Headers and auth are replaced if they are provided.
 	Edit(ctx context.Context, req *EditRequest) (*Listener, tiny_errors.ErrorHandler)
+
+	// RotateSecret generates a new secret of the listener. The current secret becomes previous and is used
+	// for signing together with the new one until its lifetime ends.
+	RotateSecret(ctx context.Context, req *RotateSecretRequest) (*Listener, tiny_errors.ErrorHandler)
+
+	// Enable enables the listener and resets its failures.
+	Enable(ctx context.Context, req *EnableRequest) (*Listener, tiny_errors.ErrorHandler)
+
+	// Disable disables the listener with the reason. Events of disabled listeners are not delivered.
+	Disable(ctx context.Context, req *DisableRequest) (*Listener, tiny_errors.ErrorHandler)
+
+	// RecordSuccess resets consecutive failures of the listener after a successful callback request.
+	RecordSuccess(ctx context.Context, id string) tiny_errors.ErrorHandler
+
+	// RecordFailure counts a failed callback request of the listener. The listener becomes unhealthy and is disabled
+	// after the thresholds of the HealthConfig of the client.
+	// Returns false if the listener is disabled.
+	RecordFailure(ctx context.Context, id string, reason string) (bool, tiny_errors.ErrorHandler)
 }
 
 // New creates a new Client implementation backed by the provided database.Client.
-func New(db *database.Client) Client {
-	return &client{
-		db: db,
+// Headers and auth of listeners can not be set or read if cipher is nil. Default thresholds are used if health is nil.
+func New(db *database.Client, cipher *encryption.Cipher, health *HealthConfig) Client {
+	c := &client{
+		db:     db,
+		cipher: cipher,
+	}
+	if health != nil {
+		c.health = *health
 	}
+	c.health = c.health.WithDefaults()
+	return c
 }
 
 func (c *client) Create(ctx context.Context, req *CreateRequest) (*Listener, tiny_errors.ErrorHandler) {
@@ -54,34 +92,103 @@ func (c *client) Create(ctx context.Context, req *CreateRequest) (*Listener, tin
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, requiredErr...)
 	}
 
-	row := c.db.QueryRowxContext(ctx, QUERY_CREATE_LISTENER, req.FileID, req.CallbackEndpoint, req.Name)
+	if err := validateSettings(req.Headers, req.Auth, false); err != nil {
+		return nil, err
+	}
+	if err := req.RetryPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	if err := validateEventTypes(req.EventTypes); err != nil {
+		return nil, err
+	}
+	if err := req.VersionFilter.Validate(); err != nil {
+		return nil, err
+	}
+	payloadMode := PAYLOAD_FULL
+	if req.PayloadMode != "" {
+		payloadMode = req.PayloadMode
+	}
+	if err := validatePayloadMode(payloadMode); err != nil {
+		return nil, err
+	}
+	var versionFilter VersionFilter
+	if req.VersionFilter != nil {
+		versionFilter = *req.VersionFilter
+	}
+	var retryPolicy RetryPolicy
+	if req.RetryPolicy != nil {
+		retryPolicy = *req.RetryPolicy
+	}
+
+	settings, err := c.encryptSettings(&RequestSettings{Headers: req.Headers, Auth: req.Auth})
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	row := transaction.From(ctx, c.db).QueryRowxContext(ctx, QUERY_CREATE_LISTENER, req.FileID, req.CallbackEndpoint, req.Name, secret, settings, retryPolicy, req.FollowRedirects, eventTypes(req.EventTypes), versionFilter, payloadMode)
 	if row.Err() != nil {
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(row.Err().Error()))
 	}
 
 	var listener Listener
-	err := row.StructScan(&listener)
-	if err != nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	scanErr := row.StructScan(&listener)
+	if scanErr != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(scanErr.Error()))
 	}
 
+	if err := c.decryptSettings(&listener); err != nil {
+		return nil, err
+	}
 	return &listener, nil
 }
 
-func (c *client) GetMany(ctx context.Context, req *GetManyRequest) ([]*Listener, tiny_errors.ErrorHandler) {
+func (c *client) GetMany(ctx context.Context, req *GetManyRequest) ([]*Listener, *string, tiny_errors.ErrorHandler) {
 	if req == nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+		return nil, nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
 	}
 
-	preparedQuery := query.New(QUERY_GET_LISTENERS).Where().EQ("file_id", req.FileID).Query().Order("name", "asc")
+	var orderColumn *string
+	var orderType *string
+	if req.Order != nil {
+		orderColumn = req.Order.Column
+		orderType = req.Order.Type
+	}
 
-	listeners := make([]*Listener, 0)
-	err := c.db.SelectContext(ctx, &listeners, preparedQuery.String())
+	column, orderExpression, err := utils.OrderColumn(orderColumn, ORDER_COLUMNS, "name")
 	if err != nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		return nil, nil, err
+	}
+	order := utils.OrderType(orderType)
+
+	preparedQuery := utils.NewListQuery(QUERY_GET_LISTENERS).Where("file_id = ?", req.FileID)
+
+	err = req.Filter.Apply(preparedQuery, LIST_COLUMNS, orderExpression, order)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return listeners, nil
+	listeners := make([]*Listener, 0)
+	dbErr := transaction.From(ctx, c.db).SelectContext(ctx, &listeners, preparedQuery.String(), preparedQuery.Args()...)
+	if dbErr != nil {
+		return nil, nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(dbErr.Error()))
+	}
+
+	for _, listener := range listeners {
+		if err := c.decryptSettings(listener); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	listeners, next := utils.NextCursor(req.Filter, listeners, func(listener *Listener) (string, string) {
+		return listener.columnValue(column), listener.ID
+	})
+
+	return listeners, next, nil
 }
 
 func (c *client) Get(ctx context.Context, req *GetRequest) (*Listener, tiny_errors.ErrorHandler) {
@@ -91,7 +198,7 @@ func (c *client) Get(ctx context.Context, req *GetRequest) (*Listener, tiny_erro
 
 	preparedQuery := query.New(QUERY_GET_LISTENERS).Where().EQ("id", req.ID).Query()
 	var listener Listener
-	err := c.db.GetContext(ctx, &listener, preparedQuery.String())
+	err := transaction.From(ctx, c.db).GetContext(ctx, &listener, preparedQuery.String())
 	if err != nil && err != sql.ErrNoRows {
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
 	}
@@ -100,6 +207,9 @@ func (c *client) Get(ctx context.Context, req *GetRequest) (*Listener, tiny_erro
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound)
 	}
 
+	if err := c.decryptSettings(&listener); err != nil {
+		return nil, err
+	}
 	return &listener, nil
 }
 
@@ -108,7 +218,7 @@ func (c *client) Delete(ctx context.Context, req *DeleteRequest) (bool, tiny_err
 		return false, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
 	}
 
-	result, err := c.db.ExecContext(ctx, QUERY_DELETE_LISTENER, req.ID)
+	result, err := transaction.From(ctx, c.db).ExecContext(ctx, QUERY_DELETE_LISTENER, req.ID)
 	if err != nil {
 		return false, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
 	}
@@ -138,42 +248,181 @@ func (c *client) Edit(ctx context.Context, req *EditRequest) (*Listener, tiny_er
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, requiredErr...)
 	}
 
-	if req.CallbackEndpoint == nil && req.Name == nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, tiny_errors.Detail("name or callback_endpoint", "required"))
+	if req.CallbackEndpoint == nil && req.Name == nil && req.Headers == nil && req.Auth == nil && req.RetryPolicy == nil && req.FollowRedirects == nil &&
+		req.EventTypes == nil && req.VersionFilter == nil && req.PayloadMode == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, tiny_errors.Detail("name, callback_endpoint, headers, auth, retry_policy, follow_redirects, event_types, version_filter or payload_mode", "required"))
+	}
+	if err := validateSettings(req.Headers, req.Auth, true); err != nil {
+		return nil, err
+	}
+	if err := req.RetryPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	if err := validateEventTypes(req.EventTypes); err != nil {
+		return nil, err
+	}
+	if err := req.VersionFilter.Validate(); err != nil {
+		return nil, err
+	}
+	if req.PayloadMode != nil {
+		if err := validatePayloadMode(*req.PayloadMode); err != nil {
+			return nil, err
+		}
 	}
 
-	tx, err := c.db.BeginTxx(ctx, nil)
+	var listener Listener
+	err := transaction.New(c.db).Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+		db := transaction.From(ctx, c.db)
+		preparedQuery := query.New(QUERY_GET_LISTENERS).Where().EQ("id", req.ID).Query()
+		err := db.QueryRowxContext(ctx, preparedQuery.String()).StructScan(&listener)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("listener does not exist"))
+			}
+			return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		}
+
+		var settings *string
+		if req.Headers != nil || req.Auth != nil {
+			if err := c.decryptSettings(&listener); err != nil {
+				return err
+			}
+			var encryptErr tiny_errors.ErrorHandler
+			settings, encryptErr = c.encryptSettings(mergeSettings(listener.Settings, req))
+			if encryptErr != nil {
+				return encryptErr
+			}
+		}
+
+		updateQuery := buildUpdateQuery(req, settings)
+		err = db.QueryRowxContext(ctx, updateQuery).StructScan(&listener)
+		if err != nil {
+			return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		}
+		return c.decryptSettings(&listener)
+	})
 	if err != nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		return nil, err
+	}
+
+	return &listener, nil
+}
+
+func (c *client) RotateSecret(ctx context.Context, req *RotateSecretRequest) (*Listener, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	lifetime := PREVIOUS_SECRET_LIFETIME
+	if req.PreviousLifetime != nil {
+		lifetime = *req.PreviousLifetime
+	}
+	if lifetime < 0 {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("previous_lifetime", "can not be negative"))
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
 	}
-	defer tx.Rollback()
 
 	var listener Listener
-	preparedQuery := query.New(QUERY_GET_LISTENERS).Where().EQ("id", req.ID).Query()
-	err = tx.QueryRowxContext(ctx, preparedQuery.String()).StructScan(&listener)
+	dbErr := transaction.From(ctx, c.db).QueryRowxContext(ctx, QUERY_ROTATE_SECRET, req.ID, secret, lifetime.Seconds()).StructScan(&listener)
+	if dbErr == sql.ErrNoRows {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("listener does not exist"))
+	}
+	if dbErr != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(dbErr.Error()))
+	}
+
+	if err := c.decryptSettings(&listener); err != nil {
+		return nil, err
+	}
+	return &listener, nil
+}
+
+func (c *client) Enable(ctx context.Context, req *EnableRequest) (*Listener, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	return c.updateState(ctx, QUERY_ENABLE_LISTENER, req.ID)
+}
+
+func (c *client) Disable(ctx context.Context, req *DisableRequest) (*Listener, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	reason := DISABLED_MANUALLY
+	if req.Reason != nil && *req.Reason != "" {
+		reason = *req.Reason
+	}
+	return c.updateState(ctx, QUERY_DISABLE_LISTENER, req.ID, reason)
+}
+
+func (c *client) RecordSuccess(ctx context.Context, id string) tiny_errors.ErrorHandler {
+	_, err := transaction.From(ctx, c.db).ExecContext(ctx, QUERY_RECORD_SUCCESS, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("listener does not exist"))
-		}
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
 	}
+	return nil
+}
+
+func (c *client) RecordFailure(ctx context.Context, id string, reason string) (bool, tiny_errors.ErrorHandler) {
+	disabledReason := fmt.Sprintf("disabled after %d consecutive failures, last error: %s", c.health.DisableThreshold, reason)
 
-	updateQuery := buildUpdateQuery(req)
-	err = tx.QueryRowxContext(ctx, updateQuery).StructScan(&listener)
+	var enabled bool
+	err := transaction.From(ctx, c.db).QueryRowxContext(ctx, QUERY_RECORD_FAILURE, id, c.health.UnhealthyThreshold, c.health.DisableThreshold, disabledReason).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return false, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("listener does not exist"))
+	}
 	if err != nil {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+		return false, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
 	}
+	return enabled, nil
+}
 
-	if err := tx.Commit(); err != nil {
+// updateState runs the query updating the listener with the given id and returns the updated listener.
+func (c *client) updateState(ctx context.Context, updateQuery string, args ...any) (*Listener, tiny_errors.ErrorHandler) {
+	var listener Listener
+	err := transaction.From(ctx, c.db).QueryRowxContext(ctx, updateQuery, args...).StructScan(&listener)
+	if err == sql.ErrNoRows {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("listener does not exist"))
+	}
+	if err != nil {
 		return nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
 	}
 
+	if err := c.decryptSettings(&listener); err != nil {
+		return nil, err
+	}
 	return &listener, nil
 }
 
-func buildUpdateQuery(req *EditRequest) string {
+// mergeSettings replaces current headers and auth with provided ones. Auth with empty type removes auth.
+func mergeSettings(current *RequestSettings, req *EditRequest) *RequestSettings {
+	settings := &RequestSettings{}
+	if current != nil {
+		*settings = *current
+	}
+	if req.Headers != nil {
+		settings.Headers = req.Headers
+	}
+	if req.Auth != nil {
+		settings.Auth = req.Auth
+		if req.Auth.Type == "" {
+			settings.Auth = nil
+		}
+	}
+	return settings
+}
+
+// buildUpdateQuery sets settings only if headers or auth are provided, nil settings are removed.
+// Provided retry policy, event types and version filter replace the stored ones.
+func buildUpdateQuery(req *EditRequest, settings *string) string {
 	queryBuilder := query.New("UPDATE listeners").Set("updated_at", "now()").Where().EQ("id", req.ID).Query().
-		Returning("id", "file_id", "callback_endpoint", "name", "created_at", "updated_at")
+		Returning(LISTENER_COLUMNS)
 
 	if req.Name != nil {
 		queryBuilder.Set("name", *req.Name)
@@ -181,6 +430,61 @@ func buildUpdateQuery(req *EditRequest) string {
 	if req.CallbackEndpoint != nil {
 		queryBuilder.Set("callback_endpoint", *req.CallbackEndpoint)
 	}
+	if req.Headers != nil || req.Auth != nil {
+		queryBuilder.Set("settings", settings)
+	}
+	if req.RetryPolicy != nil {
+		retryPolicy, _ := json.Marshal(req.RetryPolicy)
+		queryBuilder.Set("retry_policy", string(retryPolicy))
+	}
+	if req.FollowRedirects != nil {
+		queryBuilder.Set("follow_redirects", *req.FollowRedirects)
+	}
+	if req.EventTypes != nil {
+		value, _ := eventTypes(req.EventTypes).Value()
+		queryBuilder.Set("event_types", value)
+	}
+	if req.VersionFilter != nil {
+		versionFilter, _ := json.Marshal(req.VersionFilter)
+		queryBuilder.Set("version_filter", string(versionFilter))
+	}
+	if req.PayloadMode != nil {
+		queryBuilder.Set("payload_mode", *req.PayloadMode)
+	}
 
 	return queryBuilder.String()
 }
+
+// validateEventTypes checks that listeners are subscribed only to known events.
+func validateEventTypes(eventTypes []string) tiny_errors.ErrorHandler {
+	for _, eventType := range eventTypes {
+		if !slices.Contains(webhook.EVENTS, eventType) {
+			return tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("event_types", "should be one of "+strings.Join(webhook.EVENTS, ", ")))
+		}
+	}
+	return nil
+}
+
+// validatePayloadMode checks that the mode is one of PAYLOAD_MODES.
+func validatePayloadMode(mode string) tiny_errors.ErrorHandler {
+	if !slices.Contains(PAYLOAD_MODES, mode) {
+		return tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("payload_mode", "should be one of "+strings.Join(PAYLOAD_MODES, ", ")))
+	}
+	return nil
+}
+
+// eventTypes returns sorted event types without duplicates. Nil event types are empty, so they are not stored as NULL.
+func eventTypes(types []string) pq.StringArray {
+	result := slices.Clone(types)
+	slices.Sort(result)
+	return append(pq.StringArray{}, slices.Compact(result)...)
+}
+
+// generateSecret returns a hex encoded random secret of SECRET_SIZE bytes.
+func generateSecret() (string, tiny_errors.ErrorHandler) {
+	secret := make([]byte, SECRET_SIZE)
+	if _, err := rand.Read(secret); err != nil {
+		return "", tiny_errors.New(custom_errors.ERR_CODE_Marshal, tiny_errors.Message(err.Error()))
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -25,14 +25,15 @@ func (m *MockClient) Create(ctx context.Context, req *CreateRequest) (*Listener,
 	return nil, err.(tiny_errors.ErrorHandler)
 }
 
-func (m *MockClient) GetMany(ctx context.Context, req *GetManyRequest) ([]*Listener, tiny_errors.ErrorHandler) {
+func (m *MockClient) GetMany(ctx context.Context, req *GetManyRequest) ([]*Listener, *string, tiny_errors.ErrorHandler) {
 	args := m.Called(ctx, req)
 	listeners := args.Get(0)
-	err := args.Get(1)
+	next, _ := args.Get(1).(*string)
+	err := args.Get(2)
 	if err == nil {
-		return listeners.([]*Listener), nil
+		return listeners.([]*Listener), next, nil
 	}
-	return nil, err.(tiny_errors.ErrorHandler)
+	return nil, nil, err.(tiny_errors.ErrorHandler)
 }
 
 func (m *MockClient) Get(ctx context.Context, req *GetRequest) (*Listener, tiny_errors.ErrorHandler) {
@@ -64,3 +65,52 @@ func (m *MockClient) Edit(ctx context.Context, req *EditRequest) (*Listener, tin
 	}
 	return nil, err.(tiny_errors.ErrorHandler)
 }
+
+func (m *MockClient) RotateSecret(ctx context.Context, req *RotateSecretRequest) (*Listener, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	listener := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return listener.(*Listener), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockClient) Enable(ctx context.Context, req *EnableRequest) (*Listener, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	listener := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return listener.(*Listener), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockClient) Disable(ctx context.Context, req *DisableRequest) (*Listener, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	listener := args.Get(0)
+	err := args.Get(1)
+	if err == nil {
+		return listener.(*Listener), nil
+	}
+	return nil, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockClient) RecordSuccess(ctx context.Context, id string) tiny_errors.ErrorHandler {
+	args := m.Called(ctx, id)
+	err := args.Get(0)
+	if err == nil {
+		return nil
+	}
+	return err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockClient) RecordFailure(ctx context.Context, id string, reason string) (bool, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, id, reason)
+	enabled := args.Bool(0)
+	err := args.Get(1)
+	if err == nil {
+		return enabled, nil
+	}
+	return enabled, err.(tiny_errors.ErrorHandler)
+}
@@ -1,9 +1,87 @@
 package listeners
 
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/Moranilt/config-keeper/pkg/webhook"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/lib/pq"
+)
+
+// LISTENER_COLUMNS returns the previous secret only until it expires.
+const LISTENER_COLUMNS = "id, file_id, callback_endpoint, name, secret, settings, retry_policy, follow_redirects, " +
+	"enabled, healthy, disabled_reason, consecutive_failures, last_success_at, last_failure_at, event_types, " +
+	"version_filter, payload_mode, " +
+	"CASE WHEN previous_secret_expires_at > now() THEN previous_secret END AS previous_secret, " +
+	"CASE WHEN previous_secret_expires_at > now() THEN previous_secret_expires_at END AS previous_secret_expires_at, " +
+	"created_at, updated_at"
+
 const (
-	QUERY_CREATE_LISTENER = "INSERT INTO listeners (file_id, callback_endpoint, name) VALUES ($1, $2, $3) RETURNING id, file_id, callback_endpoint, name, created_at, updated_at"
-	QUERY_GET_LISTENERS   = "SELECT id, file_id, callback_endpoint, name, created_at, updated_at FROM listeners"
+	QUERY_CREATE_LISTENER = `INSERT INTO listeners (file_id, callback_endpoint, name, secret, settings, retry_policy, follow_redirects, event_types,
+	version_filter, payload_mode) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING ` + LISTENER_COLUMNS
+	QUERY_GET_LISTENERS   = "SELECT " + LISTENER_COLUMNS + " FROM listeners"
 	QUERY_DELETE_LISTENER = "DELETE FROM listeners WHERE id = $1"
+	QUERY_ROTATE_SECRET   = `UPDATE listeners SET previous_secret = secret, previous_secret_expires_at = now() + make_interval(secs => $3),
+	secret = $2, updated_at = now() WHERE id = $1 RETURNING ` + LISTENER_COLUMNS
+	QUERY_ENABLE_LISTENER = `UPDATE listeners SET enabled = true, healthy = true, disabled_reason = NULL, consecutive_failures = 0,
+	updated_at = now() WHERE id = $1 RETURNING ` + LISTENER_COLUMNS
+	QUERY_DISABLE_LISTENER = "UPDATE listeners SET enabled = false, disabled_reason = $2, updated_at = now() WHERE id = $1 RETURNING " + LISTENER_COLUMNS
+	QUERY_RECORD_SUCCESS   = "UPDATE listeners SET consecutive_failures = 0, healthy = true, last_success_at = now() WHERE id = $1"
+	// QUERY_RECORD_FAILURE uses values of the row before the update on the right side of assignments.
+	QUERY_RECORD_FAILURE = `UPDATE listeners SET consecutive_failures = consecutive_failures + 1, last_failure_at = now(),
+	healthy = consecutive_failures + 1 < $2, enabled = enabled AND consecutive_failures + 1 < $3,
+	disabled_reason = CASE WHEN enabled AND consecutive_failures + 1 >= $3 THEN $4 ELSE disabled_reason END
+	WHERE id = $1 RETURNING enabled`
+)
+
+const (
+	// SECRET_SIZE is a number of random bytes in secrets of listeners.
+	SECRET_SIZE = 32
+	// PREVIOUS_SECRET_LIFETIME is a default time during which payloads are also signed with the previous secret after rotation.
+	PREVIOUS_SECRET_LIFETIME = 24 * time.Hour
+	// DEFAULT_UNHEALTHY_THRESHOLD is a default number of consecutive failed callback requests after which the listener is unhealthy.
+	DEFAULT_UNHEALTHY_THRESHOLD = 5
+	// DEFAULT_DISABLE_THRESHOLD is a default number of consecutive failed callback requests after which the listener is disabled.
+	DEFAULT_DISABLE_THRESHOLD = 50
+	// DISABLED_MANUALLY is a reason of listeners disabled without a reason.
+	DISABLED_MANUALLY = "disabled manually"
+
+	AUTH_BEARER = "bearer"
+	AUTH_BASIC  = "basic"
+
+	// PAYLOAD_FULL sends the file with all its contents.
+	PAYLOAD_FULL = "full"
+	// PAYLOAD_VERSION sends the file with the content of the version changed by the event.
+	PAYLOAD_VERSION = "version"
+	// PAYLOAD_DIFF sends the file without contents and changed values of the content.
+	PAYLOAD_DIFF = "diff"
+	// PAYLOAD_NOTIFICATION sends the file without contents and a URL to fetch them.
+	PAYLOAD_NOTIFICATION = "notification"
+)
+
+var (
+	// ORDER_COLUMNS is a list of columns allowed for sorting listeners.
+	ORDER_COLUMNS = map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	}
+
+	LIST_COLUMNS = utils.ListColumns{
+		ID:        "id",
+		Name:      "name",
+		CreatedAt: "created_at",
+		UpdatedAt: "updated_at",
+	}
+
+	AUTH_TYPES = []string{AUTH_BEARER, AUTH_BASIC}
+
+	PAYLOAD_MODES = []string{PAYLOAD_FULL, PAYLOAD_VERSION, PAYLOAD_DIFF, PAYLOAD_NOTIFICATION}
+
+	// RESERVED_HEADERS are set by callback requests and can not be changed by custom headers.
+	RESERVED_HEADERS = []string{"Authorization", "Content-Type", "Content-Length", "Host", webhook.HEADER_TIMESTAMP, webhook.HEADER_SIGNATURE}
 )
 
 type Listener struct {
@@ -11,18 +89,128 @@ type Listener struct {
 	FileID           string `db:"file_id" json:"file_id"`
 	CallbackEndpoint string `db:"callback_endpoint" json:"callback_endpoint"`
 	Name             string `db:"name" json:"name"`
-	CreatedAt        string `db:"created_at" json:"created_at"`
-	UpdatedAt        string `db:"updated_at" json:"updated_at"`
+	// Secret signs payloads sent to the listener. It is returned only when it is generated.
+	Secret         string  `db:"secret" json:"-"`
+	PreviousSecret *string `db:"previous_secret" json:"-"`
+	// PreviousSecretExpiresAt is set while payloads are also signed with the previous secret.
+	PreviousSecretExpiresAt *string `db:"previous_secret_expires_at" json:"previous_secret_expires_at"`
+	// EncryptedSettings are RequestSettings encrypted by the client, Settings are set when they are decrypted.
+	EncryptedSettings *string          `db:"settings" json:"-"`
+	Settings          *RequestSettings `db:"-" json:"-"`
+	// HeaderNames and AuthType describe Settings without values.
+	HeaderNames []string    `db:"-" json:"header_names,omitempty"`
+	AuthType    *string     `db:"-" json:"auth_type,omitempty"`
+	RetryPolicy RetryPolicy `db:"retry_policy" json:"retry_policy"`
+	// FollowRedirects allows to post callback requests again to the location of redirects.
+	FollowRedirects bool `db:"follow_redirects" json:"follow_redirects"`
+	// Enabled listeners receive events. Events of disabled listeners are moved to dead letters.
+	Enabled bool `db:"enabled" json:"enabled"`
+	// Healthy is false after HealthConfig.UnhealthyThreshold consecutive failed callback requests.
+	Healthy             bool    `db:"healthy" json:"healthy"`
+	DisabledReason      *string `db:"disabled_reason" json:"disabled_reason"`
+	ConsecutiveFailures int     `db:"consecutive_failures" json:"consecutive_failures"`
+	LastSuccessAt       *string `db:"last_success_at" json:"last_success_at"`
+	LastFailureAt       *string `db:"last_failure_at" json:"last_failure_at"`
+	// EventTypes are types of events sent to the listener, it is subscribed to all events if they are empty.
+	EventTypes pq.StringArray `db:"event_types" json:"event_types"`
+	// VersionFilter limits events of contents by their versions, events of files are always sent.
+	VersionFilter VersionFilter `db:"version_filter" json:"version_filter"`
+	// PayloadMode is one of PAYLOAD_MODES and defines what is sent in callback requests.
+	PayloadMode string `db:"payload_mode" json:"payload_mode"`
+	CreatedAt   string `db:"created_at" json:"created_at"`
+	UpdatedAt   string `db:"updated_at" json:"updated_at"`
+}
+
+// Auth is credentials sent in Authorization header of callback requests.
+type Auth struct {
+	Type     string `json:"type"`
+	Token    string `json:"token,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// HealthConfig describes after how many consecutive failed callback requests listeners become unhealthy and
+// are disabled. Zero values are replaced by defaults.
+type HealthConfig struct {
+	UnhealthyThreshold int
+	DisableThreshold   int
+}
+
+// WithDefaults returns a copy of the config with default values of zero fields.
+func (c HealthConfig) WithDefaults() HealthConfig {
+	if c.UnhealthyThreshold == 0 {
+		c.UnhealthyThreshold = DEFAULT_UNHEALTHY_THRESHOLD
+	}
+	if c.DisableThreshold == 0 {
+		c.DisableThreshold = DEFAULT_DISABLE_THRESHOLD
+	}
+	return c
+}
+
+// RequestSettings are attached to callback requests. They are kept encrypted because they contain credentials.
+type RequestSettings struct {
+	Headers map[string]string `json:"headers,omitempty"`
+	Auth    *Auth             `json:"auth,omitempty"`
+}
+
+// RequestHeaders returns custom headers and Authorization header of callback requests.
+func (l *Listener) RequestHeaders() map[string]string {
+	headers := make(map[string]string)
+	if l.Settings == nil {
+		return headers
+	}
+
+	for key, value := range l.Settings.Headers {
+		headers[key] = value
+	}
+	if auth := l.Settings.Auth; auth != nil {
+		switch auth.Type {
+		case AUTH_BEARER:
+			headers["Authorization"] = "Bearer " + auth.Token
+		case AUTH_BASIC:
+			headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password))
+		}
+	}
+	return headers
+}
+
+// Secrets returns active secrets of the listener, the current secret is first.
+func (l *Listener) Secrets() []string {
+	var secrets []string
+	if l.Secret != "" {
+		secrets = append(secrets, l.Secret)
+	}
+	if l.PreviousSecret != nil {
+		secrets = append(secrets, *l.PreviousSecret)
+	}
+	return secrets
 }
 
 type CreateRequest struct {
-	Name             string `json:"name"`
-	CallbackEndpoint string `json:"callback_endpoint"`
-	FileID           string `json:"file_id"`
+	Name             string            `json:"name"`
+	CallbackEndpoint string            `json:"callback_endpoint"`
+	FileID           string            `json:"file_id"`
+	Headers          map[string]string `json:"headers"`
+	Auth             *Auth             `json:"auth"`
+	// RetryPolicy uses default values if it is nil.
+	RetryPolicy     *RetryPolicy `json:"retry_policy"`
+	FollowRedirects bool         `json:"follow_redirects"`
+	// EventTypes are types of events sent to the listener, all events are sent if they are empty.
+	EventTypes    []string       `json:"event_types"`
+	VersionFilter *VersionFilter `json:"version_filter"`
+	// PayloadMode is PAYLOAD_FULL if it is empty.
+	PayloadMode string `json:"payload_mode"`
+}
+
+type Order struct {
+	Column *string
+	Type   *string
 }
 
 type GetManyRequest struct {
-	FileID string `json:"file_id"`
+	FileID string            `json:"file_id"`
+	Order  *Order            `json:"-"`
+	Filter *utils.ListFilter `json:"-"`
 }
 
 type GetRequest struct {
@@ -33,8 +221,49 @@ type DeleteRequest struct {
 	ID string `json:"id"`
 }
 
+type RotateSecretRequest struct {
+	ID string `json:"id"`
+	// PreviousLifetime is a time during which the previous secret is still used, PREVIOUS_SECRET_LIFETIME if nil.
+	PreviousLifetime *time.Duration `json:"previous_lifetime"`
+}
+
+type EnableRequest struct {
+	ID string `json:"id"`
+}
+
+type DisableRequest struct {
+	ID string `json:"id"`
+	// Reason is shown in the listener, DISABLED_MANUALLY if nil.
+	Reason *string `json:"reason"`
+}
+
 type EditRequest struct {
 	ID               string  `json:"id"`
 	Name             *string `json:"name"`
 	CallbackEndpoint *string `json:"callback_endpoint"`
+	// Headers replace all custom headers if they are not nil, an empty map removes them.
+	Headers map[string]string `json:"headers"`
+	// Auth replaces credentials if it is not nil, auth with empty type removes them.
+	Auth *Auth `json:"auth"`
+	// RetryPolicy replaces the whole policy if it is not nil.
+	RetryPolicy     *RetryPolicy `json:"retry_policy"`
+	FollowRedirects *bool        `json:"follow_redirects"`
+	// EventTypes replace all event types if they are not nil, empty event types subscribe the listener to all events.
+	EventTypes []string `json:"event_types"`
+	// VersionFilter replaces the whole filter if it is not nil, an empty filter removes it.
+	VersionFilter *VersionFilter `json:"version_filter"`
+	PayloadMode   *string        `json:"payload_mode"`
+}
+
+func (l *Listener) columnValue(column string) string {
+	switch column {
+	case "id":
+		return l.ID
+	case "created_at":
+		return l.CreatedAt
+	case "updated_at":
+		return l.UpdatedAt
+	default:
+		return l.Name
+	}
 }
@@ -0,0 +1,149 @@
+package listeners
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/http-utils/tiny_errors"
+)
+
+const (
+	DEFAULT_MAX_ATTEMPTS = 10
+	DEFAULT_BASE_DELAY   = time.Second
+	DEFAULT_MAX_DELAY    = time.Hour
+	DEFAULT_JITTER       = 0.2
+	DEFAULT_TIMEOUT      = 10 * time.Second
+
+	MAX_ATTEMPTS_LIMIT = 100
+	MAX_DELAY_LIMIT    = 24 * time.Hour
+	// MAX_TIMEOUT is less than the lease of claimed events, so requests end before events are claimed again.
+	MAX_TIMEOUT = time.Minute
+)
+
+// RetryPolicy describes how failed callback requests of the listener are retried. Delays grow exponentially
+// from BaseDelayMs up to MaxDelayMs and are randomly shifted by Jitter fraction of the delay.
+// Zero fields use default values. It is stored as JSONB.
+type RetryPolicy struct {
+	// MaxAttempts is a number of attempts after which the event is moved to dead letters.
+	MaxAttempts int      `json:"max_attempts,omitempty"`
+	BaseDelayMs int64    `json:"base_delay_ms,omitempty"`
+	MaxDelayMs  int64    `json:"max_delay_ms,omitempty"`
+	Jitter      *float64 `json:"jitter,omitempty"`
+	// TimeoutMs limits a single request to the listener.
+	TimeoutMs int64 `json:"timeout_ms,omitempty"`
+}
+
+func (p RetryPolicy) Value() (driver.Value, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan fills fields which are not stored with default values, so listeners always show the policy in use.
+func (p *RetryPolicy) Scan(src any) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*p = RetryPolicy{}.WithDefaults()
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return fmt.Errorf("unsupported retry policy type %T", src)
+	}
+
+	var policy RetryPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return err
+	}
+	*p = policy.WithDefaults()
+	return nil
+}
+
+// WithDefaults returns a copy of the policy with default values of zero fields.
+func (p RetryPolicy) WithDefaults() RetryPolicy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = DEFAULT_MAX_ATTEMPTS
+	}
+	if p.BaseDelayMs == 0 {
+		p.BaseDelayMs = DEFAULT_BASE_DELAY.Milliseconds()
+		if p.MaxDelayMs > 0 {
+			p.BaseDelayMs = min(p.BaseDelayMs, p.MaxDelayMs)
+		}
+	}
+	if p.MaxDelayMs == 0 {
+		p.MaxDelayMs = max(DEFAULT_MAX_DELAY.Milliseconds(), p.BaseDelayMs)
+	}
+	if p.Jitter == nil {
+		jitter := DEFAULT_JITTER
+		p.Jitter = &jitter
+	}
+	if p.TimeoutMs == 0 {
+		p.TimeoutMs = DEFAULT_TIMEOUT.Milliseconds()
+	}
+	return p
+}
+
+// Timeout returns a time limit of a single request.
+func (p RetryPolicy) Timeout() time.Duration {
+	return time.Duration(p.WithDefaults().TimeoutMs) * time.Millisecond
+}
+
+// Delay returns a time before the next attempt after the failed attempt with the given number, starting from 1.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	p = p.WithDefaults()
+	maxDelay := time.Duration(p.MaxDelayMs) * time.Millisecond
+
+	delay := time.Duration(p.BaseDelayMs) * time.Millisecond
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	if *p.Jitter > 0 {
+		delay += time.Duration(float64(delay) * *p.Jitter * (2*rand.Float64() - 1))
+	}
+	return delay
+}
+
+// Validate checks that values are in allowed ranges. Zero values are allowed and replaced with defaults.
+func (p *RetryPolicy) Validate() tiny_errors.ErrorHandler {
+	if p == nil {
+		return nil
+	}
+
+	var options []tiny_errors.ErrorOption
+	if p.MaxAttempts < 0 || p.MaxAttempts > MAX_ATTEMPTS_LIMIT {
+		options = append(options, tiny_errors.Detail("retry_policy.max_attempts", fmt.Sprintf("should be from 1 to %d", MAX_ATTEMPTS_LIMIT)))
+	}
+	if p.BaseDelayMs < 0 || p.BaseDelayMs > MAX_DELAY_LIMIT.Milliseconds() {
+		options = append(options, tiny_errors.Detail("retry_policy.base_delay_ms", fmt.Sprintf("should be from 1 to %d", MAX_DELAY_LIMIT.Milliseconds())))
+	}
+	if p.MaxDelayMs < 0 || p.MaxDelayMs > MAX_DELAY_LIMIT.Milliseconds() {
+		options = append(options, tiny_errors.Detail("retry_policy.max_delay_ms", fmt.Sprintf("should be from 1 to %d", MAX_DELAY_LIMIT.Milliseconds())))
+	}
+	if p.MaxDelayMs > 0 && p.BaseDelayMs > p.MaxDelayMs {
+		options = append(options, tiny_errors.Detail("retry_policy.base_delay_ms", "can not be greater than max_delay_ms"))
+	}
+	if p.Jitter != nil && (*p.Jitter < 0 || *p.Jitter > 1) {
+		options = append(options, tiny_errors.Detail("retry_policy.jitter", "should be from 0 to 1"))
+	}
+	if p.TimeoutMs < 0 || p.TimeoutMs > MAX_TIMEOUT.Milliseconds() {
+		options = append(options, tiny_errors.Detail("retry_policy.timeout_ms", fmt.Sprintf("should be from 1 to %d", MAX_TIMEOUT.Milliseconds())))
+	}
+
+	if len(options) > 0 {
+		return tiny_errors.New(custom_errors.ERR_CODE_NotValid, options...)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package listeners
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/http-utils/tiny_errors"
+)
+
+// encryptSettings returns nil for empty settings, so listeners without them do not need the encryption key.
+func (c *client) encryptSettings(settings *RequestSettings) (*string, tiny_errors.ErrorHandler) {
+	if settings == nil || (len(settings.Headers) == 0 && settings.Auth == nil) {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Marshal, tiny_errors.Message(err.Error()))
+	}
+
+	encrypted, err := c.cipher.Encrypt(data)
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message(err.Error()))
+	}
+	return &encrypted, nil
+}
+
+// decryptSettings sets Settings of the listener and describes them with HeaderNames and AuthType.
+func (c *client) decryptSettings(listener *Listener) tiny_errors.ErrorHandler {
+	if listener.EncryptedSettings == nil {
+		return nil
+	}
+
+	data, err := c.cipher.Decrypt(*listener.EncryptedSettings)
+	if err != nil {
+		return tiny_errors.New(custom_errors.ERR_CODE_Marshal, tiny_errors.Message(err.Error()), tiny_errors.Detail("listener_id", listener.ID))
+	}
+
+	var settings RequestSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return tiny_errors.New(custom_errors.ERR_CODE_Marshal, tiny_errors.Message(err.Error()), tiny_errors.Detail("listener_id", listener.ID))
+	}
+
+	listener.Settings = &settings
+	for name := range settings.Headers {
+		listener.HeaderNames = append(listener.HeaderNames, name)
+	}
+	sort.Strings(listener.HeaderNames)
+	if settings.Auth != nil {
+		listener.AuthType = &settings.Auth.Type
+	}
+	return nil
+}
+
+// validateSettings checks names of headers and required fields of auth. Names of headers are canonicalized.
+// Auth with empty type is allowed only if allowEmptyAuth is set.
+func validateSettings(headers map[string]string, auth *Auth, allowEmptyAuth bool) tiny_errors.ErrorHandler {
+	for name, value := range headers {
+		canonical := http.CanonicalHeaderKey(name)
+		if !validHeaderName(name) || strings.ContainsAny(value, "\r\n") {
+			return tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("headers", "header "+name+" is not valid"))
+		}
+		if slices.Contains(RESERVED_HEADERS, canonical) {
+			return tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("headers", "header "+canonical+" can not be changed"))
+		}
+		if canonical != name {
+			delete(headers, name)
+			headers[canonical] = value
+		}
+	}
+
+	if auth == nil || (auth.Type == "" && allowEmptyAuth) {
+		return nil
+	}
+	switch auth.Type {
+	case AUTH_BEARER:
+		if auth.Token == "" {
+			return tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, tiny_errors.Detail("auth.token", "required"))
+		}
+	case AUTH_BASIC:
+		if auth.Username == "" {
+			return tiny_errors.New(custom_errors.ERR_CODE_REQUIRED_FIELD, tiny_errors.Detail("auth.username", "required"))
+		}
+		if strings.Contains(auth.Username, ":") {
+			return tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("auth.username", "can not contain colon"))
+		}
+	default:
+		return tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("auth.type", "should be one of "+strings.Join(AUTH_TYPES, ", ")))
+	}
+	return nil
+}
+
+// validHeaderName checks that the name is a token of RFC 7230.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > 127 || !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,207 @@
+package listeners
+
+import (
+	"cmp"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/http-utils/tiny_errors"
+)
+
+// RANGE_OPERATORS are operators of comparisons in version ranges. Longer operators are first, so they are
+// matched before their prefixes.
+var RANGE_OPERATORS = []string{">=", "<=", ">", "<", "="}
+
+// VersionFilter limits events of contents sent to the listener by their versions. A version matches the filter
+// if it matches all fields which are set, an empty filter matches all versions. It is stored as JSONB.
+type VersionFilter struct {
+	// Version is an exact version.
+	Version string `json:"version,omitempty"`
+	// Range is a list of comparisons separated by spaces, e.g. `>=1.2.0 <2.0.0`. Versions are compared by
+	// numbers separated by dots, a leading `v` is ignored and missing numbers are zeros.
+	Range string `json:"range,omitempty"`
+	// Tag is a glob pattern of versions, e.g. `release-*`. Versions synced from git are named after tags.
+	Tag string `json:"tag,omitempty"`
+}
+
+// comparison is a single comparison of a version range.
+type comparison struct {
+	operator string
+	version  version
+}
+
+// version is a parsed version. Versions with a pre-release suffix are lower than the same version without it.
+type version struct {
+	numbers    []int
+	prerelease string
+}
+
+func (f VersionFilter) Value() (driver.Value, error) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (f *VersionFilter) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		*f = VersionFilter{}
+		return nil
+	case []byte:
+		return json.Unmarshal(value, f)
+	case string:
+		return json.Unmarshal([]byte(value), f)
+	default:
+		return fmt.Errorf("unsupported version filter type %T", src)
+	}
+}
+
+// Match reports whether the version matches the filter. Versions which are not numbers never match a range.
+func (f VersionFilter) Match(name string) bool {
+	if f.Version != "" && f.Version != name {
+		return false
+	}
+	if f.Tag != "" {
+		if ok, _ := path.Match(f.Tag, name); !ok {
+			return false
+		}
+	}
+	if f.Range != "" {
+		comparisons, err := parseRange(f.Range)
+		if err != nil {
+			return false
+		}
+		current, ok := parseVersion(name)
+		if !ok {
+			return false
+		}
+		for _, c := range comparisons {
+			if !c.match(current) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Validate checks that the range and the tag can be parsed.
+func (f *VersionFilter) Validate() tiny_errors.ErrorHandler {
+	if f == nil {
+		return nil
+	}
+
+	var options []tiny_errors.ErrorOption
+	if f.Range != "" {
+		if _, err := parseRange(f.Range); err != nil {
+			options = append(options, tiny_errors.Detail("version_filter.range", err.Error()))
+		}
+	}
+	if f.Tag != "" {
+		if _, err := path.Match(f.Tag, ""); err != nil {
+			options = append(options, tiny_errors.Detail("version_filter.tag", "should be a glob pattern"))
+		}
+	}
+
+	if len(options) > 0 {
+		return tiny_errors.New(custom_errors.ERR_CODE_NotValid, options...)
+	}
+	return nil
+}
+
+// parseRange parses comparisons of the range, a comparison without an operator checks equality.
+func parseRange(value string) ([]comparison, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil, errors.New("should contain at least one comparison")
+	}
+
+	comparisons := make([]comparison, 0, len(fields))
+	for _, field := range fields {
+		c := comparison{operator: "="}
+		for _, operator := range RANGE_OPERATORS {
+			if strings.HasPrefix(field, operator) {
+				c.operator = operator
+				field = strings.TrimPrefix(field, operator)
+				break
+			}
+		}
+
+		parsed, ok := parseVersion(field)
+		if !ok {
+			return nil, fmt.Errorf("%q is not a version", field)
+		}
+		c.version = parsed
+		comparisons = append(comparisons, c)
+	}
+	return comparisons, nil
+}
+
+// parseVersion parses versions like `v1.2.3`, `1.2` or `1.2.3-rc.1`. Build metadata after `+` is ignored.
+func parseVersion(value string) (version, bool) {
+	value = strings.TrimPrefix(value, "v")
+	value, _, _ = strings.Cut(value, "+")
+	value, prerelease, _ := strings.Cut(value, "-")
+
+	parts := strings.Split(value, ".")
+	numbers := make([]int, 0, len(parts))
+	for _, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return version{}, false
+		}
+		numbers = append(numbers, number)
+	}
+	return version{numbers: numbers, prerelease: prerelease}, true
+}
+
+func (c comparison) match(v version) bool {
+	result := v.compare(c.version)
+	switch c.operator {
+	case ">=":
+		return result >= 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	case "<":
+		return result < 0
+	default:
+		return result == 0
+	}
+}
+
+// compare returns -1, 0 or 1 if the version is lower, equal or greater than other. Pre-release suffixes are
+// compared as strings.
+func (v version) compare(other version) int {
+	for i := 0; i < max(len(v.numbers), len(other.numbers)); i++ {
+		var a, b int
+		if i < len(v.numbers) {
+			a = v.numbers[i]
+		}
+		if i < len(other.numbers) {
+			b = other.numbers[i]
+		}
+		if result := cmp.Compare(a, b); result != 0 {
+			return result
+		}
+	}
+
+	switch {
+	case v.prerelease == other.prerelease:
+		return 0
+	case v.prerelease == "":
+		return 1
+	case other.prerelease == "":
+		return -1
+	default:
+		return strings.Compare(v.prerelease, other.prerelease)
+	}
+}
@@ -0,0 +1,22 @@
+package transaction
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DB is implemented by *sqlx.DB and *sqlx.Tx, so clients run the same queries inside and outside of transactions.
+type DB interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+}
+
+type contextKey struct{}
+
+// unit is a transaction with side effects which are called after commit.
+type unit struct {
+	tx      *sqlx.Tx
+	effects []func()
+}
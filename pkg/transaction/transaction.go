@@ -0,0 +1,71 @@
+package transaction
+
+import (
+	"context"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/http-utils/clients/database"
+	"github.com/Moranilt/http-utils/tiny_errors"
+)
+
+type manager struct {
+	db *database.Client
+}
+
+type Manager interface {
+	// Run calls fn in a transaction which is committed if fn returns no error and rolled back otherwise.
+	// Clients should get a connection with From, so all their queries made with the context of fn run in
+	// the transaction. If ctx already has a transaction, fn joins it and changes are committed by the outer Run.
+	Run(ctx context.Context, fn func(ctx context.Context) tiny_errors.ErrorHandler) tiny_errors.ErrorHandler
+}
+
+// New creates a new instance of the Manager interface, which runs functions in transactions of the database.
+func New(db *database.Client) Manager {
+	return &manager{
+		db: db,
+	}
+}
+
+func (m *manager) Run(ctx context.Context, fn func(ctx context.Context) tiny_errors.ErrorHandler) tiny_errors.ErrorHandler {
+	if _, ok := ctx.Value(contextKey{}).(*unit); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	defer tx.Rollback()
+
+	u := &unit{tx: tx}
+	if err := fn(context.WithValue(ctx, contextKey{}, u)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	for _, effect := range u.effects {
+		effect()
+	}
+	return nil
+}
+
+// From returns the transaction of ctx or db if ctx has no transaction.
+func From(ctx context.Context, db *database.Client) DB {
+	if u, ok := ctx.Value(contextKey{}).(*unit); ok {
+		return u.tx
+	}
+	return db
+}
+
+// AfterCommit registers a side effect which is called after the transaction of ctx is committed.
+// Side effects of rolled back transactions are dropped. Without a transaction fn is called immediately.
+func AfterCommit(ctx context.Context, fn func()) {
+	if u, ok := ctx.Value(contextKey{}).(*unit); ok {
+		u.effects = append(u.effects, fn)
+		return
+	}
+	fn()
+}
@@ -0,0 +1,22 @@
+package transaction
+
+import (
+	"context"
+
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockManager struct {
+	mock.Mock
+}
+
+func NewMock() *MockManager {
+	return new(MockManager)
+}
+
+// Run calls fn with ctx without a transaction, so side effects registered with AfterCommit are called immediately.
+func (m *MockManager) Run(ctx context.Context, fn func(ctx context.Context) tiny_errors.ErrorHandler) tiny_errors.ErrorHandler {
+	m.Called(ctx)
+	return fn(ctx)
+}
@@ -0,0 +1,115 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/http-utils/clients/database"
+	database_mock "github.com/Moranilt/http-utils/clients/database/mock"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const testQuery = "UPDATE files SET name = $1"
+
+func TestManager_Run(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	db := &database.Client{mockDb}
+	manager := New(db)
+
+	tests := []struct {
+		name            string
+		mockSetup       func()
+		fn              func(ctx context.Context, effects *[]string) tiny_errors.ErrorHandler
+		expectedEffects []string
+		expectedError   tiny_errors.ErrorHandler
+	}{
+		{
+			name: "side effects are called after commit",
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(testQuery)).WithArgs("name").WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+			},
+			fn: func(ctx context.Context, effects *[]string) tiny_errors.ErrorHandler {
+				AfterCommit(ctx, func() { *effects = append(*effects, "first") })
+				if _, err := From(ctx, db).ExecContext(ctx, testQuery, "name"); err != nil {
+					return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+				}
+				AfterCommit(ctx, func() { *effects = append(*effects, "second") })
+				assert.Empty(t, *effects)
+				return nil
+			},
+			expectedEffects: []string{"first", "second"},
+		},
+		{
+			name: "nested run joins the transaction",
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectExec(regexp.QuoteMeta(testQuery)).WithArgs("inner").WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+			},
+			fn: func(ctx context.Context, effects *[]string) tiny_errors.ErrorHandler {
+				return manager.Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+					AfterCommit(ctx, func() { *effects = append(*effects, "inner") })
+					if _, err := From(ctx, db).ExecContext(ctx, testQuery, "inner"); err != nil {
+						return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+					}
+					return nil
+				})
+			},
+			expectedEffects: []string{"inner"},
+		},
+		{
+			name: "side effects are dropped on rollback",
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectRollback()
+			},
+			fn: func(ctx context.Context, effects *[]string) tiny_errors.ErrorHandler {
+				AfterCommit(ctx, func() { *effects = append(*effects, "dropped") })
+				return tiny_errors.New(custom_errors.ERR_CODE_NotFound)
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotFound),
+		},
+		{
+			name: "begin error",
+			mockSetup: func() {
+				sqlMock.ExpectBegin().WillReturnError(errors.New("begin error"))
+			},
+			fn: func(ctx context.Context, effects *[]string) tiny_errors.ErrorHandler {
+				return nil
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message("begin error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			var effects []string
+			err := manager.Run(context.Background(), func(ctx context.Context) tiny_errors.ErrorHandler {
+				return tt.fn(ctx, &effects)
+			})
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
+				assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tt.expectedEffects, effects)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAfterCommit_WithoutTransaction(t *testing.T) {
+	called := false
+	AfterCommit(context.Background(), func() { called = true })
+	assert.True(t, called)
+}
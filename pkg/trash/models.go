@@ -0,0 +1,77 @@
+package trash
+
+import "github.com/Moranilt/config-keeper/utils"
+
+const (
+	ITEM_TYPE_FOLDER = "folder"
+	ITEM_TYPE_FILE   = "file"
+)
+
+const (
+	// QUERY_GET_ITEMS returns only top-level deleted items: folders and files which were deleted
+	// directly and not together with their parent folder.
+	QUERY_GET_ITEMS = `SELECT id, type, name, parent_id, deleted_at FROM (
+		SELECT f.id, 'folder' AS type, f.name, f.parent_id, f.deleted_at
+		FROM folders f
+		LEFT JOIN folders p ON p.id = f.parent_id
+		WHERE f.deleted_at IS NOT NULL
+		AND (p.id IS NULL OR p.deleted_at IS NULL OR p.deleted_at <> f.deleted_at)
+
+		UNION ALL
+		SELECT f.id, 'file' AS type, f.name, f.folder_id AS parent_id, f.deleted_at
+		FROM files f
+		LEFT JOIN folders p ON p.id = f.folder_id
+		WHERE f.deleted_at IS NOT NULL
+		AND (p.id IS NULL OR p.deleted_at IS NULL OR p.deleted_at <> f.deleted_at)
+	) trash`
+	QUERY_GET_DELETED_FOLDER     = "SELECT id, name, parent_id FROM folders WHERE id = $1 AND deleted_at IS NOT NULL"
+	QUERY_GET_DELETED_FILE       = "SELECT id, name, folder_id AS parent_id FROM files WHERE id = $1 AND deleted_at IS NOT NULL"
+	QUERY_FOLDER_IS_ALIVE        = "SELECT EXISTS(SELECT 1 FROM folders WHERE id = $1 AND deleted_at IS NULL)"
+	QUERY_FOLDER_NAME_IS_TAKEN   = "SELECT EXISTS(SELECT 1 FROM folders WHERE name = $1 AND parent_id IS NOT DISTINCT FROM $2 AND deleted_at IS NULL)"
+	QUERY_FILE_NAME_IS_TAKEN     = "SELECT EXISTS(SELECT 1 FROM files WHERE name = $1 AND folder_id IS NOT DISTINCT FROM $2 AND deleted_at IS NULL)"
+	QUERY_RESTORE_FOLDER_SUBTREE = `WITH RECURSIVE subtree AS (
+		SELECT id, deleted_at FROM folders WHERE id = $1
+		UNION ALL
+		SELECT f.id, f.deleted_at FROM folders f JOIN subtree s ON f.parent_id = s.id WHERE f.deleted_at = s.deleted_at
+	),
+	restored_files AS (
+		UPDATE files f SET deleted_at = NULL
+		FROM subtree s
+		WHERE f.folder_id = s.id AND f.deleted_at = s.deleted_at
+	)
+	UPDATE folders SET deleted_at = NULL WHERE id IN (SELECT id FROM subtree)`
+	QUERY_RESTORE_FILE  = "UPDATE files SET deleted_at = NULL WHERE id = $1"
+	QUERY_PURGE_FOLDERS = "DELETE FROM folders WHERE deleted_at < $1"
+	QUERY_PURGE_FILES   = "DELETE FROM files WHERE deleted_at < $1"
+)
+
+var (
+	// LIST_COLUMNS is used for filtering trash items. Items are always sorted by deletion time.
+	LIST_COLUMNS = utils.ListColumns{
+		ID:   "id",
+		Name: "name",
+	}
+)
+
+// Item is a folder or a file in the trash.
+type Item struct {
+	ID        string  `json:"id" db:"id"`
+	Type      string  `json:"type" db:"type"`
+	Name      string  `json:"name" db:"name"`
+	ParentID  *string `json:"parent_id" db:"parent_id"`
+	DeletedAt string  `json:"deleted_at" db:"deleted_at"`
+}
+
+type GetManyRequest struct {
+	Filter *utils.ListFilter
+}
+
+type RestoreRequest struct {
+	ID string
+}
+
+type deletedItem struct {
+	ID       string  `db:"id"`
+	Name     string  `db:"name"`
+	ParentID *string `db:"parent_id"`
+}
@@ -0,0 +1,52 @@
+package trash
+
+import (
+	"context"
+	"time"
+
+	"github.com/Moranilt/http-utils/logger"
+)
+
+type Purger interface {
+	// Run periodically removes items which stay in the trash longer than retention period.
+	// The loop will continue until the provided context is canceled.
+	Run(ctx context.Context)
+}
+
+type purger struct {
+	log       logger.Logger
+	trash     Client
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewPurger(log logger.Logger, trash Client, retention time.Duration, interval time.Duration) Purger {
+	return &purger{
+		log:       log,
+		trash:     trash,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+func (p *purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.log.Info("Stopping trash purger")
+			return
+		case <-ticker.C:
+			purged, err := p.trash.Purge(ctx, time.Now().Add(-p.retention))
+			if err != nil {
+				p.log.Errorf("Error while purging trash: %s", err)
+				continue
+			}
+			if purged > 0 {
+				p.log.Infof("Purged %d items from the trash", purged)
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package trash
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/pkg/transaction"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/clients/database"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/jmoiron/sqlx"
+)
+
+type client struct {
+	db *database.Client
+}
+
+type Client interface {
+	// GetMany retrieves items deleted directly by user, newest first. Nested folders and files
+	// deleted together with their parent are not listed. Returns cursor of the next page if there are more items.
+	GetMany(ctx context.Context, req *GetManyRequest) ([]*Item, *string, tiny_errors.ErrorHandler)
+
+	// RestoreFolder restores a folder with all nested folders and files deleted together with it.
+	RestoreFolder(ctx context.Context, req *RestoreRequest) (bool, tiny_errors.ErrorHandler)
+
+	// RestoreFile restores a file with all its contents and listeners.
+	RestoreFile(ctx context.Context, req *RestoreRequest) (bool, tiny_errors.ErrorHandler)
+
+	// Purge permanently removes folders and files deleted before provided time in a single transaction.
+	// Returns the number of removed folders and files.
+	Purge(ctx context.Context, before time.Time) (int64, tiny_errors.ErrorHandler)
+}
+
+// New creates a new instance of the Client interface using the provided database client.
+func New(db *database.Client) Client {
+	return &client{
+		db: db,
+	}
+}
+
+func (c *client) GetMany(ctx context.Context, req *GetManyRequest) ([]*Item, *string, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return nil, nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	preparedQuery := utils.NewListQuery(QUERY_GET_ITEMS)
+	err := req.Filter.Apply(preparedQuery, LIST_COLUMNS, "deleted_at", utils.ORDER_DESC)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	items := make([]*Item, 0)
+	dbErr := c.db.SelectContext(ctx, &items, preparedQuery.String(), preparedQuery.Args()...)
+	if dbErr != nil {
+		return nil, nil, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(dbErr.Error()))
+	}
+
+	items, next := utils.NextCursor(req.Filter, items, func(item *Item) (string, string) {
+		return item.DeletedAt, item.ID
+	})
+
+	return items, next, nil
+}
+
+func (c *client) RestoreFolder(ctx context.Context, req *RestoreRequest) (bool, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return false, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	return c.restore(ctx, req.ID, QUERY_GET_DELETED_FOLDER, QUERY_FOLDER_NAME_IS_TAKEN, QUERY_RESTORE_FOLDER_SUBTREE)
+}
+
+func (c *client) RestoreFile(ctx context.Context, req *RestoreRequest) (bool, tiny_errors.ErrorHandler) {
+	if req == nil {
+		return false, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+
+	return c.restore(ctx, req.ID, QUERY_GET_DELETED_FILE, QUERY_FILE_NAME_IS_TAKEN, QUERY_RESTORE_FILE)
+}
+
+func (c *client) Purge(ctx context.Context, before time.Time) (int64, tiny_errors.ErrorHandler) {
+	var purged int64
+	err := transaction.New(c.db).Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+		purged = 0
+		for _, q := range []string{QUERY_PURGE_FOLDERS, QUERY_PURGE_FILES} {
+			result, err := transaction.From(ctx, c.db).ExecContext(ctx, q, before)
+			if err != nil {
+				return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+			}
+
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+			}
+			purged += affected
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+// restore checks that the item is in the trash, its parent folder is not deleted and there is
+// no other item with the same name, then runs restoreQuery in a single transaction.
+func (c *client) restore(ctx context.Context, id, getQuery, nameTakenQuery, restoreQuery string) (bool, tiny_errors.ErrorHandler) {
+	tx, err := c.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	defer tx.Rollback()
+
+	var item deletedItem
+	err = tx.GetContext(ctx, &item, getQuery, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.HTTPStatus(http.StatusNotFound))
+		}
+		return false, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	if item.ParentID != nil {
+		alive, err := exists(ctx, tx, QUERY_FOLDER_IS_ALIVE, *item.ParentID)
+		if err != nil {
+			return false, err
+		}
+		if !alive {
+			return false, tiny_errors.New(
+				custom_errors.ERR_CODE_NotValid,
+				tiny_errors.Message("parent folder is in the trash, restore it first"),
+				tiny_errors.Detail("parent_id", *item.ParentID),
+			)
+		}
+	}
+
+	taken, tErr := exists(ctx, tx, nameTakenQuery, item.Name, item.ParentID)
+	if tErr != nil {
+		return false, tErr
+	}
+	if taken {
+		return false, utils.ExistsError("item with such name already exists")
+	}
+
+	_, err = tx.ExecContext(ctx, restoreQuery, id)
+	if err != nil {
+		return false, utils.DatabaseError(err, "item with such name already exists")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+
+	return true, nil
+}
+
+func exists(ctx context.Context, tx *sqlx.Tx, q string, args ...any) (bool, tiny_errors.ErrorHandler) {
+	var result bool
+	err := tx.QueryRowxContext(ctx, q, args...).Scan(&result)
+	if err != nil {
+		return false, tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+	}
+	return result, nil
+}
@@ -0,0 +1,58 @@
+package trash
+
+import (
+	"context"
+	"time"
+
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockClient struct {
+	mock.Mock
+}
+
+func NewMock() *MockClient {
+	return new(MockClient)
+}
+
+func (m *MockClient) GetMany(ctx context.Context, req *GetManyRequest) ([]*Item, *string, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	items := args.Get(0)
+	next, _ := args.Get(1).(*string)
+	err := args.Get(2)
+	if err == nil {
+		return items.([]*Item), next, nil
+	}
+	return nil, nil, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockClient) RestoreFolder(ctx context.Context, req *RestoreRequest) (bool, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	restored := args.Bool(0)
+	err := args.Get(1)
+	if err == nil {
+		return restored, nil
+	}
+	return restored, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockClient) RestoreFile(ctx context.Context, req *RestoreRequest) (bool, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, req)
+	restored := args.Bool(0)
+	err := args.Get(1)
+	if err == nil {
+		return restored, nil
+	}
+	return restored, err.(tiny_errors.ErrorHandler)
+}
+
+func (m *MockClient) Purge(ctx context.Context, before time.Time) (int64, tiny_errors.ErrorHandler) {
+	args := m.Called(ctx, before)
+	purged := args.Get(0).(int64)
+	err := args.Get(1)
+	if err == nil {
+		return purged, nil
+	}
+	return purged, err.(tiny_errors.ErrorHandler)
+}
@@ -0,0 +1,256 @@
+package trash
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/clients/database"
+	database_mock "github.com/Moranilt/http-utils/clients/database/mock"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_GetMany(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb})
+
+	itemsRows := func() *sqlmock.Rows {
+		return sqlMock.NewRows([]string{"id", "type", "name", "parent_id", "deleted_at"}).
+			AddRow("folder_id", ITEM_TYPE_FOLDER, "folder_name", nil, "2020-01-02T00:00:00Z").
+			AddRow("file_id", ITEM_TYPE_FILE, "file_name", utils.MakePointer("parent_id"), "2020-01-01T00:00:00Z")
+	}
+
+	expectedItems := []*Item{
+		{ID: "folder_id", Type: ITEM_TYPE_FOLDER, Name: "folder_name", DeletedAt: "2020-01-02T00:00:00Z"},
+		{ID: "file_id", Type: ITEM_TYPE_FILE, Name: "file_name", ParentID: utils.MakePointer("parent_id"), DeletedAt: "2020-01-01T00:00:00Z"},
+	}
+
+	tests := []struct {
+		name           string
+		req            *GetManyRequest
+		mockSetup      func()
+		expectedResult []*Item
+		expectedNext   *string
+		expectedError  tiny_errors.ErrorHandler
+	}{
+		{
+			name: "success",
+			req:  &GetManyRequest{},
+			mockSetup: func() {
+				preparedQuery := utils.NewListQuery(QUERY_GET_ITEMS).Order("deleted_at", utils.ORDER_DESC).Order("id", utils.ORDER_DESC)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnRows(itemsRows())
+			},
+			expectedResult: expectedItems,
+		},
+		{
+			name: "success with limit and next cursor",
+			req:  &GetManyRequest{Filter: &utils.ListFilter{Limit: utils.MakePointer(1)}},
+			mockSetup: func() {
+				preparedQuery := utils.NewListQuery(QUERY_GET_ITEMS).Order("deleted_at", utils.ORDER_DESC).Order("id", utils.ORDER_DESC).Limit(2)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnRows(itemsRows())
+			},
+			expectedResult: expectedItems[:1],
+			expectedNext:   utils.MakePointer(utils.EncodeCursor("deleted_at DESC", "2020-01-02T00:00:00Z", "folder_id")),
+		},
+		{
+			name:          "empty request",
+			req:           nil,
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_BodyRequired),
+		},
+		{
+			name: "sql error",
+			req:  &GetManyRequest{},
+			mockSetup: func() {
+				preparedQuery := utils.NewListQuery(QUERY_GET_ITEMS).Order("deleted_at", utils.ORDER_DESC).Order("id", utils.ORDER_DESC)
+				sqlMock.ExpectQuery(regexp.QuoteMeta(preparedQuery.String())).WillReturnError(assert.AnError)
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(assert.AnError.Error())),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			items, next, err := client.GetMany(context.Background(), tt.req)
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
+				assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+				assert.Nil(t, items)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResult, items)
+				assert.Equal(t, tt.expectedNext, next)
+			}
+		})
+	}
+}
+
+func TestClient_RestoreFolder(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb})
+
+	deletedRow := func(parentID *string) *sqlmock.Rows {
+		return sqlMock.NewRows([]string{"id", "name", "parent_id"}).AddRow("folder_id", "folder_name", parentID)
+	}
+	existsRow := func(exists bool) *sqlmock.Rows {
+		return sqlMock.NewRows([]string{"exists"}).AddRow(exists)
+	}
+
+	tests := []struct {
+		name           string
+		req            *RestoreRequest
+		mockSetup      func()
+		expectedResult bool
+		expectedError  tiny_errors.ErrorHandler
+	}{
+		{
+			name: "success",
+			req:  &RestoreRequest{ID: "folder_id"},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_DELETED_FOLDER)).WithArgs("folder_id").WillReturnRows(deletedRow(utils.MakePointer("parent_id")))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_IS_ALIVE)).WithArgs("parent_id").WillReturnRows(existsRow(true))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_NAME_IS_TAKEN)).WithArgs("folder_name", "parent_id").WillReturnRows(existsRow(false))
+				sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_RESTORE_FOLDER_SUBTREE)).WithArgs("folder_id").WillReturnResult(sqlmock.NewResult(0, 3))
+				sqlMock.ExpectCommit()
+			},
+			expectedResult: true,
+		},
+		{
+			name: "success without parent",
+			req:  &RestoreRequest{ID: "folder_id"},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_DELETED_FOLDER)).WithArgs("folder_id").WillReturnRows(deletedRow(nil))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_NAME_IS_TAKEN)).WithArgs("folder_name", nil).WillReturnRows(existsRow(false))
+				sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_RESTORE_FOLDER_SUBTREE)).WithArgs("folder_id").WillReturnResult(sqlmock.NewResult(0, 1))
+				sqlMock.ExpectCommit()
+			},
+			expectedResult: true,
+		},
+		{
+			name: "not found",
+			req:  &RestoreRequest{ID: "folder_id"},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_DELETED_FOLDER)).WithArgs("folder_id").WillReturnError(sql.ErrNoRows)
+				sqlMock.ExpectRollback()
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotFound),
+		},
+		{
+			name: "parent folder is deleted",
+			req:  &RestoreRequest{ID: "folder_id"},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_DELETED_FOLDER)).WithArgs("folder_id").WillReturnRows(deletedRow(utils.MakePointer("parent_id")))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_IS_ALIVE)).WithArgs("parent_id").WillReturnRows(existsRow(false))
+				sqlMock.ExpectRollback()
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("parent folder is in the trash, restore it first")),
+		},
+		{
+			name: "name is taken",
+			req:  &RestoreRequest{ID: "folder_id"},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_DELETED_FOLDER)).WithArgs("folder_id").WillReturnRows(deletedRow(nil))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_NAME_IS_TAKEN)).WithArgs("folder_name", nil).WillReturnRows(existsRow(true))
+				sqlMock.ExpectRollback()
+			},
+			expectedError: utils.ExistsError("item with such name already exists"),
+		},
+		{
+			name:          "empty request",
+			req:           nil,
+			mockSetup:     func() {},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_BodyRequired),
+		},
+		{
+			name: "restore error",
+			req:  &RestoreRequest{ID: "folder_id"},
+			mockSetup: func() {
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_DELETED_FOLDER)).WithArgs("folder_id").WillReturnRows(deletedRow(nil))
+				sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_NAME_IS_TAKEN)).WithArgs("folder_name", nil).WillReturnRows(existsRow(false))
+				sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_RESTORE_FOLDER_SUBTREE)).WithArgs("folder_id").WillReturnError(assert.AnError)
+				sqlMock.ExpectRollback()
+			},
+			expectedError: tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(assert.AnError.Error())),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			restored, err := client.RestoreFolder(context.Background(), tt.req)
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError.GetCode(), err.GetCode())
+				if tt.expectedError.GetMessage() != "" {
+					assert.Equal(t, tt.expectedError.GetMessage(), err.GetMessage())
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedResult, restored)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestClient_RestoreFile(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb})
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_GET_DELETED_FILE)).WithArgs("file_id").WillReturnRows(
+		sqlMock.NewRows([]string{"id", "name", "parent_id"}).AddRow("file_id", "file_name", "folder_id"),
+	)
+	sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FOLDER_IS_ALIVE)).WithArgs("folder_id").WillReturnRows(sqlMock.NewRows([]string{"exists"}).AddRow(true))
+	sqlMock.ExpectQuery(regexp.QuoteMeta(QUERY_FILE_NAME_IS_TAKEN)).WithArgs("file_name", "folder_id").WillReturnRows(sqlMock.NewRows([]string{"exists"}).AddRow(false))
+	sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_RESTORE_FILE)).WithArgs("file_id").WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectCommit()
+
+	restored, err := client.RestoreFile(context.Background(), &RestoreRequest{ID: "file_id"})
+	assert.Nil(t, err)
+	assert.True(t, restored)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestClient_Purge(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	mockDb, sqlMock := database_mock.NewSQlMock(t)
+	client := New(&database.Client{mockDb})
+	before := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_PURGE_FOLDERS)).WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 2))
+	sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_PURGE_FILES)).WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 3))
+	sqlMock.ExpectCommit()
+
+	purged, err := client.Purge(context.Background(), before)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), purged)
+
+	// folders are not purged if files can not be purged
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_PURGE_FOLDERS)).WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 2))
+	sqlMock.ExpectExec(regexp.QuoteMeta(QUERY_PURGE_FILES)).WithArgs(before).WillReturnError(assert.AnError)
+	sqlMock.ExpectRollback()
+
+	purged, err = client.Purge(context.Background(), before)
+	assert.NotNil(t, err)
+	assert.Equal(t, custom_errors.ERR_CODE_Database, err.GetCode())
+	assert.Equal(t, int64(0), purged)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
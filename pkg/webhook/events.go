@@ -0,0 +1,19 @@
+package webhook
+
+// Types of events sent to listeners. A type of the event is in `event` field of the payload.
+const (
+	EVENT_CONTENT_CREATED = "content.created"
+	EVENT_CONTENT_UPDATED = "content.updated"
+	EVENT_CONTENT_DELETED = "content.deleted"
+	EVENT_FILE_RENAMED    = "file.renamed"
+	EVENT_FILE_DELETED    = "file.deleted"
+)
+
+// EVENTS is a list of event types listeners can subscribe to.
+var EVENTS = []string{
+	EVENT_CONTENT_CREATED,
+	EVENT_CONTENT_UPDATED,
+	EVENT_CONTENT_DELETED,
+	EVENT_FILE_RENAMED,
+	EVENT_FILE_DELETED,
+}
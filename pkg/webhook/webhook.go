@@ -0,0 +1,121 @@
+// Package webhook signs payloads sent to listeners and verifies them on the side of receivers.
+//
+// Every request has a timestamp header with unix time in seconds and a signature header with one or more
+// `v1=<hex>` items separated by commas. A signature is HMAC-SHA256 of `<timestamp>.<body>` with the secret
+// of the listener. After rotation of the secret requests are signed with both secrets until the previous one
+// expires, so receivers can switch to the new secret at any time during this period.
+//
+// Receivers verify requests with VerifyRequest:
+//
+//	body, err := webhook.VerifyRequest(r, secret, webhook.DEFAULT_TOLERANCE)
+//	if err != nil {
+//		w.WriteHeader(http.StatusUnauthorized)
+//		return
+//	}
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HEADER_TIMESTAMP = "X-Config-Keeper-Timestamp"
+	HEADER_SIGNATURE = "X-Config-Keeper-Signature"
+
+	SIGNATURE_VERSION = "v1"
+
+	// DEFAULT_TOLERANCE is a maximum difference between the timestamp of the request and the time of
+	// the receiver. Requests outside of it are rejected, so captured requests can not be replayed later.
+	DEFAULT_TOLERANCE = 5 * time.Minute
+)
+
+var (
+	ErrNoSignature = errors.New("webhook: timestamp or signature header is missing")
+	ErrTimestamp   = errors.New("webhook: timestamp is not valid or outside of tolerance")
+	ErrSignature   = errors.New("webhook: signature does not match")
+)
+
+// Sign returns a hex encoded signature of the body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Headers returns headers of the body sent at timestamp signed with every secret.
+// The signature header is omitted if there are no secrets.
+func Headers(timestamp time.Time, body []byte, secrets ...string) map[string]string {
+	unix := timestamp.Unix()
+	headers := map[string]string{
+		HEADER_TIMESTAMP: strconv.FormatInt(unix, 10),
+	}
+
+	signatures := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		signatures = append(signatures, SIGNATURE_VERSION+"="+Sign(secret, unix, body))
+	}
+	if len(signatures) > 0 {
+		headers[HEADER_SIGNATURE] = strings.Join(signatures, ",")
+	}
+	return headers
+}
+
+// Verify checks values of timestamp and signature headers of the body with the secret.
+// The body should be exactly the same bytes as received.
+func Verify(body []byte, timestamp string, signature string, secret string, tolerance time.Duration) error {
+	return verify(body, timestamp, signature, secret, tolerance, time.Now())
+}
+
+// VerifyRequest reads the body of the request and verifies it with the secret. The body is returned
+// and is also available in r.Body for further processing.
+func VerifyRequest(r *http.Request, secret string, tolerance time.Duration) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	err = Verify(body, r.Header.Get(HEADER_TIMESTAMP), r.Header.Get(HEADER_SIGNATURE), secret, tolerance)
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func verify(body []byte, timestamp string, signature string, secret string, tolerance time.Duration, now time.Time) error {
+	if timestamp == "" || signature == "" {
+		return ErrNoSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrTimestamp
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrTimestamp
+	}
+
+	expected := []byte(Sign(secret, unix, body))
+	for _, item := range strings.Split(signature, ",") {
+		version, value, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found || version != SIGNATURE_VERSION {
+			continue
+		}
+		if hmac.Equal(expected, []byte(value)) {
+			return nil
+		}
+	}
+	return ErrSignature
+}
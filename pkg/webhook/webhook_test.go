@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{"a":1}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686", Sign("secret", 1700000000, []byte(`{"a":1}`)))
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"a":1}`)
+	now := time.Unix(1700000000, 0)
+	headers := Headers(now, body, "new", "old")
+
+	tests := []struct {
+		name      string
+		body      []byte
+		timestamp string
+		signature string
+		secret    string
+		now       time.Time
+		expected  error
+	}{
+		{
+			name:      "current secret",
+			body:      body,
+			timestamp: headers[HEADER_TIMESTAMP],
+			signature: headers[HEADER_SIGNATURE],
+			secret:    "new",
+			now:       now,
+		},
+		{
+			name:      "previous secret",
+			body:      body,
+			timestamp: headers[HEADER_TIMESTAMP],
+			signature: headers[HEADER_SIGNATURE],
+			secret:    "old",
+			now:       now.Add(time.Minute),
+		},
+		{
+			name:      "wrong secret",
+			body:      body,
+			timestamp: headers[HEADER_TIMESTAMP],
+			signature: headers[HEADER_SIGNATURE],
+			secret:    "other",
+			now:       now,
+			expected:  ErrSignature,
+		},
+		{
+			name:      "changed body",
+			body:      []byte(`{"a":2}`),
+			timestamp: headers[HEADER_TIMESTAMP],
+			signature: headers[HEADER_SIGNATURE],
+			secret:    "new",
+			now:       now,
+			expected:  ErrSignature,
+		},
+		{
+			name:      "changed timestamp",
+			body:      body,
+			timestamp: strconv.FormatInt(now.Unix()+1, 10),
+			signature: headers[HEADER_SIGNATURE],
+			secret:    "new",
+			now:       now,
+			expected:  ErrSignature,
+		},
+		{
+			name:      "replayed request",
+			body:      body,
+			timestamp: headers[HEADER_TIMESTAMP],
+			signature: headers[HEADER_SIGNATURE],
+			secret:    "new",
+			now:       now.Add(DEFAULT_TOLERANCE + time.Second),
+			expected:  ErrTimestamp,
+		},
+		{
+			name:      "missing signature",
+			body:      body,
+			timestamp: headers[HEADER_TIMESTAMP],
+			secret:    "new",
+			now:       now,
+			expected:  ErrNoSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verify(tt.body, tt.timestamp, tt.signature, tt.secret, DEFAULT_TOLERANCE, tt.now)
+			assert.Equal(t, tt.expected, err)
+		})
+	}
+}
+
+func TestHeaders_WithoutSecrets(t *testing.T) {
+	headers := Headers(time.Unix(1700000000, 0), []byte("body"))
+	assert.Equal(t, map[string]string{HEADER_TIMESTAMP: "1700000000"}, headers)
+}
+
+func TestVerifyRequest(t *testing.T) {
+	body := []byte(`{"a":1}`)
+	r := httptest.NewRequest(http.MethodPost, "/callback", bytes.NewReader(body))
+	for key, value := range Headers(time.Now(), body, "secret") {
+		r.Header.Set(key, value)
+	}
+
+	received, err := VerifyRequest(r, "secret", DEFAULT_TOLERANCE)
+	assert.NoError(t, err)
+	assert.Equal(t, body, received)
+
+	rest, err := io.ReadAll(r.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, body, rest)
+}
@@ -1,16 +1,33 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/Moranilt/config-keeper/custom_errors"
 	"github.com/Moranilt/config-keeper/models"
+	"github.com/Moranilt/config-keeper/pkg/archive"
+	"github.com/Moranilt/config-keeper/pkg/batch"
 	"github.com/Moranilt/config-keeper/pkg/callback"
 	"github.com/Moranilt/config-keeper/pkg/content_formats"
+	"github.com/Moranilt/config-keeper/pkg/dead_letters"
+	"github.com/Moranilt/config-keeper/pkg/deliveries"
+	"github.com/Moranilt/config-keeper/pkg/envfile"
 	"github.com/Moranilt/config-keeper/pkg/file_contents"
 	"github.com/Moranilt/config-keeper/pkg/files"
 	"github.com/Moranilt/config-keeper/pkg/folders"
+	"github.com/Moranilt/config-keeper/pkg/formats"
+	"github.com/Moranilt/config-keeper/pkg/gitsync"
+	"github.com/Moranilt/config-keeper/pkg/kubernetes"
 	"github.com/Moranilt/config-keeper/pkg/listeners"
+	"github.com/Moranilt/config-keeper/pkg/transaction"
+	"github.com/Moranilt/config-keeper/pkg/trash"
+	"github.com/Moranilt/config-keeper/pkg/webhook"
 	"github.com/Moranilt/config-keeper/utils"
 	"github.com/Moranilt/http-utils/clients/database"
 	"github.com/Moranilt/http-utils/logger"
@@ -31,18 +48,33 @@ type Repository struct {
 	files          files.Client
 	fileContent    file_contents.Client
 	listeners      listeners.Client
-	callback       callback.CallbackChannel
+	deliveries     deliveries.Client
+	deadLetters    dead_letters.Client
+	callback       callback.Outbox
+	requests       callback.RequestsController
 	contentFormats content_formats.Client
+	trash          trash.Client
+	batch          batch.Client
+	archive        archive.Client
+	gitSync        gitsync.Syncer
+	tx             transaction.Manager
 }
 
 func New(
 	db *database.Client,
-	callback callback.CallbackChannel,
+	callback callback.Outbox,
+	requests callback.RequestsController,
 	folders folders.Client,
 	files files.Client,
 	fileContent file_contents.Client,
 	listeners listeners.Client,
+	deliveries deliveries.Client,
+	deadLetters dead_letters.Client,
 	contentFormats content_formats.Client,
+	trash trash.Client,
+	batch batch.Client,
+	archive archive.Client,
+	gitSync gitsync.Syncer,
 	logger logger.Logger,
 ) *Repository {
 	return &Repository{
@@ -53,8 +85,16 @@ func New(
 		files:          files,
 		fileContent:    fileContent,
 		listeners:      listeners,
+		deliveries:     deliveries,
+		deadLetters:    deadLetters,
 		callback:       callback,
+		requests:       requests,
 		contentFormats: contentFormats,
+		trash:          trash,
+		batch:          batch,
+		archive:        archive,
+		gitSync:        gitSync,
+		tx:             transaction.New(db),
 	}
 }
 
@@ -78,27 +118,34 @@ func (repo *Repository) CreateFolder(ctx context.Context, req *models.CreateFold
 		return nil, err
 	}
 
-	exists, err := repo.folders.Exists(ctx, &folders.ExistsRequest{
-		Name:     &clearName,
-		ParentID: req.ParentID,
-	})
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "ExistsFolder")
-		return nil, err
-	}
+	var folder *folders.Folder
+	err = repo.tx.Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+		exists, err := repo.folders.Exists(ctx, &folders.ExistsRequest{
+			Name:     &clearName,
+			ParentID: req.ParentID,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "ExistsFolder")
+			return err
+		}
 
-	if exists {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Exists)
-	}
+		if exists {
+			return utils.ExistsError("folder with such name already exists")
+		}
 
-	folder, err := repo.folders.Create(ctx, &folders.CreateRequest{
-		Name:     clearName,
-		ParentID: req.ParentID,
+		folder, err = repo.folders.Create(ctx, &folders.CreateRequest{
+			Name:     clearName,
+			ParentID: req.ParentID,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "NewFolder")
+			return err
+		}
+		return nil
 	})
 	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "NewFolder")
 		return nil, err
 	}
 
@@ -106,6 +153,7 @@ func (repo *Repository) CreateFolder(ctx context.Context, req *models.CreateFold
 		ID:        folder.ID,
 		Name:      folder.Name,
 		ParentID:  folder.ParentID,
+		Metadata:  folder.Metadata,
 		CreatedAt: folder.CreatedAt,
 		UpdatedAt: folder.UpdatedAt,
 	}, nil
@@ -118,41 +166,50 @@ func (repo *Repository) GetFolder(ctx context.Context, req *models.GetFolderRequ
 	))
 	defer span.End()
 
-	var (
-		folderWithPath *folders.FolderWithPath
-		err            tiny_errors.ErrorHandler
-		parentID       *string
-	)
+	folderWithPath, err := repo.folders.Get(ctx, &folders.GetRequest{
+		ID: req.FolderID,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetFolder")
+		return nil, err
+	}
+	parentID := &folderWithPath.ID
 
-	if req.FolderID == "root" {
-		folderWithPath = &folders.FolderWithPath{
-			Folder: folders.Folder{
-				ID:        "root",
-				Name:      "root",
-				ParentID:  nil,
-				CreatedAt: "1979-01-01T00:00:00Z",
-				UpdatedAt: "1979-01-01T00:00:00Z",
-			},
-			Path: "root",
-		}
-	} else {
-		parentID = &req.FolderID
-		folderWithPath, err = repo.folders.Get(ctx, &folders.GetRequest{
-			ID: req.FolderID,
-		})
-		if err != nil {
-			span.RecordError(err)
-			span.SetStatus(codes.Error, "GetFolder")
-			return nil, err
-		}
+	foldersFilter, err := listFilter(req.ListParams, req.FoldersCursor)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "ListFilter")
+		return nil, err
+	}
+
+	filesFilter, err := listFilter(req.ListParams, req.FilesCursor)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "ListFilter")
+		return nil, err
 	}
 
-	folders, err := repo.folders.GetMany(ctx, &folders.GetManyRequest{
+	labels, err := utils.ParseLabels(req.Labels)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "ParseLabels")
+		return nil, err
+	}
+
+	metadataFilter := &utils.MetadataFilter{
+		OwnerTeam: req.OwnerTeam,
+		Labels:    labels,
+	}
+
+	folders, foldersNext, err := repo.folders.GetMany(ctx, &folders.GetManyRequest{
 		ParentID: parentID,
+		Metadata: metadataFilter,
 		Order: &folders.Order{
 			Column: req.OrderColumn,
 			Type:   req.OrderType,
 		},
+		Filter: foldersFilter,
 	})
 	if err != nil {
 		span.RecordError(err)
@@ -160,8 +217,14 @@ func (repo *Repository) GetFolder(ctx context.Context, req *models.GetFolderRequ
 		return nil, err
 	}
 
-	files, err := repo.files.GetMany(ctx, &files.GetManyRequest{
+	files, filesNext, err := repo.files.GetMany(ctx, &files.GetManyRequest{
 		FolderID: parentID,
+		Metadata: metadataFilter,
+		Order: &files.Order{
+			Column: req.OrderColumn,
+			Type:   req.OrderType,
+		},
+		Filter: filesFilter,
 	})
 	if err != nil {
 		span.RecordError(err)
@@ -170,14 +233,17 @@ func (repo *Repository) GetFolder(ctx context.Context, req *models.GetFolderRequ
 	}
 
 	return &models.GetFolderResponse{
-		ID:        folderWithPath.ID,
-		Name:      folderWithPath.Name,
-		ParentID:  folderWithPath.ParentID,
-		CreatedAt: folderWithPath.CreatedAt,
-		UpdatedAt: folderWithPath.UpdatedAt,
-		Path:      folderWithPath.Path,
-		Folders:   folders,
-		Files:     files,
+		ID:                folderWithPath.ID,
+		Name:              folderWithPath.Name,
+		ParentID:          folderWithPath.ParentID,
+		Metadata:          folderWithPath.Metadata,
+		CreatedAt:         folderWithPath.CreatedAt,
+		UpdatedAt:         folderWithPath.UpdatedAt,
+		Path:              folderWithPath.Path,
+		Folders:           folders,
+		Files:             files,
+		FoldersNextCursor: foldersNext,
+		FilesNextCursor:   filesNext,
 	}, nil
 }
 
@@ -188,12 +254,41 @@ func (repo *Repository) DeleteFolder(ctx context.Context, req *models.DeleteFold
 	))
 	defer span.End()
 
-	removed, err := repo.folders.Delete(ctx, &folders.DeleteRequest{
-		ID: req.FolderID,
+	var removed bool
+	err := repo.tx.Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+		// files are selected before they are deleted with the folder
+		nested, err := repo.files.GetNested(ctx, &files.GetNestedRequest{
+			FolderID: req.FolderID,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "GetNested")
+			return err
+		}
+
+		removed, err = repo.folders.Delete(ctx, &folders.DeleteRequest{
+			ID: req.FolderID,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Delete")
+			return err
+		}
+		if !removed {
+			return nil
+		}
+
+		for _, file := range nested {
+			err := repo.sendEvent(ctx, span, file.ID, webhook.EVENT_FILE_DELETED, &callback.EventDetails{
+				Name: &file.Name,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Delete")
 		return nil, err
 	}
 
@@ -206,26 +301,14 @@ func (repo *Repository) EditFolder(ctx context.Context, req *models.EditFolderRe
 	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
 	ctx, span := repo.tracer.Start(ctx, "EditFolder", trace.WithAttributes(
 		attribute.String("folder_id", req.FolderID),
-		attribute.String("name", req.Name),
 	))
 	defer span.End()
 
-	clearName, err := utils.ClearName(req.Name)
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "ClearName")
-		return nil, err
-	}
-
 	requiredFields := []utils.RequiredField{
 		{
 			Name:  "id",
 			Value: req.FolderID,
 		},
-		{
-			Name:  "name",
-			Value: clearName,
-		},
 	}
 
 	errFields := utils.ValidateRequiredFields(requiredFields)
@@ -236,9 +319,17 @@ func (repo *Repository) EditFolder(ctx context.Context, req *models.EditFolderRe
 		return nil, err
 	}
 
+	name, err := editName(req.Name)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "ClearName")
+		return nil, err
+	}
+
 	folder, err := repo.folders.Edit(ctx, &folders.EditRequest{
-		ID:   req.FolderID,
-		Name: clearName,
+		ID:       req.FolderID,
+		Name:     name,
+		Metadata: &req.MetadataUpdate,
 	})
 	if err != nil {
 		span.RecordError(err)
@@ -266,20 +357,7 @@ func (repo *Repository) CreateFile(ctx context.Context, req *models.CreateFileRe
 		return nil, err
 	}
 
-	exists, err := repo.folders.Exists(ctx, &folders.ExistsRequest{
-		Name:     &clearName,
-		ParentID: req.FolderID,
-	})
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "ExistsFolder")
-		return nil, err
-	}
-
-	if exists {
-		return nil, tiny_errors.New(custom_errors.ERR_CODE_Exists)
-	}
-
+	// duplicate names are rejected by the unique index of files
 	file, err := repo.files.Create(ctx, &files.CreateRequest{
 		Name:     clearName,
 		FolderID: req.FolderID,
@@ -300,12 +378,37 @@ func (repo *Repository) DeleteFile(ctx context.Context, req *models.DeleteFileRe
 	))
 	defer span.End()
 
-	removed, err := repo.files.Delete(ctx, &files.DeleteRequest{
-		ID: req.ID,
+	var removed bool
+	err := repo.tx.Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+		file, err := repo.files.Get(ctx, &files.GetRequest{
+			ID: req.ID,
+		})
+		if err != nil && err.GetCode() == custom_errors.ERR_CODE_NotFound {
+			return nil
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "GetFile")
+			return err
+		}
+
+		removed, err = repo.files.Delete(ctx, &files.DeleteRequest{
+			ID: req.ID,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Delete")
+			return err
+		}
+		if !removed {
+			return nil
+		}
+
+		return repo.sendEvent(ctx, span, file.ID, webhook.EVENT_FILE_DELETED, &callback.EventDetails{
+			Name: &file.Name,
+		})
 	})
 	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Delete")
 		return nil, err
 	}
 
@@ -318,30 +421,59 @@ func (repo *Repository) EditFile(ctx context.Context, req *models.EditFileReques
 	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
 	ctx, span := repo.tracer.Start(ctx, "EditFile", trace.WithAttributes(
 		attribute.String("file_id", req.FileID),
-		attribute.String("name", req.Name),
 	))
 	defer span.End()
 
-	clearName, err := utils.ClearName(req.Name)
+	name, err := editName(req.Name)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "ClearName")
 		return nil, err
 	}
 
-	file, err := repo.files.Edit(ctx, &files.EditRequest{
-		FileID: req.FileID,
-		Name:   clearName,
+	var file *files.File
+	err = repo.tx.Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+		var err tiny_errors.ErrorHandler
+		var previous *files.File
+		if name != nil {
+			previous, err = repo.files.Get(ctx, &files.GetRequest{
+				ID: req.FileID,
+			})
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, "GetFile")
+				return err
+			}
+		}
+
+		file, err = repo.files.Edit(ctx, &files.EditRequest{
+			FileID:   req.FileID,
+			Name:     name,
+			Metadata: &req.MetadataUpdate,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Edit")
+			return err
+		}
+
+		if previous == nil || previous.Name == file.Name {
+			return nil
+		}
+		return repo.sendEvent(ctx, span, file.ID, webhook.EVENT_FILE_RENAMED, &callback.EventDetails{
+			Name:         &file.Name,
+			PreviousName: &previous.Name,
+		})
 	})
 	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Edit")
 		return nil, err
 	}
 
 	return (*models.EditFileResponse)(file), nil
 }
 
+// GetFile returns the file with a list of its versions. Data of contents is returned only if it is included
+// with `content`, contents kept in blobs are returned without data anyway and can be read with GetFileContents.
 func (repo *Repository) GetFile(ctx context.Context, req *models.GetFileRequest) (*models.GetFileResponse, tiny_errors.ErrorHandler) {
 	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
 	ctx, span := repo.tracer.Start(ctx, "GetFile", trace.WithAttributes(
@@ -349,6 +481,13 @@ func (repo *Repository) GetFile(ctx context.Context, req *models.GetFileRequest)
 	))
 	defer span.End()
 
+	include, err := includeParam(req.Include, models.INCLUDE_CONTENT)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "IncludeParam")
+		return nil, err
+	}
+
 	file, err := repo.files.Get(ctx, &files.GetRequest{
 		ID: req.FileID,
 	})
@@ -358,8 +497,9 @@ func (repo *Repository) GetFile(ctx context.Context, req *models.GetFileRequest)
 		return nil, err
 	}
 
-	fileContents, err := repo.fileContent.GetMany(ctx, &file_contents.GetManyRequest{
-		FileID: req.FileID,
+	fileContents, _, err := repo.fileContent.GetMany(ctx, &file_contents.GetManyRequest{
+		FileID:      req.FileID,
+		SkipContent: !include[models.INCLUDE_CONTENT],
 	})
 	if err != nil {
 		span.RecordError(err)
@@ -381,15 +521,44 @@ func (repo *Repository) CreateFileContent(ctx context.Context, req *models.Creat
 	))
 	defer span.End()
 
-	filesContent, err := repo.fileContent.Create(ctx, &file_contents.CreateRequest{
-		FileID:   req.FileID,
-		Content:  req.Content,
-		Version:  req.Version,
-		FormatID: req.FormatID,
+	var contentFormat *content_formats.ContentFormat
+	if req.FormatID != "" {
+		var err tiny_errors.ErrorHandler
+		contentFormat, err = repo.contentFormats.Get(ctx, &content_formats.GetRequest{
+			ID: req.FormatID,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "GetContentFormat")
+			return nil, err
+		}
+		if err := validateContent(contentFormat, req.Content); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "ValidateContent")
+			return nil, err
+		}
+	}
+
+	var filesContent *file_contents.FileContent
+	err := repo.tx.Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+		var err tiny_errors.ErrorHandler
+		filesContent, err = repo.fileContent.Create(ctx, &file_contents.CreateRequest{
+			FileID:   req.FileID,
+			Content:  req.Content,
+			Version:  req.Version,
+			FormatID: req.FormatID,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "CreateFileContent")
+			return err
+		}
+
+		details := contentDetails(filesContent)
+		details.Changes = contentChanges(contentFormat, nil, &req.Content)
+		return repo.sendEvent(ctx, span, filesContent.FileID, webhook.EVENT_CONTENT_CREATED, details)
 	})
 	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "CreateFileContent")
 		return nil, err
 	}
 
@@ -403,9 +572,22 @@ func (repo *Repository) GetFileContents(ctx context.Context, req *models.GetFile
 	))
 	defer span.End()
 
-	filesContent, err := repo.fileContent.GetMany(ctx, &file_contents.GetManyRequest{
+	filter, err := listFilter(req.ListParams, req.Cursor)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "ListFilter")
+		return nil, err
+	}
+
+	filesContent, next, err := repo.fileContent.GetMany(ctx, &file_contents.GetManyRequest{
 		FileID:  req.FileID,
 		Version: req.Version,
+		Order: &file_contents.Order{
+			Column: req.OrderColumn,
+			Type:   req.OrderType,
+		},
+		Filter:    filter,
+		LoadBlobs: true,
 	})
 	if err != nil {
 		span.RecordError(err)
@@ -413,7 +595,10 @@ func (repo *Repository) GetFileContents(ctx context.Context, req *models.GetFile
 		return nil, err
 	}
 
-	return (*models.GetFileContentsResponse)(&filesContent), nil
+	return &models.GetFileContentsResponse{
+		Contents:   filesContent,
+		NextCursor: next,
+	}, nil
 }
 
 func (repo *Repository) EditFileContent(ctx context.Context, req *models.EditFileContentRequest) (*models.EditFileContentResponse, tiny_errors.ErrorHandler) {
@@ -423,20 +608,73 @@ func (repo *Repository) EditFileContent(ctx context.Context, req *models.EditFil
 	))
 	defer span.End()
 
-	filesContent, err := repo.fileContent.Edit(ctx, &file_contents.EditRequest{
-		FileContentID: req.ContentID,
-		Content:       req.Content,
-		Version:       req.Version,
+	var contentFormat *content_formats.ContentFormat
+	if req.Content != nil {
+		var err tiny_errors.ErrorHandler
+		contentFormat, err = repo.contentFormats.Get(ctx, &content_formats.GetRequest{
+			ContentID: req.ContentID,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "GetContentFormat")
+			return nil, err
+		}
+		if err := validateContent(contentFormat, *req.Content); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "ValidateContent")
+			return nil, err
+		}
+	}
+
+	var filesContent *file_contents.FileContent
+	err := repo.tx.Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+		previous, err := repo.fileContent.Get(ctx, &file_contents.GetRequest{
+			ID: req.ContentID,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "GetFileContent")
+			return err
+		}
+
+		// previous data is read only if changes of the content can be found
+		var previousData *string
+		if contentFormat != nil {
+			if _, ok := contentFormat.Handler(); ok {
+				_, data, err := repo.fileVersion(ctx, previous.FileID, &previous.Version)
+				if err != nil {
+					span.RecordError(err)
+					span.SetStatus(codes.Error, "GetPreviousContent")
+					return err
+				}
+				previousData = &data
+			}
+		}
+
+		filesContent, err = repo.fileContent.Edit(ctx, &file_contents.EditRequest{
+			FileContentID: req.ContentID,
+			Content:       req.Content,
+			Version:       req.Version,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "EditFileContent")
+			return err
+		}
+
+		details := contentDetails(filesContent)
+		if previous.Version != filesContent.Version {
+			details.PreviousVersion = &previous.Version
+		}
+		if previousData != nil {
+			details.Changes = contentChanges(contentFormat, previousData, req.Content)
+		}
+		return repo.sendEvent(ctx, span, filesContent.FileID, webhook.EVENT_CONTENT_UPDATED, details)
 	})
 	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "EditFileContent")
 		return nil, err
 	}
 
-	go repo.callback.Send(&callback.CallbackRequest{
-		FileID: filesContent.FileID,
-	})
 	return (*models.EditFileContentResponse)(filesContent), nil
 }
 
@@ -447,12 +685,35 @@ func (repo *Repository) DeleteFileContent(ctx context.Context, req *models.Delet
 	))
 	defer span.End()
 
-	removed, err := repo.fileContent.Delete(ctx, &file_contents.DeleteRequest{
-		ID: req.ContentID,
+	var removed bool
+	err := repo.tx.Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+		fileContent, err := repo.fileContent.Get(ctx, &file_contents.GetRequest{
+			ID: req.ContentID,
+		})
+		if err != nil && err.GetCode() == custom_errors.ERR_CODE_NotFound {
+			return nil
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "GetFileContent")
+			return err
+		}
+
+		removed, err = repo.fileContent.Delete(ctx, &file_contents.DeleteRequest{
+			ID: req.ContentID,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Delete")
+			return err
+		}
+		if !removed {
+			return nil
+		}
+
+		return repo.sendEvent(ctx, span, fileContent.FileID, webhook.EVENT_CONTENT_DELETED, contentDetails(fileContent))
 	})
 	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Delete")
 		return nil, err
 	}
 
@@ -468,10 +729,26 @@ func (repo *Repository) CreateListener(ctx context.Context, req *models.CreateLi
 	))
 	defer span.End()
 
+	_, err := repo.files.Get(ctx, &files.GetRequest{
+		ID: req.FileID,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetFile")
+		return nil, err
+	}
+
 	listener, err := repo.listeners.Create(ctx, &listeners.CreateRequest{
 		FileID:           req.FileID,
 		Name:             req.Name,
 		CallbackEndpoint: req.CallbackEndpoint,
+		Headers:          req.Headers,
+		Auth:             req.Auth,
+		RetryPolicy:      req.RetryPolicy,
+		FollowRedirects:  req.FollowRedirects,
+		EventTypes:       req.EventTypes,
+		VersionFilter:    req.VersionFilter,
+		PayloadMode:      req.PayloadMode,
 	})
 	if err != nil {
 		span.RecordError(err)
@@ -479,7 +756,10 @@ func (repo *Repository) CreateListener(ctx context.Context, req *models.CreateLi
 		return nil, err
 	}
 
-	return (*models.CreateListenerResponse)(listener), nil
+	return &models.CreateListenerResponse{
+		Listener: listener,
+		Secret:   listener.Secret,
+	}, nil
 }
 
 func (repo *Repository) GetListener(ctx context.Context, req *models.GetListenerRequest) (*models.GetListenerResponse, tiny_errors.ErrorHandler) {
@@ -489,9 +769,7 @@ func (repo *Repository) GetListener(ctx context.Context, req *models.GetListener
 	))
 	defer span.End()
 
-	listener, err := repo.listeners.Get(ctx, &listeners.GetRequest{
-		ID: req.ListenerID,
-	})
+	listener, err := repo.activeListener(ctx, req.ListenerID)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "GetListener")
@@ -501,15 +779,37 @@ func (repo *Repository) GetListener(ctx context.Context, req *models.GetListener
 	return (*models.GetListenerResponse)(listener), nil
 }
 
-func (repo *Repository) GetFileListeners(ctx context.Context, req *models.GetFileListenersRequest) (*models.GetFileListenersResponse, tiny_errors.ErrorHandler) {
+// GetListenerDeliveries returns attempts to deliver events to the listener of the file, optionally filtered by status and time.
+func (repo *Repository) GetListenerDeliveries(ctx context.Context, req *models.GetListenerDeliveriesRequest) (*models.GetListenerDeliveriesResponse, tiny_errors.ErrorHandler) {
 	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
-	ctx, span := repo.tracer.Start(ctx, "GetFileListeners", trace.WithAttributes(
-		attribute.String("id", req.FileID),
+	ctx, span := repo.tracer.Start(ctx, "GetListenerDeliveries", trace.WithAttributes(
+		attribute.String("file_id", req.FileID),
+		attribute.String("listener_id", req.ListenerID),
 	))
 	defer span.End()
 
-	listeners, err := repo.listeners.GetMany(ctx, &listeners.GetManyRequest{
-		FileID: req.FileID,
+	filter, err := listFilter(req.ListParams, req.Cursor)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "ListFilter")
+		return nil, err
+	}
+
+	_, err = repo.fileListener(ctx, req.FileID, req.ListenerID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetListener")
+		return nil, err
+	}
+
+	deliveries, next, err := repo.deliveries.GetMany(ctx, &deliveries.GetManyRequest{
+		ListenerID: req.ListenerID,
+		Status:     req.Status,
+		Order: &deliveries.Order{
+			Column: req.OrderColumn,
+			Type:   req.OrderType,
+		},
+		Filter: filter,
 	})
 	if err != nil {
 		span.RecordError(err)
@@ -517,49 +817,396 @@ func (repo *Repository) GetFileListeners(ctx context.Context, req *models.GetFil
 		return nil, err
 	}
 
-	return (*models.GetFileListenersResponse)(&listeners), nil
+	return &models.GetListenerDeliveriesResponse{
+		Deliveries: deliveries,
+		NextCursor: next,
+	}, nil
 }
 
-func (repo *Repository) EditListener(ctx context.Context, req *models.EditListenerRequest) (*models.EditListenerResponse, tiny_errors.ErrorHandler) {
+// RotateListenerSecret generates a new secret of the listener of the file. Payloads are signed with both secrets
+// until the previous one expires, so receivers can be updated without rejecting deliveries.
+func (repo *Repository) RotateListenerSecret(ctx context.Context, req *models.RotateListenerSecretRequest) (*models.RotateListenerSecretResponse, tiny_errors.ErrorHandler) {
 	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
-	ctx, span := repo.tracer.Start(ctx, "EditListener", trace.WithAttributes(
-		attribute.String("id", req.ListenerID),
+	ctx, span := repo.tracer.Start(ctx, "RotateListenerSecret", trace.WithAttributes(
+		attribute.String("file_id", req.FileID),
+		attribute.String("listener_id", req.ListenerID),
 	))
 	defer span.End()
 
-	listener, err := repo.listeners.Edit(ctx, &listeners.EditRequest{
+	_, err := repo.fileListener(ctx, req.FileID, req.ListenerID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetListener")
+		return nil, err
+	}
+
+	var lifetime *time.Duration
+	if req.PreviousLifetime != nil {
+		lifetime = utils.MakePointer(time.Duration(*req.PreviousLifetime) * time.Second)
+	}
+
+	listener, err := repo.listeners.RotateSecret(ctx, &listeners.RotateSecretRequest{
 		ID:               req.ListenerID,
-		Name:             req.Name,
-		CallbackEndpoint: req.CallbackEndpoint,
+		PreviousLifetime: lifetime,
 	})
 	if err != nil {
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "Edit")
+		span.SetStatus(codes.Error, "RotateSecret")
 		return nil, err
 	}
 
-	return (*models.EditListenerResponse)(listener), nil
+	return &models.RotateListenerSecretResponse{
+		Listener: listener,
+		Secret:   listener.Secret,
+	}, nil
 }
 
-func (repo *Repository) DeleteListener(ctx context.Context, req *models.DeleteListenerRequest) (*models.DeleteListenerResponse, tiny_errors.ErrorHandler) {
+// EnableListener enables the listener of the file and resets its failures. Events skipped while the listener
+// was disabled are not sent, they can be replayed from dead letters.
+func (repo *Repository) EnableListener(ctx context.Context, req *models.EnableListenerRequest) (*models.EnableListenerResponse, tiny_errors.ErrorHandler) {
 	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
-	ctx, span := repo.tracer.Start(ctx, "DeleteListener", trace.WithAttributes(
+	ctx, span := repo.tracer.Start(ctx, "EnableListener", trace.WithAttributes(
+		attribute.String("file_id", req.FileID),
 		attribute.String("listener_id", req.ListenerID),
 	))
 	defer span.End()
 
-	removed, err := repo.listeners.Delete(ctx, &listeners.DeleteRequest{
+	_, err := repo.fileListener(ctx, req.FileID, req.ListenerID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetListener")
+		return nil, err
+	}
+
+	listener, err := repo.listeners.Enable(ctx, &listeners.EnableRequest{
 		ID: req.ListenerID,
 	})
 	if err != nil {
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "Delete")
+		span.SetStatus(codes.Error, "Enable")
 		return nil, err
 	}
 
-	return &models.DeleteListenerResponse{
-		Status: removed,
-	}, nil
+	return (*models.EnableListenerResponse)(listener), nil
+}
+
+// DisableListener stops sending events to the listener of the file. The reason is shown in the listener.
+func (repo *Repository) DisableListener(ctx context.Context, req *models.DisableListenerRequest) (*models.DisableListenerResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	ctx, span := repo.tracer.Start(ctx, "DisableListener", trace.WithAttributes(
+		attribute.String("file_id", req.FileID),
+		attribute.String("listener_id", req.ListenerID),
+	))
+	defer span.End()
+
+	_, err := repo.fileListener(ctx, req.FileID, req.ListenerID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetListener")
+		return nil, err
+	}
+
+	listener, err := repo.listeners.Disable(ctx, &listeners.DisableRequest{
+		ID:     req.ListenerID,
+		Reason: req.Reason,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Disable")
+		return nil, err
+	}
+
+	return (*models.DisableListenerResponse)(listener), nil
+}
+
+// RedeliverListener sends the file to the listener again through the outbox as content.updated event. If EventID is set,
+// the event should be in the delivery log of the listener, it is sent with its type and new attempts are recorded for it.
+// Contents are always in their current state and details of the event are not kept.
+func (repo *Repository) RedeliverListener(ctx context.Context, req *models.RedeliverListenerRequest) (*models.RedeliverListenerResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	ctx, span := repo.tracer.Start(ctx, "RedeliverListener", trace.WithAttributes(
+		attribute.String("file_id", req.FileID),
+		attribute.String("listener_id", req.ListenerID),
+	))
+	defer span.End()
+
+	listener, err := repo.fileListener(ctx, req.FileID, req.ListenerID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetListener")
+		return nil, err
+	}
+	if !listener.Enabled {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("listener is disabled"))
+	}
+
+	event := webhook.EVENT_CONTENT_UPDATED
+	if req.EventID != nil {
+		eventType, err := repo.deliveries.EventType(ctx, req.ListenerID, *req.EventID)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "EventType")
+			return nil, err
+		}
+		if eventType == nil {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("event was not delivered to the listener"), tiny_errors.HTTPStatus(http.StatusNotFound))
+		}
+		event = *eventType
+	}
+
+	err = repo.callback.Send(ctx, &callback.CallbackRequest{
+		FileID:     req.FileID,
+		ListenerID: &req.ListenerID,
+		EventID:    req.EventID,
+		Event:      event,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "SendCallback")
+		return nil, err
+	}
+
+	return &models.RedeliverListenerResponse{
+		Status: true,
+	}, nil
+}
+
+// GetListenerDeadLetters returns events which were not delivered to the listener of the file after all attempts of its retry policy.
+func (repo *Repository) GetListenerDeadLetters(ctx context.Context, req *models.GetListenerDeadLettersRequest) (*models.GetListenerDeadLettersResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	ctx, span := repo.tracer.Start(ctx, "GetListenerDeadLetters", trace.WithAttributes(
+		attribute.String("file_id", req.FileID),
+		attribute.String("listener_id", req.ListenerID),
+	))
+	defer span.End()
+
+	filter, err := listFilter(req.ListParams, req.Cursor)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "ListFilter")
+		return nil, err
+	}
+
+	_, err = repo.fileListener(ctx, req.FileID, req.ListenerID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetListener")
+		return nil, err
+	}
+
+	deadLetters, next, err := repo.deadLetters.GetMany(ctx, &dead_letters.GetManyRequest{
+		ListenerID: req.ListenerID,
+		Order: &dead_letters.Order{
+			Column: req.OrderColumn,
+			Type:   req.OrderType,
+		},
+		Filter: filter,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetMany")
+		return nil, err
+	}
+
+	return &models.GetListenerDeadLettersResponse{
+		DeadLetters: deadLetters,
+		NextCursor:  next,
+	}, nil
+}
+
+// ReplayListenerDeadLetters removes dead letters of the listener and sends them to the outbox again in one transaction.
+// Attempts of replayed events are recorded in the delivery log of original events and start from the first attempt.
+func (repo *Repository) ReplayListenerDeadLetters(ctx context.Context, req *models.ReplayListenerDeadLettersRequest) (*models.ReplayListenerDeadLettersResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	ctx, span := repo.tracer.Start(ctx, "ReplayListenerDeadLetters", trace.WithAttributes(
+		attribute.String("file_id", req.FileID),
+		attribute.String("listener_id", req.ListenerID),
+	))
+	defer span.End()
+
+	listener, err := repo.fileListener(ctx, req.FileID, req.ListenerID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetListener")
+		return nil, err
+	}
+	if !listener.Enabled {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("listener is disabled"))
+	}
+
+	var replayed int
+	err = repo.tx.Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+		deadLetters, err := repo.deadLetters.Take(ctx, &dead_letters.TakeRequest{
+			ListenerID: req.ListenerID,
+			IDs:        req.IDs,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Take")
+			return err
+		}
+
+		for _, deadLetter := range deadLetters {
+			err = repo.callback.Send(ctx, &callback.CallbackRequest{
+				FileID:     deadLetter.FileID,
+				ListenerID: &deadLetter.ListenerID,
+				EventID:    &deadLetter.EventID,
+				Event:      deadLetter.EventType,
+				Details:    deadLetter.Details,
+			})
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, "SendCallback")
+				return err
+			}
+		}
+		replayed = len(deadLetters)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ReplayListenerDeadLettersResponse{
+		Replayed: replayed,
+	}, nil
+}
+
+// PingListener sends the ping event to the listener and returns its response. Listener errors are returned in the response.
+func (repo *Repository) PingListener(ctx context.Context, req *models.PingListenerRequest) (*models.PingListenerResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	ctx, span := repo.tracer.Start(ctx, "PingListener", trace.WithAttributes(
+		attribute.String("file_id", req.FileID),
+		attribute.String("listener_id", req.ListenerID),
+	))
+	defer span.End()
+
+	listener, err := repo.fileListener(ctx, req.FileID, req.ListenerID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetListener")
+		return nil, err
+	}
+
+	result, pingErr := repo.requests.Ping(ctx, listener)
+	if pingErr != nil {
+		span.RecordError(pingErr)
+		span.SetStatus(codes.Error, "Ping")
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Internal, tiny_errors.Message(pingErr.Error()), tiny_errors.HTTPStatus(http.StatusInternalServerError))
+	}
+
+	return (*models.PingListenerResponse)(result), nil
+}
+
+func (repo *Repository) GetFileListeners(ctx context.Context, req *models.GetFileListenersRequest) (*models.GetFileListenersResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	ctx, span := repo.tracer.Start(ctx, "GetFileListeners", trace.WithAttributes(
+		attribute.String("id", req.FileID),
+	))
+	defer span.End()
+
+	filter, err := listFilter(req.ListParams, req.Cursor)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "ListFilter")
+		return nil, err
+	}
+
+	_, err = repo.files.Get(ctx, &files.GetRequest{
+		ID: req.FileID,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetFile")
+		return nil, err
+	}
+
+	listeners, next, err := repo.listeners.GetMany(ctx, &listeners.GetManyRequest{
+		FileID: req.FileID,
+		Order: &listeners.Order{
+			Column: req.OrderColumn,
+			Type:   req.OrderType,
+		},
+		Filter: filter,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetMany")
+		return nil, err
+	}
+
+	return &models.GetFileListenersResponse{
+		Listeners:  listeners,
+		NextCursor: next,
+	}, nil
+}
+
+func (repo *Repository) EditListener(ctx context.Context, req *models.EditListenerRequest) (*models.EditListenerResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	ctx, span := repo.tracer.Start(ctx, "EditListener", trace.WithAttributes(
+		attribute.String("id", req.ListenerID),
+	))
+	defer span.End()
+
+	_, err := repo.activeListener(ctx, req.ListenerID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetListener")
+		return nil, err
+	}
+
+	listener, err := repo.listeners.Edit(ctx, &listeners.EditRequest{
+		ID:               req.ListenerID,
+		Name:             req.Name,
+		CallbackEndpoint: req.CallbackEndpoint,
+		Headers:          req.Headers,
+		Auth:             req.Auth,
+		RetryPolicy:      req.RetryPolicy,
+		FollowRedirects:  req.FollowRedirects,
+		EventTypes:       req.EventTypes,
+		VersionFilter:    req.VersionFilter,
+		PayloadMode:      req.PayloadMode,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Edit")
+		return nil, err
+	}
+
+	return (*models.EditListenerResponse)(listener), nil
+}
+
+func (repo *Repository) DeleteListener(ctx context.Context, req *models.DeleteListenerRequest) (*models.DeleteListenerResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	ctx, span := repo.tracer.Start(ctx, "DeleteListener", trace.WithAttributes(
+		attribute.String("listener_id", req.ListenerID),
+	))
+	defer span.End()
+
+	_, err := repo.activeListener(ctx, req.ListenerID)
+	if err != nil && err.GetCode() == custom_errors.ERR_CODE_NotFound {
+		return &models.DeleteListenerResponse{
+			Status: false,
+		}, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetListener")
+		return nil, err
+	}
+
+	removed, err := repo.listeners.Delete(ctx, &listeners.DeleteRequest{
+		ID: req.ListenerID,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Delete")
+		return nil, err
+	}
+
+	return &models.DeleteListenerResponse{
+		Status: removed,
+	}, nil
 }
 
 func (repo *Repository) GetContentFormats(ctx context.Context, req *models.GetContentFormatsRequest) (*models.GetContentFormatsResponse, tiny_errors.ErrorHandler) {
@@ -576,3 +1223,893 @@ func (repo *Repository) GetContentFormats(ctx context.Context, req *models.GetCo
 
 	return (*models.GetContentFormatsResponse)(&contentFormats), nil
 }
+
+func (repo *Repository) GetContentFormat(ctx context.Context, req *models.GetContentFormatRequest) (*models.GetContentFormatResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	ctx, span := repo.tracer.Start(ctx, "GetContentFormat", trace.WithAttributes(
+		attribute.String("format_id", req.FormatID),
+	))
+	defer span.End()
+
+	contentFormat, err := repo.contentFormats.Get(ctx, &content_formats.GetRequest{
+		ID: req.FormatID,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Get")
+		return nil, err
+	}
+
+	return (*models.GetContentFormatResponse)(contentFormat), nil
+}
+
+func (repo *Repository) CreateContentFormat(ctx context.Context, req *models.CreateContentFormatRequest) (*models.CreateContentFormatResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	ctx, span := repo.tracer.Start(ctx, "CreateContentFormat", trace.WithAttributes(
+		attribute.String("name", req.Name),
+	))
+	defer span.End()
+
+	contentFormat, err := repo.contentFormats.Create(ctx, &content_formats.CreateRequest{
+		Name:      req.Name,
+		MimeType:  req.MimeType,
+		Extension: req.Extension,
+		Validator: req.Validator,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Create")
+		return nil, err
+	}
+
+	return (*models.CreateContentFormatResponse)(contentFormat), nil
+}
+
+func (repo *Repository) EditContentFormat(ctx context.Context, req *models.EditContentFormatRequest) (*models.EditContentFormatResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	ctx, span := repo.tracer.Start(ctx, "EditContentFormat", trace.WithAttributes(
+		attribute.String("format_id", req.FormatID),
+	))
+	defer span.End()
+
+	contentFormat, err := repo.contentFormats.Edit(ctx, &content_formats.EditRequest{
+		ID:        req.FormatID,
+		Name:      req.Name,
+		MimeType:  req.MimeType,
+		Extension: req.Extension,
+		Validator: req.Validator,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Edit")
+		return nil, err
+	}
+
+	return (*models.EditContentFormatResponse)(contentFormat), nil
+}
+
+// DeleteContentFormat removes the format if it is not used by any file content.
+func (repo *Repository) DeleteContentFormat(ctx context.Context, req *models.DeleteContentFormatRequest) (*models.DeleteContentFormatResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	ctx, span := repo.tracer.Start(ctx, "DeleteContentFormat", trace.WithAttributes(
+		attribute.String("format_id", req.FormatID),
+	))
+	defer span.End()
+
+	removed, err := repo.contentFormats.Delete(ctx, &content_formats.DeleteRequest{
+		ID: req.FormatID,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Delete")
+		return nil, err
+	}
+
+	return &models.DeleteContentFormatResponse{
+		Status: removed,
+	}, nil
+}
+
+func (repo *Repository) GetTrash(ctx context.Context, req *models.GetTrashRequest) (*models.GetTrashResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	ctx, span := repo.tracer.Start(ctx, "GetTrash")
+	defer span.End()
+
+	limit, err := utils.ParseLimit(req.Limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "ParseLimit")
+		return nil, err
+	}
+
+	items, next, err := repo.trash.GetMany(ctx, &trash.GetManyRequest{
+		Filter: &utils.ListFilter{
+			Limit:      &limit,
+			Cursor:     req.Cursor,
+			NamePrefix: req.NamePrefix,
+		},
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetMany")
+		return nil, err
+	}
+
+	return &models.GetTrashResponse{
+		Items:      items,
+		NextCursor: next,
+	}, nil
+}
+
+// RestoreFolder restores a folder from the trash together with nested folders and files which were deleted with it.
+// Parent folder should not be in the trash and there should be no other folder with the same name.
+func (repo *Repository) RestoreFolder(ctx context.Context, req *models.RestoreFolderRequest) (*models.RestoreFolderResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	ctx, span := repo.tracer.Start(ctx, "RestoreFolder", trace.WithAttributes(
+		attribute.String("folder_id", req.FolderID),
+	))
+	defer span.End()
+
+	restored, err := repo.trash.RestoreFolder(ctx, &trash.RestoreRequest{
+		ID: req.FolderID,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "RestoreFolder")
+		return nil, err
+	}
+
+	return &models.RestoreFolderResponse{
+		Status: restored,
+	}, nil
+}
+
+// RestoreFile restores a file from the trash. Folder of the file should not be in the trash
+// and there should be no other file with the same name.
+func (repo *Repository) RestoreFile(ctx context.Context, req *models.RestoreFileRequest) (*models.RestoreFileResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	ctx, span := repo.tracer.Start(ctx, "RestoreFile", trace.WithAttributes(
+		attribute.String("file_id", req.FileID),
+	))
+	defer span.End()
+
+	restored, err := repo.trash.RestoreFile(ctx, &trash.RestoreRequest{
+		ID: req.FileID,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "RestoreFile")
+		return nil, err
+	}
+
+	return &models.RestoreFileResponse{
+		Status: restored,
+	}, nil
+}
+
+// Batch applies all operations in a single transaction. References between operations are resolved
+// in order, so a file created by one operation can get contents in the next one.
+//
+// Callbacks for files whose contents were changed are stored in the outbox in the same transaction,
+// so listeners are notified once per file and only after the whole batch is committed.
+func (repo *Repository) Batch(ctx context.Context, req *models.BatchRequest) (*models.BatchResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+	ctx, span := repo.tracer.Start(ctx, "Batch", trace.WithAttributes(
+		attribute.Int("operations", len(req.Operations)),
+	))
+	defer span.End()
+
+	var result *batch.ExecuteResponse
+	err := repo.tx.Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+		var err tiny_errors.ErrorHandler
+		result, err = repo.batch.Execute(ctx, &batch.ExecuteRequest{
+			Operations: req.Operations,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Execute")
+			return err
+		}
+
+		return repo.sendCallbacks(ctx, span, result.Events)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BatchResponse{
+		Results: result.Results,
+	}, nil
+}
+
+// ExportFolder packs the folder with all nested folders, files and their contents into an archive.
+// Directory layout of the archive mirrors the folders tree, formats and metadata are stored in the manifest.
+//
+// With kubernetes format every folder with files is rendered as a ConfigMap, files labeled as secrets are
+// rendered as a Secret. Names and namespaces are built from templates using path of the folder.
+//
+// With env format env files of the folder are merged into a single script of shell export statements.
+func (repo *Repository) ExportFolder(ctx context.Context, req *models.ExportFolderRequest) (*models.ExportFolderResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+	ctx, span := repo.tracer.Start(ctx, "ExportFolder", trace.WithAttributes(
+		attribute.String("folder_id", req.FolderID),
+		attribute.String("format", req.Format),
+	))
+	defer span.End()
+
+	if req.Format == kubernetes.FORMAT {
+		resp, err := repo.exportFolderKubernetes(ctx, req)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "ExportKubernetes")
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	if req.Format == envfile.FORMAT {
+		resp, err := repo.exportFolderEnv(ctx, req)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "ExportEnv")
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	format := archiveFormat(req.Format)
+	err := archive.ValidateFormat(format)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "ValidateFormat")
+		return nil, err
+	}
+
+	manifest, err := repo.archive.Export(ctx, &archive.ExportRequest{
+		FolderID: req.FolderID,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Export")
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeErr := archive.Write(&buf, format, manifest)
+	if writeErr != nil {
+		span.RecordError(writeErr)
+		span.SetStatus(codes.Error, "Write")
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Marshal, tiny_errors.Message(writeErr.Error()))
+	}
+
+	return &models.ExportFolderResponse{
+		FileName:    manifest.Name + "." + format,
+		ContentType: archive.ContentType(format),
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+func (repo *Repository) exportFolderKubernetes(ctx context.Context, req *models.ExportFolderRequest) (*models.ExportFolderResponse, tiny_errors.ErrorHandler) {
+	folder, err := repo.folders.Get(ctx, &folders.GetRequest{
+		ID: req.FolderID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := repo.archive.Export(ctx, &archive.ExportRequest{
+		FolderID: folder.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := kubernetes.GroupsFromManifest(manifest, folder.Path, req.Version)
+	data, err := renderKubernetes(groups, req.KubernetesParams)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ExportFolderResponse{
+		FileName:    folder.Name + ".yaml",
+		ContentType: kubernetes.CONTENT_TYPE,
+		Data:        data,
+	}, nil
+}
+
+// ExportFile renders a version of the file as a Kubernetes ConfigMap, or as a Secret if the file is labeled as a secret.
+// With env format variables of the file are rendered as shell export statements. With a name of another format handler
+// the content is converted into that format and values of keys matching req.Mask are masked.
+// The latest version is used if version is not provided.
+func (repo *Repository) ExportFile(ctx context.Context, req *models.ExportFileRequest) (*models.ExportFileResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+	ctx, span := repo.tracer.Start(ctx, "ExportFile", trace.WithAttributes(
+		attribute.String("file_id", req.FileID),
+		attribute.String("format", req.Format),
+	))
+	defer span.End()
+
+	convert := req.Format != "" && req.Format != kubernetes.FORMAT && req.Format != envfile.FORMAT
+	if _, ok := formats.Get(req.Format); convert && !ok {
+		err := tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("format", strings.Join(append([]string{kubernetes.FORMAT}, formats.Names()...), ", ")))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "ValidateFormat")
+		return nil, err
+	}
+	for _, pattern := range req.Mask {
+		if _, matchErr := path.Match(pattern, ""); matchErr != nil {
+			err := tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("mask", "should be a list of glob patterns"))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "ValidateMask")
+			return nil, err
+		}
+	}
+
+	file, err := repo.files.Get(ctx, &files.GetRequest{
+		ID: req.FileID,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetFile")
+		return nil, err
+	}
+
+	content, data, err := repo.fileVersion(ctx, file.ID, req.Version)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetFileContents")
+		return nil, err
+	}
+
+	if convert {
+		converted, err := repo.convertContent(ctx, content, data, req.Format, req.Mask)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Convert")
+			return nil, err
+		}
+
+		return &models.ExportFileResponse{
+			FileName:    file.Name + "." + req.Format,
+			ContentType: formats.CONTENT_TYPE,
+			Data:        []byte(converted),
+		}, nil
+	}
+
+	if req.Format == envfile.FORMAT {
+		isEnv, err := repo.isEnvContent(ctx, content)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "GetContentFormat")
+			return nil, err
+		}
+		if !isEnv {
+			err := tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("format of the file content is not "+envfile.FORMAT))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "ValidateContentFormat")
+			return nil, err
+		}
+
+		script, err := renderEnv([]*namedContent{{Name: file.Name, Data: data}})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "RenderEnv")
+			return nil, err
+		}
+
+		return &models.ExportFileResponse{
+			FileName:    file.Name + ".sh",
+			ContentType: envfile.CONTENT_TYPE,
+			Data:        script,
+		}, nil
+	}
+
+	folder, err := repo.folders.Get(ctx, &folders.GetRequest{
+		ID: folders.ResolveParentID(file.FolderID),
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "GetFolder")
+		return nil, err
+	}
+
+	manifests, err := renderKubernetes([]*kubernetes.Group{
+		{
+			Path: folder.Path,
+			Files: []*kubernetes.File{
+				{Name: file.Name, Data: data, Secret: kubernetes.IsSecret(file.Labels)},
+			},
+		},
+	}, req.KubernetesParams)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Render")
+		return nil, err
+	}
+
+	return &models.ExportFileResponse{
+		FileName:    file.Name + ".yaml",
+		ContentType: kubernetes.CONTENT_TYPE,
+		Data:        manifests,
+	}, nil
+}
+
+// exportFolderEnv merges env files of the folder without nested folders. Files are merged in order of req.Files,
+// or in order of names if files are not provided, so values of the latest file win. Without req.Files
+// files with other formats or without requested version are skipped.
+func (repo *Repository) exportFolderEnv(ctx context.Context, req *models.ExportFolderRequest) (*models.ExportFolderResponse, tiny_errors.ErrorHandler) {
+	folder, err := repo.folders.Get(ctx, &folders.GetRequest{
+		ID: req.FolderID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	folderFiles, err := repo.folderFiles(ctx, folder.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []*files.File
+	if len(req.Files) == 0 {
+		selected = folderFiles
+	} else {
+		byName := make(map[string]*files.File, len(folderFiles))
+		for _, file := range folderFiles {
+			byName[file.Name] = file
+		}
+		for _, name := range req.Files {
+			file, ok := byName[name]
+			if !ok {
+				return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("file does not exist"), tiny_errors.Detail("files", name), tiny_errors.HTTPStatus(http.StatusNotFound))
+			}
+			selected = append(selected, file)
+		}
+	}
+
+	var sources []*namedContent
+	for _, file := range selected {
+		content, data, err := repo.fileVersion(ctx, file.ID, req.Version)
+		if err != nil {
+			if len(req.Files) == 0 && err.GetCode() == custom_errors.ERR_CODE_NotFound {
+				continue
+			}
+			if err.GetCode() == custom_errors.ERR_CODE_NotFound {
+				err = tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message(err.GetMessage()), tiny_errors.Detail("files", file.Name), tiny_errors.HTTPStatus(http.StatusNotFound))
+			}
+			return nil, err
+		}
+
+		isEnv, err := repo.isEnvContent(ctx, content)
+		if err != nil {
+			return nil, err
+		}
+		if !isEnv {
+			if len(req.Files) == 0 {
+				continue
+			}
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("format of the file content is not "+envfile.FORMAT), tiny_errors.Detail("files", file.Name))
+		}
+		sources = append(sources, &namedContent{Name: file.Name, Data: data})
+	}
+
+	script, err := renderEnv(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ExportFolderResponse{
+		FileName:    folder.Name + ".sh",
+		ContentType: envfile.CONTENT_TYPE,
+		Data:        script,
+	}, nil
+}
+
+// convertContent converts data of the content from the format of its validator into the target format.
+// Values of keys matching any of mask patterns are replaced with formats.MASK.
+func (repo *Repository) convertContent(ctx context.Context, content *file_contents.FileContent, data string, target string, mask []string) (string, tiny_errors.ErrorHandler) {
+	contentFormat, err := repo.contentFormats.Get(ctx, &content_formats.GetRequest{
+		ContentID: content.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+	if contentFormat.Validator == nil || *contentFormat.Validator == "" {
+		return "", tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("format of the file content has no validator and can not be converted"))
+	}
+
+	converted, convertErr := formats.Convert(*contentFormat.Validator, target, data)
+	if convertErr == nil && len(mask) > 0 {
+		converted, convertErr = formats.Mask(target, converted, func(key string) bool {
+			return slices.ContainsFunc(mask, func(pattern string) bool {
+				ok, _ := path.Match(pattern, key)
+				return ok
+			})
+		})
+	}
+	if convertErr != nil {
+		return "", tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message(convertErr.Error()))
+	}
+	return converted, nil
+}
+
+// isEnvContent reports whether the format of the content is validated as env file. Formats are matched by
+// the validator, so formats with any name can be exported as env files.
+func (repo *Repository) isEnvContent(ctx context.Context, content *file_contents.FileContent) (bool, tiny_errors.ErrorHandler) {
+	contentFormat, err := repo.contentFormats.Get(ctx, &content_formats.GetRequest{
+		ContentID: content.ID,
+	})
+	if err != nil && err.GetCode() == custom_errors.ERR_CODE_NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return contentFormat.Validator != nil && *contentFormat.Validator == envfile.FORMAT, nil
+}
+
+// folderFiles returns all files of the folder ordered by name.
+func (repo *Repository) folderFiles(ctx context.Context, folderID string) ([]*files.File, tiny_errors.ErrorHandler) {
+	var result []*files.File
+	filter := &utils.ListFilter{
+		Limit: utils.MakePointer(utils.MAX_LIMIT),
+	}
+	for {
+		page, next, err := repo.files.GetMany(ctx, &files.GetManyRequest{
+			FolderID: &folderID,
+			Order: &files.Order{
+				Column: utils.MakePointer("name"),
+				Type:   utils.MakePointer(utils.ORDER_ASC),
+			},
+			Filter: filter,
+		})
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, page...)
+		if next == nil {
+			return result, nil
+		}
+		filter.Cursor = next
+	}
+}
+
+// fileVersion returns the content of the file with provided version or the latest content, and its decoded data.
+func (repo *Repository) fileVersion(ctx context.Context, fileID string, version *string) (*file_contents.FileContent, string, tiny_errors.ErrorHandler) {
+	contents, _, err := repo.fileContent.GetMany(ctx, &file_contents.GetManyRequest{
+		FileID:  fileID,
+		Version: version,
+		Order: &file_contents.Order{
+			Column: utils.MakePointer("created_at"),
+			Type:   utils.MakePointer(utils.ORDER_DESC),
+		},
+		Filter: &utils.ListFilter{
+			Limit: utils.MakePointer(1),
+		},
+		LoadBlobs: true,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(contents) == 0 {
+		return nil, "", tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("file content does not exist"), tiny_errors.HTTPStatus(http.StatusNotFound))
+	}
+
+	data, decodeErr := utils.Base64ToString(contents[0].Content)
+	if decodeErr != nil {
+		return nil, "", tiny_errors.New(custom_errors.ERR_CODE_Marshal, tiny_errors.Message(decodeErr.Error()))
+	}
+	return contents[0], data, nil
+}
+
+// ImportFolder recreates folders tree from the archive inside the folder. Existing folders, files and versions
+// with the same names are reused, so the same archive can be imported several times.
+//
+// With DryRun nothing is changed and the response shows what would be created or updated.
+func (repo *Repository) ImportFolder(ctx context.Context, req *models.ImportFolderRequest) (*models.ImportFolderResponse, tiny_errors.ErrorHandler) {
+	if req == nil || len(req.Archive) == 0 {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "folder_id", req.FolderID, "format", req.Format, "dry_run", req.DryRun)
+	ctx, span := repo.tracer.Start(ctx, "ImportFolder", trace.WithAttributes(
+		attribute.String("folder_id", req.FolderID),
+		attribute.String("format", req.Format),
+		attribute.Bool("dry_run", req.DryRun),
+	))
+	defer span.End()
+
+	manifest, err := archive.Read(req.Archive, archiveFormat(req.Format))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Read")
+		return nil, err
+	}
+
+	importReq := &archive.ImportRequest{
+		FolderID: req.FolderID,
+		Manifest: manifest,
+		DryRun:   req.DryRun,
+	}
+
+	var result *archive.ImportResponse
+	if req.DryRun {
+		result, err = repo.archive.Import(ctx, importReq)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Import")
+			return nil, err
+		}
+	} else {
+		err = repo.tx.Run(ctx, func(ctx context.Context) tiny_errors.ErrorHandler {
+			var err tiny_errors.ErrorHandler
+			result, err = repo.archive.Import(ctx, importReq)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, "Import")
+				return err
+			}
+
+			return repo.sendCallbacks(ctx, span, result.Events)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.ImportFolderResponse{
+		DryRun:    req.DryRun,
+		Changes:   result.Changes,
+		Unchanged: result.Unchanged,
+	}, nil
+}
+
+// SyncGit imports files of the configured git repository. Ref of the request overrides configured ref,
+// e.g. to sync a specific tag. Listeners of changed files are called by the syncer.
+func (repo *Repository) SyncGit(ctx context.Context, req *models.SyncGitRequest) (*models.SyncGitResponse, tiny_errors.ErrorHandler) {
+	repo.log.WithRequestId(ctx).InfoContext(ctx, TracerName, "data", req)
+	if req == nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_BodyRequired)
+	}
+	ctx, span := repo.tracer.Start(ctx, "SyncGit", trace.WithAttributes(
+		attribute.Bool("dry_run", req.DryRun),
+	))
+	defer span.End()
+
+	if repo.gitSync == nil {
+		err := tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message("git sync is not configured"))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "NotConfigured")
+		return nil, err
+	}
+
+	result, err := repo.gitSync.Sync(ctx, &gitsync.SyncRequest{
+		Ref:    req.Ref,
+		DryRun: req.DryRun,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Sync")
+		return nil, err
+	}
+
+	return &models.SyncGitResponse{
+		DryRun:    req.DryRun,
+		Commit:    result.Commit,
+		Version:   result.Version,
+		Changes:   result.Changes,
+		Unchanged: result.Unchanged,
+		Skipped:   result.Skipped,
+	}, nil
+}
+
+// sendCallbacks stores events of batches and imports in the outbox.
+func (repo *Repository) sendCallbacks(ctx context.Context, span trace.Span, events []*callback.CallbackRequest) tiny_errors.ErrorHandler {
+	for _, event := range events {
+		if err := repo.sendEvent(ctx, span, event.FileID, event.Event, event.Details); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendEvent stores the event of the file in the outbox, it is sent to listeners subscribed to the event.
+func (repo *Repository) sendEvent(ctx context.Context, span trace.Span, fileID string, event string, details *callback.EventDetails) tiny_errors.ErrorHandler {
+	err := repo.callback.Send(ctx, &callback.CallbackRequest{
+		FileID:  fileID,
+		Event:   event,
+		Details: details,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "SendCallback")
+		return err
+	}
+	return nil
+}
+
+// contentDetails describe events of the content with its ID and version.
+func contentDetails(fileContent *file_contents.FileContent) *callback.EventDetails {
+	return &callback.EventDetails{
+		ContentID: &fileContent.ID,
+		Version:   &fileContent.Version,
+	}
+}
+
+// contentChanges returns changed values between two contents of the format, contents which are nil are empty.
+// Returns nil if the format has no handler or contents can not be decoded.
+func contentChanges(contentFormat *content_formats.ContentFormat, oldData, newData *string) []*formats.Change {
+	if contentFormat == nil {
+		return nil
+	}
+	handler, ok := contentFormat.Handler()
+	if !ok {
+		return nil
+	}
+
+	trees := make([]map[string]any, 2)
+	for i, data := range []*string{oldData, newData} {
+		trees[i] = map[string]any{}
+		if data == nil {
+			continue
+		}
+		tree, err := handler.Decode(*data)
+		if err != nil {
+			return nil
+		}
+		trees[i] = tree
+	}
+	return formats.DiffTrees(trees[0], trees[1])
+}
+
+// fileListener returns the listener if it belongs to the file which is not in the trash.
+func (repo *Repository) fileListener(ctx context.Context, fileID, listenerID string) (*listeners.Listener, tiny_errors.ErrorHandler) {
+	listener, err := repo.activeListener(ctx, listenerID)
+	if err != nil {
+		return nil, err
+	}
+	if listener.FileID != fileID {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotFound, tiny_errors.Message("listener does not exist"), tiny_errors.HTTPStatus(http.StatusNotFound))
+	}
+	return listener, nil
+}
+
+// activeListener returns the listener if its file is not in the trash. Listeners of files in the trash are kept
+// to receive file.deleted events, but they can not be read or changed until the file is restored.
+func (repo *Repository) activeListener(ctx context.Context, listenerID string) (*listeners.Listener, tiny_errors.ErrorHandler) {
+	listener, err := repo.listeners.Get(ctx, &listeners.GetRequest{
+		ID: listenerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = repo.files.Get(ctx, &files.GetRequest{
+		ID: listener.FileID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return listener, nil
+}
+
+// listFilter converts list query parameters into a filter used by clients. Limit is always set,
+// so public endpoints never return unbounded lists.
+func listFilter(params models.ListParams, cursor *string) (*utils.ListFilter, tiny_errors.ErrorHandler) {
+	limit, err := utils.ParseLimit(params.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &utils.ListFilter{
+		Limit:         &limit,
+		Cursor:        cursor,
+		NamePrefix:    params.NamePrefix,
+		CreatedAfter:  params.CreatedAfter,
+		CreatedBefore: params.CreatedBefore,
+		UpdatedAfter:  params.UpdatedAfter,
+		UpdatedBefore: params.UpdatedBefore,
+	}
+
+	if err := utils.ValidateTimeFilters(filter); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// includeParam parses comma separated names of optional data. Only allowed names can be included.
+func includeParam(include *string, allowed ...string) (map[string]bool, tiny_errors.ErrorHandler) {
+	result := make(map[string]bool)
+	if include == nil {
+		return result, nil
+	}
+
+	for _, name := range strings.Split(*include, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(allowed, name) {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("include", "should be one of "+strings.Join(allowed, ", ")))
+		}
+		result[name] = true
+	}
+	return result, nil
+}
+
+// editName clears provided name. Nil name means that name should not be changed.
+func editName(name *string) (*string, tiny_errors.ErrorHandler) {
+	if name == nil {
+		return nil, nil
+	}
+
+	clearName, err := utils.ClearName(*name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clearName, nil
+}
+
+// archiveFormat returns tar.gz if format is not provided.
+func archiveFormat(format string) string {
+	if format == "" {
+		return archive.FORMAT_TAR_GZ
+	}
+	return format
+}
+
+// renderKubernetes renders groups into manifests. Errors of templates are returned as not valid params.
+func renderKubernetes(groups []*kubernetes.Group, params models.KubernetesParams) ([]byte, tiny_errors.ErrorHandler) {
+	var buf bytes.Buffer
+	err := kubernetes.Render(&buf, groups, &kubernetes.Options{
+		NamespaceTemplate: params.NamespaceTemplate,
+		NameTemplate:      params.NameTemplate,
+	})
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message(err.Error()))
+	}
+	return buf.Bytes(), nil
+}
+
+type namedContent struct {
+	Name string
+	Data string
+}
+
+// renderEnv parses env files and writes merged variables as shell export statements.
+// Values of the latest source win.
+func renderEnv(sources []*namedContent) ([]byte, tiny_errors.ErrorHandler) {
+	variables := make([][]*envfile.Variable, 0, len(sources))
+	for _, source := range sources {
+		parsed, err := envfile.Parse(source.Data)
+		if err != nil {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message(err.Error()), tiny_errors.Detail("files", source.Name))
+		}
+		variables = append(variables, parsed)
+	}
+
+	var buf bytes.Buffer
+	err := envfile.Write(&buf, envfile.Merge(variables...))
+	if err != nil {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_Marshal, tiny_errors.Message(err.Error()))
+	}
+	return buf.Bytes(), nil
+}
+
+// validateContent checks content with the validator of the format.
+func validateContent(contentFormat *content_formats.ContentFormat, content string) tiny_errors.ErrorHandler {
+	if err := contentFormat.Validate(content); err != nil {
+		return tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Message(err.Error()), tiny_errors.Detail("content", "not valid "+contentFormat.Name))
+	}
+	return nil
+}
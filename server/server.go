@@ -11,17 +11,25 @@ import (
 	"github.com/Moranilt/config-keeper/custom_errors"
 	"github.com/Moranilt/config-keeper/endpoints"
 	"github.com/Moranilt/config-keeper/middleware"
+	"github.com/Moranilt/config-keeper/pkg/archive"
+	"github.com/Moranilt/config-keeper/pkg/batch"
+	"github.com/Moranilt/config-keeper/pkg/blobs"
 	"github.com/Moranilt/config-keeper/pkg/callback"
 	"github.com/Moranilt/config-keeper/pkg/content_formats"
+	"github.com/Moranilt/config-keeper/pkg/dead_letters"
+	"github.com/Moranilt/config-keeper/pkg/deliveries"
+	"github.com/Moranilt/config-keeper/pkg/encryption"
 	"github.com/Moranilt/config-keeper/pkg/file_contents"
 	"github.com/Moranilt/config-keeper/pkg/files"
 	"github.com/Moranilt/config-keeper/pkg/folders"
+	"github.com/Moranilt/config-keeper/pkg/gitsync"
 	"github.com/Moranilt/config-keeper/pkg/listeners"
+	"github.com/Moranilt/config-keeper/pkg/transaction"
+	"github.com/Moranilt/config-keeper/pkg/trash"
 	"github.com/Moranilt/config-keeper/repository"
 	"github.com/Moranilt/config-keeper/service"
 	"github.com/Moranilt/config-keeper/tracer"
 	"github.com/Moranilt/config-keeper/transport"
-	"github.com/Moranilt/http-utils/client"
 	"github.com/Moranilt/http-utils/clients/database"
 	"github.com/Moranilt/http-utils/logger"
 	"github.com/Moranilt/http-utils/tiny_errors"
@@ -33,8 +41,6 @@ import (
 
 const (
 	DB_DRIVER_NAME = "postgres"
-
-	CALLBACK_CAPACITY = 10
 )
 
 // Run is the main entry point for the application. It sets up the necessary
@@ -69,28 +75,71 @@ func Run(ctx context.Context) {
 		log.Fatalf("migration: %v", err)
 	}
 
+	blobStorage := blobs.New(&blobs.Config{
+		Dir:          cfg.Content.BlobDir,
+		MaxSize:      cfg.Content.MaxSize,
+		TotalMaxSize: cfg.Content.TotalMaxSize,
+		InlineSize:   cfg.Content.InlineSize,
+	})
+
+	var listenersCipher *encryption.Cipher
+	if len(cfg.ListenersEncryptionKey) > 0 {
+		listenersCipher, err = encryption.New(cfg.ListenersEncryptionKey)
+		if err != nil {
+			log.Fatalf("listeners encryption: %v", err)
+		}
+	}
+
 	foldersClient := folders.New(db)
 	filesClient := files.New(db)
-	fileContentClient := file_contents.New(db)
-	listenersClient := listeners.New(db)
+	fileContentClient := file_contents.New(db, blobStorage)
+	listenersClient := listeners.New(db, listenersCipher, cfg.Listeners)
+	deliveriesClient := deliveries.New(db)
+	deadLettersClient := dead_letters.New(db)
 	contentFormatsCLient := content_formats.New(db)
+	trashClient := trash.New(db)
+	batchClient := batch.New(db, blobStorage)
+	archiveClient := archive.New(db, blobStorage)
+
+	callbackOutbox := callback.NewOutbox(db)
+
+	var gitSyncer gitsync.Syncer
+	if cfg.GitSync.RepoPath != "" {
+		gitSyncer = gitsync.NewSyncer(log, transaction.New(db), archiveClient, contentFormatsCLient, callbackOutbox, &gitsync.Config{
+			RepoPath: cfg.GitSync.RepoPath,
+			Ref:      cfg.GitSync.Ref,
+			Dir:      cfg.GitSync.Dir,
+			FolderID: cfg.GitSync.FolderID,
+			Interval: cfg.GitSync.Interval,
+		})
+	}
 
-	callbackChannel := callback.NewChannel(CALLBACK_CAPACITY)
+	httpClient := callback.NewHTTPClient(60 * time.Second)
+	requestsController := callback.NewRequestsController(log, httpClient, deliveriesClient)
 
-	repo := repository.New(db, callbackChannel, foldersClient, filesClient, fileContentClient, listenersClient, contentFormatsCLient, log)
+	repo := repository.New(db, callbackOutbox, requestsController, foldersClient, filesClient, fileContentClient, listenersClient, deliveriesClient, deadLettersClient, contentFormatsCLient, trashClient, batchClient, archiveClient, gitSyncer, log)
 	svc := service.New(log, repo)
 	mw := middleware.New(log)
 	ep := endpoints.MakeEndpoints(svc, mw)
 	health := endpoints.MakeHealth(db)
 	ep = append(ep, health)
-	server := transport.New(fmt.Sprintf(":%s", cfg.Port), ep, mw)
+	server := transport.New(fmt.Sprintf(":%s", cfg.Port), ep, mw, cfg.MaxBodySize)
 
-	httpClient := client.New()
-	client.SetTimeout(60 * time.Second)
-	requestsController := callback.NewRequestsController(log, httpClient)
-	callbackService := callback.New(log, callbackChannel, filesClient, listenersClient, fileContentClient, requestsController)
+	callbackService := callback.New(log, callbackOutbox, filesClient, listenersClient, fileContentClient, requestsController, cfg.PublicURL)
 	go callbackService.Run(ctx)
 
+	trashPurger := trash.NewPurger(log, trashClient, cfg.Trash.Retention, cfg.Trash.PurgeInterval)
+	go trashPurger.Run(ctx)
+
+	if cfg.Content.BlobDir != "" {
+		blobSweeper := blobs.NewSweeper(log, blobStorage, db, cfg.Content.SweepInterval)
+		go blobSweeper.Run(ctx)
+	}
+
+	if gitSyncer != nil {
+		go gitSyncer.Run(ctx)
+	}
+
 	g, gCtx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
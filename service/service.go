@@ -1,11 +1,22 @@
 package service
 
 import (
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/config-keeper/models"
+	"github.com/Moranilt/config-keeper/pkg/archive"
 	"github.com/Moranilt/config-keeper/repository"
+	"github.com/Moranilt/config-keeper/utils"
 	"github.com/Moranilt/http-utils/handler"
 	"github.com/Moranilt/http-utils/logger"
+	"github.com/Moranilt/http-utils/response"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/gorilla/mux"
 )
 
 type FolderService interface {
@@ -13,6 +24,8 @@ type FolderService interface {
 	GetFolder(w http.ResponseWriter, r *http.Request)
 	DeleteFolder(w http.ResponseWriter, r *http.Request)
 	EditFolder(w http.ResponseWriter, r *http.Request)
+	ExportFolder(w http.ResponseWriter, r *http.Request)
+	ImportFolder(w http.ResponseWriter, r *http.Request)
 }
 
 type FileService interface {
@@ -20,6 +33,7 @@ type FileService interface {
 	DeleteFile(w http.ResponseWriter, r *http.Request)
 	EditFile(w http.ResponseWriter, r *http.Request)
 	GetFile(w http.ResponseWriter, r *http.Request)
+	ExportFile(w http.ResponseWriter, r *http.Request)
 }
 
 type FileContentServices interface {
@@ -33,12 +47,38 @@ type ListenersService interface {
 	CreateListener(w http.ResponseWriter, r *http.Request)
 	GetListener(w http.ResponseWriter, r *http.Request)
 	GetFileListeners(w http.ResponseWriter, r *http.Request)
+	GetListenerDeliveries(w http.ResponseWriter, r *http.Request)
+	RotateListenerSecret(w http.ResponseWriter, r *http.Request)
+	EnableListener(w http.ResponseWriter, r *http.Request)
+	DisableListener(w http.ResponseWriter, r *http.Request)
+	RedeliverListener(w http.ResponseWriter, r *http.Request)
+	GetListenerDeadLetters(w http.ResponseWriter, r *http.Request)
+	ReplayListenerDeadLetters(w http.ResponseWriter, r *http.Request)
+	PingListener(w http.ResponseWriter, r *http.Request)
 	EditListener(w http.ResponseWriter, r *http.Request)
 	DeleteListener(w http.ResponseWriter, r *http.Request)
 }
 
 type ContentFormatsService interface {
 	GetContentFormats(w http.ResponseWriter, r *http.Request)
+	GetContentFormat(w http.ResponseWriter, r *http.Request)
+	CreateContentFormat(w http.ResponseWriter, r *http.Request)
+	EditContentFormat(w http.ResponseWriter, r *http.Request)
+	DeleteContentFormat(w http.ResponseWriter, r *http.Request)
+}
+
+type TrashService interface {
+	GetTrash(w http.ResponseWriter, r *http.Request)
+	RestoreFolder(w http.ResponseWriter, r *http.Request)
+	RestoreFile(w http.ResponseWriter, r *http.Request)
+}
+
+type BatchService interface {
+	Batch(w http.ResponseWriter, r *http.Request)
+}
+
+type SyncService interface {
+	SyncGit(w http.ResponseWriter, r *http.Request)
 }
 
 type Service interface {
@@ -47,6 +87,9 @@ type Service interface {
 	FileContentServices
 	ListenersService
 	ContentFormatsService
+	TrashService
+	BatchService
+	SyncService
 }
 
 type service struct {
@@ -116,6 +159,7 @@ func (s *service) CreateFileContent(w http.ResponseWriter, r *http.Request) {
 func (s *service) GetFile(w http.ResponseWriter, r *http.Request) {
 	handler.New(w, r, s.log, s.repo.GetFile).
 		WithVars().
+		WithQuery().
 		Run(http.StatusOK)
 }
 
@@ -154,6 +198,61 @@ func (s *service) GetListener(w http.ResponseWriter, r *http.Request) {
 
 func (s *service) GetFileListeners(w http.ResponseWriter, r *http.Request) {
 	handler.New(w, r, s.log, s.repo.GetFileListeners).
+		WithVars().
+		WithQuery().
+		Run(http.StatusOK)
+}
+
+func (s *service) GetListenerDeliveries(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.GetListenerDeliveries).
+		WithVars().
+		WithQuery().
+		Run(http.StatusOK)
+}
+
+func (s *service) RotateListenerSecret(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.RotateListenerSecret).
+		WithVars().
+		WithJSON().
+		Run(http.StatusOK)
+}
+
+func (s *service) EnableListener(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.EnableListener).
+		WithVars().
+		Run(http.StatusOK)
+}
+
+func (s *service) DisableListener(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.DisableListener).
+		WithVars().
+		WithJSON().
+		Run(http.StatusOK)
+}
+
+func (s *service) RedeliverListener(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.RedeliverListener).
+		WithVars().
+		WithJSON().
+		Run(http.StatusAccepted)
+}
+
+func (s *service) GetListenerDeadLetters(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.GetListenerDeadLetters).
+		WithVars().
+		WithQuery().
+		Run(http.StatusOK)
+}
+
+func (s *service) ReplayListenerDeadLetters(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.ReplayListenerDeadLetters).
+		WithVars().
+		WithJSON().
+		Run(http.StatusAccepted)
+}
+
+func (s *service) PingListener(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.PingListener).
 		WithVars().
 		Run(http.StatusOK)
 }
@@ -175,3 +274,179 @@ func (s *service) GetContentFormats(w http.ResponseWriter, r *http.Request) {
 	handler.New(w, r, s.log, s.repo.GetContentFormats).
 		Run(http.StatusOK)
 }
+
+func (s *service) GetContentFormat(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.GetContentFormat).
+		WithVars().
+		Run(http.StatusOK)
+}
+
+func (s *service) CreateContentFormat(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.CreateContentFormat).
+		WithJSON().
+		Run(http.StatusCreated)
+}
+
+func (s *service) EditContentFormat(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.EditContentFormat).
+		WithVars().
+		WithJSON().
+		Run(http.StatusOK)
+}
+
+func (s *service) DeleteContentFormat(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.DeleteContentFormat).
+		WithVars().
+		Run(http.StatusOK)
+}
+
+func (s *service) GetTrash(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.GetTrash).
+		WithQuery().
+		Run(http.StatusOK)
+}
+
+func (s *service) RestoreFolder(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.RestoreFolder).
+		WithVars().
+		Run(http.StatusOK)
+}
+
+func (s *service) RestoreFile(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.RestoreFile).
+		WithVars().
+		Run(http.StatusOK)
+}
+
+func (s *service) Batch(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.Batch).
+		WithJSON().
+		Run(http.StatusOK)
+}
+
+func (s *service) SyncGit(w http.ResponseWriter, r *http.Request) {
+	handler.New(w, r, s.log, s.repo.SyncGit).
+		WithJSON().
+		Run(http.StatusOK)
+}
+
+// ExportFolder responds with the archive itself instead of JSON, so it is not using handler.
+func (s *service) ExportFolder(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.repo.ExportFolder(r.Context(), &models.ExportFolderRequest{
+		FolderID:         mux.Vars(r)["folder_id"],
+		Format:           r.URL.Query().Get("format"),
+		Version:          versionParam(r),
+		Files:            listParam(r, "files"),
+		KubernetesParams: kubernetesParams(r),
+	})
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	attachmentResponse(w, resp.FileName, resp.ContentType, resp.Data)
+}
+
+// ExportFile responds with the manifest itself instead of JSON, so it is not using handler.
+func (s *service) ExportFile(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.repo.ExportFile(r.Context(), &models.ExportFileRequest{
+		FileID:           mux.Vars(r)["file_id"],
+		Format:           r.URL.Query().Get("format"),
+		Version:          versionParam(r),
+		Mask:             listParam(r, "mask"),
+		KubernetesParams: kubernetesParams(r),
+	})
+	if err != nil {
+		errorResponse(w, err)
+		return
+	}
+
+	attachmentResponse(w, resp.FileName, resp.ContentType, resp.Data)
+}
+
+// ImportFolder reads the archive from the raw request body, so it is not using handler.
+func (s *service) ImportFolder(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := &models.ImportFolderRequest{
+		FolderID: mux.Vars(r)["folder_id"],
+		Format:   query.Get("format"),
+	}
+
+	if value := query.Get("dry_run"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			errorResponse(w, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("dry_run", "should be true or false")))
+			return
+		}
+		req.DryRun = dryRun
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, archive.MAX_ARCHIVE_SIZE))
+	if err != nil {
+		response.ErrorResponse(w, err, utils.BodyErrorStatus(err))
+		return
+	}
+	req.Archive = data
+
+	resp, tErr := s.repo.ImportFolder(r.Context(), req)
+	if tErr != nil {
+		errorResponse(w, tErr)
+		return
+	}
+
+	response.Default(w, resp, nil, http.StatusOK)
+}
+
+// errorResponse writes error for handlers which are not using handler package.
+func errorResponse(w http.ResponseWriter, err tiny_errors.ErrorHandler) {
+	status := http.StatusBadRequest
+	switch err.GetCode() {
+	case custom_errors.ERR_CODE_NotFound:
+		status = http.StatusNotFound
+	case custom_errors.ERR_CODE_TooLarge:
+		status = http.StatusRequestEntityTooLarge
+	case custom_errors.ERR_CODE_Exists:
+		status = http.StatusConflict
+	case custom_errors.ERR_CODE_Database, custom_errors.ERR_CODE_Marshal, custom_errors.ERR_CODE_Internal:
+		status = http.StatusInternalServerError
+	}
+	response.ErrorResponse(w, err, status)
+}
+
+func kubernetesParams(r *http.Request) models.KubernetesParams {
+	query := r.URL.Query()
+	return models.KubernetesParams{
+		NamespaceTemplate: query.Get("namespace_template"),
+		NameTemplate:      query.Get("name_template"),
+	}
+}
+
+func versionParam(r *http.Request) *string {
+	query := r.URL.Query()
+	if !query.Has("version") {
+		return nil
+	}
+	version := query.Get("version")
+	return &version
+}
+
+// listParam reads comma separated values of the parameter, the parameter can also be repeated.
+func listParam(r *http.Request, name string) []string {
+	var values []string
+	for _, value := range r.URL.Query()[name] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+func attachmentResponse(w http.ResponseWriter, fileName, contentType string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
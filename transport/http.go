@@ -1,17 +1,23 @@
 package transport
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"net/http"
 	"time"
 
 	"github.com/Moranilt/config-keeper/endpoints"
 	"github.com/Moranilt/config-keeper/middleware"
+	"github.com/Moranilt/config-keeper/utils"
+	"github.com/Moranilt/http-utils/response"
 	"github.com/gorilla/mux"
 )
 
-func New(addr string, endpoints []endpoints.Endpoint, mw *middleware.Middleware) *http.Server {
+// New creates a server with all endpoints. Request bodies larger than maxBodySize are rejected.
+func New(addr string, endpoints []endpoints.Endpoint, mw *middleware.Middleware, maxBodySize int64) *http.Server {
 	router := mux.NewRouter()
-	router.Use(mw.Default, mw.Otel)
+	router.Use(mw.Default, mw.Otel, mw.Fields, limitBody(maxBodySize))
 
 	for _, endpoint := range endpoints {
 		handler := applyMiddleware(endpoint.HandleFunc, endpoint.Middleware)
@@ -33,3 +39,27 @@ func applyMiddleware(handler http.Handler, mws []middleware.EndpointMiddlewareFu
 	}
 	return handler
 }
+
+// limitBody responds with 413 if the body is larger than maxSize. Bodies without declared length are read
+// before handlers, so the limit is not reported by handlers as an error of decoding.
+func limitBody(maxSize int64) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxSize {
+				response.ErrorResponse(w, fmt.Errorf("request body is larger than %d bytes", maxSize), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+			if r.ContentLength < 0 {
+				data, err := io.ReadAll(r.Body)
+				if err != nil {
+					response.ErrorResponse(w, err, utils.BodyErrorStatus(err))
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(data))
+				r.ContentLength = int64(len(data))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitBody(t *testing.T) {
+	var received map[string]string
+	handler := limitBody(32)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		body           string
+		chunked        bool
+		expectedStatus int
+	}{
+		{name: "declared length within limit", body: `{"key":"value"}`, expectedStatus: http.StatusOK},
+		{name: "declared length over limit", body: `{"key":"` + strings.Repeat("a", 64) + `"}`, expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "chunked body within limit", body: `{"key":"value"}`, chunked: true, expectedStatus: http.StatusOK},
+		{name: "chunked body over limit", body: `{"key":"` + strings.Repeat("a", 64) + `"}`, chunked: true, expectedStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = bytes.NewBufferString(tt.body)
+			if tt.chunked {
+				// readers without known length are sent without Content-Length
+				body = io.MultiReader(body)
+			}
+			r := httptest.NewRequest(http.MethodPost, "/batch", body)
+			if tt.chunked {
+				r.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+)
+
+// BodyErrorStatus returns HTTP status of an error of reading the request body. Only bodies which are larger
+// than the limit of http.MaxBytesReader are 413, other errors like disconnects and timeouts are 400.
+func BodyErrorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
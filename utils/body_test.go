@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyErrorStatus(t *testing.T) {
+	assert.Equal(t, http.StatusRequestEntityTooLarge, BodyErrorStatus(&http.MaxBytesError{Limit: 10}))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, BodyErrorStatus(fmt.Errorf("read: %w", &http.MaxBytesError{Limit: 10})))
+	assert.Equal(t, http.StatusBadRequest, BodyErrorStatus(errors.New("unexpected EOF")))
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/lib/pq"
+)
+
+// PG_UNIQUE_VIOLATION is a code of Postgres errors returned when a unique index is violated.
+const PG_UNIQUE_VIOLATION pq.ErrorCode = "23505"
+
+// ExistsError returns ERR_CODE_Exists with HTTP status 409.
+func ExistsError(message string) tiny_errors.ErrorHandler {
+	return tiny_errors.New(custom_errors.ERR_CODE_Exists, tiny_errors.Message(message), tiny_errors.HTTPStatus(http.StatusConflict))
+}
+
+// DatabaseError converts an error of the query. Violations of unique indexes are returned as ExistsError
+// with existsMessage, so concurrent requests which passed existence checks get the same error.
+// Other errors are returned as ERR_CODE_Database.
+func DatabaseError(err error, existsMessage string) tiny_errors.ErrorHandler {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == PG_UNIQUE_VIOLATION {
+		return ExistsError(existsMessage)
+	}
+	return tiny_errors.New(custom_errors.ERR_CODE_Database, tiny_errors.Message(err.Error()))
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseError(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+
+	tests := []struct {
+		name            string
+		err             error
+		expectedCode    int
+		expectedMessage string
+	}{
+		{
+			name:            "unique violation",
+			err:             &pq.Error{Code: PG_UNIQUE_VIOLATION, Constraint: "idx_files_folder_id_name"},
+			expectedCode:    custom_errors.ERR_CODE_Exists,
+			expectedMessage: "file already exists",
+		},
+		{
+			name:            "wrapped unique violation",
+			err:             fmt.Errorf("insert: %w", &pq.Error{Code: PG_UNIQUE_VIOLATION}),
+			expectedCode:    custom_errors.ERR_CODE_Exists,
+			expectedMessage: "file already exists",
+		},
+		{
+			name:            "other postgres error",
+			err:             &pq.Error{Code: "23503", Message: "foreign key violation"},
+			expectedCode:    custom_errors.ERR_CODE_Database,
+			expectedMessage: "pq: foreign key violation",
+		},
+		{
+			name:            "other error",
+			err:             errors.New("connection refused"),
+			expectedCode:    custom_errors.ERR_CODE_Database,
+			expectedMessage: "connection refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DatabaseError(tt.err, "file already exists")
+			assert.Equal(t, tt.expectedCode, err.GetCode())
+			assert.Equal(t, tt.expectedMessage, err.GetMessage())
+		})
+	}
+}
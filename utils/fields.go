@@ -0,0 +1,56 @@
+package utils
+
+import "strings"
+
+// ParseFields splits comma separated field paths. Empty paths are skipped.
+func ParseFields(value string) []string {
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// SelectFields keeps only selected fields of decoded JSON value. Nested fields are separated with dots,
+// e.g. `contents.version` keeps only versions of contents. Fields of lists are applied to every item.
+// A field without nested fields keeps the whole value, unknown fields are ignored.
+func SelectFields(value any, fields []string) any {
+	if len(fields) == 0 {
+		return value
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		nested := make(map[string][]string)
+		keep := make(map[string]bool)
+		for _, field := range fields {
+			name, rest, found := strings.Cut(field, ".")
+			if !found {
+				keep[name] = true
+				continue
+			}
+			nested[name] = append(nested[name], rest)
+		}
+
+		result := make(map[string]any)
+		for name, item := range value {
+			switch {
+			case keep[name]:
+				result[name] = item
+			case nested[name] != nil:
+				result[name] = SelectFields(item, nested[name])
+			}
+		}
+		return result
+	case []any:
+		result := make([]any, len(value))
+		for i, item := range value {
+			result[i] = SelectFields(item, fields)
+		}
+		return result
+	default:
+		return value
+	}
+}
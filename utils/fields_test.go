@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectFields(t *testing.T) {
+	value := map[string]any{
+		"id":   "file_id",
+		"name": "config.yaml",
+		"labels": map[string]any{
+			"team": "core",
+		},
+		"contents": []any{
+			map[string]any{"id": "content_1", "version": "v1", "size": 10},
+			map[string]any{"id": "content_2", "version": "v2", "size": 20},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		fields   string
+		expected any
+	}{
+		{
+			name:     "without fields",
+			fields:   " , ",
+			expected: value,
+		},
+		{
+			name:   "top level and nested fields",
+			fields: "id, labels, contents.version, unknown",
+			expected: map[string]any{
+				"id":     "file_id",
+				"labels": map[string]any{"team": "core"},
+				"contents": []any{
+					map[string]any{"version": "v1"},
+					map[string]any{"version": "v2"},
+				},
+			},
+		},
+		{
+			name:   "nested field of an object",
+			fields: "labels.team,labels.owner",
+			expected: map[string]any{
+				"labels": map[string]any{"team": "core"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SelectFields(value, ParseFields(tt.fields)))
+		})
+	}
+}
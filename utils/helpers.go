@@ -64,3 +64,11 @@ func isEmptyValue(v any) bool {
 func StringToBase64(str string) string {
 	return base64.StdEncoding.EncodeToString([]byte(str))
 }
+
+func Base64ToString(str string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
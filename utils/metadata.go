@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/http-utils/tiny_errors"
+)
+
+const (
+	MAX_LABELS             = 64
+	MAX_LABEL_KEY_LENGTH   = 63
+	MAX_LABEL_VALUE_LENGTH = 255
+	MAX_OWNER_LENGTH       = 255
+	MAX_DESCRIPTION_LENGTH = 4096
+)
+
+var labelKeyRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.\-/]*$`)
+
+// Labels are arbitrary key/value pairs stored as JSONB.
+type Labels map[string]string
+
+func (l Labels) Value() (driver.Value, error) {
+	if l == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (l *Labels) Scan(src any) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*l = Labels{}
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return fmt.Errorf("unsupported labels type %T", src)
+	}
+
+	labels := Labels{}
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return err
+	}
+	*l = labels
+	return nil
+}
+
+// Validate checks amount of labels, format of keys and length of values.
+func (l Labels) Validate() []tiny_errors.ErrorOption {
+	var options []tiny_errors.ErrorOption
+	if len(l) > MAX_LABELS {
+		options = append(options, tiny_errors.Detail("labels", fmt.Sprintf("should contain at most %d labels", MAX_LABELS)))
+	}
+
+	for _, key := range l.keys() {
+		if len(key) > MAX_LABEL_KEY_LENGTH || !labelKeyRegexp.MatchString(key) {
+			options = append(options, tiny_errors.Detail("labels."+key, fmt.Sprintf(
+				"key should start with a letter or digit, contain only letters, digits, '_', '.', '-', '/' and be at most %d characters long",
+				MAX_LABEL_KEY_LENGTH,
+			)))
+			continue
+		}
+		if len(l[key]) > MAX_LABEL_VALUE_LENGTH {
+			options = append(options, tiny_errors.Detail("labels."+key, fmt.Sprintf("value should be at most %d characters long", MAX_LABEL_VALUE_LENGTH)))
+		}
+	}
+
+	return options
+}
+
+func (l Labels) keys() []string {
+	keys := make([]string, 0, len(l))
+	for key := range l {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseLabels parses labels from "key=value,key2=value2" format used in query strings.
+func ParseLabels(value *string) (Labels, tiny_errors.ErrorHandler) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+
+	labels := Labels{}
+	for _, pair := range strings.Split(*value, ",") {
+		key, labelValue, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("labels", "should be in key=value,key2=value2 format"))
+		}
+		labels[key] = strings.TrimSpace(labelValue)
+	}
+
+	if options := labels.Validate(); len(options) > 0 {
+		return nil, tiny_errors.New(custom_errors.ERR_CODE_NotValid, options...)
+	}
+
+	return labels, nil
+}
+
+// Metadata describes who owns an item and what it is used for.
+type Metadata struct {
+	Description *string `json:"description" db:"description"`
+	OwnerTeam   *string `json:"owner_team" db:"owner_team"`
+	Contact     *string `json:"contact" db:"contact"`
+	Labels      Labels  `json:"labels" db:"labels"`
+}
+
+// MetadataUpdate contains metadata fields which should be changed. Nil fields stay untouched,
+// empty strings clear the value and provided labels replace existing ones.
+type MetadataUpdate struct {
+	Description *string `json:"description"`
+	OwnerTeam   *string `json:"owner_team"`
+	Contact     *string `json:"contact"`
+	Labels      Labels  `json:"labels"`
+}
+
+// IsEmpty returns true if there is nothing to update.
+func (m *MetadataUpdate) IsEmpty() bool {
+	return m == nil || (m.Description == nil && m.OwnerTeam == nil && m.Contact == nil && m.Labels == nil)
+}
+
+func (m *MetadataUpdate) Validate() tiny_errors.ErrorHandler {
+	if m == nil {
+		return nil
+	}
+
+	var options []tiny_errors.ErrorOption
+	if m.Description != nil && len(*m.Description) > MAX_DESCRIPTION_LENGTH {
+		options = append(options, tiny_errors.Detail("description", fmt.Sprintf("should be at most %d characters long", MAX_DESCRIPTION_LENGTH)))
+	}
+	if m.OwnerTeam != nil && len(*m.OwnerTeam) > MAX_OWNER_LENGTH {
+		options = append(options, tiny_errors.Detail("owner_team", fmt.Sprintf("should be at most %d characters long", MAX_OWNER_LENGTH)))
+	}
+	if m.Contact != nil && len(*m.Contact) > MAX_OWNER_LENGTH {
+		options = append(options, tiny_errors.Detail("contact", fmt.Sprintf("should be at most %d characters long", MAX_OWNER_LENGTH)))
+	}
+	options = append(options, m.Labels.Validate()...)
+
+	if len(options) > 0 {
+		return tiny_errors.New(custom_errors.ERR_CODE_NotValid, options...)
+	}
+	return nil
+}
+
+// Apply adds provided fields to the update query.
+func (m *MetadataUpdate) Apply(q *UpdateQuery) {
+	if m == nil {
+		return
+	}
+
+	fields := []struct {
+		column string
+		value  *string
+	}{
+		{column: "description", value: m.Description},
+		{column: "owner_team", value: m.OwnerTeam},
+		{column: "contact", value: m.Contact},
+	}
+
+	for _, field := range fields {
+		if field.value == nil {
+			continue
+		}
+		if *field.value == "" {
+			q.Set(field.column, nil)
+		} else {
+			q.Set(field.column, *field.value)
+		}
+	}
+
+	if m.Labels != nil {
+		q.Set("labels", m.Labels)
+	}
+}
+
+// MetadataFilter filters items by owner team and labels. Items should contain all provided labels.
+type MetadataFilter struct {
+	OwnerTeam *string
+	Labels    Labels
+}
+
+func (f *MetadataFilter) Apply(q *ListQuery) {
+	if f == nil {
+		return
+	}
+
+	if f.OwnerTeam != nil && *f.OwnerTeam != "" {
+		q.Where("owner_team = ?", *f.OwnerTeam)
+	}
+
+	if len(f.Labels) > 0 {
+		labels, _ := f.Labels.Value()
+		q.Where("labels @> ?::jsonb", labels)
+	}
+}
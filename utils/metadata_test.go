@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLabels(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+
+	labels, err := ParseLabels(MakePointer("env=prod, team=core"))
+	assert.Nil(t, err)
+	assert.Equal(t, Labels{"env": "prod", "team": "core"}, labels)
+
+	labels, err = ParseLabels(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, labels)
+
+	_, err = ParseLabels(MakePointer("env"))
+	assert.NotNil(t, err)
+	assert.Equal(t, custom_errors.ERR_CODE_NotValid, err.GetCode())
+
+	_, err = ParseLabels(MakePointer("-env=prod"))
+	assert.NotNil(t, err)
+}
+
+func TestLabels_Scan(t *testing.T) {
+	var labels Labels
+	assert.NoError(t, labels.Scan([]byte(`{"env":"prod"}`)))
+	assert.Equal(t, Labels{"env": "prod"}, labels)
+
+	assert.NoError(t, labels.Scan(nil))
+	assert.Equal(t, Labels{}, labels)
+
+	assert.Error(t, labels.Scan(10))
+
+	value, err := Labels(nil).Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", value)
+}
+
+func TestMetadataUpdate_Apply(t *testing.T) {
+	update := &MetadataUpdate{
+		Description: MakePointer(""),
+		OwnerTeam:   MakePointer("platform"),
+		Labels:      Labels{"env": "prod"},
+	}
+
+	q := NewUpdateQuery("files").SetRaw("updated_at = now()")
+	update.Apply(q)
+	q.Where("id = ?", "123").Returning("id", "name")
+
+	assert.Equal(t, "UPDATE files SET updated_at = now(), description = $1, owner_team = $2, labels = $3 WHERE id = $4 RETURNING id, name", q.String())
+	assert.Equal(t, []any{nil, "platform", Labels{"env": "prod"}, "123"}, q.Args())
+	assert.False(t, update.IsEmpty())
+	assert.True(t, (&MetadataUpdate{}).IsEmpty())
+}
+
+func TestMetadataFilter_Apply(t *testing.T) {
+	filter := &MetadataFilter{OwnerTeam: MakePointer("platform"), Labels: Labels{"env": "prod"}}
+
+	q := NewListQuery("SELECT id FROM files")
+	filter.Apply(q)
+
+	assert.Equal(t, "SELECT id FROM files WHERE owner_team = $1 AND labels @> $2::jsonb", q.String())
+	assert.Equal(t, []any{"platform", `{"env":"prod"}`}, q.Args())
+}
@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/http-utils/tiny_errors"
+)
+
+const (
+	DEFAULT_LIMIT = 50
+	MAX_LIMIT     = 500
+
+	ORDER_ASC  = "ASC"
+	ORDER_DESC = "DESC"
+)
+
+// ListFilter describes pagination and filtering options shared by all list queries.
+//
+// A nil Limit means "no limit" and is used by internal callers which need the full list.
+type ListFilter struct {
+	Limit         *int
+	Cursor        *string
+	NamePrefix    *string
+	CreatedAfter  *string
+	CreatedBefore *string
+	UpdatedAfter  *string
+	UpdatedBefore *string
+
+	// order is set by Apply and stored in cursors, so they are not used with another order.
+	order string
+}
+
+// ListColumns maps filter fields to SQL expressions of a specific table.
+type ListColumns struct {
+	ID        string
+	Name      string
+	CreatedAt string
+	UpdatedAt string
+}
+
+type cursor struct {
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// CursorOrder returns the order of a list kept in its cursors, e.g. `name DESC`.
+func CursorOrder(orderExpression, orderType string) string {
+	return orderExpression + " " + orderType
+}
+
+// EncodeCursor builds an opaque cursor from the order of the list and the sort value and id of the last returned row.
+func EncodeCursor(order, value, id string) string {
+	data, _ := json.Marshal(cursor{Order: order, Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor created by EncodeCursor. Cursors of the list sorted in another order are not valid,
+// because their values belong to another column or direction.
+func DecodeCursor(value string, order string) (string, string, tiny_errors.ErrorHandler) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", "", tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("cursor", "not valid"))
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return "", "", tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("cursor", "not valid"))
+	}
+	if c.Order != order {
+		return "", "", tiny_errors.New(custom_errors.ERR_CODE_NotValid, tiny_errors.Detail("cursor", "belongs to a list with another order_column or order_type"))
+	}
+
+	return c.Value, c.ID, nil
+}
+
+// ParseLimit converts limit from query string. Empty value returns DEFAULT_LIMIT.
+func ParseLimit(value *string) (int, tiny_errors.ErrorHandler) {
+	if value == nil || *value == "" {
+		return DEFAULT_LIMIT, nil
+	}
+
+	limit, err := strconv.Atoi(*value)
+	if err != nil || limit <= 0 || limit > MAX_LIMIT {
+		return 0, tiny_errors.New(
+			custom_errors.ERR_CODE_NotValid,
+			tiny_errors.Detail("limit", fmt.Sprintf("should be a number between 1 and %d", MAX_LIMIT)),
+		)
+	}
+
+	return limit, nil
+}
+
+// ValidateTimeFilters checks that provided time bounds are in RFC3339 format.
+func ValidateTimeFilters(filter *ListFilter) tiny_errors.ErrorHandler {
+	if filter == nil {
+		return nil
+	}
+
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{name: "created_after", value: filter.CreatedAfter},
+		{name: "created_before", value: filter.CreatedBefore},
+		{name: "updated_after", value: filter.UpdatedAfter},
+		{name: "updated_before", value: filter.UpdatedBefore},
+	}
+
+	var options []tiny_errors.ErrorOption
+	for _, field := range fields {
+		if field.value == nil {
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339, *field.value); err != nil {
+			options = append(options, tiny_errors.Detail(field.name, "should be in RFC3339 format"))
+		}
+	}
+
+	if len(options) > 0 {
+		return tiny_errors.New(custom_errors.ERR_CODE_NotValid, options...)
+	}
+
+	return nil
+}
+
+// OrderType normalizes order direction. Anything except DESC is treated as ASC.
+func OrderType(value *string) string {
+	if value != nil && strings.ToUpper(*value) == ORDER_DESC {
+		return ORDER_DESC
+	}
+	return ORDER_ASC
+}
+
+// OrderColumn returns SQL expression for requested column. If column is not in allowed
+// list, it returns an error, when column is not provided - defaultColumn is used.
+func OrderColumn(value *string, allowed map[string]string, defaultColumn string) (string, string, tiny_errors.ErrorHandler) {
+	if value == nil || *value == "" {
+		return defaultColumn, allowed[defaultColumn], nil
+	}
+
+	expression, ok := allowed[*value]
+	if !ok {
+		columns := make([]string, 0, len(allowed))
+		for name := range allowed {
+			columns = append(columns, name)
+		}
+		sort.Strings(columns)
+		return "", "", tiny_errors.New(
+			custom_errors.ERR_CODE_NotValid,
+			tiny_errors.Detail("order_column", "allowed values: "+strings.Join(columns, ", ")),
+		)
+	}
+
+	return *value, expression, nil
+}
+
+// ListQuery is a small builder for list queries with positional arguments.
+// Use "?" as a placeholder inside conditions, it will be replaced with $N.
+type ListQuery struct {
+	base       string
+	conditions []string
+	args       []any
+	order      []string
+	limit      int
+}
+
+func NewListQuery(base string) *ListQuery {
+	return &ListQuery{base: base}
+}
+
+func (q *ListQuery) Where(condition string, args ...any) *ListQuery {
+	q.args, condition = bindArgs(q.args, condition, args)
+	q.conditions = append(q.conditions, condition)
+	return q
+}
+
+func (q *ListQuery) Order(expression, orderType string) *ListQuery {
+	q.order = append(q.order, expression+" "+orderType)
+	return q
+}
+
+func (q *ListQuery) Limit(limit int) *ListQuery {
+	q.limit = limit
+	return q
+}
+
+func (q *ListQuery) Args() []any {
+	return q.args
+}
+
+func (q *ListQuery) String() string {
+	var b strings.Builder
+	b.WriteString(q.base)
+	if len(q.conditions) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(q.conditions, " AND "))
+	}
+	if len(q.order) > 0 {
+		b.WriteString(" ORDER BY ")
+		b.WriteString(strings.Join(q.order, ", "))
+	}
+	if q.limit > 0 {
+		b.WriteString(fmt.Sprintf(" LIMIT %d", q.limit))
+	}
+	return b.String()
+}
+
+// Apply adds filter conditions, keyset condition for cursor, order and limit to the query.
+//
+// Limit is increased by one to find out whether there is a next page, use NextCursor to trim the result.
+func (f *ListFilter) Apply(q *ListQuery, columns ListColumns, orderExpression, orderType string) tiny_errors.ErrorHandler {
+	if f != nil {
+		f.order = CursorOrder(orderExpression, orderType)
+		if f.NamePrefix != nil && *f.NamePrefix != "" && columns.Name != "" {
+			q.Where(columns.Name+" LIKE ?", escapeLike(*f.NamePrefix)+"%")
+		}
+		if f.CreatedAfter != nil {
+			q.Where(columns.CreatedAt+" >= ?", *f.CreatedAfter)
+		}
+		if f.CreatedBefore != nil {
+			q.Where(columns.CreatedAt+" < ?", *f.CreatedBefore)
+		}
+		if f.UpdatedAfter != nil {
+			q.Where(columns.UpdatedAt+" >= ?", *f.UpdatedAfter)
+		}
+		if f.UpdatedBefore != nil {
+			q.Where(columns.UpdatedAt+" < ?", *f.UpdatedBefore)
+		}
+		if f.Cursor != nil && *f.Cursor != "" {
+			value, id, err := DecodeCursor(*f.Cursor, f.order)
+			if err != nil {
+				return err
+			}
+			operator := ">"
+			if orderType == ORDER_DESC {
+				operator = "<"
+			}
+			q.Where(fmt.Sprintf("(%s, %s) %s (?, ?)", orderExpression, columns.ID, operator), value, id)
+		}
+	}
+
+	q.Order(orderExpression, orderType).Order(columns.ID, orderType)
+
+	if f != nil && f.Limit != nil {
+		q.Limit(*f.Limit + 1)
+	}
+	return nil
+}
+
+// NextCursor trims items to the requested limit and returns cursor for the next page.
+// cursorValue should return the sort value and id of the provided item. The filter should be applied to the query first.
+func NextCursor[T any](f *ListFilter, items []T, cursorValue func(T) (string, string)) ([]T, *string) {
+	if f == nil || f.Limit == nil || len(items) <= *f.Limit {
+		return items, nil
+	}
+
+	items = items[:*f.Limit]
+	value, id := cursorValue(items[len(items)-1])
+	next := EncodeCursor(f.order, value, id)
+	return items, &next
+}
+
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/Moranilt/config-keeper/custom_errors"
+	"github.com/Moranilt/http-utils/tiny_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+
+	order := CursorOrder("created_at", ORDER_DESC)
+	cursor := EncodeCursor(order, "2020-01-01T00:00:00Z", "id_1")
+	value, id, err := DecodeCursor(cursor, order)
+	assert.Nil(t, err)
+	assert.Equal(t, "2020-01-01T00:00:00Z", value)
+	assert.Equal(t, "id_1", id)
+
+	_, _, err = DecodeCursor("not valid cursor", order)
+	assert.NotNil(t, err)
+	assert.Equal(t, custom_errors.ERR_CODE_NotValid, err.GetCode())
+
+	_, _, err = DecodeCursor(cursor, CursorOrder("created_at", ORDER_ASC))
+	assert.NotNil(t, err)
+	assert.Equal(t, custom_errors.ERR_CODE_NotValid, err.GetCode())
+
+	_, _, err = DecodeCursor(cursor, CursorOrder("name", ORDER_DESC))
+	assert.NotNil(t, err)
+	assert.Equal(t, custom_errors.ERR_CODE_NotValid, err.GetCode())
+}
+
+func TestParseLimit(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+
+	tests := []struct {
+		name          string
+		value         *string
+		expectedLimit int
+		expectedError bool
+	}{
+		{name: "empty value", value: nil, expectedLimit: DEFAULT_LIMIT},
+		{name: "valid value", value: MakePointer("10"), expectedLimit: 10},
+		{name: "not a number", value: MakePointer("ten"), expectedError: true},
+		{name: "zero", value: MakePointer("0"), expectedError: true},
+		{name: "greater than max", value: MakePointer("501"), expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, err := ParseLimit(tt.value)
+			if tt.expectedError {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedLimit, limit)
+		})
+	}
+}
+
+func TestListFilter_Apply(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+
+	columns := ListColumns{ID: "id", Name: "name", CreatedAt: "created_at", UpdatedAt: "updated_at"}
+	filter := &ListFilter{
+		Limit:         MakePointer(10),
+		Cursor:        MakePointer(EncodeCursor("name DESC", "b", "id_2")),
+		NamePrefix:    MakePointer("a_%"),
+		CreatedBefore: MakePointer("2020-01-01T00:00:00Z"),
+	}
+
+	q := NewListQuery("SELECT id FROM folders").Where("parent_id = ?", "parent")
+	err := filter.Apply(q, columns, "name", ORDER_DESC)
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		"SELECT id FROM folders WHERE parent_id = $1 AND name LIKE $2 AND created_at < $3 AND (name, id) < ($4, $5) ORDER BY name DESC, id DESC LIMIT 11",
+		q.String(),
+	)
+	assert.Equal(t, []any{"parent", `a\_\%%`, "2020-01-01T00:00:00Z", "b", "id_2"}, q.Args())
+
+	items, next := NextCursor(filter, make([]string, 11), func(item string) (string, string) {
+		return "value", "id"
+	})
+	assert.Len(t, items, 10)
+	assert.Equal(t, EncodeCursor("name DESC", "value", "id"), *next)
+
+	items, next = NextCursor(filter, make([]string, 3), func(item string) (string, string) {
+		return "value", "id"
+	})
+	assert.Len(t, items, 3)
+	assert.Nil(t, next)
+}
+
+func TestOrderColumn(t *testing.T) {
+	tiny_errors.Init(custom_errors.ERRORS)
+	allowed := map[string]string{"name": "f.name", "created_at": "f.created_at"}
+
+	column, expression, err := OrderColumn(nil, allowed, "name")
+	assert.Nil(t, err)
+	assert.Equal(t, "name", column)
+	assert.Equal(t, "f.name", expression)
+
+	column, expression, err = OrderColumn(MakePointer("created_at"), allowed, "name")
+	assert.Nil(t, err)
+	assert.Equal(t, "created_at", column)
+	assert.Equal(t, "f.created_at", expression)
+
+	_, _, err = OrderColumn(MakePointer("password"), allowed, "name")
+	assert.NotNil(t, err)
+	assert.Equal(t, map[string]string{"order_column": "allowed values: created_at, name"}, err.GetDetails())
+
+	assert.Equal(t, ORDER_DESC, OrderType(MakePointer("desc")))
+	assert.Equal(t, ORDER_ASC, OrderType(MakePointer("random")))
+	assert.Equal(t, ORDER_ASC, OrderType(nil))
+}
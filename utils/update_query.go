@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UpdateQuery is a small builder for UPDATE queries with positional arguments.
+// Use "?" as a placeholder inside conditions, it will be replaced with $N.
+type UpdateQuery struct {
+	table      string
+	sets       []string
+	conditions []string
+	args       []any
+	returning  []string
+}
+
+func NewUpdateQuery(table string) *UpdateQuery {
+	return &UpdateQuery{table: table}
+}
+
+// Set adds "column = $N" to the query, value is passed as an argument.
+func (q *UpdateQuery) Set(column string, value any) *UpdateQuery {
+	q.args = append(q.args, value)
+	q.sets = append(q.sets, fmt.Sprintf("%s = $%d", column, len(q.args)))
+	return q
+}
+
+// SetRaw adds expression as is, e.g. "updated_at = now()".
+func (q *UpdateQuery) SetRaw(expression string) *UpdateQuery {
+	q.sets = append(q.sets, expression)
+	return q
+}
+
+func (q *UpdateQuery) Where(condition string, args ...any) *UpdateQuery {
+	q.args, condition = bindArgs(q.args, condition, args)
+	q.conditions = append(q.conditions, condition)
+	return q
+}
+
+func (q *UpdateQuery) Returning(columns ...string) *UpdateQuery {
+	q.returning = append(q.returning, columns...)
+	return q
+}
+
+func (q *UpdateQuery) Args() []any {
+	return q.args
+}
+
+func (q *UpdateQuery) String() string {
+	var b strings.Builder
+	b.WriteString("UPDATE ")
+	b.WriteString(q.table)
+	b.WriteString(" SET ")
+	b.WriteString(strings.Join(q.sets, ", "))
+	if len(q.conditions) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(q.conditions, " AND "))
+	}
+	if len(q.returning) > 0 {
+		b.WriteString(" RETURNING ")
+		b.WriteString(strings.Join(q.returning, ", "))
+	}
+	return b.String()
+}
+
+// bindArgs appends args and replaces "?" placeholders in condition with their positions.
+func bindArgs(current []any, condition string, args []any) ([]any, string) {
+	for _, arg := range args {
+		current = append(current, arg)
+		condition = strings.Replace(condition, "?", fmt.Sprintf("$%d", len(current)), 1)
+	}
+	return current, condition
+}